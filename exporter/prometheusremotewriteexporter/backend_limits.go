@@ -0,0 +1,163 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+// BackendLimits describes the limits the remote write endpoint enforces on the series it receives, so that the
+// series it would reject aren't sent. 0 means the limit isn't enforced.
+type BackendLimits struct {
+	// MaxLabelsPerSeries is the maximum number of labels of a series, its metric name included.
+	MaxLabelsPerSeries int `mapstructure:"max_labels_per_series"`
+
+	// LabelsPerSeriesPolicy controls the series with more labels than MaxLabelsPerSeries: "drop" drops them, and
+	// "truncate" keeps their metric name and removes the labels sorting last. It defaults to "drop".
+	LabelsPerSeriesPolicy string `mapstructure:"labels_per_series_policy"`
+
+	// MaxLabelNameBytes is the maximum length of a label name.
+	MaxLabelNameBytes int `mapstructure:"max_label_name_bytes"`
+
+	// MaxLabelValueBytes is the maximum length of a label value, the metric name included. The metric names are
+	// shortened to Config.MaxMetricNameBytes first, and their series are dropped if they are still longer.
+	MaxLabelValueBytes int `mapstructure:"max_label_value_bytes"`
+
+	// MaxSeriesPerRequest is an alias of Config.MaxSeriesPerRequest, which is set to it, so that every limit of
+	// the endpoint can be set here.
+	MaxSeriesPerRequest int `mapstructure:"max_series_per_request"`
+}
+
+// The limits reported in the telemetry of the exporter when they are violated.
+const (
+	limitMaxLabelsPerSeries  = "max_labels_per_series"
+	limitMaxLabelNameBytes   = "max_label_name_bytes"
+	limitMaxLabelValueBytes  = "max_label_value_bytes"
+	limitMaxSeriesPerRequest = "max_series_per_request"
+)
+
+const (
+	// labelsPerSeriesPolicyDrop drops the series with too many labels. It is the default.
+	labelsPerSeriesPolicyDrop = "drop"
+	// labelsPerSeriesPolicyTruncate removes the labels of the series with too many labels.
+	labelsPerSeriesPolicyTruncate = "truncate"
+)
+
+// droppedLabelsLabel is the label holding the number of labels removed from a truncated series, when
+// record_dropped_label_count is set.
+const droppedLabelsLabel = "__dropped_labels__"
+
+// limitSeries truncates or drops the series of tsMap violating the label limits, counting each of them once under
+// the first limit it violates, and records the violations. The truncated series are dropped, and counted again,
+// if they still violate a limit or if they were truncated to the labels of another series, which they would be
+// sent as a duplicate of.
+func (prwe *prwExporter) limitSeries(ctx context.Context, tsMap map[string]*prompb.TimeSeries) {
+	violations := map[string]int{}
+	var truncated []string
+	for key, ts := range tsMap {
+		limit := prwe.backendLimits.violatedLabelLimit(ts.Labels)
+		if limit == limitMaxLabelsPerSeries && prwe.backendLimits.LabelsPerSeriesPolicy == labelsPerSeriesPolicyTruncate {
+			violations[limit]++
+			ts.Labels = prwe.backendLimits.truncateLabels(ts.Labels, prwe.recordDroppedLabelCount)
+			limit = prwe.backendLimits.violatedLabelLimit(ts.Labels)
+			if limit == "" {
+				truncated = append(truncated, key)
+			}
+		}
+		if limit != "" {
+			violations[limit]++
+			delete(tsMap, key)
+		}
+	}
+	if len(truncated) > 0 {
+		violations[limitMaxLabelsPerSeries] += dropTruncatedDuplicates(tsMap, truncated)
+	}
+	for limit, numSeries := range violations {
+		prwe.telemetry.recordLimitViolations(ctx, limit, numSeries)
+	}
+}
+
+// dropTruncatedDuplicates drops the series of tsMap, among those whose keys are truncated, having the same labels
+// as another series, and returns their number. The series that weren't truncated are kept, and so is the truncated
+// series with the first key, so that the same one is kept from a batch to the next.
+func dropTruncatedDuplicates(tsMap map[string]*prompb.TimeSeries, truncated []string) int {
+	isTruncated := make(map[string]bool, len(truncated))
+	for _, key := range truncated {
+		isTruncated[key] = true
+	}
+	seen := make(map[uint64]bool, len(tsMap))
+	for key, ts := range tsMap {
+		if !isTruncated[key] {
+			seen[labelsHash(ts.Labels)] = true
+		}
+	}
+	sort.Strings(truncated)
+	dropped := 0
+	for _, key := range truncated {
+		hash := labelsHash(tsMap[key].Labels)
+		if seen[hash] {
+			delete(tsMap, key)
+			dropped++
+			continue
+		}
+		seen[hash] = true
+	}
+	return dropped
+}
+
+// violatedLabelLimit returns the first label limit violated by labels, or an empty string if there is none.
+func (l *BackendLimits) violatedLabelLimit(labels []prompb.Label) string {
+	if l.MaxLabelsPerSeries > 0 && len(labels) > l.MaxLabelsPerSeries {
+		return limitMaxLabelsPerSeries
+	}
+	for _, label := range labels {
+		if l.MaxLabelNameBytes > 0 && len(label.Name) > l.MaxLabelNameBytes {
+			return limitMaxLabelNameBytes
+		}
+		if l.MaxLabelValueBytes > 0 && len(label.Value) > l.MaxLabelValueBytes {
+			return limitMaxLabelValueBytes
+		}
+	}
+	return ""
+}
+
+// truncateLabels returns the metric name and the first other labels of labels, up to MaxLabelsPerSeries of them.
+// When recordCount is set, one of them is the number of labels removed, in the droppedLabelsLabel label.
+func (l *BackendLimits) truncateLabels(labels []prompb.Label, recordCount bool) []prompb.Label {
+	kept := make([]prompb.Label, 0, l.MaxLabelsPerSeries)
+	others := l.MaxLabelsPerSeries
+	for _, label := range labels {
+		if label.Name == model.MetricNameLabel {
+			kept = append(kept, label)
+			others--
+		}
+	}
+	if recordCount {
+		others--
+	}
+	for _, label := range labels {
+		if label.Name != model.MetricNameLabel && others > 0 {
+			kept = append(kept, label)
+			others--
+		}
+	}
+	if recordCount {
+		// The count label replaces one of the labels it counts.
+		kept = append(kept, prompb.Label{Name: droppedLabelsLabel, Value: strconv.Itoa(len(labels) - len(kept))})
+	}
+	sort.Sort(prometheusremotewrite.ByLabelName(kept))
+	return kept
+}
+
+// hasLabelLimits reports whether any of the label limits is enforced.
+func (l *BackendLimits) hasLabelLimits() bool {
+	return l.MaxLabelsPerSeries > 0 || l.MaxLabelNameBytes > 0 || l.MaxLabelValueBytes > 0
+}
@@ -0,0 +1,202 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// limitViolationsTelemetry sums the recorded limit violations by limit, and discards the rest of the telemetry.
+type limitViolationsTelemetry struct {
+	nopTelemetry
+	violations map[string]int
+}
+
+func (t *limitViolationsTelemetry) recordLimitViolations(_ context.Context, limit string, numViolations int) {
+	t.violations[limit] += numViolations
+}
+
+func TestPushMetricsBackendLimits(t *testing.T) {
+	gauge := func(name string, attrs map[string]string) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName(name)
+		dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetDoubleValue(1)
+		for k, v := range attrs {
+			dp.Attributes().PutStr(k, v)
+		}
+		return metric
+	}
+	md := getMetricsFromMetricList(
+		gauge("first", map[string]string{"job": "a"}),
+		gauge("second", map[string]string{"job": "a"}),
+		gauge("third", map[string]string{"job": "a"}),
+		gauge("many_labels", map[string]string{"job": "a", "env": "b", "zone": "c"}),
+		gauge("long_name", map[string]string{"a_long_label_name": "a"}),
+		gauge("long_value", map[string]string{"job": strings.Repeat("a", 21)}),
+	)
+
+	var requests []*prompb.WriteRequest
+	sink := ExportSinkFunc(func(_ context.Context, reqs []*prompb.WriteRequest) error {
+		requests = append(requests, reqs...)
+		return nil
+	})
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.BackendLimits = &BackendLimits{
+		MaxLabelsPerSeries:  3,
+		MaxLabelNameBytes:   10,
+		MaxLabelValueBytes:  20,
+		MaxSeriesPerRequest: 2,
+	}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	telemetry := &limitViolationsTelemetry{violations: map[string]int{}}
+	prwe.telemetry = telemetry
+	require.NoError(t, prwe.PushMetrics(context.Background(), md))
+
+	var names []string
+	for _, req := range requests {
+		assert.LessOrEqual(t, len(req.Timeseries), 2)
+		for _, ts := range req.Timeseries {
+			names = append(names, ts.Labels[0].Value)
+		}
+	}
+	assert.ElementsMatch(t, []string{"first", "second", "third"}, names)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, map[string]int{
+		limitMaxLabelsPerSeries:  1,
+		limitMaxLabelNameBytes:   1,
+		limitMaxLabelValueBytes:  1,
+		limitMaxSeriesPerRequest: 1,
+	}, telemetry.violations)
+}
+
+func TestBackendLimitsMaxSeriesPerRequest(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.BackendLimits = &BackendLimits{MaxSeriesPerRequest: 500}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, 500, cfg.MaxSeriesPerRequest)
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	assert.Equal(t, 500, prwe.maxSeriesPerRequest)
+
+	// The alias can be set to the same value.
+	require.NoError(t, cfg.Validate())
+
+	cfg.MaxSeriesPerRequest = 100
+	assert.EqualError(t, cfg.Validate(),
+		"backend_limits max_series_per_request is an alias of max_series_per_request, they can't be set to different values")
+}
+
+func TestPushMetricsTruncateLabels(t *testing.T) {
+	metric := pmetric.NewMetric()
+	metric.SetName("many_labels")
+	dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetDoubleValue(1)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		dp.Attributes().PutStr(name, "value")
+	}
+
+	tests := []struct {
+		name                    string
+		recordDroppedLabelCount bool
+		wantLabels              []prompb.Label
+	}{
+		{
+			name: "truncate",
+			wantLabels: []prompb.Label{
+				{Name: "__name__", Value: "many_labels"},
+				{Name: "a", Value: "value"},
+				{Name: "b", Value: "value"},
+				{Name: "c", Value: "value"},
+			},
+		},
+		{
+			name:                    "record dropped label count",
+			recordDroppedLabelCount: true,
+			wantLabels: []prompb.Label{
+				{Name: "__dropped_labels__", Value: "3"},
+				{Name: "__name__", Value: "many_labels"},
+				{Name: "a", Value: "value"},
+				{Name: "b", Value: "value"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exported []prompb.TimeSeries
+			sink := ExportSinkFunc(func(_ context.Context, reqs []*prompb.WriteRequest) error {
+				for _, req := range reqs {
+					exported = append(exported, req.Timeseries...)
+				}
+				return nil
+			})
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.BackendLimits = &BackendLimits{MaxLabelsPerSeries: 4, LabelsPerSeriesPolicy: labelsPerSeriesPolicyTruncate}
+			cfg.RecordDroppedLabelCount = tt.recordDroppedLabelCount
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			telemetry := &limitViolationsTelemetry{violations: map[string]int{}}
+			prwe.telemetry = telemetry
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric)))
+
+			require.Len(t, exported, 1)
+			assert.Equal(t, tt.wantLabels, exported[0].Labels)
+			assert.Equal(t, map[string]int{limitMaxLabelsPerSeries: 1}, telemetry.violations)
+		})
+	}
+}
+
+func TestPushMetricsTruncateLabelsDuplicates(t *testing.T) {
+	metric := pmetric.NewMetric()
+	metric.SetName("many_labels")
+	dps := metric.SetEmptyGauge().DataPoints()
+	// The series only differ in a label that is truncated.
+	for _, value := range []string{"1", "2"} {
+		dp := dps.AppendEmpty()
+		dp.SetDoubleValue(1)
+		for _, name := range []string{"a", "b", "c", "d"} {
+			dp.Attributes().PutStr(name, "value")
+		}
+		dp.Attributes().PutStr("e", value)
+	}
+
+	var exported []prompb.TimeSeries
+	sink := ExportSinkFunc(func(_ context.Context, reqs []*prompb.WriteRequest) error {
+		for _, req := range reqs {
+			exported = append(exported, req.Timeseries...)
+		}
+		return nil
+	})
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.BackendLimits = &BackendLimits{MaxLabelsPerSeries: 4, LabelsPerSeriesPolicy: labelsPerSeriesPolicyTruncate}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	telemetry := &limitViolationsTelemetry{violations: map[string]int{}}
+	prwe.telemetry = telemetry
+	require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric)))
+
+	require.Len(t, exported, 1, "the duplicate of the truncated series should be dropped")
+	assert.Equal(t, []prompb.Label{
+		{Name: "__name__", Value: "many_labels"},
+		{Name: "a", Value: "value"},
+		{Name: "b", Value: "value"},
+		{Name: "c", Value: "value"},
+	}, exported[0].Labels)
+	assert.Equal(t, map[string]int{limitMaxLabelsPerSeries: 3}, telemetry.violations)
+}
@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"sort"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// batchGroupingMixed packs the series of every resource together, by size. It is the default.
+	batchGroupingMixed = "mixed"
+	// batchGroupingByResource sends the series of every resource in their own requests.
+	batchGroupingByResource = "by_resource"
+)
+
+// groupByResource splits tsMap into the series of every resource, identified by their job and instance labels
+// as the target_info series are, ordered by resource.
+func groupByResource(tsMap map[string]*prompb.TimeSeries) []map[string]*prompb.TimeSeries {
+	groups := map[[2]string]map[string]*prompb.TimeSeries{}
+	for key, ts := range tsMap {
+		var resource [2]string
+		for _, label := range ts.Labels {
+			switch label.Name {
+			case model.JobLabel:
+				resource[0] = label.Value
+			case model.InstanceLabel:
+				resource[1] = label.Value
+			}
+		}
+		group, ok := groups[resource]
+		if !ok {
+			group = map[string]*prompb.TimeSeries{}
+			groups[resource] = group
+		}
+		group[key] = ts
+	}
+
+	resources := make([][2]string, 0, len(groups))
+	for resource := range groups {
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i][0] != resources[j][0] {
+			return resources[i][0] < resources[j][0]
+		}
+		return resources[i][1] < resources[j][1]
+	})
+	grouped := make([]map[string]*prompb.TimeSeries, 0, len(resources))
+	for _, resource := range resources {
+		grouped = append(grouped, groups[resource])
+	}
+	return grouped
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsBatchGrouping(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for _, service := range []string{"checkout", "payment", "cart"} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", service)
+		metrics := rm.ScopeMetrics().AppendEmpty().Metrics()
+		for _, name := range []string{"requests", "errors"} {
+			metric := metrics.AppendEmpty()
+			metric.SetName(name)
+			metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+		}
+	}
+
+	tests := []struct {
+		grouping string
+		// wantJobs holds the jobs of the series of every request.
+		wantJobs [][]string
+	}{
+		{
+			grouping: batchGroupingMixed,
+			wantJobs: [][]string{{"cart", "cart", "checkout", "checkout", "payment", "payment"}},
+		},
+		{
+			grouping: batchGroupingByResource,
+			wantJobs: [][]string{{"cart", "cart"}, {"checkout", "checkout"}, {"payment", "payment"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grouping, func(t *testing.T) {
+			var requests []*prompb.WriteRequest
+			sink := ExportSinkFunc(func(_ context.Context, reqs []*prompb.WriteRequest) error {
+				requests = append(requests, reqs...)
+				return nil
+			})
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.BatchGrouping = tt.grouping
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			require.NoError(t, prwe.PushMetrics(context.Background(), md))
+
+			var gotJobs [][]string
+			for _, req := range requests {
+				var jobs []string
+				for _, ts := range req.Timeseries {
+					for _, label := range ts.Labels {
+						if label.Name == model.JobLabel {
+							jobs = append(jobs, label.Value)
+						}
+					}
+				}
+				// The series of a request are in no particular order.
+				slices.Sort(jobs)
+				gotJobs = append(gotJobs, jobs)
+			}
+			assert.Equal(t, tt.wantJobs, gotJobs)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+// Coalesce configures accumulating the samples of the same series across PushMetrics calls, so that
+// they are sent, or written to the WAL, in a single request.
+type Coalesce struct {
+	// FlushInterval is the longest time samples are held before being sent.
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+
+	// MaxSamples is the number of held samples at which they are sent without waiting for the flush interval.
+	MaxSamples int `mapstructure:"max_samples"`
+}
+
+const (
+	defaultCoalesceFlushInterval       = 5 * time.Second
+	defaultCoalesceMaxSamples          = 50000
+	defaultHistogramCountFlushInterval = time.Second
+)
+
+// coalescer accumulates the translated series of successive pushes, keyed by the hash of their labels, since
+// the keys of the series returned by FromMetrics are only unique within a push. The samples of a series are
+// appended in the order they were added.
+type coalescer struct {
+	// flushMu serializes the flushes, so that the samples are sent in the order they were added.
+	flushMu sync.Mutex
+
+	mu         sync.Mutex // mu protects the fields below.
+	series     map[uint64]*prompb.TimeSeries
+	metadata   map[string]*prompb.MetricMetadata
+	samples    int
+	maxSamples int
+	// buffered is the number of series added since the last flush, counting a series once per push.
+	buffered int
+}
+
+func newCoalescer(cfg *Coalesce) *coalescer {
+	return &coalescer{
+		series:     make(map[uint64]*prompb.TimeSeries),
+		metadata:   make(map[string]*prompb.MetricMetadata),
+		maxSamples: cfg.MaxSamples,
+	}
+}
+
+// add accumulates the series and metadata, and returns whether the held samples reached the limit.
+func (c *coalescer) add(tsMap map[string]*prompb.TimeSeries, m []*prompb.MetricMetadata) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.buffered += len(tsMap)
+	for _, ts := range tsMap {
+		c.samples += len(ts.Samples) + len(ts.Histograms)
+		key := labelsHash(ts.Labels)
+		held, ok := c.series[key]
+		if !ok {
+			c.series[key] = ts
+			continue
+		}
+		held.Samples = append(held.Samples, ts.Samples...)
+		held.Histograms = append(held.Histograms, ts.Histograms...)
+		held.Exemplars = append(held.Exemplars, ts.Exemplars...)
+	}
+	for _, entry := range m {
+		c.metadata[entry.MetricFamilyName] = entry
+	}
+	return c.samples >= c.maxSamples
+}
+
+// take returns the held series and metadata, and the number of series added since the last flush, and
+// starts accumulating anew.
+func (c *coalescer) take() (map[string]*prompb.TimeSeries, []*prompb.MetricMetadata, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tsMap := make(map[string]*prompb.TimeSeries, len(c.series))
+	for key, ts := range c.series {
+		tsMap[strconv.FormatUint(key, 10)] = ts
+	}
+	var m []*prompb.MetricMetadata
+	if len(c.metadata) > 0 {
+		m = make([]*prompb.MetricMetadata, 0, len(c.metadata))
+		for _, entry := range c.metadata {
+			m = append(m, entry)
+		}
+		sort.Slice(m, func(i, j int) bool { return m[i].MetricFamilyName < m[j].MetricFamilyName })
+	}
+	c.series = make(map[uint64]*prompb.TimeSeries, len(tsMap))
+	c.metadata = make(map[string]*prompb.MetricMetadata, len(c.metadata))
+	buffered := c.buffered
+	c.samples, c.buffered = 0, 0
+	return tsMap, m, buffered
+}
+
+// coalesce adds the series to the coalescer, flushing it when it holds too many samples, or too many
+// series for the buffer and the buffer full policy is spill_to_wal. kinds tells the _count series of the histograms
+// apart for the histogram count fast path.
+func (prwe *prwExporter) coalesce(ctx context.Context, tsMap map[string]*prompb.TimeSeries, m []*prompb.MetricMetadata,
+	kinds map[string]sampleKind,
+) error {
+	if prwe.histogramCounts != nil {
+		if counts := splitHistogramCounts(tsMap, kinds); len(counts) > 0 && prwe.histogramCounts.add(counts, nil) {
+			if err := prwe.flushHistogramCounts(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	bufferFull := false
+	if prwe.seriesBuffer != nil {
+		if prwe.bufferFullPolicy == bufferFullPolicySpillToWAL {
+			bufferFull = !prwe.seriesBuffer.add(len(tsMap))
+		} else if err := prwe.seriesBuffer.acquire(ctx, len(tsMap)); err != nil {
+			return err
+		}
+	}
+	if prwe.coalescer.add(tsMap, m) || bufferFull {
+		return prwe.flushCoalesced(ctx)
+	}
+	return nil
+}
+
+// flushCoalesced sends the series held by the coalescer.
+func (prwe *prwExporter) flushCoalesced(ctx context.Context) error {
+	prwe.coalescer.flushMu.Lock()
+	defer prwe.coalescer.flushMu.Unlock()
+	tsMap, m, buffered := prwe.coalescer.take()
+	err := prwe.handleExport(ctx, tsMap, m)
+	if prwe.seriesBuffer != nil {
+		prwe.seriesBuffer.release(buffered)
+	}
+	return err
+}
+
+// flushHistogramCounts sends the histogram _count series held by the fast path coalescer.
+func (prwe *prwExporter) flushHistogramCounts(ctx context.Context) error {
+	prwe.histogramCounts.flushMu.Lock()
+	defer prwe.histogramCounts.flushMu.Unlock()
+	tsMap, _, _ := prwe.histogramCounts.take()
+	return prwe.handleExport(ctx, tsMap, nil)
+}
+
+// flushCoalescedPeriodically flushes the coalescer every flush interval until the exporter shuts down,
+// which flushes it one last time.
+func (prwe *prwExporter) flushCoalescedPeriodically(interval time.Duration) {
+	prwe.flushPeriodically(interval, prwe.flushCoalesced, "failed to flush the coalesced samples")
+}
+
+// flushHistogramCountsPeriodically flushes the histogram _count series every interval until the exporter
+// shuts down, which flushes them one last time.
+func (prwe *prwExporter) flushHistogramCountsPeriodically(interval time.Duration) {
+	prwe.flushPeriodically(interval, prwe.flushHistogramCounts, "failed to flush the histogram _count series")
+}
+
+func (prwe *prwExporter) flushPeriodically(interval time.Duration, flush func(context.Context) error, errMsg string) {
+	ticker := time.NewTicker(interval)
+	prwe.wg.Add(1)
+	go func() {
+		defer prwe.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-prwe.closeChan:
+				return
+			case <-ticker.C:
+				if err := flush(context.Background()); err != nil {
+					prwe.settings.Logger.Error(errMsg, zap.Error(err))
+				}
+			}
+		}
+	}()
+}
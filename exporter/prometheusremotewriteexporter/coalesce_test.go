@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+func TestCoalesceIntoFewWALEntries(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxSamples  int
+		wantEntries int
+	}{
+		{name: "flushed_on_shutdown", maxSamples: 1000, wantEntries: 1},
+		{name: "flushed_on_max_samples", maxSamples: 40, wantEntries: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.WAL = &WALConfig{Directory: dir}
+			cfg.Coalesce = &Coalesce{FlushInterval: time.Hour, MaxSamples: tt.maxSamples}
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			// The WAL is opened without exporting its entries, so that they can be inspected.
+			require.NoError(t, prwe.wal.retrieveWALIndices())
+
+			start := time.Unix(1700000000, 0)
+			for i := 0; i < 100; i++ {
+				gauge := pmetric.NewMetric()
+				gauge.SetName("coalesced_gauge")
+				dp := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+				dp.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Duration(i) * time.Second)))
+				dp.SetDoubleValue(float64(i))
+				require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(gauge)))
+			}
+			require.NoError(t, prwe.Shutdown(context.Background()))
+
+			pwal := newWAL(cfg.WAL, doNothingExportSink)
+			require.NoError(t, pwal.retrieveWALIndices())
+			t.Cleanup(func() {
+				assert.NoError(t, pwal.stop())
+			})
+			require.Equal(t, uint64(tt.wantEntries), pwal.wWALIndex.Load())
+
+			// The samples of the single series are kept in the order they were pushed.
+			var samples []prompb.Sample
+			for index := uint64(1); index <= pwal.wWALIndex.Load(); index++ {
+				req, err := pwal.readPrompbFromWAL(context.Background(), index)
+				require.NoError(t, err)
+				require.Len(t, req.Timeseries, 1)
+				samples = append(samples, req.Timeseries[0].Samples...)
+			}
+			require.Len(t, samples, 100)
+			for i, sample := range samples {
+				assert.Equal(t, float64(i), sample.Value)
+				assert.Equal(t, start.Add(time.Duration(i)*time.Second).UnixMilli(), sample.Timestamp)
+			}
+		})
+	}
+}
+
+func TestCoalesceKeepsSeriesApart(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.Coalesce = &Coalesce{FlushInterval: time.Hour, MaxSamples: 1000}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+
+	start := time.Unix(1700000000, 0)
+	names := []string{"first_gauge", "second_gauge", "third_gauge"}
+	for i := 0; i < 3; i++ {
+		metrics := pmetric.NewMetrics()
+		scopeMetrics := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+		// Every push holds the series in a different order.
+		for j := range names {
+			series := (i + j) % len(names)
+			gauge := scopeMetrics.Metrics().AppendEmpty()
+			gauge.SetName(names[series])
+			dp := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+			dp.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Duration(i) * time.Second)))
+			dp.SetDoubleValue(float64(100*series + i))
+		}
+		tsMap, err := prometheusremotewrite.FromMetrics(metrics, prwe.exporterSettings)
+		require.NoError(t, err)
+		prwe.coalescer.add(tsMap, nil)
+	}
+
+	tsMap, _, _ := prwe.coalescer.take()
+	require.Len(t, tsMap, len(names))
+	for _, ts := range tsMap {
+		series := slices.Index(names, ts.Labels[0].Value)
+		require.NotEqual(t, -1, series)
+		require.Len(t, ts.Samples, 3)
+		for i, sample := range ts.Samples {
+			assert.Equal(t, float64(100*series+i), sample.Value, "samples of %q should be kept apart, in order", names[series])
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"fmt"
+	"os"
+)
+
+// resolveCollectorID returns the value of the collector_id_label label: the configured collector ID, or the hostname
+// when it is empty.
+func resolveCollectorID(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect the hostname for collector_id_label, set collector_id instead: %w", err)
+	}
+	if hostname == "" {
+		return "", fmt.Errorf("the hostname is empty, set collector_id for collector_id_label")
+	}
+	return hostname, nil
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsCollectorIDLabel(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		collectorID string
+		want        string
+	}{
+		{name: "hostname", want: hostname},
+		{name: "configured", collectorID: "collector-1", want: "collector-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []prompb.TimeSeries
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					got = append(got, req.Timeseries...)
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.ExternalLabels = map[string]string{"cluster": "prod"}
+			cfg.CollectorIDLabel = "collector_id"
+			cfg.CollectorID = tt.collectorID
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			metric := pmetric.NewMetric()
+			metric.SetName("foo")
+			pt := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+			pt.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+			pt.SetDoubleValue(1)
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric)))
+
+			require.Len(t, got, 1)
+			for _, ts := range got {
+				assert.Contains(t, ts.Labels, prompb.Label{Name: "collector_id", Value: tt.want})
+				assert.Contains(t, ts.Labels, prompb.Label{Name: "cluster", Value: "prod"})
+			}
+		})
+	}
+}
+
+func TestCollectorIDLabelValidation(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.CollectorIDLabel = "collector.id"
+	assert.EqualError(t, cfg.Validate(), `collector_id_label: "collector.id" isn't a valid label name`)
+
+	cfg.CollectorIDLabel = "collector_id"
+	cfg.ExternalLabels = map[string]string{"collector.id": "collector-1"}
+	assert.EqualError(t, cfg.Validate(), `collector_id_label "collector_id" is also set in external_labels`)
+}
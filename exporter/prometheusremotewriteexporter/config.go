@@ -5,13 +5,19 @@ package prometheusremotewriteexporter // import "github.com/open-telemetry/opent
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"time"
 
+	"github.com/prometheus/common/model"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configretry"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/resourcetotelemetry"
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 )
 
 // Config defines configuration for Remote Write exporter.
@@ -19,6 +25,51 @@ type Config struct {
 	TimeoutSettings           exporterhelper.TimeoutConfig `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct.
 	configretry.BackOffConfig `mapstructure:"retry_on_failure"`
 
+	// FileArchive archives the requests to local files, in addition to or instead of sending them.
+	FileArchive *FileArchive `mapstructure:"file_archive,omitempty"`
+
+	// DeadLetter writes the batches that are dropped permanently to local files, for later inspection or replay.
+	DeadLetter *DeadLetter `mapstructure:"dead_letter,omitempty"`
+
+	// MaxBufferedSeries bounds the number of series held in memory while they are coalesced, or sent when the WAL is
+	// disabled, 0 means no limit.
+	MaxBufferedSeries int `mapstructure:"max_buffered_series"`
+
+	// BufferFullPolicy controls what happens to a push that doesn't fit in MaxBufferedSeries: "block" waits for
+	// the buffered series to be sent and "spill_to_wal" writes the coalesced series to the WAL right away.
+	BufferFullPolicy string `mapstructure:"buffer_full_policy"`
+
+	// MinFlushInterval is the minimum time between the starts of two requests sent by the same consumer, the
+	// requests waiting meanwhile, 0 means requests are sent as soon as a consumer is available
+	MinFlushInterval time.Duration `mapstructure:"min_flush_interval"`
+
+	// Coalesce accumulates the samples of the same series across pushes before sending them, nil means every
+	// push is sent on its own.
+	Coalesce *Coalesce `mapstructure:"coalesce,omitempty"`
+
+	// HistogramCountFastPath sends the _count series of the classic histograms every HistogramCountFlushInterval,
+	// while their _bucket and _sum series are coalesced, so that their rate is up to date even with a long flush
+	// interval
+	HistogramCountFastPath bool `mapstructure:"histogram_count_fast_path"`
+
+	// HistogramCountFlushInterval is the longest time the _count series of the histograms are held before being
+	// sent when HistogramCountFastPath is set, shorter than the flush interval of Coalesce
+	HistogramCountFlushInterval time.Duration `mapstructure:"histogram_count_flush_interval"`
+
+	// RetryBudget caps the rate of retries shared by all the requests, nil means retries aren't capped.
+	RetryBudget *RetryBudget `mapstructure:"retry_budget,omitempty"`
+
+	// RetryTimeoutMultiplier is the factor the timeout grows by on every retry of a request, 0 means every
+	// attempt gets the configured timeout
+	RetryTimeoutMultiplier float64 `mapstructure:"retry_timeout_multiplier"`
+
+	// MaxRetryTimeout caps the timeout of an attempt when RetryTimeoutMultiplier is set
+	MaxRetryTimeout time.Duration `mapstructure:"max_retry_timeout"`
+
+	// MaxRetries bounds the number of times a request is retried, in addition to the max_elapsed_time of
+	// retry_on_failure, 0 means retries are only bounded by time
+	MaxRetries int `mapstructure:"max_retries"`
+
 	// prefix attached to each exported metric name
 	// See: https://prometheus.io/docs/practices/naming/#metric-names
 	Namespace string `mapstructure:"namespace"`
@@ -30,11 +81,100 @@ type Config struct {
 	// ExternalLabels defines a map of label keys and values that are allowed to start with reserved prefix "__"
 	ExternalLabels map[string]string `mapstructure:"external_labels"`
 
+	// CollectorIDLabel is the name of a label added to every series, holding CollectorID, so that the collector
+	// that sent a series can be told apart. No label is added when it is empty
+	CollectorIDLabel string `mapstructure:"collector_id_label"`
+
+	// CollectorID is the value of the CollectorIDLabel label, the hostname when it is empty
+	CollectorID string `mapstructure:"collector_id"`
+
 	ClientConfig confighttp.ClientConfig `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct.
 
+	// EndpointFromEnv allows reading the endpoint from an environment variable, which is read again periodically
+	// so that changes are picked up at runtime. The configured endpoint is used while the variable is unset.
+	EndpointFromEnv *EndpointFromEnv `mapstructure:"endpoint_from_env,omitempty"`
+
+	// FollowRedirects controls whether redirect responses are followed, sending the body and all the headers of
+	// the request again, instead of being handled like unsuccessful responses
+	FollowRedirects bool `mapstructure:"follow_redirects"`
+
+	// StrictStatusCodes flags the requests answered with another 2xx status than 204 No Content, the status the
+	// remote write specification recommends, with a warning and the unexpected_status request outcome. They are
+	// still considered sent
+	StrictStatusCodes bool `mapstructure:"strict_status_codes"`
+
+	// DialTimeout bounds the time to establish a connection to the endpoint, DNS resolution and TLS handshake
+	// included, separately from the timeout of the whole request, 0 means only the timeout of the request applies
+	DialTimeout time.Duration `mapstructure:"dial_timeout"`
+
+	// ShutdownDrainTimeout bounds the time the exports of the WAL in progress on shutdown are waited for, so that
+	// their entries are truncated from the WAL, before they are cancelled, 0 means they are cancelled right away
+	ShutdownDrainTimeout time.Duration `mapstructure:"shutdown_drain_timeout"`
+
+	// EmitRequestID controls whether every request carries a unique X-Request-ID header, kept when the request is
+	// retried, which is also logged and returned along with the error when the request fails
+	EmitRequestID bool `mapstructure:"emit_request_id"`
+
+	// Backend is the kind of remote write endpoint, which enables the settings specific to it: empty for any
+	// endpoint or "thanos" for Thanos Receive
+	Backend string `mapstructure:"backend"`
+
+	// Thanos configures the requests sent to Thanos Receive, it requires the "thanos" backend
+	Thanos *ThanosConfig `mapstructure:"thanos,omitempty"`
+
 	// maximum size in bytes of time series batch sent to remote storage
 	MaxBatchSizeBytes int `mapstructure:"max_batch_size_bytes"`
 
+	// maximum number of samples in a single request sent to remote storage, 0 means no limit
+	MaxSamplesPerRequest int `mapstructure:"max_samples_per_request"`
+
+	// maximum number of time series in a single request sent to remote storage, 0 means no limit
+	MaxSeriesPerRequest int `mapstructure:"max_series_per_request"`
+
+	// BatchGrouping controls how the series are assembled into requests: "mixed" packs the series of every resource
+	// together, and "by_resource" sends the series of every resource in their own requests, still split by size.
+	// Defaults to "mixed".
+	BatchGrouping string `mapstructure:"batch_grouping"`
+
+	// BackendLimits drops the series violating the limits of the endpoint and splits the batches exceeding them,
+	// nil means no limits are enforced
+	BackendLimits *BackendLimits `mapstructure:"backend_limits,omitempty"`
+
+	// RecordDroppedLabelCount adds a __dropped_labels__ label holding the number of labels removed from the series
+	// truncated to fit the backend_limits max_labels_per_series
+	RecordDroppedLabelCount bool `mapstructure:"record_dropped_label_count"`
+
+	// requests smaller than this number of bytes are sent uncompressed, without a Content-Encoding header, 0 means
+	// requests are always compressed
+	CompressionMinBytes int `mapstructure:"compression_min_bytes"`
+
+	// SnappyFormat is the snappy format the requests are compressed with: "block", as the remote write specification
+	// requires, or "stream", the framed format some proxies expect, which has to be opted in explicitly
+	SnappyFormat string `mapstructure:"snappy_format"`
+
+	// ProtocolFallback controls whether requests are sent using remote write 2.0, falling back to remote write 1.0
+	// when the endpoint rejects them with a 415 or 406 status. The negotiated version is kept until a request fails.
+	ProtocolFallback bool `mapstructure:"protocol_fallback"`
+
+	// MetadataResendInterval is how often the help and unit of a series are sent again when they didn't change,
+	// using remote write 2.0, only their type being sent in the meantime. 0 means they are sent with every request
+	MetadataResendInterval time.Duration `mapstructure:"metadata_resend_interval"`
+
+	// maximum number of samples of a single series within any SeriesRateLimitInterval, the excess samples are dropped
+	// keeping the most recent ones, 0 means no limit
+	MaxSamplesPerSeriesPerInterval int `mapstructure:"max_samples_per_series_per_interval"`
+
+	// SeriesRateLimitInterval is the sliding window MaxSamplesPerSeriesPerInterval applies to
+	SeriesRateLimitInterval time.Duration `mapstructure:"series_rate_limit_interval"`
+
+	// OverloadSamplingRate keeps only every Nth sample of every series while the WAL is overloaded, 0 or 1 means
+	// all the samples are kept
+	OverloadSamplingRate int `mapstructure:"overload_sampling_rate"`
+
+	// OverloadHighWaterMark is the number of WAL entries waiting to be exported at which the WAL is overloaded, it
+	// stays so until they drain below half of it
+	OverloadHighWaterMark int `mapstructure:"overload_high_water_mark"`
+
 	// maximum amount of parallel requests to do when handling large batch request
 	MaxBatchRequestParallelism *int `mapstructure:"max_batch_request_parallelism"`
 
@@ -44,6 +184,15 @@ type Config struct {
 	ResourceToTelemetrySettings resourcetotelemetry.Settings `mapstructure:"resource_to_telemetry_conversion"`
 	WAL                         *WALConfig                   `mapstructure:"wal"`
 
+	// PriorityRules split the series by the priority of the first rule their metric name matches, normal when
+	// they match none, into a WAL per priority. The entries of a WAL are only exported once the WALs of higher
+	// priority are drained. It requires the WAL
+	PriorityRules []PriorityRule `mapstructure:"priority_rules"`
+
+	// ValueScaling multiplies the values of the metrics by the multiplier of the first rule their name matches, to
+	// convert their unit for example. The counts and the values that aren't finite are left as they are
+	ValueScaling []ValueScalingRule `mapstructure:"value_scaling"`
+
 	// TargetInfo allows customizing the target_info metric
 	TargetInfo *TargetInfo `mapstructure:"target_info,omitempty"`
 
@@ -55,8 +204,143 @@ type Config struct {
 	// AddMetricSuffixes controls whether unit and type suffixes are added to metrics on export
 	AddMetricSuffixes bool `mapstructure:"add_metric_suffixes"`
 
+	// UnitSuffixMode controls how units are turned into metric name suffixes: "otel" maps them to Prometheus
+	// base unit names, "raw" appends them as is and "none" doesn't append them
+	UnitSuffixMode prometheustranslator.UnitSuffixMode `mapstructure:"unit_suffix_mode"`
+
+	// UnitSuffixOverrides maps units to the suffix used for them, whatever the unit suffix mode
+	UnitSuffixOverrides map[string]string `mapstructure:"unit_suffix_overrides"`
+
 	// SendMetadata controls whether prometheus metadata will be generated and sent
 	SendMetadata bool `mapstructure:"send_metadata"`
+
+	// InvalidLabelNamePolicy controls what happens to attributes that aren't valid Prometheus label names:
+	// "sanitize" replaces the invalid characters, "drop_series" drops the series and "error" rejects the batch.
+	InvalidLabelNamePolicy prometheusremotewrite.InvalidLabelNamePolicy `mapstructure:"invalid_label_name_policy"`
+
+	// LabelNameRemapping maps attribute keys to the label names they are translated to, instead of sanitizing them
+	LabelNameRemapping map[string]string `mapstructure:"label_name_remapping"`
+
+	// LabelCollisionPolicy controls how attributes translated to the same label name are merged: "concatenate" joins
+	// their values and "prefer_remapped" keeps the value of the attribute remapped by LabelNameRemapping
+	LabelCollisionPolicy prometheusremotewrite.LabelCollisionPolicy `mapstructure:"label_collision_policy"`
+
+	// NameConflictPolicy controls the name of the series whose attributes include a __name__ that differs from the
+	// translated metric name: "override" uses the translated name, "preserve" keeps the attribute and "error" drops
+	// the series
+	NameConflictPolicy prometheusremotewrite.NameConflictPolicy `mapstructure:"name_conflict_policy"`
+
+	// HistogramNaNPolicy controls the _sum series of the histogram data points whose sum is NaN: "pass" sends the
+	// NaN, "drop" omits the sample and "zero_sum" sends 0. Stale markers are sent whatever the policy
+	HistogramNaNPolicy prometheusremotewrite.HistogramNaNPolicy `mapstructure:"histogram_nan_policy"`
+
+	// DropHistogramBuckets controls whether only the _count and _sum series of the classic histograms are sent,
+	// without their _bucket series, to save cardinality
+	DropHistogramBuckets bool `mapstructure:"drop_histogram_buckets"`
+
+	// MaxMetricNameBytes is the maximum length of the metric names, including their namespace and suffixes,
+	// 0 means no limit
+	MaxMetricNameBytes int `mapstructure:"max_metric_name_bytes"`
+
+	// MetricNameLengthPolicy controls what happens to the metric names longer than MaxMetricNameBytes: "truncate"
+	// shortens them, ending them with a hash of the whole name, and "drop" drops their series
+	MetricNameLengthPolicy string `mapstructure:"metric_name_length_policy"`
+
+	// MetricTypeConflictPolicy controls what happens to the metrics translated to the same metric name as a metric
+	// of another type earlier in the batch: "drop_later" drops them, "suffix_type" appends their type to their
+	// name and "error" rejects the batch. Conflicts aren't detected when it is empty
+	MetricTypeConflictPolicy string `mapstructure:"metric_type_conflict_policy"`
+
+	// PartialTranslationPolicy controls what happens to a batch some metrics of which fail to be translated:
+	// "drop_resource", or its alias "drop_failed", drops only the metrics that failed and exports the series that
+	// were translated, and "drop_batch" drops the whole batch
+	PartialTranslationPolicy string `mapstructure:"partial_translation_policy"`
+
+	// EmptyMetricsPolicy controls how metrics without data points are reported: "ignore" doesn't report them,
+	// "log" logs their names at debug level and "count" counts them by name
+	EmptyMetricsPolicy string `mapstructure:"empty_metrics_policy"`
+
+	// UnsupportedTypePolicy controls the metrics whose type can't be translated, such as an unset one: "drop" counts
+	// and skips them, like the other metrics that fail to be translated, and "error" rejects the batch
+	UnsupportedTypePolicy string `mapstructure:"unsupported_type_policy"`
+
+	// DuplicateDataPointPolicy controls the data points translated to the same series and timestamp, which Prometheus
+	// rejects: "last_wins" keeps the last one, "first_wins" keeps the first one and "error" rejects the batch. They
+	// are left as they are when it is empty
+	DuplicateDataPointPolicy string `mapstructure:"duplicate_data_point_policy"`
+
+	// EnforceSampleOrder controls whether the samples of every series are sorted by timestamp before being sent,
+	// keeping only the last of the samples with the same timestamp. The samples are sorted when they are batched
+	// into requests either way
+	EnforceSampleOrder bool `mapstructure:"enforce_sample_order"`
+
+	// ValidateMonotonicTimestamps controls whether the series whose samples aren't in strictly increasing timestamp
+	// order are counted and logged, without changing them, to catch upstream bugs
+	ValidateMonotonicTimestamps bool `mapstructure:"validate_monotonic_timestamps"`
+
+	// TrackLastSent controls whether the timestamp of the last sample sent for every recently sent series is
+	// tracked, for debugging
+	TrackLastSent bool `mapstructure:"track_last_sent"`
+
+	// DetectSeriesGaps controls whether the series that stop being received for longer than SeriesGapThreshold
+	// times the interval between their samples are counted, for data-quality monitoring
+	DetectSeriesGaps bool `mapstructure:"detect_series_gaps"`
+
+	// SeriesGapThreshold is the number of intervals between its samples a series must miss to be counted as having
+	// a gap, 0 means 2
+	SeriesGapThreshold float64 `mapstructure:"series_gap_threshold"`
+
+	// EmitHeartbeat controls whether an otelcol_remote_write_up series with value 1 is sent on every flush
+	EmitHeartbeat bool `mapstructure:"emit_heartbeat"`
+
+	// HeartbeatLabels defines a map of label keys and values added to the heartbeat series, on top of the external labels
+	HeartbeatLabels map[string]string `mapstructure:"heartbeat_labels"`
+
+	// DropZeroValueCounters controls whether monotonic sum series that have been zero since the exporter started are dropped
+	DropZeroValueCounters bool `mapstructure:"drop_zero_value_counters"`
+
+	// EmitCounterResetSamples controls whether a zero valued sample is added at the time a monotonic sum series resets
+	EmitCounterResetSamples bool `mapstructure:"emit_counter_reset_samples"`
+
+	// ExemplarsFromSampledOnly controls whether exemplars that aren't linked to a sampled trace are dropped
+	ExemplarsFromSampledOnly bool `mapstructure:"exemplars_from_sampled_only"`
+
+	// MaxExemplarsPerSeries caps the number of exemplars sent for a single series, 0 means no limit
+	MaxExemplarsPerSeries int `mapstructure:"max_exemplars_per_series"`
+
+	// ExemplarMetricFilter is a regular expression the whole name of a metric must match for its exemplars to be
+	// sent, the exemplars of all the metrics are sent if it is empty
+	ExemplarMetricFilter string `mapstructure:"exemplar_metric_filter"`
+
+	// ExemplarTimestampPolicy controls the exemplars whose timestamp is outside the interval of their data point:
+	// "keep" sends them as they are, "drop" drops them and "clamp" moves their timestamp to the closest end of the
+	// interval. Defaults to "keep".
+	ExemplarTimestampPolicy string `mapstructure:"exemplar_timestamp_policy"`
+
+	// PromoteScopeAttributes lists the instrumentation scope attributes that are added as labels to the series of the scope
+	PromoteScopeAttributes []string `mapstructure:"promote_scope_attributes"`
+
+	// EmitDroppedAttributesLabel controls whether the number of attributes dropped by the resource and the instrumentation
+	// scope of a series is added as the otel_dropped_attributes_count label, when it isn't zero
+	EmitDroppedAttributesLabel bool `mapstructure:"emit_dropped_attributes_label"`
+
+	// EmitTemporalityLabel controls whether the series of the sums and histograms get a label holding the aggregation
+	// temporality of their metric
+	EmitTemporalityLabel bool `mapstructure:"emit_temporality_label"`
+
+	// TemporalityLabelName is the name of the label added by emit_temporality_label, __temporality__ if empty
+	TemporalityLabelName string `mapstructure:"temporality_label_name"`
+
+	// TimestampRounding controls how the nanosecond timestamps of the data points are converted to milliseconds:
+	// "truncate" drops the sub-millisecond part and "nearest" rounds them to the nearest millisecond
+	TimestampRounding prometheusremotewrite.TimestampRounding `mapstructure:"timestamp_rounding"`
+
+	// NoRecordedValueAsStale controls whether the data points flagged with NoRecordedValue are sent as Prometheus stale
+	// markers, instead of their values
+	NoRecordedValueAsStale bool `mapstructure:"no_recorded_value_as_stale"`
+
+	// TranslationConcurrency is the number of goroutines the resources of a batch are translated with
+	TranslationConcurrency int `mapstructure:"translation_concurrency"`
 }
 
 type CreatedMetric struct {
@@ -67,6 +351,25 @@ type CreatedMetric struct {
 type TargetInfo struct {
 	// Enabled if false the target_info metric is not generated by the exporter
 	Enabled bool `mapstructure:"enabled"`
+
+	// ExcludeAttributes lists the resource attributes that are not added as labels to the target_info metric
+	ExcludeAttributes []string `mapstructure:"exclude_attributes"`
+
+	// SkipWithoutIdentity if true the target_info metric is not generated for resources without service.name and
+	// service.instance.id
+	SkipWithoutIdentity bool `mapstructure:"skip_without_identity"`
+
+	// IncludeSchemaURL if true the schema URL of the resource, when it has one, is added to the target_info metric
+	// as the schema_url label
+	IncludeSchemaURL bool `mapstructure:"include_schema_url"`
+
+	// EmitInterval is how often the target_info metric of a resource is emitted when its attributes didn't change,
+	// 0 emits it along with every batch of metrics of the resource
+	EmitInterval time.Duration `mapstructure:"emit_interval"`
+
+	// SeparateRequest if true the target_info series are sent in their own requests, and written to their own WAL
+	// entries, rather than along with the other series
+	SeparateRequest bool `mapstructure:"separate_request"`
 }
 
 // RemoteWriteQueue allows to configure the remote write queue.
@@ -82,8 +385,37 @@ type RemoteWriteQueue struct {
 	// NumWorkers configures the number of workers used by
 	// the collector to fan out remote write requests.
 	NumConsumers int `mapstructure:"num_consumers"`
+
+	// SeriesAffinity controls whether every series is always sent by the same worker, so that the samples
+	// of a series are never sent concurrently and arrive in order.
+	SeriesAffinity bool `mapstructure:"series_affinity"`
+
+	// DispatchOrder is the order the pending requests are sent in: "fifo" sends the requests of every export in
+	// order, "newest_first" sends those holding the newest samples first across all the exports
+	DispatchOrder string `mapstructure:"dispatch_order"`
 }
 
+const (
+	defaultSeriesRateLimitInterval = time.Minute
+	defaultMaxRetryTimeout         = time.Minute
+	// seriesRateLimitMaxSeries bounds the number of series whose recent samples are tracked for rate limiting.
+	seriesRateLimitMaxSeries = 100000
+	// lastSentMaxSeries bounds the number of series whose last sent timestamp is tracked.
+	lastSentMaxSeries = 100000
+	// metadataCacheMaxSeries bounds the number of series whose last sent metadata is tracked.
+	metadataCacheMaxSeries = 100000
+	// overloadSamplingMaxSeries bounds the number of series whose samples are counted while the WAL is overloaded.
+	overloadSamplingMaxSeries = 100000
+	// counterResetMaxSeries bounds the number of counter series whose last value is tracked to detect resets.
+	counterResetMaxSeries = 100000
+	// targetInfoMaxSeries bounds the number of resources whose last target_info emission is tracked.
+	targetInfoMaxSeries = 100000
+	// seriesGapsMaxSeries bounds the number of series whose interval is tracked to detect gaps.
+	seriesGapsMaxSeries = 100000
+	// zeroCountersMaxSeries bounds the number of nonzero counter series remembered to drop the zero value ones.
+	zeroCountersMaxSeries = 100000
+)
+
 // TODO(jbd): Add capacity, max_samples_per_send to QueueConfig.
 
 var _ component.Config = (*Config)(nil)
@@ -106,6 +438,15 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("remote write consumer number can't be negative")
 	}
 
+	switch cfg.RemoteWriteQueue.DispatchOrder {
+	case "", dispatchOrderFIFO, dispatchOrderNewestFirst:
+	default:
+		return fmt.Errorf("dispatch_order must be one of %q or %q", dispatchOrderFIFO, dispatchOrderNewestFirst)
+	}
+	if cfg.RemoteWriteQueue.DispatchOrder == dispatchOrderNewestFirst && cfg.RemoteWriteQueue.SeriesAffinity {
+		return fmt.Errorf("dispatch_order %q can't be used with series_affinity", dispatchOrderNewestFirst)
+	}
+
 	if cfg.TargetInfo == nil {
 		cfg.TargetInfo = &TargetInfo{
 			Enabled: true,
@@ -123,6 +464,445 @@ func (cfg *Config) Validate() error {
 		// Defaults to ~2.81MB
 		cfg.MaxBatchSizeBytes = 3000000
 	}
+	if cfg.MaxSamplesPerRequest < 0 {
+		return fmt.Errorf("max_samples_per_request can't be negative")
+	}
+	if cfg.MaxSeriesPerRequest < 0 {
+		return fmt.Errorf("max_series_per_request can't be negative")
+	}
+	if cfg.CompressionMinBytes < 0 {
+		return fmt.Errorf("compression_min_bytes can't be negative")
+	}
+	switch cfg.SnappyFormat {
+	case "", snappyFormatBlock, snappyFormatStream:
+	default:
+		return fmt.Errorf("snappy_format must be one of %q or %q", snappyFormatBlock, snappyFormatStream)
+	}
+	if cfg.MaxSamplesPerSeriesPerInterval < 0 {
+		return fmt.Errorf("max_samples_per_series_per_interval can't be negative")
+	}
+	if cfg.SeriesRateLimitInterval < 0 {
+		return fmt.Errorf("series_rate_limit_interval can't be negative")
+	}
+	if cfg.SeriesRateLimitInterval == 0 {
+		cfg.SeriesRateLimitInterval = defaultSeriesRateLimitInterval
+	}
+	if cfg.OverloadSamplingRate < 0 {
+		return fmt.Errorf("overload_sampling_rate can't be negative")
+	}
+	if cfg.OverloadHighWaterMark < 0 {
+		return fmt.Errorf("overload_high_water_mark can't be negative")
+	}
+	if cfg.OverloadSamplingRate > 1 {
+		if cfg.WAL == nil {
+			return fmt.Errorf("overload_sampling_rate requires the WAL to be enabled")
+		}
+		if cfg.OverloadHighWaterMark == 0 {
+			return fmt.Errorf("overload_sampling_rate requires overload_high_water_mark")
+		}
+	}
+	if cfg.MaxExemplarsPerSeries < 0 {
+		return fmt.Errorf("max_exemplars_per_series can't be negative")
+	}
+	if _, err := cfg.exemplarMetricFilter(); err != nil {
+		return err
+	}
+	switch cfg.ExemplarTimestampPolicy {
+	case "", exemplarTimestampPolicyKeep, exemplarTimestampPolicyDrop, exemplarTimestampPolicyClamp:
+	default:
+		return fmt.Errorf("exemplar_timestamp_policy must be one of %q, %q or %q", exemplarTimestampPolicyKeep,
+			exemplarTimestampPolicyDrop, exemplarTimestampPolicyClamp)
+	}
+	if cfg.RetryTimeoutMultiplier != 0 && cfg.RetryTimeoutMultiplier < 1 {
+		return fmt.Errorf("retry_timeout_multiplier must be at least 1")
+	}
+	if cfg.MaxRetryTimeout < 0 {
+		return fmt.Errorf("max_retry_timeout can't be negative")
+	}
+	if cfg.MaxRetries < 0 {
+		return fmt.Errorf("max_retries can't be negative")
+	}
+	if cfg.MetadataResendInterval < 0 {
+		return fmt.Errorf("metadata_resend_interval can't be negative")
+	}
+	if cfg.TargetInfo != nil && cfg.TargetInfo.EmitInterval < 0 {
+		return fmt.Errorf("target_info emit_interval can't be negative")
+	}
+	if cfg.DialTimeout < 0 {
+		return fmt.Errorf("dial_timeout can't be negative")
+	}
+	if cfg.SeriesGapThreshold != 0 && !(cfg.SeriesGapThreshold >= 1) {
+		return fmt.Errorf("series_gap_threshold must be at least 1")
+	}
+	if cfg.ShutdownDrainTimeout < 0 {
+		return fmt.Errorf("shutdown_drain_timeout can't be negative")
+	}
+	if cfg.CollectorIDLabel == "" && cfg.CollectorID != "" {
+		return fmt.Errorf("collector_id requires collector_id_label to be set")
+	}
+	if cfg.CollectorIDLabel != "" && !model.LabelName(cfg.CollectorIDLabel).IsValidLegacy() {
+		return fmt.Errorf("collector_id_label: %q isn't a valid label name", cfg.CollectorIDLabel)
+	}
+	if name := cfg.TemporalityLabelName; name != "" {
+		if !model.LabelName(name).IsValidLegacy() {
+			return fmt.Errorf("temporality_label_name: %q isn't a valid label name", name)
+		}
+		if name == model.MetricNameLabel {
+			return fmt.Errorf("temporality_label_name can't be %q", model.MetricNameLabel)
+		}
+	}
+	for key := range cfg.ExternalLabels {
+		if cfg.CollectorIDLabel != "" && prometheustranslator.NormalizeLabel(key) == cfg.CollectorIDLabel {
+			return fmt.Errorf("collector_id_label %q is also set in external_labels", cfg.CollectorIDLabel)
+		}
+	}
+	if cfg.RetryTimeoutMultiplier > 0 && cfg.MaxRetryTimeout == 0 {
+		cfg.MaxRetryTimeout = defaultMaxRetryTimeout
+	}
+	if cfg.FileArchive != nil {
+		if cfg.FileArchive.Directory == "" {
+			return fmt.Errorf("file_archive requires a directory")
+		}
+		if cfg.FileArchive.RotationSizeBytes < 0 {
+			return fmt.Errorf("file_archive rotation_size_bytes can't be negative")
+		}
+		if cfg.FileArchive.RotationSizeBytes == 0 {
+			cfg.FileArchive.RotationSizeBytes = defaultArchiveRotationSizeBytes
+		}
+	}
+	if cfg.DeadLetter != nil {
+		if cfg.DeadLetter.Directory == "" {
+			return fmt.Errorf("dead_letter requires a directory")
+		}
+		if cfg.DeadLetter.RotationSizeBytes < 0 {
+			return fmt.Errorf("dead_letter rotation_size_bytes can't be negative")
+		}
+		if cfg.DeadLetter.MaxTotalSizeBytes < 0 {
+			return fmt.Errorf("dead_letter max_total_size_bytes can't be negative")
+		}
+		if cfg.DeadLetter.RotationSizeBytes == 0 {
+			cfg.DeadLetter.RotationSizeBytes = defaultArchiveRotationSizeBytes
+		}
+		if cfg.DeadLetter.MaxTotalSizeBytes == 0 {
+			cfg.DeadLetter.MaxTotalSizeBytes = defaultDeadLetterMaxTotalSizeBytes
+		}
+		if cfg.DeadLetter.MaxTotalSizeBytes < cfg.DeadLetter.RotationSizeBytes {
+			return fmt.Errorf("dead_letter max_total_size_bytes can't be less than rotation_size_bytes")
+		}
+	}
+	if cfg.MaxBufferedSeries < 0 {
+		return fmt.Errorf("max_buffered_series can't be negative")
+	}
+	switch cfg.BufferFullPolicy {
+	case "", bufferFullPolicyBlock:
+	case bufferFullPolicySpillToWAL:
+		if cfg.WAL == nil {
+			return fmt.Errorf("buffer_full_policy %q requires the WAL to be enabled", bufferFullPolicySpillToWAL)
+		}
+	default:
+		return fmt.Errorf("buffer_full_policy must be one of %q or %q", bufferFullPolicyBlock, bufferFullPolicySpillToWAL)
+	}
+	if cfg.MinFlushInterval < 0 {
+		return fmt.Errorf("min_flush_interval can't be negative")
+	}
+	if cfg.Coalesce != nil {
+		if cfg.Coalesce.FlushInterval < 0 {
+			return fmt.Errorf("coalesce flush_interval can't be negative")
+		}
+		if cfg.Coalesce.MaxSamples < 0 {
+			return fmt.Errorf("coalesce max_samples can't be negative")
+		}
+		if cfg.Coalesce.FlushInterval == 0 {
+			cfg.Coalesce.FlushInterval = defaultCoalesceFlushInterval
+		}
+		if cfg.Coalesce.MaxSamples == 0 {
+			cfg.Coalesce.MaxSamples = defaultCoalesceMaxSamples
+		}
+	}
+	if cfg.HistogramCountFastPath && cfg.Coalesce == nil {
+		return fmt.Errorf("histogram_count_fast_path requires coalesce")
+	}
+	if cfg.HistogramCountFlushInterval < 0 {
+		return fmt.Errorf("histogram_count_flush_interval can't be negative")
+	}
+	if cfg.HistogramCountFastPath {
+		if cfg.HistogramCountFlushInterval == 0 {
+			cfg.HistogramCountFlushInterval = defaultHistogramCountFlushInterval
+		}
+		if cfg.HistogramCountFlushInterval >= cfg.Coalesce.FlushInterval {
+			return fmt.Errorf("histogram_count_flush_interval must be shorter than the coalesce flush_interval")
+		}
+	}
+	if cfg.BackendLimits != nil {
+		limits := cfg.BackendLimits
+		if limits.MaxLabelsPerSeries < 0 {
+			return fmt.Errorf("backend_limits max_labels_per_series can't be negative")
+		}
+		if limits.MaxLabelNameBytes < 0 {
+			return fmt.Errorf("backend_limits max_label_name_bytes can't be negative")
+		}
+		if limits.MaxLabelValueBytes < 0 {
+			return fmt.Errorf("backend_limits max_label_value_bytes can't be negative")
+		}
+		if limits.MaxSeriesPerRequest < 0 {
+			return fmt.Errorf("backend_limits max_series_per_request can't be negative")
+		}
+		switch limits.LabelsPerSeriesPolicy {
+		case "", labelsPerSeriesPolicyDrop, labelsPerSeriesPolicyTruncate:
+		default:
+			return fmt.Errorf("backend_limits labels_per_series_policy must be one of %q or %q", labelsPerSeriesPolicyDrop,
+				labelsPerSeriesPolicyTruncate)
+		}
+		if limits.MaxSeriesPerRequest > 0 {
+			if cfg.MaxSeriesPerRequest > 0 && cfg.MaxSeriesPerRequest != limits.MaxSeriesPerRequest {
+				return fmt.Errorf("backend_limits max_series_per_request is an alias of max_series_per_request, they can't be set to different values")
+			}
+			cfg.MaxSeriesPerRequest = limits.MaxSeriesPerRequest
+		}
+	}
+	if cfg.RecordDroppedLabelCount {
+		if cfg.BackendLimits == nil || cfg.BackendLimits.LabelsPerSeriesPolicy != labelsPerSeriesPolicyTruncate {
+			return fmt.Errorf("record_dropped_label_count requires backend_limits labels_per_series_policy to be %q",
+				labelsPerSeriesPolicyTruncate)
+		}
+		if cfg.BackendLimits.MaxLabelsPerSeries == 1 {
+			return fmt.Errorf("record_dropped_label_count requires backend_limits max_labels_per_series to be at least 2")
+		}
+	}
+	if cfg.RetryBudget != nil {
+		if cfg.RetryBudget.Rate <= 0 {
+			return fmt.Errorf("retry_budget rate must be positive")
+		}
+		if cfg.RetryBudget.Burst < 0 {
+			return fmt.Errorf("retry_budget burst can't be negative")
+		}
+		if cfg.RetryBudget.Burst == 0 {
+			cfg.RetryBudget.Burst = defaultRetryBudgetBurst
+		}
+	}
+	if cfg.EndpointFromEnv != nil {
+		if cfg.EndpointFromEnv.Variable == "" {
+			return fmt.Errorf("endpoint_from_env requires a variable")
+		}
+		if cfg.EndpointFromEnv.RefreshInterval < 0 {
+			return fmt.Errorf("endpoint_from_env refresh_interval can't be negative")
+		}
+		if cfg.EndpointFromEnv.RefreshInterval == 0 {
+			cfg.EndpointFromEnv.RefreshInterval = defaultEndpointRefreshInterval
+		}
+	}
+	switch cfg.Backend {
+	case "", backendThanos:
+	default:
+		return fmt.Errorf("backend must be empty or %q", backendThanos)
+	}
+	if cfg.Thanos != nil {
+		if cfg.Backend != backendThanos {
+			return fmt.Errorf("thanos requires the %q backend", backendThanos)
+		}
+		if err := cfg.Thanos.validate(cfg.ClientConfig.Headers); err != nil {
+			return err
+		}
+	}
+	if len(cfg.PriorityRules) > 0 && cfg.WAL == nil {
+		return fmt.Errorf("priority_rules requires the WAL to be enabled")
+	}
+	for _, rule := range cfg.PriorityRules {
+		switch rule.Priority {
+		case priorityHigh, priorityNormal, priorityLow:
+		default:
+			return fmt.Errorf("priority_rules priority must be one of %q, %q or %q", priorityHigh, priorityNormal, priorityLow)
+		}
+	}
+	if _, err := compilePriorityRules(cfg.PriorityRules); err != nil {
+		return err
+	}
+	for _, rule := range cfg.ValueScaling {
+		if rule.Multiplier == 0 || math.IsNaN(rule.Multiplier) || math.IsInf(rule.Multiplier, 0) {
+			return fmt.Errorf("value_scaling multiplier must be a finite number other than 0")
+		}
+	}
+	if _, err := compileValueScalingRules(cfg.ValueScaling); err != nil {
+		return err
+	}
+	if cfg.WAL != nil {
+		switch cfg.WAL.CorruptionPolicy {
+		case "", walCorruptionPolicyFail, walCorruptionPolicyQuarantine, walCorruptionPolicyRepair:
+		default:
+			return fmt.Errorf("wal corruption_policy must be one of %q, %q or %q", walCorruptionPolicyFail,
+				walCorruptionPolicyQuarantine, walCorruptionPolicyRepair)
+		}
+		switch cfg.WAL.IncompatiblePolicy {
+		case "", walIncompatiblePolicyFail, walIncompatiblePolicyQuarantine:
+		default:
+			return fmt.Errorf("wal incompatible_policy must be one of %q or %q", walIncompatiblePolicyFail,
+				walIncompatiblePolicyQuarantine)
+		}
+		if cfg.WAL.ReplayConcurrency < 0 {
+			return fmt.Errorf("wal replay_concurrency can't be negative")
+		}
+		if cfg.WAL.ReplayAcceptTimeout < 0 {
+			return fmt.Errorf("wal replay_accept_timeout can't be negative")
+		}
+		if cfg.WAL.MaxEntryAge < 0 {
+			return fmt.Errorf("wal max_entry_age can't be negative")
+		}
+		if cfg.WAL.AuditSampleRate < 0 || cfg.WAL.AuditSampleRate > 1 {
+			return fmt.Errorf("wal audit_sample_rate must be between 0 and 1")
+		}
+		if cfg.WAL.MaxEntryBytes < 0 {
+			return fmt.Errorf("wal max_entry_bytes can't be negative")
+		}
+		switch cfg.WAL.EntrySizePolicy {
+		case "", walEntrySizePolicyReject, walEntrySizePolicySplit:
+		default:
+			return fmt.Errorf("wal entry_size_policy must be one of %q or %q", walEntrySizePolicyReject,
+				walEntrySizePolicySplit)
+		}
+		switch cfg.WAL.ShutdownStrategy {
+		case "", walShutdownStrategyOldestFirst, walShutdownStrategyNewestFirst:
+		default:
+			return fmt.Errorf("wal shutdown_strategy must be one of %q or %q", walShutdownStrategyOldestFirst,
+				walShutdownStrategyNewestFirst)
+		}
+	}
+	switch cfg.InvalidLabelNamePolicy {
+	case "":
+		cfg.InvalidLabelNamePolicy = prometheusremotewrite.InvalidLabelNamePolicySanitize
+	case prometheusremotewrite.InvalidLabelNamePolicySanitize, prometheusremotewrite.InvalidLabelNamePolicyDropSeries,
+		prometheusremotewrite.InvalidLabelNamePolicyError:
+	default:
+		return fmt.Errorf("invalid_label_name_policy must be one of %q, %q or %q", prometheusremotewrite.InvalidLabelNamePolicySanitize,
+			prometheusremotewrite.InvalidLabelNamePolicyDropSeries, prometheusremotewrite.InvalidLabelNamePolicyError)
+	}
+	for key, name := range cfg.LabelNameRemapping {
+		if !model.LabelName(name).IsValidLegacy() {
+			return fmt.Errorf("label_name_remapping: %q isn't a valid label name for attribute %q", name, key)
+		}
+	}
+	switch cfg.LabelCollisionPolicy {
+	case "", prometheusremotewrite.LabelCollisionPolicyConcatenate, prometheusremotewrite.LabelCollisionPolicyPreferRemapped:
+	default:
+		return fmt.Errorf("label_collision_policy must be one of %q or %q", prometheusremotewrite.LabelCollisionPolicyConcatenate,
+			prometheusremotewrite.LabelCollisionPolicyPreferRemapped)
+	}
+	switch cfg.NameConflictPolicy {
+	case "", prometheusremotewrite.NameConflictPolicyOverride, prometheusremotewrite.NameConflictPolicyPreserve,
+		prometheusremotewrite.NameConflictPolicyError:
+	default:
+		return fmt.Errorf("name_conflict_policy must be one of %q, %q or %q", prometheusremotewrite.NameConflictPolicyOverride,
+			prometheusremotewrite.NameConflictPolicyPreserve, prometheusremotewrite.NameConflictPolicyError)
+	}
+	switch cfg.HistogramNaNPolicy {
+	case "", prometheusremotewrite.HistogramNaNPolicyPass, prometheusremotewrite.HistogramNaNPolicyDrop,
+		prometheusremotewrite.HistogramNaNPolicyZeroSum:
+	default:
+		return fmt.Errorf("histogram_nan_policy must be one of %q, %q or %q", prometheusremotewrite.HistogramNaNPolicyPass,
+			prometheusremotewrite.HistogramNaNPolicyDrop, prometheusremotewrite.HistogramNaNPolicyZeroSum)
+	}
+	if cfg.MaxMetricNameBytes < 0 {
+		return fmt.Errorf("max_metric_name_bytes can't be negative")
+	}
+	switch cfg.MetricNameLengthPolicy {
+	case "", metricNameLengthPolicyTruncate:
+		if cfg.MaxMetricNameBytes > 0 && cfg.MaxMetricNameBytes <= minTruncatedMetricNameBytes {
+			return fmt.Errorf("max_metric_name_bytes must be greater than %d to truncate the metric names", minTruncatedMetricNameBytes)
+		}
+	case metricNameLengthPolicyDrop:
+	default:
+		return fmt.Errorf("metric_name_length_policy must be one of %q or %q", metricNameLengthPolicyTruncate,
+			metricNameLengthPolicyDrop)
+	}
+	switch cfg.MetricTypeConflictPolicy {
+	case "", metricTypeConflictPolicyDropLater, metricTypeConflictPolicySuffixType, metricTypeConflictPolicyError:
+	default:
+		return fmt.Errorf("metric_type_conflict_policy must be one of %q, %q or %q", metricTypeConflictPolicyDropLater,
+			metricTypeConflictPolicySuffixType, metricTypeConflictPolicyError)
+	}
+	switch cfg.TimestampRounding {
+	case "", prometheusremotewrite.TimestampRoundingTruncate, prometheusremotewrite.TimestampRoundingNearest:
+	default:
+		return fmt.Errorf("timestamp_rounding must be one of %q or %q", prometheusremotewrite.TimestampRoundingTruncate,
+			prometheusremotewrite.TimestampRoundingNearest)
+	}
+	if cfg.TranslationConcurrency < 0 {
+		return fmt.Errorf("translation_concurrency can't be negative")
+	}
+	switch cfg.BatchGrouping {
+	case "", batchGroupingMixed, batchGroupingByResource:
+	default:
+		return fmt.Errorf("batch_grouping must be one of %q or %q", batchGroupingMixed, batchGroupingByResource)
+	}
+	switch cfg.PartialTranslationPolicy {
+	case "", partialTranslationPolicyDropResource, partialTranslationPolicyDropFailed, partialTranslationPolicyDropBatch:
+	default:
+		return fmt.Errorf("partial_translation_policy must be one of %q or %q", partialTranslationPolicyDropResource,
+			partialTranslationPolicyDropBatch)
+	}
+	switch cfg.EmptyMetricsPolicy {
+	case "", emptyMetricsPolicyIgnore, emptyMetricsPolicyLog, emptyMetricsPolicyCount:
+	default:
+		return fmt.Errorf("empty_metrics_policy must be one of %q, %q or %q", emptyMetricsPolicyIgnore,
+			emptyMetricsPolicyLog, emptyMetricsPolicyCount)
+	}
+	switch cfg.UnsupportedTypePolicy {
+	case "", unsupportedTypePolicyDrop, unsupportedTypePolicyError:
+	default:
+		return fmt.Errorf("unsupported_type_policy must be one of %q or %q", unsupportedTypePolicyDrop,
+			unsupportedTypePolicyError)
+	}
+	switch cfg.DuplicateDataPointPolicy {
+	case "", duplicateDataPointPolicyLastWins, duplicateDataPointPolicyFirstWins, duplicateDataPointPolicyError:
+	default:
+		return fmt.Errorf("duplicate_data_point_policy must be one of %q, %q or %q", duplicateDataPointPolicyLastWins,
+			duplicateDataPointPolicyFirstWins, duplicateDataPointPolicyError)
+	}
+	switch cfg.UnitSuffixMode {
+	case "", prometheustranslator.UnitSuffixModeOTel, prometheustranslator.UnitSuffixModeRaw, prometheustranslator.UnitSuffixModeNone:
+	default:
+		return fmt.Errorf("unit_suffix_mode must be one of %q, %q or %q", prometheustranslator.UnitSuffixModeOTel,
+			prometheustranslator.UnitSuffixModeRaw, prometheustranslator.UnitSuffixModeNone)
+	}
 
 	return nil
 }
+
+// mutatesData reports whether the exporter modifies the metrics it is given, which then have to be copied when they
+// are shared with other consumers.
+func (cfg *Config) mutatesData() bool {
+	return cfg.DropZeroValueCounters || cfg.validatesExemplarTimestamps() || cfg.EmitCounterResetSamples ||
+		cfg.MetricTypeConflictPolicy != "" || len(cfg.ValueScaling) > 0
+}
+
+// validatesExemplarTimestamps reports whether the exemplars whose timestamp is outside the interval of their data
+// point are dropped or clamped.
+func (cfg *Config) validatesExemplarTimestamps() bool {
+	return cfg.ExemplarTimestampPolicy != "" && cfg.ExemplarTimestampPolicy != exemplarTimestampPolicyKeep
+}
+
+// exemplarMetricFilter compiles ExemplarMetricFilter, anchored so that it matches whole metric names. It returns
+// nil if it is empty.
+func (cfg *Config) exemplarMetricFilter() (*regexp.Regexp, error) {
+	if cfg.ExemplarMetricFilter == "" {
+		return nil, nil
+	}
+	filter, err := regexp.Compile("^(?:" + cfg.ExemplarMetricFilter + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid exemplar_metric_filter %q: %w", cfg.ExemplarMetricFilter, err)
+	}
+	return filter, nil
+}
+
+const defaultTemporalityLabelName = "__temporality__"
+
+// temporalityLabel returns the name of the label holding the aggregation temporality of the series, or "" if it
+// isn't emitted.
+func (cfg *Config) temporalityLabel() string {
+	if !cfg.EmitTemporalityLabel {
+		return ""
+	}
+	if cfg.TemporalityLabelName != "" {
+		return cfg.TemporalityLabelName
+	}
+	return defaultTemporalityLabelName
+}
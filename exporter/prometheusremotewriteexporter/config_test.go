@@ -21,6 +21,7 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadata"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/resourcetotelemetry"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -80,7 +81,13 @@ func TestLoadConfig(t *testing.T) {
 				TargetInfo: &TargetInfo{
 					Enabled: true,
 				},
-				CreatedMetric: &CreatedMetric{Enabled: true},
+				CreatedMetric:           &CreatedMetric{Enabled: true},
+				EnforceSampleOrder:      true,
+				FollowRedirects:         true,
+				InvalidLabelNamePolicy:  prometheusremotewrite.InvalidLabelNamePolicySanitize,
+				NoRecordedValueAsStale:  true,
+				SeriesRateLimitInterval: time.Minute,
+				TranslationConcurrency:  1,
 			},
 		},
 		{
@@ -95,6 +102,246 @@ func TestLoadConfig(t *testing.T) {
 			id:           component.NewIDWithName(metadata.Type, "less_than_1_max_batch_request_parallelism"),
 			errorMessage: "max_batch_request_parallelism can't be set to below 1",
 		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_max_samples_per_request"),
+			errorMessage: "max_samples_per_request can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_max_samples_per_series_per_interval"),
+			errorMessage: "max_samples_per_series_per_interval can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_max_exemplars_per_series"),
+			errorMessage: "max_exemplars_per_series can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "retry_budget_without_rate"),
+			errorMessage: "retry_budget rate must be positive",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_wal_corruption_policy"),
+			errorMessage: `wal corruption_policy must be one of "fail", "quarantine" or "repair"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_wal_incompatible_policy"),
+			errorMessage: `wal incompatible_policy must be one of "fail" or "quarantine"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "retry_timeout_multiplier_below_one"),
+			errorMessage: "retry_timeout_multiplier must be at least 1",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_max_retries"),
+			errorMessage: "max_retries can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "file_archive_without_directory"),
+			errorMessage: "file_archive requires a directory",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_unit_suffix_mode"),
+			errorMessage: `unit_suffix_mode must be one of "otel", "raw" or "none"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "invalid_remapped_label_name"),
+			errorMessage: `label_name_remapping: "status.code" isn't a valid label name for attribute "http.status_code"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_compression_min_bytes"),
+			errorMessage: "compression_min_bytes can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_min_flush_interval"),
+			errorMessage: "min_flush_interval can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_coalesce_max_samples"),
+			errorMessage: "coalesce max_samples can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "histogram_count_fast_path_without_coalesce"),
+			errorMessage: "histogram_count_fast_path requires coalesce",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_histogram_count_flush_interval"),
+			errorMessage: "histogram_count_flush_interval can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "histogram_count_flush_interval_not_shorter"),
+			errorMessage: "histogram_count_flush_interval must be shorter than the coalesce flush_interval",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_partial_translation_policy"),
+			errorMessage: `partial_translation_policy must be one of "drop_resource" or "drop_batch"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_snappy_format"),
+			errorMessage: `snappy_format must be one of "block" or "stream"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "spill_to_wal_without_wal"),
+			errorMessage: `buffer_full_policy "spill_to_wal" requires the WAL to be enabled`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_wal_replay_concurrency"),
+			errorMessage: "wal replay_concurrency can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_wal_max_entry_age"),
+			errorMessage: "wal max_entry_age can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "wal_audit_sample_rate_above_one"),
+			errorMessage: "wal audit_sample_rate must be between 0 and 1",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_wal_shutdown_strategy"),
+			errorMessage: `wal shutdown_strategy must be one of "oldest_first" or "newest_first"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "max_metric_name_bytes_too_small_to_truncate"),
+			errorMessage: "max_metric_name_bytes must be greater than 25 to truncate the metric names",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_metric_name_length_policy"),
+			errorMessage: "metric_name_length_policy must be one of \"truncate\" or \"drop\"",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_timestamp_rounding"),
+			errorMessage: "timestamp_rounding must be one of \"truncate\" or \"nearest\"",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "dead_letter_total_size_below_rotation_size"),
+			errorMessage: "dead_letter max_total_size_bytes can't be less than rotation_size_bytes",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_translation_concurrency"),
+			errorMessage: "translation_concurrency can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_name_conflict_policy"),
+			errorMessage: `name_conflict_policy must be one of "override", "preserve" or "error"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_histogram_nan_policy"),
+			errorMessage: `histogram_nan_policy must be one of "pass", "drop" or "zero_sum"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_metric_type_conflict_policy"),
+			errorMessage: `metric_type_conflict_policy must be one of "drop_later", "suffix_type" or "error"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_metadata_resend_interval"),
+			errorMessage: "metadata_resend_interval can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_dial_timeout"),
+			errorMessage: "dial_timeout can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "collector_id_without_label"),
+			errorMessage: "collector_id requires collector_id_label to be set",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_backend_limit"),
+			errorMessage: "backend_limits max_label_name_bytes can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "conflicting_series_per_request"),
+			errorMessage: "backend_limits max_series_per_request is an alias of max_series_per_request, they can't be set to different values",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_labels_per_series_policy"),
+			errorMessage: "backend_limits labels_per_series_policy must be one of \"drop\" or \"truncate\"",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "dropped_label_count_without_truncate"),
+			errorMessage: "record_dropped_label_count requires backend_limits labels_per_series_policy to be \"truncate\"",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "overload_sampling_without_high_water_mark"),
+			errorMessage: "overload_sampling_rate requires overload_high_water_mark",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_priority"),
+			errorMessage: `priority_rules priority must be one of "high", "normal" or "low"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "temporality_label_name_conflict"),
+			errorMessage: `temporality_label_name can't be "__name__"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "dispatch_order_with_series_affinity"),
+			errorMessage: `dispatch_order "newest_first" can't be used with series_affinity`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "invalid_exemplar_metric_filter"),
+			errorMessage: "invalid exemplar_metric_filter \"latency_(seconds\": error parsing regexp: missing closing ): `^(?:latency_(seconds)$`",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_wal_replay_accept_timeout"),
+			errorMessage: "wal replay_accept_timeout can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_wal_entry_size_policy"),
+			errorMessage: `wal entry_size_policy must be one of "reject" or "split"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_target_info_emit_interval"),
+			errorMessage: "target_info emit_interval can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_duplicate_data_point_policy"),
+			errorMessage: `duplicate_data_point_policy must be one of "last_wins", "first_wins" or "error"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "value_scaling_zero_multiplier"),
+			errorMessage: "value_scaling multiplier must be a finite number other than 0",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "negative_shutdown_drain_timeout"),
+			errorMessage: "shutdown_drain_timeout can't be negative",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "series_gap_threshold_below_one"),
+			errorMessage: "series_gap_threshold must be at least 1",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_unsupported_type_policy"),
+			errorMessage: `unsupported_type_policy must be one of "drop" or "error"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_batch_grouping"),
+			errorMessage: `batch_grouping must be one of "mixed" or "by_resource"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_exemplar_timestamp_policy"),
+			errorMessage: `exemplar_timestamp_policy must be one of "keep", "drop" or "clamp"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_empty_metrics_policy"),
+			errorMessage: `empty_metrics_policy must be one of "ignore", "log" or "count"`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "thanos_without_thanos_backend"),
+			errorMessage: `thanos requires the "thanos" backend`,
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "thanos_tenant_in_headers"),
+			errorMessage: "thanos tenant is also set by the thanos-tenant header in headers",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "thanos_replica_in_headers"),
+			errorMessage: "thanos replica is also set by the thanos-replica header in headers",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "endpoint_from_env_without_variable"),
+			errorMessage: "endpoint_from_env requires a variable",
+		},
+		{
+			id:           component.NewIDWithName(metadata.Type, "unknown_invalid_label_name_policy"),
+			errorMessage: `invalid_label_name_policy must be one of "sanitize", "drop_series" or "error"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -142,6 +389,20 @@ func TestDisabledTargetInfo(t *testing.T) {
 	assert.False(t, cfg.(*Config).TargetInfo.Enabled)
 }
 
+func TestTargetInfoExcludeAttributes(t *testing.T) {
+	cm, err := confmaptest.LoadConf(filepath.Join("testdata", "config.yaml"))
+	require.NoError(t, err)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+
+	sub, err := cm.Sub(component.NewIDWithName(metadata.Type, "target_info_exclude_attributes").String())
+	require.NoError(t, err)
+	require.NoError(t, sub.Unmarshal(cfg))
+
+	assert.True(t, cfg.(*Config).TargetInfo.Enabled)
+	assert.Equal(t, []string{"process.command_line", "process.pid"}, cfg.(*Config).TargetInfo.ExcludeAttributes)
+}
+
 func toPtr[T any](val T) *T {
 	return &val
 }
@@ -14,6 +14,7 @@ type ctxKey int
 
 const (
 	loggerCtxKey ctxKey = iota
+	walSourceIDCtxKey
 )
 
 func contextWithLogger(ctx context.Context, log *zap.Logger) context.Context {
@@ -33,3 +34,15 @@ func loggerFromContext(ctx context.Context) (*zap.Logger, error) {
 
 	return l, nil
 }
+
+// ContextWithWALSourceID returns a context tagging the WAL entries persisted with it with sourceID, for
+// example the ID of the pipeline they come from. The source ID is logged at debug level when the entries
+// are read back from the WAL for export. Entries aren't tagged unless a source ID is set.
+func ContextWithWALSourceID(ctx context.Context, sourceID string) context.Context {
+	return context.WithValue(ctx, walSourceIDCtxKey, sourceID)
+}
+
+func walSourceIDFromContext(ctx context.Context) string {
+	sourceID, _ := ctx.Value(walSourceIDCtxKey).(string)
+	return sourceID
+}
@@ -0,0 +1,107 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// counterResetTracker detects the resets of cumulative monotonic sums, whose value drops below the previous
+// one, and makes them explicit by inserting a zero valued data point at the time of the reset. The previous
+// value of the least recently seen series is forgotten once more than maxSeries are tracked.
+type counterResetTracker struct {
+	mu     sync.Mutex
+	series *seriesLRU[counterResetEntry]
+}
+
+type counterResetEntry struct {
+	value     float64
+	timestamp pcommon.Timestamp
+}
+
+func newCounterResetTracker(maxSeries int) *counterResetTracker {
+	return &counterResetTracker{series: newSeriesLRU[counterResetEntry](maxSeries)}
+}
+
+// injectResetSamples adds a zero valued data point to md for every counter reset it detects, and returns
+// the number of data points added. The data points of a series are expected in the order of their timestamps,
+// like SDKs send them.
+func (c *counterResetTracker) injectResetSamples(md pmetric.Metrics) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	injected := 0
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		resourceMetrics := resourceMetricsSlice.At(i)
+		scopeMetricsSlice := resourceMetrics.ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			scopeMetrics := scopeMetricsSlice.At(j)
+			metricSlice := scopeMetrics.Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				if metric.Type() != pmetric.MetricTypeSum || !metric.Sum().IsMonotonic() ||
+					metric.Sum().AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+					continue
+				}
+				dataPoints := metric.Sum().DataPoints()
+				// The injected data points are appended, only the received ones are looked at.
+				for l, n := 0, dataPoints.Len(); l < n; l++ {
+					pt := dataPoints.At(l)
+					if pt.Flags().NoRecordedValue() {
+						continue
+					}
+					key := seriesHash(resourceMetrics.Resource(), scopeMetrics.Scope(), metric.Name(), pt.Attributes())
+					entry, found := c.series.getOrAdd(key)
+					value := numberDataPointValue(pt)
+					if found && pt.Timestamp() <= entry.timestamp {
+						// Older or duplicate data points aren't compared to the newer value.
+						continue
+					}
+					if found && value < entry.value {
+						if timestamp, ok := counterResetTimestamp(entry.timestamp, pt.StartTimestamp(), pt.Timestamp()); ok {
+							resetPt := dataPoints.AppendEmpty()
+							pt.CopyTo(resetPt)
+							resetPt.SetTimestamp(timestamp)
+							if resetPt.ValueType() == pmetric.NumberDataPointValueTypeInt {
+								resetPt.SetIntValue(0)
+							} else {
+								resetPt.SetDoubleValue(0)
+							}
+							resetPt.Exemplars().RemoveIf(func(pmetric.Exemplar) bool { return true })
+							injected++
+						}
+					}
+					entry.value, entry.timestamp = value, pt.Timestamp()
+				}
+			}
+		}
+	}
+	return injected
+}
+
+func (c *counterResetTracker) takeStats() seriesCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.series.takeStats()
+}
+
+// counterResetTimestamp returns the time of the reset of a counter between its data points at previous and
+// timestamp: the start of the data point after the reset, or the millisecond before it when the start isn't
+// in between. It returns false when no sample can be placed in between, as samples have millisecond precision.
+func counterResetTimestamp(previous, start, timestamp pcommon.Timestamp) (pcommon.Timestamp, bool) {
+	reset := start
+	if reset <= previous || reset >= timestamp {
+		reset = timestamp - pcommon.Timestamp(time.Millisecond)
+	}
+	millis := func(t pcommon.Timestamp) uint64 { return uint64(t) / uint64(time.Millisecond) }
+	if millis(reset) <= millis(previous) || millis(reset) >= millis(timestamp) {
+		return 0, false
+	}
+	return reset, true
+}
@@ -0,0 +1,123 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsEmitCounterResetSamples(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	counter := func(value float64, startTime, timestamp time.Time) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName("requests")
+		sum := metric.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		dp := sum.DataPoints().AppendEmpty()
+		if !startTime.IsZero() {
+			dp.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
+		}
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		dp.SetDoubleValue(value)
+		return metric
+	}
+	sample := func(value float64, timestamp time.Time) prompb.Sample {
+		return prompb.Sample{Value: value, Timestamp: timestamp.UnixMilli()}
+	}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		// resetStart is the start time of the data point after the reset.
+		resetStart time.Time
+		want       []prompb.Sample
+	}{
+		{
+			name:       "disabled",
+			resetStart: start.Add(45 * time.Second),
+			want:       []prompb.Sample{sample(10, start.Add(30*time.Second)), sample(3, start.Add(60*time.Second))},
+		},
+		{
+			name:       "reset at start time",
+			enabled:    true,
+			resetStart: start.Add(45 * time.Second),
+			want: []prompb.Sample{
+				sample(10, start.Add(30*time.Second)),
+				sample(0, start.Add(45*time.Second)),
+				sample(3, start.Add(60*time.Second)),
+			},
+		},
+		{
+			name:    "reset without start time",
+			enabled: true,
+			want: []prompb.Sample{
+				sample(10, start.Add(30*time.Second)),
+				sample(0, start.Add(60*time.Second-time.Millisecond)),
+				sample(3, start.Add(60*time.Second)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []prompb.Sample
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						got = append(got, ts.Samples...)
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.EmitCounterResetSamples = tt.enabled
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(counter(5, start, start.Add(15*time.Second)))))
+			got = nil
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(counter(10, start, start.Add(30*time.Second)))))
+			// The counter restarted and dropped below its previous value.
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(counter(3, tt.resetStart, start.Add(60*time.Second)))))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCounterResetTimestamp(t *testing.T) {
+	ms := func(n int) pcommon.Timestamp { return pcommon.Timestamp(n) * pcommon.Timestamp(time.Millisecond) }
+	tests := []struct {
+		name                       string
+		previous, start, timestamp pcommon.Timestamp
+		want                       pcommon.Timestamp
+		wantOK                     bool
+	}{
+		{name: "start in between", previous: ms(1000), start: ms(1500), timestamp: ms(2000), want: ms(1500), wantOK: true},
+		{name: "start before previous", previous: ms(1000), start: ms(500), timestamp: ms(2000), want: ms(1999), wantOK: true},
+		{name: "no start", previous: ms(1000), timestamp: ms(2000), want: ms(1999), wantOK: true},
+		{name: "start in the same millisecond as previous", previous: ms(1000), start: ms(1000) + 1, timestamp: ms(1001) + 5, wantOK: false},
+		{name: "consecutive milliseconds", previous: ms(1000), timestamp: ms(1001), wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := counterResetTimestamp(tt.previous, tt.start, tt.timestamp)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+// DeadLetter configures writing the batches that are dropped permanently to local files.
+type DeadLetter struct {
+	// Directory is the directory the dead-letter files are written to.
+	Directory string `mapstructure:"directory"`
+
+	// RotationSizeBytes is the size above which a new dead-letter file is started.
+	RotationSizeBytes int64 `mapstructure:"rotation_size_bytes"`
+
+	// MaxTotalSizeBytes is the total size of the dead-letter files, above which the oldest ones are removed.
+	MaxTotalSizeBytes int64 `mapstructure:"max_total_size_bytes"`
+}
+
+const defaultDeadLetterMaxTotalSizeBytes = 1 << 30
+
+// newDeadLetterWriter returns the archiver writing the dead-letter files, which use the format of the file
+// archive.
+func newDeadLetterWriter(cfg *DeadLetter) *fileArchiver {
+	return &fileArchiver{
+		directory:    cfg.Directory,
+		prefix:       "dead_letter",
+		rotationSize: cfg.RotationSizeBytes,
+		maxTotalSize: cfg.MaxTotalSizeBytes,
+	}
+}
+
+// writeDeadLetter writes the request, which is dropped permanently, to the dead-letter files, if they are
+// enabled. Failing to do so is only logged, as the request is dropped either way.
+func (prwe *prwExporter) writeDeadLetter(writeReq *prompb.WriteRequest) {
+	if prwe.deadLetter == nil {
+		return
+	}
+	data, err := proto.Marshal(writeReq)
+	if err == nil {
+		err = prwe.deadLetter.write(snappy.Encode(nil, data))
+	}
+	if err != nil {
+		prwe.settings.Logger.Error("failed to write the dropped batch to the dead-letter files", zap.Error(err),
+			zap.Int("series", len(writeReq.Timeseries)))
+	}
+}
+
+// writeDeadLetterSeries writes the translated series of a batch that is dropped permanently to the
+// dead-letter files, if they are enabled.
+func (prwe *prwExporter) writeDeadLetterSeries(tsMap map[string]*prompb.TimeSeries) {
+	if prwe.deadLetter == nil || len(tsMap) == 0 {
+		return
+	}
+	writeReq := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(tsMap))}
+	for _, ts := range tsMap {
+		writeReq.Timeseries = append(writeReq.Timeseries, *ts)
+	}
+	prwe.writeDeadLetter(writeReq)
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func TestDeadLetter(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusBadRequest)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.BackOffConfig.Enabled = false
+	cfg.DeadLetter = &DeadLetter{Directory: dir}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	require.NoError(t, prwe.Start(context.Background(), componenttest.NewNopHost()))
+
+	rejected := makeReq(0)[0]
+	err = prwe.execute(context.Background(), rejected)
+	assert.True(t, consumererror.IsPermanent(err))
+
+	// Requests that failed only because the endpoint was unavailable aren't dead-lettered.
+	status.Store(http.StatusServiceUnavailable)
+	assert.Error(t, prwe.execute(context.Background(), makeReq(1)[0]))
+	require.NoError(t, prwe.Shutdown(context.Background()))
+
+	files, deadLettered := readArchiveFiles(t, filepath.Join(dir, "dead_letter-*.archive"))
+	assert.Equal(t, 1, files)
+	assert.Equal(t, []*prompb.WriteRequest{rejected}, deadLettered)
+}
+
+func TestDeadLetterMaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	// Every record gets a file of its own, and only two files are kept.
+	writer := newDeadLetterWriter(&DeadLetter{Directory: dir, RotationSizeBytes: 5, MaxTotalSizeBytes: 10})
+	for _, record := range []string{"abcd", "efgh", "ijkl"} {
+		require.NoError(t, writer.write([]byte(record)))
+	}
+	require.NoError(t, writer.close())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "dead_letter-*.archive"))
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	var contents []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		contents = append(contents, string(data))
+	}
+	assert.Equal(t, []string{"\x04efgh", "\x04ijkl"}, contents)
+}
@@ -0,0 +1,58 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// errDialTimeout is the cause of the requests canceled because no connection to the endpoint was established
+// within dial_timeout.
+var errDialTimeout = errors.New("timed out establishing a connection to the endpoint")
+
+// dialTimeoutRoundTripper fails the requests that don't get a connection to the endpoint within timeout, DNS
+// resolution and TLS handshake included, without waiting for the timeout of the whole request. Once a
+// connection is obtained, new or reused, only the timeout of the request applies.
+type dialTimeoutRoundTripper struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (rt *dialTimeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(rt.timeout, func() { cancel(errDialTimeout) })
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { timer.Stop() },
+	})
+	resp, err := rt.next.RoundTrip(req.WithContext(ctx))
+	timer.Stop()
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errDialTimeout) {
+			err = fmt.Errorf("%w after %s: %w", errDialTimeout, rt.timeout, err)
+		}
+		cancel(nil)
+		return nil, err
+	}
+	// The body is read after RoundTrip returns, the context is canceled once it is closed.
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnCloseBody cancels the context of the request once the body of its response is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func TestDialTimeout(t *testing.T) {
+	writeReq := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+	}}}
+	newExporter := func(t *testing.T, endpoint string) *prwExporter {
+		cfg := createDefaultConfig().(*Config)
+		cfg.ClientConfig.Endpoint = endpoint
+		cfg.ClientConfig.Timeout = 30 * time.Second
+		cfg.DialTimeout = 200 * time.Millisecond
+		cfg.BackOffConfig.Enabled = false
+		require.NoError(t, cfg.Validate())
+		prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+		require.NoError(t, err)
+		require.NoError(t, prwe.Start(context.Background(), componenttest.NewNopHost()))
+		t.Cleanup(func() {
+			assert.NoError(t, prwe.Shutdown(context.Background()))
+		})
+		return prwe
+	}
+
+	t.Run("unreachable endpoint", func(t *testing.T) {
+		// The endpoint accepts TCP connections but never answers the TLS handshake, like a host dropping packets.
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		go func() {
+			var conns []net.Conn
+			defer func() {
+				for _, conn := range conns {
+					conn.Close()
+				}
+			}()
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conns = append(conns, conn)
+			}
+		}()
+		prwe := newExporter(t, "https://"+ln.Addr().String())
+
+		begin := time.Now()
+		err = prwe.execute(context.Background(), writeReq)
+		require.ErrorIs(t, err, errDialTimeout)
+		assert.Less(t, time.Since(begin), 10*time.Second, "the dial timeout should fire before the request timeout")
+	})
+
+	t.Run("slow response", func(t *testing.T) {
+		// Only establishing the connection is bounded by the dial timeout.
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(500 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		prwe := newExporter(t, server.URL)
+
+		require.NoError(t, prwe.execute(context.Background(), writeReq))
+	})
+}
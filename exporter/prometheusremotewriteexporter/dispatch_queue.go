@@ -0,0 +1,164 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// dispatchOrderFIFO sends the requests of every export in order, concurrent exports sending theirs
+	// independently. It is the default.
+	dispatchOrderFIFO = "fifo"
+	// dispatchOrderNewestFirst sends the pending requests of all the exports holding the newest samples first,
+	// so that recent data preempts the replay of a backlog.
+	dispatchOrderNewestFirst = "newest_first"
+)
+
+// dispatchPriority orders the requests waiting to be sent, those with the newest samples first and then the
+// smallest ones, the order they were queued in breaking ties.
+type dispatchPriority struct {
+	newest int64
+	series int
+	seq    uint64
+}
+
+func (p dispatchPriority) before(other dispatchPriority) bool {
+	if p.newest != other.newest {
+		return p.newest > other.newest
+	}
+	if p.series != other.series {
+		return p.series < other.series
+	}
+	return p.seq < other.seq
+}
+
+// requestNewestTimestamp returns the timestamp of the newest sample or histogram of req, math.MinInt64 if it
+// holds none.
+func requestNewestTimestamp(req *prompb.WriteRequest) int64 {
+	newest := int64(math.MinInt64)
+	for _, ts := range req.Timeseries {
+		if timestamp, ok := newestTimestamp(ts); ok {
+			newest = max(newest, timestamp)
+		}
+	}
+	return newest
+}
+
+type dispatchWaiter struct {
+	priority dispatchPriority
+	ready    chan struct{}
+	index    int
+}
+
+type dispatchHeap []*dispatchWaiter
+
+func (h dispatchHeap) Len() int           { return len(h) }
+func (h dispatchHeap) Less(i, j int) bool { return h[i].priority.before(h[j].priority) }
+func (h dispatchHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *dispatchHeap) Push(x any) {
+	waiter := x.(*dispatchWaiter)
+	waiter.index = len(*h)
+	*h = append(*h, waiter)
+}
+
+func (h *dispatchHeap) Pop() any {
+	old := *h
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	waiter.index = -1
+	return waiter
+}
+
+// dispatchQueue bounds the number of requests sent concurrently across all the exports, and hands the free
+// slots to the waiting requests by priority rather than in the order they arrived.
+type dispatchQueue struct {
+	mu      sync.Mutex
+	free    int
+	seq     uint64
+	waiting dispatchHeap
+}
+
+func newDispatchQueue(slots int) *dispatchQueue {
+	return &dispatchQueue{free: max(slots, 1)}
+}
+
+// acquire blocks until req may be sent, or ctx is done. The slot must be given back with release once req
+// was sent.
+func (q *dispatchQueue) acquire(ctx context.Context, req *prompb.WriteRequest) error {
+	q.mu.Lock()
+	if q.free > 0 && len(q.waiting) == 0 {
+		q.free--
+		q.mu.Unlock()
+		return nil
+	}
+	q.seq++
+	waiter := &dispatchWaiter{
+		priority: dispatchPriority{newest: requestNewestTimestamp(req), series: len(req.Timeseries), seq: q.seq},
+		ready:    make(chan struct{}),
+	}
+	heap.Push(&q.waiting, waiter)
+	q.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if waiter.index >= 0 {
+			heap.Remove(&q.waiting, waiter.index)
+			return ctx.Err()
+		}
+		// The slot was handed over meanwhile, it goes to the next request.
+		q.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+// release gives back a slot, to the waiting request of highest priority if any.
+func (q *dispatchQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *dispatchQueue) releaseLocked() {
+	if len(q.waiting) == 0 {
+		q.free++
+		return
+	}
+	close(heap.Pop(&q.waiting).(*dispatchWaiter).ready)
+}
+
+// len returns the number of requests waiting for a slot.
+func (q *dispatchQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+// sortByDispatchPriority sorts requests by the priority they are dispatched with, keeping the order of those
+// of equal priority.
+func sortByDispatchPriority(requests []*prompb.WriteRequest) {
+	priorities := make(map[*prompb.WriteRequest]dispatchPriority, len(requests))
+	for _, req := range requests {
+		priorities[req] = dispatchPriority{newest: requestNewestTimestamp(req), series: len(req.Timeseries)}
+	}
+	sort.SliceStable(requests, func(i, j int) bool {
+		return priorities[requests[i]].before(priorities[requests[j]])
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+// dispatchTestRequest returns a request of series samples named after name, all at timestamp.
+func dispatchTestRequest(name string, series int, timestamp int64) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{}
+	for i := 0; i < series; i++ {
+		req.Timeseries = append(req.Timeseries, prompb.TimeSeries{
+			Labels:  []prompb.Label{{Name: "__name__", Value: name}, {Name: "i", Value: fmt.Sprint(i)}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: timestamp}},
+		})
+	}
+	return req
+}
+
+func TestDispatchQueuePreemption(t *testing.T) {
+	q := newDispatchQueue(1)
+	ctx := context.Background()
+	// The only slot is taken, so that the next requests wait.
+	require.NoError(t, q.acquire(ctx, dispatchTestRequest("busy", 1, 0)))
+
+	var mu sync.Mutex
+	var dispatched []string
+	var wg sync.WaitGroup
+	enqueue := func(name string, req *prompb.WriteRequest, waiting int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, q.acquire(ctx, req))
+			mu.Lock()
+			dispatched = append(dispatched, name)
+			mu.Unlock()
+			q.release()
+		}()
+		require.Eventually(t, func() bool { return q.len() == waiting }, time.Second, time.Millisecond)
+	}
+	enqueue("old", dispatchTestRequest("old", 100, 1_000), 1)
+	enqueue("recent", dispatchTestRequest("recent", 1, 2_000), 2)
+	enqueue("recent_large", dispatchTestRequest("recent_large", 10, 2_000), 3)
+
+	// A waiting request whose context is done leaves the queue.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, q.acquire(cancelled, dispatchTestRequest("cancelled", 1, 3_000)), context.Canceled)
+	assert.Equal(t, 3, q.len())
+
+	q.release()
+	wg.Wait()
+	assert.Equal(t, []string{"recent", "recent_large", "old"}, dispatched)
+	assert.Equal(t, 1, q.free)
+}
+
+func TestExportDispatchOrderNewestFirst(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		assert.NoError(t, err)
+		req := &prompb.WriteRequest{}
+		assert.NoError(t, proto.Unmarshal(data, req))
+		received = append(received, req.Timeseries[0].Labels[0].Value)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	for order, want := range map[string][]string{
+		dispatchOrderFIFO:        {"old", "recent"},
+		dispatchOrderNewestFirst: {"recent", "old"},
+	} {
+		received = nil
+		cfg := createDefaultConfig().(*Config)
+		cfg.ClientConfig.Endpoint = server.URL
+		cfg.RemoteWriteQueue.NumConsumers = 1
+		cfg.RemoteWriteQueue.DispatchOrder = order
+		require.NoError(t, cfg.Validate())
+		prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+		require.NoError(t, err)
+		prwe.client = server.Client()
+
+		requests := []*prompb.WriteRequest{dispatchTestRequest("old", 100, 1_000), dispatchTestRequest("recent", 1, 2_000)}
+		require.NoError(t, prwe.export(context.Background(), requests))
+		assert.Equal(t, want, received, order)
+		assert.Equal(t, "old", requests[0].Timeseries[0].Labels[0].Value, "the requests of the caller shouldn't be reordered")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// duplicateDataPointPolicyLastWins keeps the last of the data points translated to the same series and timestamp.
+	duplicateDataPointPolicyLastWins = "last_wins"
+	// duplicateDataPointPolicyFirstWins keeps the first of the data points translated to the same series and timestamp.
+	duplicateDataPointPolicyFirstWins = "first_wins"
+	// duplicateDataPointPolicyError rejects the batches holding data points translated to the same series and timestamp.
+	duplicateDataPointPolicyError = "error"
+)
+
+// droppedReasonDuplicateDataPoint is the reason reported for the samples of the duplicate data points that were
+// resolved by the duplicate data point policy.
+const droppedReasonDuplicateDataPoint = "duplicate_data_point"
+
+// resolveDuplicateDataPoints resolves the data points translated to the same series and timestamp, which
+// Prometheus rejects, according to the duplicate data point policy. The samples and histograms of a series are
+// in the order of their data points, which tells which ones come first. It returns an error if the policy
+// rejects duplicates and some were found, leaving tsMap as it is.
+func (prwe *prwExporter) resolveDuplicateDataPoints(ctx context.Context, tsMap map[string]*prompb.TimeSeries) error {
+	sampleTimestamp := func(s *prompb.Sample) int64 { return s.Timestamp }
+	histogramTimestamp := func(h *prompb.Histogram) int64 { return h.Timestamp }
+	if prwe.duplicateDataPointPolicy == duplicateDataPointPolicyError {
+		for _, ts := range tsMap {
+			if hasDuplicateTimestamps(ts.Samples, sampleTimestamp) || hasDuplicateTimestamps(ts.Histograms, histogramTimestamp) {
+				return fmt.Errorf("series of metric %q has several data points with the same timestamp", seriesMetricName(ts))
+			}
+		}
+		return nil
+	}
+
+	lastWins := prwe.duplicateDataPointPolicy == duplicateDataPointPolicyLastWins
+	dropped := 0
+	for _, ts := range tsMap {
+		var droppedSamples, droppedHistograms int
+		ts.Samples, droppedSamples = dedupByTimestamp(ts.Samples, sampleTimestamp, lastWins)
+		ts.Histograms, droppedHistograms = dedupByTimestamp(ts.Histograms, histogramTimestamp, lastWins)
+		dropped += droppedSamples + droppedHistograms
+	}
+	if dropped > 0 {
+		prwe.telemetry.recordDroppedSamples(ctx, droppedReasonDuplicateDataPoint, dropped)
+	}
+	return nil
+}
+
+// dedupByTimestamp keeps only one of the points with the same timestamp, the last one if lastWins is set and
+// the first one otherwise, in place and in the order of their first occurrence. It returns the remaining
+// points and the number of points dropped.
+func dedupByTimestamp[T any](points []T, timestamp func(*T) int64, lastWins bool) ([]T, int) {
+	if isOrderedByTimestamp(points, timestamp) {
+		return points, 0
+	}
+	positions := make(map[int64]int, len(points))
+	kept := points[:0]
+	for i := range points {
+		t := timestamp(&points[i])
+		if j, found := positions[t]; found {
+			if lastWins {
+				kept[j] = points[i]
+			}
+			continue
+		}
+		positions[t] = len(kept)
+		kept = append(kept, points[i])
+	}
+	return kept, len(points) - len(kept)
+}
+
+// hasDuplicateTimestamps returns whether several of the points have the same timestamp, without changing them.
+func hasDuplicateTimestamps[T any](points []T, timestamp func(*T) int64) bool {
+	if isOrderedByTimestamp(points, timestamp) {
+		return false
+	}
+	seen := make(map[int64]struct{}, len(points))
+	for i := range points {
+		t := timestamp(&points[i])
+		if _, found := seen[t]; found {
+			return true
+		}
+		seen[t] = struct{}{}
+	}
+	return false
+}
+
+// seriesMetricName returns the value of the __name__ label of ts.
+func seriesMetricName(ts *prompb.TimeSeries) string {
+	for _, label := range ts.Labels {
+		if label.Name == labels.MetricName {
+			return label.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsDuplicateDataPointPolicy(t *testing.T) {
+	timestamp := time.UnixMilli(1700000000000)
+	// Two data points with the same attributes and timestamp, as reported by a buggy SDK.
+	metric := pmetric.NewMetric()
+	metric.SetName("duplicated")
+	gauge := metric.SetEmptyGauge()
+	for _, value := range []float64{1, 2} {
+		dp := gauge.DataPoints().AppendEmpty()
+		dp.Attributes().PutStr("host", "a")
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		dp.SetDoubleValue(value)
+	}
+	// A data point of another series at the same timestamp isn't a duplicate.
+	dp := gauge.DataPoints().AppendEmpty()
+	dp.Attributes().PutStr("host", "b")
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	dp.SetDoubleValue(3)
+
+	tests := []struct {
+		name        string
+		policy      string
+		wantSamples map[string][]float64
+		wantDropped int
+		wantErr     bool
+	}{
+		{name: "unset", wantSamples: map[string][]float64{"a": {1, 2}, "b": {3}}},
+		{name: "last wins", policy: duplicateDataPointPolicyLastWins, wantSamples: map[string][]float64{"a": {2}, "b": {3}}, wantDropped: 1},
+		{name: "first wins", policy: duplicateDataPointPolicyFirstWins, wantSamples: map[string][]float64{"a": {1}, "b": {3}}, wantDropped: 1},
+		{name: "error", policy: duplicateDataPointPolicyError, wantSamples: map[string][]float64{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string][]float64{}
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						for _, label := range ts.Labels {
+							if label.Name == "host" {
+								for _, sample := range ts.Samples {
+									got[label.Value] = append(got[label.Value], sample.Value)
+								}
+							}
+						}
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			// The samples aren't deduplicated when they are sent, to observe the policy alone.
+			cfg.EnforceSampleOrder = false
+			cfg.DuplicateDataPointPolicy = tt.policy
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			tel := &droppedSamplesTelemetry{dropped: map[string]int{}}
+			prwe.telemetry = tel
+
+			err = prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric))
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, consumererror.IsPermanent(err))
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantSamples, got)
+			assert.Equal(t, tt.wantDropped, tel.dropped[droppedReasonDuplicateDataPoint])
+		})
+	}
+}
+
+// TestResolveDuplicateDataPointsErrorKeepsBatch checks that the error policy leaves the rejected batch as it was
+// translated, for the dead letter file.
+func TestResolveDuplicateDataPointsErrorKeepsBatch(t *testing.T) {
+	batch := func() map[string]*prompb.TimeSeries {
+		return map[string]*prompb.TimeSeries{
+			"deduplicated": {
+				Labels:  []prompb.Label{{Name: "__name__", Value: "a"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}, {Value: 2, Timestamp: 1000}},
+			},
+			"duplicated": {
+				Labels:     []prompb.Label{{Name: "__name__", Value: "b"}},
+				Histograms: []prompb.Histogram{{Sum: 1, Timestamp: 1000}, {Sum: 2, Timestamp: 1000}},
+			},
+		}
+	}
+	cfg := createDefaultConfig().(*Config)
+	cfg.DuplicateDataPointPolicy = duplicateDataPointPolicyError
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+
+	tsMap := batch()
+	require.Error(t, prwe.resolveDuplicateDataPoints(context.Background(), tsMap))
+	assert.Equal(t, batch(), tsMap)
+}
+
+func TestHasDuplicateTimestamps(t *testing.T) {
+	timestamp := func(s *prompb.Sample) int64 { return s.Timestamp }
+	assert.False(t, hasDuplicateTimestamps([]prompb.Sample{{Timestamp: 2000}, {Timestamp: 1000}, {Timestamp: 3000}}, timestamp))
+	assert.True(t, hasDuplicateTimestamps([]prompb.Sample{{Timestamp: 2000}, {Timestamp: 1000}, {Timestamp: 2000}}, timestamp))
+}
+
+func TestDedupByTimestamp(t *testing.T) {
+	points := func() []prompb.Sample {
+		return []prompb.Sample{
+			{Value: 1, Timestamp: 2000},
+			{Value: 2, Timestamp: 1000},
+			{Value: 3, Timestamp: 2000},
+			{Value: 4, Timestamp: 3000},
+			{Value: 5, Timestamp: 2000},
+		}
+	}
+	timestamp := func(s *prompb.Sample) int64 { return s.Timestamp }
+
+	kept, dropped := dedupByTimestamp(points(), timestamp, true)
+	assert.Equal(t, []prompb.Sample{{Value: 5, Timestamp: 2000}, {Value: 2, Timestamp: 1000}, {Value: 4, Timestamp: 3000}}, kept)
+	assert.Equal(t, 2, dropped)
+
+	kept, dropped = dedupByTimestamp(points(), timestamp, false)
+	assert.Equal(t, []prompb.Sample{{Value: 1, Timestamp: 2000}, {Value: 2, Timestamp: 1000}, {Value: 4, Timestamp: 3000}}, kept)
+	assert.Equal(t, 2, dropped)
+}
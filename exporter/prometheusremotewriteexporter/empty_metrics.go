@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+const (
+	// emptyMetricsPolicyIgnore drops the metrics without data points silently, besides failing their translation. It is the default.
+	emptyMetricsPolicyIgnore = "ignore"
+	// emptyMetricsPolicyLog logs the name of every metric without data points at debug level.
+	emptyMetricsPolicyLog = "log"
+	// emptyMetricsPolicyCount counts the metrics without data points by metric name.
+	emptyMetricsPolicyCount = "count"
+)
+
+// emptyMetricNames returns the names of the metrics of md that have a type but no data points.
+func emptyMetricNames(md pmetric.Metrics) []string {
+	var names []string
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				n := 0
+				//exhaustive:enforce
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					n = metric.Gauge().DataPoints().Len()
+				case pmetric.MetricTypeSum:
+					n = metric.Sum().DataPoints().Len()
+				case pmetric.MetricTypeHistogram:
+					n = metric.Histogram().DataPoints().Len()
+				case pmetric.MetricTypeExponentialHistogram:
+					n = metric.ExponentialHistogram().DataPoints().Len()
+				case pmetric.MetricTypeSummary:
+					n = metric.Summary().DataPoints().Len()
+				case pmetric.MetricTypeEmpty:
+					// Metrics without a type aren't translated for another reason.
+					continue
+				}
+				if n == 0 {
+					names = append(names, metric.Name())
+				}
+			}
+		}
+	}
+	return names
+}
+
+// reportEmptyMetrics logs or counts the metrics of md without data points, depending on the empty metrics policy.
+func (prwe *prwExporter) reportEmptyMetrics(ctx context.Context, md pmetric.Metrics) {
+	if prwe.emptyMetricsPolicy == "" || prwe.emptyMetricsPolicy == emptyMetricsPolicyIgnore {
+		return
+	}
+	for _, name := range emptyMetricNames(md) {
+		if prwe.emptyMetricsPolicy == emptyMetricsPolicyLog {
+			prwe.settings.Logger.Debug("metric has no data points", zap.String("metric", name))
+		} else {
+			prwe.telemetry.recordEmptyMetric(ctx, name)
+		}
+	}
+}
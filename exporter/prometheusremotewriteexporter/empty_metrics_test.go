@@ -0,0 +1,104 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
+)
+
+func TestPushMetricsEmptyMetricsPolicy(t *testing.T) {
+	exporterAttr := attribute.String("exporter", "prometheusremotewrite")
+	expectedEmptyMetrics := func(counts map[string]int64) metricdata.Metrics {
+		var dataPoints []metricdata.DataPoint[int64]
+		for name, count := range counts {
+			dataPoints = append(dataPoints, metricdata.DataPoint[int64]{
+				Value:      count,
+				Attributes: attribute.NewSet(exporterAttr, attribute.String("metric_name", name)),
+			})
+		}
+		return metricdata.Metrics{
+			Name:        "otelcol_exporter_prometheusremotewrite_empty_metrics",
+			Description: "Number of metrics without data points received by the exporter, by metric name, when empty_metrics_policy is count",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints:  dataPoints,
+			},
+		}
+	}
+
+	for _, policy := range []string{"", emptyMetricsPolicyIgnore, emptyMetricsPolicyLog, emptyMetricsPolicyCount} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.EmptyMetricsPolicy = policy
+			require.NoError(t, cfg.Validate())
+			tel := metadatatest.SetupTelemetry()
+			set := tel.NewSettings()
+			core, logs := observer.New(zapcore.DebugLevel)
+			set.Logger = zap.New(core)
+			sink := ExportSinkFunc(func(context.Context, []*prompb.WriteRequest) error { return nil })
+			prwe, err := newPRWExporter(cfg, set, WithExportSink(sink))
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				md := getMetricsFromMetricList(validMetrics1[validIntGauge], invalidMetrics[emptyGauge], invalidMetrics[emptySum])
+				require.NoError(t, prwe.PushMetrics(context.Background(), md))
+			}
+
+			var loggedEmpty []string
+			for _, entry := range logs.FilterMessage("metric has no data points").All() {
+				loggedEmpty = append(loggedEmpty, entry.ContextMap()["metric"].(string))
+			}
+			if policy == emptyMetricsPolicyLog {
+				assert.ElementsMatch(t, []string{emptyGauge, emptySum, emptyGauge, emptySum}, loggedEmpty)
+			} else {
+				assert.Empty(t, loggedEmpty)
+			}
+
+			expectedMetrics := []metricdata.Metrics{
+				{
+					Name:        "otelcol_exporter_prometheusremotewrite_failed_translations",
+					Description: "Number of translation operations that failed to translate metrics from Otel to Prometheus",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.CumulativeTemporality,
+						IsMonotonic: true,
+						DataPoints:  []metricdata.DataPoint[int64]{{Value: 2, Attributes: attribute.NewSet(exporterAttr)}},
+					},
+				},
+				{
+					Name:        "otelcol_exporter_prometheusremotewrite_translated_time_series",
+					Description: "Number of Prometheus time series that were translated from OTel metrics",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.CumulativeTemporality,
+						IsMonotonic: true,
+						DataPoints:  []metricdata.DataPoint[int64]{{Value: 2, Attributes: attribute.NewSet(exporterAttr)}},
+					},
+				},
+				expectedSamplesMetric(map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 2}),
+				expectedLastBatchSeriesMetric(1),
+			}
+			if policy == emptyMetricsPolicyCount {
+				expectedMetrics = append(expectedMetrics, expectedEmptyMetrics(map[string]int64{emptyGauge: 2, emptySum: 2}))
+			}
+			tel.AssertMetrics(t, expectedMetrics, metricdatatest.IgnoreTimestamp())
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+// EndpointFromEnv configures reading the endpoint from an environment variable at runtime.
+type EndpointFromEnv struct {
+	// Variable is the name of the environment variable holding the endpoint.
+	Variable string `mapstructure:"variable"`
+
+	// RefreshInterval is how often the environment variable is read again.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+}
+
+const defaultEndpointRefreshInterval = 30 * time.Second
+
+// endpointFromEnv returns the endpoint held by the environment variable configured in cfg, or the
+// configured endpoint if the variable is unset or empty.
+func endpointFromEnv(cfg *Config) string {
+	if cfg.EndpointFromEnv != nil {
+		if endpoint := os.Getenv(cfg.EndpointFromEnv.Variable); endpoint != "" {
+			return endpoint
+		}
+	}
+	return cfg.ClientConfig.Endpoint
+}
+
+// endpointAttribute returns the scheme, host and path of the endpoint, that its requests are reported by. The
+// credentials, query parameters and fragment it may hold are left out, as they may be secret or unbounded.
+func endpointAttribute(endpoint *url.URL) string {
+	return (&url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: endpoint.Path}).String()
+}
+
+// target returns the endpoint and client to send the next request with. Requests that are already
+// being sent keep using the ones they started with when the endpoint changes.
+func (prwe *prwExporter) target() (*url.URL, *http.Client) {
+	prwe.endpointMu.RLock()
+	defer prwe.endpointMu.RUnlock()
+	return prwe.endpointURL, prwe.client
+}
+
+// watchEndpointFromEnv reads the endpoint environment variable every refresh interval until the
+// exporter is shut down, and switches to a new client whenever the endpoint changes.
+func (prwe *prwExporter) watchEndpointFromEnv(host component.Host) {
+	ticker := time.NewTicker(prwe.endpointFromEnv.RefreshInterval)
+	prwe.wg.Add(1)
+	go func() {
+		defer prwe.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-prwe.closeChan:
+				return
+			case <-ticker.C:
+				if err := prwe.refreshEndpoint(host); err != nil {
+					prwe.settings.Logger.Error("failed to switch to the endpoint read from the environment, keeping the current one",
+						zap.String("variable", prwe.endpointFromEnv.Variable), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// EndpointResolver returns the URL of the remote write endpoint to send a request to. When it returns an
+// error, the request isn't sent and is retried like a failed request.
+type EndpointResolver func(ctx context.Context) (string, error)
+
+// resolveTarget returns the endpoint and client to send the next request with, asking the endpoint resolver
+// for the endpoint when there is one.
+func (prwe *prwExporter) resolveTarget(ctx context.Context) (*url.URL, *http.Client, error) {
+	endpointURL, client := prwe.target()
+	if prwe.endpointResolver == nil {
+		return endpointURL, client, nil
+	}
+	endpoint, err := prwe.endpointResolver(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve the endpoint: %w", err)
+	}
+	endpointURL, err = url.ParseRequestURI(endpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid endpoint returned by the endpoint resolver: %w", err)
+	}
+	return endpointURL, client, nil
+}
+
+// refreshEndpoint switches to the endpoint held by the environment variable, if it changed.
+func (prwe *prwExporter) refreshEndpoint(host component.Host) error {
+	endpoint := os.Getenv(prwe.endpointFromEnv.Variable)
+	current, _ := prwe.target()
+	if endpoint == "" || endpoint == current.String() {
+		return nil
+	}
+
+	endpointURL, err := url.ParseRequestURI(endpoint)
+	if err != nil {
+		return errors.New("invalid endpoint")
+	}
+	clientSettings := *prwe.clientSettings
+	clientSettings.Endpoint = endpoint
+	client, err := prwe.toClient(context.Background(), host, &clientSettings)
+	if err != nil {
+		return err
+	}
+
+	prwe.endpointMu.Lock()
+	previous := prwe.client
+	prwe.endpointURL, prwe.client = endpointURL, client
+	prwe.endpointMu.Unlock()
+	// In-flight requests keep their connections, only the idle ones are closed.
+	previous.CloseIdleConnections()
+
+	prwe.settings.Logger.Info("switched to the endpoint read from the environment",
+		zap.String("variable", prwe.endpointFromEnv.Variable), zap.String("endpoint", endpointURL.Redacted()))
+	return nil
+}
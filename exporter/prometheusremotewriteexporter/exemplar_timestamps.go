@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// exemplarTimestampPolicyKeep sends the exemplars as they are. It is the default.
+	exemplarTimestampPolicyKeep = "keep"
+	// exemplarTimestampPolicyDrop drops the exemplars whose timestamp is outside the interval of their data point.
+	exemplarTimestampPolicyDrop = "drop"
+	// exemplarTimestampPolicyClamp moves the timestamp of the exemplars outside the interval of their data point
+	// to the closest end of the interval.
+	exemplarTimestampPolicyClamp = "clamp"
+)
+
+// validateExemplarTimestamps drops or clamps, as policy sets, the exemplars of md whose timestamp is outside the
+// interval of their data point, from its start time, when it has one, to its timestamp, which backends reject as
+// in the future or out of order. It returns the number of exemplars dropped.
+func validateExemplarTimestamps(md pmetric.Metrics, policy string) int {
+	dropped := 0
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				//exhaustive:enforce
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints := metric.Gauge().DataPoints()
+					for l := 0; l < dataPoints.Len(); l++ {
+						pt := dataPoints.At(l)
+						dropped += validateDataPointExemplars(pt.Exemplars(), pt.StartTimestamp(), pt.Timestamp(), policy)
+					}
+				case pmetric.MetricTypeSum:
+					dataPoints := metric.Sum().DataPoints()
+					for l := 0; l < dataPoints.Len(); l++ {
+						pt := dataPoints.At(l)
+						dropped += validateDataPointExemplars(pt.Exemplars(), pt.StartTimestamp(), pt.Timestamp(), policy)
+					}
+				case pmetric.MetricTypeHistogram:
+					dataPoints := metric.Histogram().DataPoints()
+					for l := 0; l < dataPoints.Len(); l++ {
+						pt := dataPoints.At(l)
+						dropped += validateDataPointExemplars(pt.Exemplars(), pt.StartTimestamp(), pt.Timestamp(), policy)
+					}
+				case pmetric.MetricTypeExponentialHistogram:
+					dataPoints := metric.ExponentialHistogram().DataPoints()
+					for l := 0; l < dataPoints.Len(); l++ {
+						pt := dataPoints.At(l)
+						dropped += validateDataPointExemplars(pt.Exemplars(), pt.StartTimestamp(), pt.Timestamp(), policy)
+					}
+				case pmetric.MetricTypeSummary, pmetric.MetricTypeEmpty:
+					// Summaries don't have exemplars.
+				}
+			}
+		}
+	}
+	return dropped
+}
+
+// validateDataPointExemplars drops or clamps the exemplars outside [start, end], start being ignored when it is
+// zero, and returns the number of exemplars dropped.
+func validateDataPointExemplars(exemplars pmetric.ExemplarSlice, start, end pcommon.Timestamp, policy string) int {
+	dropped := 0
+	exemplars.RemoveIf(func(exemplar pmetric.Exemplar) bool {
+		ts := exemplar.Timestamp()
+		if ts <= end && (start == 0 || ts >= start) {
+			return false
+		}
+		if policy == exemplarTimestampPolicyDrop {
+			dropped++
+			return true
+		}
+		if ts > end {
+			exemplar.SetTimestamp(end)
+		} else {
+			exemplar.SetTimestamp(start)
+		}
+		return false
+	})
+	return dropped
+}
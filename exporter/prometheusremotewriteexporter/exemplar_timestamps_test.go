@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// droppedExemplarsTelemetry counts the recorded dropped exemplars and discards the rest of the telemetry.
+type droppedExemplarsTelemetry struct {
+	nopTelemetry
+	dropped int
+}
+
+func (d *droppedExemplarsTelemetry) recordDroppedExemplars(_ context.Context, numExemplars int) {
+	d.dropped += numExemplars
+}
+
+func TestPushMetricsExemplarTimestampPolicy(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	end := start.Add(time.Minute)
+	newMetrics := func() pmetric.Metrics {
+		metric := pmetric.NewMetric()
+		metric.SetName("requests")
+		dp := metric.SetEmptySum().DataPoints().AppendEmpty()
+		metric.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		dp.SetDoubleValue(10)
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(end))
+		// One exemplar before the interval of the data point, one within it and one in the future.
+		for i, timestamp := range []time.Time{start.Add(-time.Minute), start.Add(30 * time.Second), end.Add(time.Hour)} {
+			exemplar := dp.Exemplars().AppendEmpty()
+			exemplar.SetDoubleValue(float64(i))
+			exemplar.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		}
+		return getMetricsFromMetricList(metric)
+	}
+
+	tests := []struct {
+		policy        string
+		wantExemplars []prompb.Exemplar
+		wantDropped   int
+	}{
+		{
+			policy: exemplarTimestampPolicyKeep,
+			wantExemplars: []prompb.Exemplar{
+				{Value: 0, Timestamp: start.Add(-time.Minute).UnixMilli()},
+				{Value: 1, Timestamp: start.Add(30 * time.Second).UnixMilli()},
+				{Value: 2, Timestamp: end.Add(time.Hour).UnixMilli()},
+			},
+		},
+		{
+			policy:        exemplarTimestampPolicyDrop,
+			wantExemplars: []prompb.Exemplar{{Value: 1, Timestamp: start.Add(30 * time.Second).UnixMilli()}},
+			wantDropped:   2,
+		},
+		{
+			policy: exemplarTimestampPolicyClamp,
+			wantExemplars: []prompb.Exemplar{
+				{Value: 0, Timestamp: start.UnixMilli()},
+				{Value: 1, Timestamp: start.Add(30 * time.Second).UnixMilli()},
+				{Value: 2, Timestamp: end.UnixMilli()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var exported []prompb.TimeSeries
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					exported = append(exported, req.Timeseries...)
+				}
+				return nil
+			})
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.ExemplarTimestampPolicy = tt.policy
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			tel := &droppedExemplarsTelemetry{}
+			prwe.telemetry = tel
+			require.NoError(t, prwe.PushMetrics(context.Background(), newMetrics()))
+
+			require.Len(t, exported, 1)
+			assert.Equal(t, tt.wantExemplars, exported[0].Exemplars)
+			assert.Equal(t, tt.wantDropped, tel.dropped)
+		})
+	}
+}
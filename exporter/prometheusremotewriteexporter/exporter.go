@@ -12,12 +12,16 @@ import (
 	"math"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
+	"github.com/google/uuid"
 	"github.com/prometheus/prometheus/prompb"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
@@ -27,6 +31,7 @@ import (
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 
@@ -37,7 +42,28 @@ import (
 
 type prwTelemetry interface {
 	recordTranslationFailure(ctx context.Context)
+	recordEmptyMetric(ctx context.Context, metricName string)
+	recordLongMetricNames(ctx context.Context, numNames int)
+	recordMetricTypeConflicts(ctx context.Context, numMetrics int)
 	recordTranslatedTimeSeries(ctx context.Context, numTS int)
+	recordWALDiskFull(ctx context.Context)
+	recordWALAuditFailures(ctx context.Context, numEntries int)
+	recordWALTruncation(ctx context.Context, index uint64)
+	recordWALOldestEntryAge(ctx context.Context, age time.Duration)
+	recordNegotiatedProtocol(ctx context.Context, version int64)
+	recordDroppedSamples(ctx context.Context, reason string, numSamples int)
+	recordLimitViolations(ctx context.Context, limit string, numViolations int)
+	recordSamples(ctx context.Context, metricType, temporality string, numSamples int)
+	recordQueueDepth(ctx context.Context, depth int64)
+	recordPaused(ctx context.Context, paused bool)
+	recordRetryBackoff(ctx context.Context, interval time.Duration)
+	recordLastBatchSeries(ctx context.Context, numSeries int)
+	recordSeriesCache(ctx context.Context, cache string, stats seriesCacheStats)
+	recordNonMonotonicSeries(ctx context.Context, numSeries int)
+	recordSeriesGaps(ctx context.Context, numSeries int)
+	recordUnsupportedMetric(ctx context.Context, metricType string)
+	recordDroppedExemplars(ctx context.Context, numExemplars int)
+	recordRequest(ctx context.Context, endpoint, outcome string)
 }
 
 type prwTelemetryOtel struct {
@@ -49,10 +75,201 @@ func (p *prwTelemetryOtel) recordTranslationFailure(ctx context.Context) {
 	p.telemetryBuilder.ExporterPrometheusremotewriteFailedTranslations.Add(ctx, 1, metric.WithAttributes(p.otelAttrs...))
 }
 
+func (p *prwTelemetryOtel) recordEmptyMetric(ctx context.Context, metricName string) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteEmptyMetrics.Add(ctx, 1, metric.WithAttributes(p.otelAttrs...),
+		metric.WithAttributes(attribute.String("metric_name", metricName)))
+}
+
+func (p *prwTelemetryOtel) recordLongMetricNames(ctx context.Context, numNames int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteLongMetricNames.Add(ctx, int64(numNames), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordMetricTypeConflicts(ctx context.Context, numMetrics int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteMetricTypeConflicts.Add(ctx, int64(numMetrics), metric.WithAttributes(p.otelAttrs...))
+}
+
 func (p *prwTelemetryOtel) recordTranslatedTimeSeries(ctx context.Context, numTS int) {
 	p.telemetryBuilder.ExporterPrometheusremotewriteTranslatedTimeSeries.Add(ctx, int64(numTS), metric.WithAttributes(p.otelAttrs...))
 }
 
+func (p *prwTelemetryOtel) recordWALDiskFull(ctx context.Context) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteWalDiskFullEvents.Add(ctx, 1, metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordWALAuditFailures(ctx context.Context, numEntries int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteWalAuditFailures.Add(ctx, int64(numEntries), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordWALTruncation(ctx context.Context, index uint64) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteWalTruncations.Add(ctx, 1, metric.WithAttributes(p.otelAttrs...))
+	p.telemetryBuilder.ExporterPrometheusremotewriteWalTruncatedIndex.Record(ctx, int64(index), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordWALOldestEntryAge(ctx context.Context, age time.Duration) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteWalOldestEntryAgeSeconds.Record(ctx, age.Seconds(), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordNegotiatedProtocol(ctx context.Context, version int64) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteNegotiatedProtocolVersion.Record(ctx, version, metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordDroppedExemplars(ctx context.Context, numExemplars int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteDroppedExemplars.Add(ctx, int64(numExemplars), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordRequest(ctx context.Context, endpoint, outcome string) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteRequests.Add(ctx, 1, metric.WithAttributes(p.otelAttrs...),
+		metric.WithAttributes(attribute.String("endpoint", endpoint), attribute.String("outcome", outcome)))
+}
+
+func (p *prwTelemetryOtel) recordDroppedSamples(ctx context.Context, reason string, numSamples int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteDroppedSamples.Add(ctx, int64(numSamples), metric.WithAttributes(p.otelAttrs...),
+		metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+func (p *prwTelemetryOtel) recordLimitViolations(ctx context.Context, limit string, numViolations int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteLimitViolations.Add(ctx, int64(numViolations), metric.WithAttributes(p.otelAttrs...),
+		metric.WithAttributes(attribute.String("limit", limit)))
+}
+
+func (p *prwTelemetryOtel) recordSamples(ctx context.Context, metricType, temporality string, numSamples int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteSamples.Add(ctx, int64(numSamples), metric.WithAttributes(p.otelAttrs...),
+		metric.WithAttributes(attribute.String("metric_type", metricType), attribute.String("temporality", temporality)))
+}
+
+func (p *prwTelemetryOtel) recordQueueDepth(ctx context.Context, depth int64) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteQueueDepth.Record(ctx, depth, metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordPaused(ctx context.Context, paused bool) {
+	var value int64
+	if paused {
+		value = 1
+	}
+	p.telemetryBuilder.ExporterPrometheusremotewritePaused.Record(ctx, value, metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordRetryBackoff(ctx context.Context, interval time.Duration) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteRetryBackoffSeconds.Record(ctx, interval.Seconds(), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordLastBatchSeries(ctx context.Context, numSeries int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteLastBatchSeries.Record(ctx, int64(numSeries), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordSeriesCache(ctx context.Context, cache string, stats seriesCacheStats) {
+	cacheAttr := metric.WithAttributes(attribute.String("cache", cache))
+	if stats.hits > 0 {
+		p.telemetryBuilder.ExporterPrometheusremotewriteSeriesCacheLookups.Add(ctx, int64(stats.hits), metric.WithAttributes(p.otelAttrs...),
+			cacheAttr, metric.WithAttributes(attribute.String("result", "hit")))
+	}
+	if stats.misses > 0 {
+		p.telemetryBuilder.ExporterPrometheusremotewriteSeriesCacheLookups.Add(ctx, int64(stats.misses), metric.WithAttributes(p.otelAttrs...),
+			cacheAttr, metric.WithAttributes(attribute.String("result", "miss")))
+	}
+	if stats.evictions > 0 {
+		p.telemetryBuilder.ExporterPrometheusremotewriteSeriesCacheEvictions.Add(ctx, int64(stats.evictions), metric.WithAttributes(p.otelAttrs...), cacheAttr)
+	}
+	p.telemetryBuilder.ExporterPrometheusremotewriteSeriesCacheSize.Record(ctx, int64(stats.size), metric.WithAttributes(p.otelAttrs...), cacheAttr)
+}
+
+func (p *prwTelemetryOtel) recordNonMonotonicSeries(ctx context.Context, numSeries int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteNonMonotonicSeries.Add(ctx, int64(numSeries), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordSeriesGaps(ctx context.Context, numSeries int) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteSeriesGapsDetected.Add(ctx, int64(numSeries), metric.WithAttributes(p.otelAttrs...))
+}
+
+func (p *prwTelemetryOtel) recordUnsupportedMetric(ctx context.Context, metricType string) {
+	p.telemetryBuilder.ExporterPrometheusremotewriteUnsupportedMetrics.Add(ctx, 1, metric.WithAttributes(p.otelAttrs...),
+		metric.WithAttributes(attribute.String("type", metricType)))
+}
+
+const (
+	// partialTranslationPolicyDropResource drops the metrics that failed to be translated, not their whole resource,
+	// and exports the others. It is the default.
+	partialTranslationPolicyDropResource = "drop_resource"
+	// partialTranslationPolicyDropFailed is an alias of partialTranslationPolicyDropResource.
+	partialTranslationPolicyDropFailed = "drop_failed"
+	// partialTranslationPolicyDropBatch rejects the whole batch with a permanent error when some of it failed to be translated.
+	partialTranslationPolicyDropBatch = "drop_batch"
+)
+
+const (
+	// snappyFormatBlock compresses the requests with the snappy block format, as the remote write specification requires. It is the default.
+	snappyFormatBlock = "block"
+	// snappyFormatStream compresses the requests with the framed snappy stream format, which isn't standard.
+	snappyFormatStream = "stream"
+	// snappyFramedContentEncoding is the Content-Encoding of the requests using the snappy stream format.
+	snappyFramedContentEncoding = "x-snappy-framed"
+)
+
+// requestIDHeader is the header carrying the ID of a request, the same for all its attempts, when emit_request_id
+// is enabled.
+const requestIDHeader = "X-Request-ID"
+
+// The outcomes of the attempts to send a request reported in the telemetry of the exporter.
+const (
+	requestOutcomeSuccess = "success"
+	requestOutcomeFailure = "failure"
+	// requestOutcomeUnexpectedStatus flags the requests accepted with another status than 204 No Content when
+	// strict_status_codes is set.
+	requestOutcomeUnexpectedStatus = "unexpected_status"
+)
+
+// droppedReasonRateLimited is the reason reported for the samples dropped by the per-series rate limit.
+const droppedReasonRateLimited = "rate_limited"
+
+// nopTelemetry discards all telemetry. It is the default for a WAL that isn't attached to an exporter.
+type nopTelemetry struct{}
+
+func (nopTelemetry) recordTranslationFailure(context.Context) {}
+
+func (nopTelemetry) recordEmptyMetric(context.Context, string) {}
+
+func (nopTelemetry) recordLongMetricNames(context.Context, int) {}
+
+func (nopTelemetry) recordMetricTypeConflicts(context.Context, int) {}
+
+func (nopTelemetry) recordTranslatedTimeSeries(context.Context, int) {}
+
+func (nopTelemetry) recordWALDiskFull(context.Context) {}
+
+func (nopTelemetry) recordWALAuditFailures(context.Context, int) {}
+
+func (nopTelemetry) recordWALTruncation(context.Context, uint64) {}
+
+func (nopTelemetry) recordWALOldestEntryAge(context.Context, time.Duration) {}
+
+func (nopTelemetry) recordNegotiatedProtocol(context.Context, int64) {}
+
+func (nopTelemetry) recordDroppedSamples(context.Context, string, int) {}
+
+func (nopTelemetry) recordDroppedExemplars(context.Context, int) {}
+
+func (nopTelemetry) recordRequest(context.Context, string, string) {}
+
+func (nopTelemetry) recordLimitViolations(context.Context, string, int) {}
+
+func (nopTelemetry) recordSamples(context.Context, string, string, int) {}
+
+func (nopTelemetry) recordQueueDepth(context.Context, int64) {}
+
+func (nopTelemetry) recordPaused(context.Context, bool) {}
+
+func (nopTelemetry) recordRetryBackoff(context.Context, time.Duration) {}
+
+func (nopTelemetry) recordLastBatchSeries(context.Context, int) {}
+
+func (nopTelemetry) recordSeriesCache(context.Context, string, seriesCacheStats) {}
+
+func (nopTelemetry) recordNonMonotonicSeries(context.Context, int) {}
+
+func (nopTelemetry) recordSeriesGaps(context.Context, int) {}
+
+func (nopTelemetry) recordUnsupportedMetric(context.Context, string) {}
+
 type buffer struct {
 	protobuf *proto.Buffer
 	snappy   []byte
@@ -70,20 +287,111 @@ var bufferPool = sync.Pool{
 
 // prwExporter converts OTLP metrics to Prometheus remote write TimeSeries and sends them to a remote endpoint.
 type prwExporter struct {
-	endpointURL       *url.URL
-	client            *http.Client
-	wg                *sync.WaitGroup
-	closeChan         chan struct{}
-	concurrency       int
-	userAgentHeader   string
-	maxBatchSizeBytes int
-	clientSettings    *confighttp.ClientConfig
-	settings          component.TelemetrySettings
-	retrySettings     configretry.BackOffConfig
-	retryOnHTTP429    bool
-	wal               *prweWAL
-	exporterSettings  prometheusremotewrite.Settings
-	telemetry         prwTelemetry
+	endpointMu           sync.RWMutex // endpointMu protects endpointURL and client, which change with endpointFromEnv.
+	endpointURL          *url.URL
+	client               *http.Client
+	endpointFromEnv      *EndpointFromEnv
+	wg                   *sync.WaitGroup
+	closeChan            chan struct{}
+	concurrency          int
+	userAgentHeader      string
+	maxBatchSizeBytes    int
+	maxSamplesPerRequest int
+	maxSeriesPerRequest  int
+	compressionMinBytes  int
+	snappyFormat         string
+	clientSettings       *confighttp.ClientConfig
+	settings             component.TelemetrySettings
+	retrySettings        configretry.BackOffConfig
+	retryOnHTTP429       bool
+	wal                  *prweWAL
+	exporterSettings     prometheusremotewrite.Settings
+	telemetry            prwTelemetry
+	tracer               trace.Tracer
+	exportSink           ExportSink
+	endpointResolver     EndpointResolver
+	statusClassifier     StatusClassifier
+	zeroCounterFilter    *zeroCounterFilter
+	counterResetTracker  *counterResetTracker
+	seriesRateLimiter    *seriesRateLimiter
+	backendLimits        *BackendLimits
+	overloadSampler      *overloadSampler
+	priorityRouter       *priorityRouter
+	valueScaler          *valueScaler
+	// priorityWALs holds the WALs of the series by priority, nil when they aren't split by priority.
+	priorityWALs         map[string]*prweWAL
+	metricNameLimiter    *metricNameLimiter
+	typeConflictResolver *metricTypeConflictResolver
+	heartbeatLabels      []prompb.Label
+	fileArchiver         *fileArchiver
+	deadLetter           *fileArchiver
+	coalescer            *coalescer
+	coalesceInterval     time.Duration
+	dropPartialBatches   bool
+	lastSentTracker      *lastSentTracker
+	metadataCache        *metadataCache
+	targetInfoThrottle   *targetInfoThrottle
+	// separateTargetInfo is the name of the target_info series sent in their own requests, empty if they aren't.
+	separateTargetInfo  string
+	seriesBuffer        *seriesBuffer
+	bufferFullPolicy    string
+	emptyMetricsPolicy  string
+	enforceSampleOrder  bool
+	validateSampleOrder bool
+	backendHeaders      map[string]string
+	followRedirects     bool
+	emitRequestID       bool
+	dialTimeout         time.Duration
+	archiveOnly         bool
+	retryBudget         *retryBudget
+	protocolFallback    bool
+	paused              pauseGate
+	// negotiatedProtocol holds the remoteWriteProtocol negotiated with the endpoint when protocolFallback is set.
+	negotiatedProtocol atomic.Int32
+	// queueDepth is the number of requests waiting for a consumer, across the concurrent exports.
+	queueDepth atomic.Int64
+	// lastError holds the error of the last request that failed to be sent, nil once a request is sent.
+	lastError atomic.Pointer[error]
+	// dispatchQueue orders the requests of the concurrent exports by priority, nil when they are sent in order.
+	dispatchQueue *dispatchQueue
+	// affinityMu holds a mutex per consumer when series affinity is enabled, the consumer a series hashes
+	// to holds it while sending the requests of the series.
+	affinityMu []sync.Mutex
+
+	// timeout is the timeout of the first attempt to send a request, it grows by retryTimeoutMultiplier on
+	// every retry up to maxRetryTimeout.
+	timeout                time.Duration
+	retryTimeoutMultiplier float64
+	maxRetryTimeout        time.Duration
+	// maxRetries bounds the number of retries of a request, 0 means they are only bounded by time.
+	maxRetries int
+	// duplicateDataPointPolicy controls the data points translated to the same series and timestamp, they are
+	// left as they are when it is empty.
+	duplicateDataPointPolicy string
+	// recordDroppedLabelCount adds the number of labels removed from the series truncated to fit the backend limits
+	// as a label.
+	recordDroppedLabelCount bool
+	// batchByResource sends the series of every resource in their own requests.
+	batchByResource bool
+	// exemplarTimestampPolicy controls the exemplars outside the interval of their data point, they are sent as
+	// they are when it is empty.
+	exemplarTimestampPolicy string
+	// strictStatusCodes only accepts the 204 No Content responses as successful.
+	strictStatusCodes bool
+	// histogramCounts coalesces the _count series of the histograms apart from the other series, to send them
+	// every histogramCountInterval, nil unless histogram_count_fast_path is set.
+	histogramCounts        *coalescer
+	histogramCountInterval time.Duration
+	// shutdownDrainTimeout bounds the time the exports of the WAL in progress on shutdown are waited for.
+	shutdownDrainTimeout time.Duration
+	// cancelWALRun cancels the exports of the WAL, nil until the WAL is turned on.
+	cancelWALRun context.CancelFunc
+	// seriesGapDetector detects the series that stop being received, nil unless detect_series_gaps is set.
+	seriesGapDetector *seriesGapDetector
+	// unsupportedTypePolicy controls the metrics whose type can't be translated.
+	unsupportedTypePolicy string
+	// flushPacer spaces the requests sent by every consumer, nil unless min_flush_interval is set.
+	flushPacer *flushPacer
 
 	// When concurrency is enabled, concurrent goroutines would potentially
 	// fight over the same batchState object. To avoid this, we use a pool
@@ -105,14 +413,39 @@ func newPRWTelemetry(set exporter.Settings) (prwTelemetry, error) {
 	}, nil
 }
 
+// ExportSink sends batches of remote write requests to a backend. The exporter hands its requests to
+// an ExportSink once they are translated, batched and, if enabled, read back from the WAL, so custom
+// transports can reuse that machinery. By default requests are sent using HTTP remote write.
+type ExportSink interface {
+	Export(ctx context.Context, requests []*prompb.WriteRequest) error
+}
+
+// ExportSinkFunc is an adapter to use an ordinary function as an ExportSink.
+type ExportSinkFunc func(ctx context.Context, requests []*prompb.WriteRequest) error
+
+// Export calls f(ctx, requests).
+func (f ExportSinkFunc) Export(ctx context.Context, requests []*prompb.WriteRequest) error {
+	return f(ctx, requests)
+}
+
 // newPRWExporter initializes a new prwExporter instance and sets fields accordingly.
-func newPRWExporter(cfg *Config, set exporter.Settings) (*prwExporter, error) {
+func newPRWExporter(cfg *Config, set exporter.Settings, opts ...FactoryOption) (*prwExporter, error) {
+	var options factoryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	sanitizedLabels, err := validateAndSanitizeExternalLabels(cfg)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.CollectorIDLabel != "" {
+		if sanitizedLabels[cfg.CollectorIDLabel], err = resolveCollectorID(cfg.CollectorID); err != nil {
+			return nil, err
+		}
+	}
 
-	endpointURL, err := url.ParseRequestURI(cfg.ClientConfig.Endpoint)
+	endpointURL, err := url.ParseRequestURI(endpointFromEnv(cfg))
 	if err != nil {
 		return nil, errors.New("invalid endpoint")
 	}
@@ -132,63 +465,275 @@ func newPRWExporter(cfg *Config, set exporter.Settings) (*prwExporter, error) {
 		concurrency = *cfg.MaxBatchRequestParallelism
 	}
 
+	exemplarMetricFilter, err := cfg.exemplarMetricFilter()
+	if err != nil {
+		return nil, err
+	}
+
 	prwe := &prwExporter{
-		endpointURL:       endpointURL,
-		wg:                new(sync.WaitGroup),
-		closeChan:         make(chan struct{}),
-		userAgentHeader:   userAgentHeader,
-		maxBatchSizeBytes: cfg.MaxBatchSizeBytes,
-		concurrency:       concurrency,
-		clientSettings:    &cfg.ClientConfig,
-		settings:          set.TelemetrySettings,
-		retrySettings:     cfg.BackOffConfig,
-		retryOnHTTP429:    retryOn429FeatureGate.IsEnabled(),
+		endpointURL:          endpointURL,
+		endpointFromEnv:      cfg.EndpointFromEnv,
+		wg:                   new(sync.WaitGroup),
+		closeChan:            make(chan struct{}),
+		userAgentHeader:      userAgentHeader,
+		maxBatchSizeBytes:    cfg.MaxBatchSizeBytes,
+		maxSamplesPerRequest: cfg.MaxSamplesPerRequest,
+		maxSeriesPerRequest:  cfg.MaxSeriesPerRequest,
+		compressionMinBytes:  cfg.CompressionMinBytes,
+		snappyFormat:         cfg.SnappyFormat,
+		emptyMetricsPolicy:   cfg.EmptyMetricsPolicy,
+		enforceSampleOrder:   cfg.EnforceSampleOrder,
+		validateSampleOrder:  cfg.ValidateMonotonicTimestamps,
+		protocolFallback:     cfg.ProtocolFallback,
+		followRedirects:      cfg.FollowRedirects,
+		emitRequestID:        cfg.EmitRequestID,
+		dialTimeout:          cfg.DialTimeout,
+		concurrency:          concurrency,
+		clientSettings:       &cfg.ClientConfig,
+		settings:             set.TelemetrySettings,
+		retrySettings:        cfg.BackOffConfig,
+		retryOnHTTP429:       retryOn429FeatureGate.IsEnabled(),
 		exporterSettings: prometheusremotewrite.Settings{
-			Namespace:           cfg.Namespace,
-			ExternalLabels:      sanitizedLabels,
-			DisableTargetInfo:   !cfg.TargetInfo.Enabled,
-			ExportCreatedMetric: cfg.CreatedMetric.Enabled,
-			AddMetricSuffixes:   cfg.AddMetricSuffixes,
-			SendMetadata:        cfg.SendMetadata,
+			Namespace:                     cfg.Namespace,
+			ExternalLabels:                sanitizedLabels,
+			DisableTargetInfo:             !cfg.TargetInfo.Enabled,
+			TargetInfoExcludeAttributes:   cfg.TargetInfo.ExcludeAttributes,
+			TargetInfoSkipWithoutIdentity: cfg.TargetInfo.SkipWithoutIdentity,
+			TargetInfoIncludeSchemaURL:    cfg.TargetInfo.IncludeSchemaURL,
+			ExportCreatedMetric:           cfg.CreatedMetric.Enabled,
+			AddMetricSuffixes:             cfg.AddMetricSuffixes,
+			SendMetadata:                  cfg.SendMetadata,
+			InvalidLabelNamePolicy:        cfg.InvalidLabelNamePolicy,
+			LabelNameRemapping:            cfg.LabelNameRemapping,
+			LabelCollisionPolicy:          cfg.LabelCollisionPolicy,
+			NameConflictPolicy:            cfg.NameConflictPolicy,
+			HistogramNaNPolicy:            cfg.HistogramNaNPolicy,
+			DropHistogramBuckets:          cfg.DropHistogramBuckets,
+			ExemplarsFromSampledOnly:      cfg.ExemplarsFromSampledOnly,
+			MaxExemplarsPerSeries:         cfg.MaxExemplarsPerSeries,
+			ExemplarMetricFilter:          exemplarMetricFilter,
+			PromoteScopeAttributes:        cfg.PromoteScopeAttributes,
+			EmitDroppedAttributesLabel:    cfg.EmitDroppedAttributesLabel,
+			TemporalityLabel:              cfg.temporalityLabel(),
+			TimestampRounding:             cfg.TimestampRounding,
+			KeepNoRecordedValues:          !cfg.NoRecordedValueAsStale,
+			TranslationConcurrency:        cfg.TranslationConcurrency,
+			UnitSuffixes: prometheustranslator.UnitSuffixes{
+				Mode:      cfg.UnitSuffixMode,
+				Overrides: cfg.UnitSuffixOverrides,
+			},
 		},
-		telemetry:      prwTelemetry,
-		batchStatePool: sync.Pool{New: func() any { return newBatchTimeServicesState() }},
+		telemetry:              prwTelemetry,
+		tracer:                 metadata.Tracer(set.TelemetrySettings),
+		batchStatePool:         sync.Pool{New: func() any { return newBatchTimeServicesState() }},
+		timeout:                cfg.ClientConfig.Timeout,
+		retryTimeoutMultiplier: cfg.RetryTimeoutMultiplier,
+		maxRetryTimeout:        cfg.MaxRetryTimeout,
+		maxRetries:             cfg.MaxRetries,
+	}
+	prwe.dropPartialBatches = cfg.PartialTranslationPolicy == partialTranslationPolicyDropBatch
+	prwe.duplicateDataPointPolicy = cfg.DuplicateDataPointPolicy
+	prwe.shutdownDrainTimeout = cfg.ShutdownDrainTimeout
+	prwe.batchByResource = cfg.BatchGrouping == batchGroupingByResource
+	prwe.strictStatusCodes = cfg.StrictStatusCodes
+	if cfg.validatesExemplarTimestamps() {
+		prwe.exemplarTimestampPolicy = cfg.ExemplarTimestampPolicy
+	}
+	prwe.unsupportedTypePolicy = cfg.UnsupportedTypePolicy
+	if cfg.MinFlushInterval > 0 {
+		prwe.flushPacer = newFlushPacer(cfg.MinFlushInterval, concurrency)
+	}
+	if cfg.SnappyFormat == snappyFormatStream {
+		set.Logger.Warn("the snappy stream format isn't part of the remote write specification, the endpoint must support it",
+			zap.String("content_encoding", snappyFramedContentEncoding))
+	}
+	if cfg.MaxBufferedSeries > 0 {
+		prwe.seriesBuffer = newSeriesBuffer(cfg.MaxBufferedSeries, prwe.closeChan)
+		prwe.bufferFullPolicy = cfg.BufferFullPolicy
+	}
+	if cfg.Thanos != nil {
+		prwe.backendHeaders = cfg.Thanos.headers()
+	}
+	if cfg.MetadataResendInterval > 0 {
+		prwe.metadataCache = newMetadataCache(cfg.MetadataResendInterval, metadataCacheMaxSeries)
+	}
+	if cfg.TrackLastSent {
+		prwe.lastSentTracker = newLastSentTracker(lastSentMaxSeries)
+	}
+	if cfg.DetectSeriesGaps {
+		prwe.seriesGapDetector = newSeriesGapDetector(cfg.SeriesGapThreshold, seriesGapsMaxSeries)
+	}
+	if cfg.RemoteWriteQueue.DispatchOrder == dispatchOrderNewestFirst {
+		prwe.dispatchQueue = newDispatchQueue(concurrency)
+	}
+	if cfg.RemoteWriteQueue.SeriesAffinity {
+		prwe.affinityMu = make([]sync.Mutex, max(concurrency, 1))
+	}
+
+	if cfg.DropZeroValueCounters {
+		prwe.zeroCounterFilter = newZeroCounterFilter(zeroCountersMaxSeries)
+	}
+	if cfg.EmitCounterResetSamples {
+		prwe.counterResetTracker = newCounterResetTracker(counterResetMaxSeries)
+	}
+	if cfg.MaxMetricNameBytes > 0 {
+		prwe.metricNameLimiter = &metricNameLimiter{maxBytes: cfg.MaxMetricNameBytes, policy: cfg.MetricNameLengthPolicy}
+	}
+	if cfg.MetricTypeConflictPolicy != "" {
+		prwe.typeConflictResolver = &metricTypeConflictResolver{policy: cfg.MetricTypeConflictPolicy, settings: prwe.exporterSettings}
+	}
+	if cfg.TargetInfo.Enabled && cfg.TargetInfo.SeparateRequest {
+		prwe.separateTargetInfo = targetInfoName(cfg.Namespace)
+	}
+	if cfg.TargetInfo.Enabled && cfg.TargetInfo.EmitInterval > 0 {
+		prwe.targetInfoThrottle = newTargetInfoThrottle(cfg.Namespace, cfg.TargetInfo.EmitInterval, targetInfoMaxSeries)
+	}
+	if len(cfg.ValueScaling) > 0 {
+		if prwe.valueScaler, err = newValueScaler(cfg.ValueScaling); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MaxSamplesPerSeriesPerInterval > 0 {
+		prwe.seriesRateLimiter = newSeriesRateLimiter(cfg.MaxSamplesPerSeriesPerInterval, cfg.SeriesRateLimitInterval, seriesRateLimitMaxSeries)
+	}
+	if cfg.BackendLimits != nil {
+		prwe.backendLimits = cfg.BackendLimits
+		prwe.recordDroppedLabelCount = cfg.RecordDroppedLabelCount
+	}
+	if cfg.RetryBudget != nil {
+		prwe.retryBudget = newRetryBudget(cfg.RetryBudget)
+	}
+	if cfg.Coalesce != nil {
+		prwe.coalescer = newCoalescer(cfg.Coalesce)
+		prwe.coalesceInterval = cfg.Coalesce.FlushInterval
+		if cfg.HistogramCountFastPath {
+			prwe.histogramCounts = newCoalescer(cfg.Coalesce)
+			prwe.histogramCountInterval = cfg.HistogramCountFlushInterval
+		}
+	}
+	if cfg.FileArchive != nil {
+		prwe.fileArchiver = newFileArchiver(cfg.FileArchive)
+		prwe.archiveOnly = cfg.FileArchive.ArchiveOnly
+	}
+	if cfg.DeadLetter != nil {
+		prwe.deadLetter = newDeadLetterWriter(cfg.DeadLetter)
+	}
+	if cfg.EmitHeartbeat {
+		if prwe.heartbeatLabels, err = heartbeatLabels(sanitizedLabels, cfg.HeartbeatLabels); err != nil {
+			return nil, err
+		}
 	}
 
 	if prwe.exporterSettings.ExportCreatedMetric {
 		prwe.settings.Logger.Warn("export_created_metric is deprecated and will be removed in a future release")
 	}
 
-	prwe.wal = newWAL(cfg.WAL, prwe.export)
+	prwe.exportSink = options.exportSink
+	prwe.endpointResolver = options.endpointResolver
+	prwe.statusClassifier = options.statusClassifier
+	if prwe.exportSink == nil {
+		prwe.exportSink = ExportSinkFunc(prwe.export)
+	}
+	prwe.wal = newWAL(cfg.WAL, prwe.exportSink.Export)
+	if prwe.wal != nil {
+		prwe.wal.telemetry = prwTelemetry
+		prwe.wal.paused = &prwe.paused
+		if cfg.OverloadSamplingRate > 1 {
+			prwe.overloadSampler = newOverloadSampler(cfg.OverloadSamplingRate, cfg.OverloadHighWaterMark, overloadSamplingMaxSeries)
+		}
+		if len(cfg.PriorityRules) > 0 {
+			if prwe.priorityRouter, err = newPriorityRouter(cfg.PriorityRules); err != nil {
+				return nil, err
+			}
+			prwe.priorityWALs = newPriorityWALs(prwe.wal, prwe.exportSink.Export)
+		}
+	}
 	return prwe, nil
 }
 
 // Start creates the prometheus client
 func (prwe *prwExporter) Start(ctx context.Context, host component.Host) (err error) {
-	prwe.client, err = prwe.clientSettings.ToClient(ctx, host, prwe.settings)
+	clientSettings := *prwe.clientSettings
+	if prwe.endpointFromEnv != nil {
+		// The client is built for the endpoint read from the environment.
+		clientSettings.Endpoint = prwe.endpointURL.String()
+	}
+	if prwe.retryTimeoutMultiplier > 0 {
+		// The timeout of every attempt is set by execute instead.
+		clientSettings.Timeout = 0
+	}
+	prwe.clientSettings = &clientSettings
+	prwe.client, err = prwe.toClient(ctx, host, prwe.clientSettings)
 	if err != nil {
 		return err
 	}
+	if prwe.endpointFromEnv != nil {
+		prwe.watchEndpointFromEnv(host)
+	}
+	if prwe.coalescer != nil {
+		prwe.flushCoalescedPeriodically(prwe.coalesceInterval)
+	}
+	if prwe.histogramCounts != nil {
+		prwe.flushHistogramCountsPeriodically(prwe.histogramCountInterval)
+	}
+	if prwe.seriesGapDetector != nil {
+		prwe.detectSeriesGapsPeriodically(seriesGapCheckInterval)
+	}
 	return prwe.turnOnWALIfEnabled(contextWithLogger(ctx, prwe.settings.Logger.Named("prw.wal")))
 }
 
-func (prwe *prwExporter) shutdownWALIfEnabled() error {
+func (prwe *prwExporter) shutdownWALIfEnabled(ctx context.Context) error {
 	if !prwe.walEnabled() {
 		return nil
 	}
-	return prwe.wal.stop()
+	var errs error
+	for _, wal := range prwe.allWALs() {
+		errs = errors.Join(errs, prwe.shutdownWAL(ctx, wal))
+	}
+	return errs
+}
+
+func (prwe *prwExporter) shutdownWAL(ctx context.Context, wal *prweWAL) error {
+	if err := wal.stop(); err != nil {
+		return err
+	}
+	if wal.walConfig.shutdownStrategy() == walShutdownStrategyNewestFirst && prwe.paused.resumedChan() == nil {
+		if err := wal.drainNewestFirst(ctx); err != nil {
+			return err
+		}
+	}
+	if wal.walConfig.CompactOnShutdown {
+		return wal.compact(ctx)
+	}
+	return nil
 }
 
 // Shutdown stops the exporter from accepting incoming calls(and return error), and wait for current export operations
 // to finish before returning
-func (prwe *prwExporter) Shutdown(context.Context) error {
+func (prwe *prwExporter) Shutdown(ctx context.Context) error {
 	select {
 	case <-prwe.closeChan:
 	default:
 		close(prwe.closeChan)
 	}
-	err := prwe.shutdownWALIfEnabled()
+	var err error
+	if prwe.coalescer != nil {
+		// The pushes in progress are waited for, so that the samples they add are flushed too.
+		prwe.wg.Wait()
+		if prwe.histogramCounts != nil {
+			err = prwe.flushHistogramCounts(ctx)
+		}
+		err = errors.Join(err, prwe.flushCoalesced(ctx))
+	}
+	prwe.drainWALExports(ctx)
+	err = errors.Join(err, prwe.shutdownWALIfEnabled(ctx))
 	prwe.wg.Wait()
+	if prwe.fileArchiver != nil {
+		err = errors.Join(err, prwe.fileArchiver.close())
+	}
+	if prwe.deadLetter != nil {
+		err = errors.Join(err, prwe.deadLetter.close())
+	}
 	return err
 }
 
@@ -203,18 +748,125 @@ func (prwe *prwExporter) PushMetrics(ctx context.Context, md pmetric.Metrics) er
 	case <-prwe.closeChan:
 		return errors.New("shutdown has been called")
 	default:
+		if !prwe.walEnabled() && prwe.paused.resumedChan() != nil {
+			return errPaused
+		}
+		if err := prwe.waitForReplay(ctx); err != nil {
+			return err
+		}
+		if prwe.typeConflictResolver != nil {
+			numConflicts, err := prwe.typeConflictResolver.resolve(md)
+			if numConflicts > 0 {
+				prwe.telemetry.recordMetricTypeConflicts(ctx, numConflicts)
+			}
+			if err != nil {
+				return consumererror.NewPermanent(err)
+			}
+		}
+		if prwe.zeroCounterFilter != nil {
+			prwe.zeroCounterFilter.filter(md)
+			prwe.recordSeriesCacheStats(ctx, seriesCacheZeroCounters, prwe.zeroCounterFilter)
+		}
+		if prwe.exemplarTimestampPolicy != "" {
+			if dropped := validateExemplarTimestamps(md, prwe.exemplarTimestampPolicy); dropped > 0 {
+				prwe.telemetry.recordDroppedExemplars(ctx, dropped)
+			}
+		}
+		if prwe.counterResetTracker != nil {
+			prwe.counterResetTracker.injectResetSamples(md)
+			prwe.recordSeriesCacheStats(ctx, seriesCacheCounterReset, prwe.counterResetTracker)
+		}
+
+		if prwe.valueScaler != nil {
+			prwe.valueScaler.scale(md)
+		}
 
+		prwe.reportEmptyMetrics(ctx, md)
 		tsMap, err := prometheusremotewrite.FromMetrics(md, prwe.exporterSettings)
+		var invalidLabelNameErr *prometheusremotewrite.InvalidLabelNameError
+		if prwe.exporterSettings.InvalidLabelNamePolicy == prometheusremotewrite.InvalidLabelNamePolicyError && errors.As(err, &invalidLabelNameErr) {
+			prwe.telemetry.recordTranslationFailure(ctx)
+			prwe.writeDeadLetterSeries(tsMap)
+			return consumererror.NewPermanent(err)
+		}
+		if prwe.reportUnsupportedMetricTypes(ctx, err) {
+			prwe.telemetry.recordTranslationFailure(ctx)
+			prwe.writeDeadLetterSeries(tsMap)
+			return consumererror.NewPermanent(err)
+		}
+		if err != nil && prwe.dropPartialBatches {
+			prwe.telemetry.recordTranslationFailure(ctx)
+			prwe.writeDeadLetterSeries(tsMap)
+			return consumererror.NewPermanent(fmt.Errorf("failed to translate metrics, dropping the batch: %w", err))
+		}
 		if err != nil {
 			prwe.telemetry.recordTranslationFailure(ctx)
 			prwe.settings.Logger.Debug("failed to translate metrics, exporting remaining metrics", zap.Error(err), zap.Int("translated", len(tsMap)))
 		}
+		if prwe.duplicateDataPointPolicy != "" {
+			if err = prwe.resolveDuplicateDataPoints(ctx, tsMap); err != nil {
+				prwe.telemetry.recordTranslationFailure(ctx)
+				prwe.writeDeadLetterSeries(tsMap)
+				return consumererror.NewPermanent(err)
+			}
+		}
 
 		prwe.telemetry.recordTranslatedTimeSeries(ctx, len(tsMap))
+		// The series are matched with their metric before they are renamed, and counted once filtered.
+		kinds := seriesKinds(md, tsMap, prwe.exporterSettings)
+		if prwe.seriesGapDetector != nil {
+			prwe.seriesGapDetector.observe(tsMap)
+			prwe.recordSeriesCacheStats(ctx, seriesCacheSeriesGaps, prwe.seriesGapDetector)
+		}
+		var limitedNames map[string]limitedName
+		if prwe.metricNameLimiter != nil {
+			limitedNames = prwe.metricNameLimiter.limitedNames(md, prwe.exporterSettings)
+			if numNames := prwe.metricNameLimiter.limitSeries(tsMap, limitedNames); numNames > 0 {
+				prwe.telemetry.recordLongMetricNames(ctx, numNames)
+			}
+		}
+		if prwe.seriesRateLimiter != nil {
+			if dropped := prwe.seriesRateLimiter.limit(tsMap); dropped > 0 {
+				prwe.telemetry.recordDroppedSamples(ctx, droppedReasonRateLimited, dropped)
+			}
+			prwe.recordSeriesCacheStats(ctx, seriesCacheSeriesRateLimit, prwe.seriesRateLimiter)
+		}
+		if prwe.targetInfoThrottle != nil {
+			prwe.targetInfoThrottle.filter(tsMap)
+			prwe.recordSeriesCacheStats(ctx, seriesCacheTargetInfo, prwe.targetInfoThrottle)
+		}
+		if prwe.backendLimits != nil && prwe.backendLimits.hasLabelLimits() {
+			prwe.limitSeries(ctx, tsMap)
+		}
+		if prwe.overloadSampler != nil {
+			prwe.sampleIfOverloaded(ctx, tsMap)
+		}
+		for kind, numSamples := range countSamples(tsMap, kinds) {
+			prwe.telemetry.recordSamples(ctx, kind.metricType, kind.temporality, numSamples)
+		}
+		if prwe.heartbeatLabels != nil {
+			// The heartbeat is added after the filters so that it is sent on every flush.
+			tsMap[heartbeatSeriesKey] = prwe.heartbeatSeries()
+		}
 
 		var m []*prompb.MetricMetadata
 		if prwe.exporterSettings.SendMetadata {
-			m = prometheusremotewrite.OtelMetricsToMetadata(md, prwe.exporterSettings.AddMetricSuffixes)
+			m = prometheusremotewrite.OtelMetricsToMetadataWithUnitSuffixes(md, prwe.exporterSettings.AddMetricSuffixes,
+				prwe.exporterSettings.UnitSuffixes)
+			if prwe.metricNameLimiter != nil {
+				m = prwe.metricNameLimiter.limitMetadata(m, limitedNames)
+			}
+		}
+
+		if prwe.coalescer != nil {
+			return prwe.coalesce(ctx, tsMap, m, kinds)
+		}
+		if prwe.seriesBuffer != nil && !prwe.walEnabled() {
+			// The series are held in memory until they are sent.
+			if err := prwe.seriesBuffer.acquire(ctx, len(tsMap)); err != nil {
+				return err
+			}
+			defer prwe.seriesBuffer.release(len(tsMap))
 		}
 
 		// Call export even if a conversion error, since there may be points that were successfully converted.
@@ -240,21 +892,68 @@ func (prwe *prwExporter) handleExport(ctx context.Context, tsMap map[string]*pro
 		return nil
 	}
 
+	if prwe.validateSampleOrder {
+		prwe.checkSampleOrder(ctx, tsMap)
+	}
+	if prwe.enforceSampleOrder {
+		prwe.orderSamples(ctx, tsMap)
+	}
+	if prwe.backendLimits != nil && prwe.maxSeriesPerRequest > 0 && len(tsMap) > prwe.maxSeriesPerRequest {
+		// The batch is split to fit the limit.
+		prwe.telemetry.recordLimitViolations(ctx, limitMaxSeriesPerRequest, 1)
+	}
+
 	state := prwe.batchStatePool.Get().(*batchTimeSeriesState)
 	defer prwe.batchStatePool.Put(state)
-	// Calls the helper function to convert and batch the TsMap to the desired format
-	requests, err := batchTimeSeries(tsMap, prwe.maxBatchSizeBytes, m, state)
-	if err != nil {
-		return err
+	var requests []*prompb.WriteRequest
+	if prwe.separateTargetInfo != "" {
+		if targetInfo := splitTargetInfo(tsMap, prwe.separateTargetInfo); len(targetInfo) > 0 {
+			var targetInfoMetadata []*prompb.MetricMetadata
+			if len(tsMap) == 0 {
+				// There are no other series to send the metadata along with.
+				targetInfoMetadata = m
+			}
+			targetInfoRequests, err := batchTimeSeries(targetInfo, prwe.maxBatchSizeBytes, prwe.maxSamplesPerRequest,
+				prwe.maxSeriesPerRequest, targetInfoMetadata, state)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, targetInfoRequests...)
+		}
 	}
+	if len(tsMap) > 0 {
+		groups := []map[string]*prompb.TimeSeries{tsMap}
+		if prwe.batchByResource {
+			groups = groupByResource(tsMap)
+		}
+		for i, group := range groups {
+			var groupMetadata []*prompb.MetricMetadata
+			if i == len(groups)-1 {
+				// The metadata is sent once, after the series.
+				groupMetadata = m
+			}
+			// Calls the helper function to convert and batch the TsMap to the desired format
+			seriesRequests, err := batchTimeSeries(group, prwe.maxBatchSizeBytes, prwe.maxSamplesPerRequest,
+				prwe.maxSeriesPerRequest, groupMetadata, state)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, seriesRequests...)
+		}
+	}
+	prwe.telemetry.recordLastBatchSeries(ctx, len(requests[len(requests)-1].Timeseries))
 	if !prwe.walEnabled() {
 		// Perform a direct export otherwise.
-		return prwe.export(ctx, requests)
+		return prwe.exportSink.Export(ctx, requests)
 	}
 
 	// Otherwise the WAL is enabled, and just persist the requests to the WAL
 	// and they'll be exported in another goroutine to the RemoteWrite endpoint.
-	if err = prwe.wal.persistToWAL(requests); err != nil {
+	if err := prwe.persistByPriority(ctx, requests); err != nil {
+		if errors.Is(err, errDiskFull) {
+			// The WAL accepts writes again once space is freed, so the batch is retried meanwhile.
+			return err
+		}
 		return consumererror.NewPermanent(err)
 	}
 	return nil
@@ -262,11 +961,19 @@ func (prwe *prwExporter) handleExport(ctx context.Context, tsMap map[string]*pro
 
 // export sends a Snappy-compressed WriteRequest containing TimeSeries to a remote write endpoint in order
 func (prwe *prwExporter) export(ctx context.Context, requests []*prompb.WriteRequest) error {
+	if prwe.affinityMu != nil {
+		return prwe.exportWithAffinity(ctx, requests)
+	}
+	if prwe.dispatchQueue != nil {
+		requests = slices.Clone(requests)
+		sortByDispatchPriority(requests)
+	}
 	input := make(chan *prompb.WriteRequest, len(requests))
 	for _, request := range requests {
 		input <- request
 	}
 	close(input)
+	prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(int64(len(requests))))
 
 	var wg sync.WaitGroup
 
@@ -289,9 +996,18 @@ func (prwe *prwExporter) export(ctx context.Context, requests []*prompb.WriteReq
 					if !ok {
 						return
 					}
-					if errExecute := prwe.execute(ctx, request); errExecute != nil {
+					if prwe.dispatchQueue != nil && prwe.dispatchQueue.acquire(ctx, request) != nil {
+						prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(-1))
+						return
+					}
+					prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(-1))
+					errExecute := prwe.execute(ctx, request)
+					if prwe.dispatchQueue != nil {
+						prwe.dispatchQueue.release()
+					}
+					if errExecute != nil {
 						mu.Lock()
-						errs = multierr.Append(errs, consumererror.NewPermanent(errExecute))
+						errs = multierr.Append(errs, exportError(request, errExecute))
 						mu.Unlock()
 					}
 				}
@@ -299,89 +1015,240 @@ func (prwe *prwExporter) export(ctx context.Context, requests []*prompb.WriteReq
 		}()
 	}
 	wg.Wait()
+	// The requests left over when the context is cancelled aren't pending anymore.
+	if left := len(input); left > 0 {
+		prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(-int64(left)))
+	}
 
 	return errs
 }
 
 func (prwe *prwExporter) execute(ctx context.Context, writeReq *prompb.WriteRequest) error {
+	if prwe.flushPacer != nil {
+		if err := prwe.flushPacer.acquire(ctx); err != nil {
+			return err
+		}
+		// The consumer is released along with the time it started sending the request.
+		defer prwe.flushPacer.release(time.Now())
+	}
 	buf := bufferPool.Get().(*buffer)
-	buf.protobuf.Reset()
 	defer bufferPool.Put(buf)
 
-	// Uses proto.Marshal to convert the WriteRequest into bytes array
-	errMarshal := buf.protobuf.Marshal(writeReq)
-	if errMarshal != nil {
-		return consumererror.NewPermanent(errMarshal)
-	}
-	// If we don't pass a buffer large enough, Snappy Encode function will not use it and instead will allocate a new buffer.
-	// Manually grow the buffer to make sure Snappy uses it and we can re-use it afterwards.
-	maxCompressedLen := snappy.MaxEncodedLen(len(buf.protobuf.Bytes()))
-	if maxCompressedLen > len(buf.snappy) {
-		if cap(buf.snappy) < maxCompressedLen {
-			buf.snappy = make([]byte, maxCompressedLen)
+	// The request is only encoded again when the protocol version changes between attempts.
+	var data []byte
+	// contentEncoding is empty when the request is smaller than compressionMinBytes, data is sent as is then.
+	var contentEncoding string
+	encodedProtocol := protocolUnnegotiated
+	// sentMetadata holds the keys of the series sent along with their full metadata using remote write 2.0.
+	var sentMetadata []uint64
+	encode := func(protocol remoteWriteProtocol) error {
+		if protocol == encodedProtocol {
+			return nil
+		}
+		buf.protobuf.Reset()
+		// Uses proto.Marshal to convert the WriteRequest into bytes array
+		var errMarshal error
+		if protocol == protocolV2 {
+			v2Req, sent := toWriteV2Request(writeReq, prwe.metadataCache)
+			sentMetadata = sent
+			errMarshal = buf.protobuf.Marshal(v2Req)
 		} else {
-			buf.snappy = buf.snappy[:maxCompressedLen]
+			errMarshal = buf.protobuf.Marshal(writeReq)
+		}
+		if errMarshal != nil {
+			return consumererror.NewPermanent(errMarshal)
 		}
+		encodedProtocol = protocol
+		if len(buf.protobuf.Bytes()) < prwe.compressionMinBytes {
+			data, contentEncoding = buf.protobuf.Bytes(), ""
+			return nil
+		}
+		if prwe.snappyFormat == snappyFormatStream {
+			var framed bytes.Buffer
+			w := snappy.NewBufferedWriter(&framed)
+			if _, err := w.Write(buf.protobuf.Bytes()); err != nil {
+				return consumererror.NewPermanent(err)
+			}
+			if err := w.Close(); err != nil {
+				return consumererror.NewPermanent(err)
+			}
+			data, contentEncoding = framed.Bytes(), snappyFramedContentEncoding
+			return nil
+		}
+		// If we don't pass a buffer large enough, Snappy Encode function will not use it and instead will allocate a new buffer.
+		// Manually grow the buffer to make sure Snappy uses it and we can re-use it afterwards.
+		maxCompressedLen := snappy.MaxEncodedLen(len(buf.protobuf.Bytes()))
+		if maxCompressedLen > len(buf.snappy) {
+			if cap(buf.snappy) < maxCompressedLen {
+				buf.snappy = make([]byte, maxCompressedLen)
+			} else {
+				buf.snappy = buf.snappy[:maxCompressedLen]
+			}
+		}
+		data, contentEncoding = snappy.Encode(buf.snappy, buf.protobuf.Bytes()), "snappy"
+		return nil
 	}
-	compressedData := snappy.Encode(buf.snappy, buf.protobuf.Bytes())
 
-	// executeFunc can be used for backoff and non backoff scenarios.
-	executeFunc := func() error {
-		// check there was no timeout in the component level to avoid retries
-		// to continue to run after a timeout
-		select {
-		case <-ctx.Done():
-			return backoff.Permanent(ctx.Err())
-		default:
-			// continue
+	if prwe.fileArchiver != nil {
+		// Requests are archived using remote write 1.0, which is sent as is unless 2.0 is in use.
+		if err := encode(protocolV1); err != nil {
+			return err
+		}
+		archived := data
+		if contentEncoding != "snappy" {
+			// Archives always hold the snappy block format.
+			archived = snappy.Encode(nil, buf.protobuf.Bytes())
+		}
+		if err := prwe.fileArchiver.write(archived); err != nil {
+			if prwe.archiveOnly {
+				return consumererror.NewPermanent(fmt.Errorf("failed to archive the request: %w", err))
+			}
+			prwe.settings.Logger.Error("failed to archive the request, sending it anyway", zap.Error(err))
+		}
+		if prwe.archiveOnly {
+			return nil
+		}
+	}
+
+	// requestID identifies the request in the logs of the endpoint, and lets it deduplicate the retries.
+	var requestID string
+	if prwe.emitRequestID {
+		requestID = uuid.NewString()
+	}
+
+	var attempts int
+	// sendFunc sends the request once using the given protocol version.
+	sendFunc := func(protocol remoteWriteProtocol) (err error) {
+		if err := encode(protocol); err != nil {
+			return backoff.Permanent(err)
 		}
 
+		reqCtx := ctx
+		if timeout := prwe.attemptTimeout(attempts - 1); timeout > 0 {
+			var cancel context.CancelFunc
+			reqCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		endpointURL, client, err := prwe.resolveTarget(reqCtx)
+		if err != nil {
+			// The request is retried, the resolver may return another endpoint then.
+			return err
+		}
+		var statusCode int
+		reqCtx, span := prwe.startSendSpan(reqCtx, endpointURL, len(writeReq.Timeseries), contentEncoding, attempts)
+		defer func() {
+			endSendSpan(span, statusCode, err)
+			outcome := requestOutcomeSuccess
+			switch {
+			case err != nil:
+				outcome = requestOutcomeFailure
+			case prwe.strictStatusCodes && statusCode != http.StatusNoContent:
+				outcome = requestOutcomeUnexpectedStatus
+			}
+			prwe.telemetry.recordRequest(ctx, endpointAttribute(endpointURL), outcome)
+		}()
 		// Create the HTTP POST request to send to the endpoint
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, prwe.endpointURL.String(), bytes.NewReader(compressedData))
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, endpointURL.String(), bytes.NewReader(data))
 		if err != nil {
 			return backoff.Permanent(consumererror.NewPermanent(err))
 		}
 
 		// Add necessary headers specified by:
 		// https://cortexmetrics.io/docs/apis/#remote-api
-		req.Header.Add("Content-Encoding", "snappy")
-		req.Header.Set("Content-Type", "application/x-protobuf")
-		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		if contentEncoding != "" {
+			req.Header.Add("Content-Encoding", contentEncoding)
+		}
+		req.Header.Set("Content-Type", protocol.contentType())
+		req.Header.Set("X-Prometheus-Remote-Write-Version", protocol.versionHeader())
 		req.Header.Set("User-Agent", prwe.userAgentHeader)
+		if requestID != "" {
+			req.Header.Set(requestIDHeader, requestID)
+		}
+		for name, value := range prwe.backendHeaders {
+			req.Header.Set(name, value)
+		}
 
-		resp, err := prwe.client.Do(req)
+		resp, err := client.Do(req)
 		if err != nil {
 			return err
 		}
 		defer resp.Body.Close()
+		statusCode = resp.StatusCode
 
 		// 2xx status code is considered a success
 		// 5xx errors are recoverable and the exporter should retry
 		// Reference for different behavior according to status code:
 		// https://github.com/prometheus/prometheus/pull/2552/files#diff-ae8db9d16d8057358e49d694522e7186
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if prwe.strictStatusCodes && resp.StatusCode != http.StatusNoContent {
+				// The request was accepted, it is only flagged: failing it would send it again, or to the dead letter.
+				prwe.settings.Logger.Warn("remote write returned another status than 204 No Content",
+					zap.String("status", resp.Status), zap.String("endpoint", endpointAttribute(endpointURL)))
+			}
+			if prwe.protocolFallback {
+				prwe.setNegotiatedProtocol(ctx, protocol)
+			}
 			return nil
 		}
 
 		body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
 		rerr := fmt.Errorf("remote write returned HTTP status %v; err = %w: %s", resp.Status, err, body)
-		if resp.StatusCode >= 500 && resp.StatusCode < 600 {
+		// 429 errors are only retried by default if RetryOnHTTP429 is enabled
+		// Reference: https://github.com/prometheus/prometheus/pull/12677
+		switch prwe.classifyStatus(resp.StatusCode, body) {
+		case ErrorClassRetryable:
 			return rerr
+		case ErrorClassThrottled:
+			return &throttledError{err: rerr, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+		case ErrorClassPermanent:
 		}
 
-		// 429 errors are recoverable and the exporter should retry if RetryOnHTTP429 enabled
-		// Reference: https://github.com/prometheus/prometheus/pull/12677
-		if prwe.retryOnHTTP429 && resp.StatusCode == http.StatusTooManyRequests {
-			return rerr
+		if protocol == protocolV2 && isUnsupportedProtocolStatus(resp.StatusCode) {
+			return fmt.Errorf("%w: %w", errUnsupportedProtocol, rerr)
 		}
 
 		return backoff.Permanent(consumererror.NewPermanent(rerr))
 	}
 
+	// throttling delays the next retry by the Retry-After of the responses throttling the request.
+	throttling := &retryAfterBackOff{maxInterval: prwe.retrySettings.MaxInterval}
+	// executeFunc can be used for backoff and non backoff scenarios.
+	executeFunc := func() error {
+		// check there was no timeout in the component level to avoid retries
+		// to continue to run after a timeout
+		select {
+		case <-ctx.Done():
+			return backoff.Permanent(ctx.Err())
+		default:
+			// continue
+		}
+		// Retries are deferred once the budget shared by all requests is exhausted, the request is
+		// failed with a retryable error so that it is sent again later.
+		if attempts > 0 && prwe.retryBudget != nil && !prwe.retryBudget.allow() {
+			return backoff.Permanent(errRetryBudgetExhausted)
+		}
+		attempts++
+
+		err := sendFunc(prwe.protocol())
+		if errors.Is(err, errUnsupportedProtocol) {
+			// The endpoint doesn't support remote write 2.0, send the request again using remote write 1.0.
+			prwe.setNegotiatedProtocol(ctx, protocolV1)
+			err = sendFunc(protocolV1)
+		}
+		var throttled *throttledError
+		if errors.As(err, &throttled) {
+			throttling.retryAfter = throttled.retryAfter
+		}
+		return err
+	}
+
 	var err error
 	if prwe.retrySettings.Enabled {
-		// Use the BackOff instance to retry the func with exponential backoff.
-		err = backoff.Retry(executeFunc, &backoff.ExponentialBackOff{
+		// Use the BackOff instance to retry the func with exponential backoff. Every request gets its own
+		// instance, so that the retries of a request start from InitialInterval whatever happened to the
+		// previous ones.
+		var b backoff.BackOff = &backoff.ExponentialBackOff{
 			InitialInterval:     prwe.retrySettings.InitialInterval,
 			RandomizationFactor: prwe.retrySettings.RandomizationFactor,
 			Multiplier:          prwe.retrySettings.Multiplier,
@@ -389,18 +1256,66 @@ func (prwe *prwExporter) execute(ctx context.Context, writeReq *prompb.WriteRequ
 			MaxElapsedTime:      prwe.retrySettings.MaxElapsedTime,
 			Stop:                backoff.Stop,
 			Clock:               backoff.SystemClock,
+		}
+		if prwe.maxRetries > 0 {
+			// Retries stop at whichever of the count and the elapsed time is reached first.
+			b = backoff.WithMaxRetries(b, uint64(prwe.maxRetries))
+		}
+		throttling.BackOff = b
+		b = throttling
+		err = backoff.RetryNotify(executeFunc, b, func(_ error, interval time.Duration) {
+			prwe.telemetry.recordRetryBackoff(ctx, interval)
 		})
+		if err == nil && attempts > 1 {
+			prwe.telemetry.recordRetryBackoff(ctx, 0)
+		}
 	} else {
 		err = executeFunc()
 	}
 
+	if prwe.metadataCache != nil {
+		if err != nil {
+			// The metadata wasn't received, send it in full with the next samples of the series.
+			prwe.metadataCache.forget(sentMetadata)
+		}
+		prwe.recordSeriesCacheStats(ctx, seriesCacheMetadata, prwe.metadataCache)
+	}
 	if err != nil {
-		return consumererror.NewPermanent(err)
+		if prwe.protocolFallback {
+			// The endpoint may have changed, negotiate the protocol version again on the next request.
+			prwe.setNegotiatedProtocol(ctx, protocolUnnegotiated)
+		}
+		if requestID != "" {
+			prwe.settings.Logger.Warn("failed to send the remote write request", zap.String("request_id", requestID),
+				zap.Int("attempts", attempts), zap.Error(err))
+			err = fmt.Errorf("request %s: %w", requestID, err)
+		}
+		if consumererror.IsPermanent(err) {
+			// The endpoint rejected the request, it would be rejected again.
+			prwe.writeDeadLetter(writeReq)
+		}
+		prwe.lastError.Store(&err)
+		return exportError(writeReq, err)
+	}
+	prwe.lastError.Store(nil)
+	if prwe.lastSentTracker != nil {
+		prwe.lastSentTracker.record(writeReq)
+		prwe.recordSeriesCacheStats(ctx, seriesCacheLastSent, prwe.lastSentTracker)
 	}
 
 	return err
 }
 
+// attemptTimeout returns the timeout of the given attempt to send a request, starting from 0, when
+// the timeout grows on every retry. Otherwise, it returns 0 and the timeout of the client applies.
+func (prwe *prwExporter) attemptTimeout(attempt int) time.Duration {
+	if prwe.retryTimeoutMultiplier <= 0 || prwe.timeout <= 0 {
+		return 0
+	}
+	timeout := float64(prwe.timeout) * math.Pow(prwe.retryTimeoutMultiplier, float64(attempt))
+	return time.Duration(min(timeout, float64(prwe.maxRetryTimeout)))
+}
+
 func (prwe *prwExporter) walEnabled() bool { return prwe.wal != nil }
 
 func (prwe *prwExporter) turnOnWALIfEnabled(ctx context.Context) error {
@@ -408,9 +1323,12 @@ func (prwe *prwExporter) turnOnWALIfEnabled(ctx context.Context) error {
 		return nil
 	}
 	cancelCtx, cancel := context.WithCancel(ctx)
-	go func() {
-		<-prwe.closeChan
-		cancel()
-	}()
-	return prwe.wal.run(cancelCtx)
+	// The exports are cancelled on shutdown, once those in progress are drained.
+	prwe.cancelWALRun = cancel
+	for _, wal := range prwe.allWALs() {
+		if err := wal.run(cancelCtx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
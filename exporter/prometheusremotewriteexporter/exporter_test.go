@@ -14,11 +14,13 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
+	"github.com/google/uuid"
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
@@ -33,6 +35,7 @@ import (
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
@@ -40,6 +43,7 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/testdata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 )
 
 // Test_NewPRWExporter checks that a new exporter instance with non-nil fields is initialized
@@ -339,6 +343,101 @@ func Test_export(t *testing.T) {
 	}
 }
 
+func TestCompressionMinBytes(t *testing.T) {
+	var contentEncoding string
+	received := &prompb.WriteRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		contentEncoding = r.Header.Get("Content-Encoding")
+		if contentEncoding == "snappy" {
+			body, err = snappy.Decode(nil, body)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, proto.Unmarshal(body, received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.CompressionMinBytes = 1024
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	tiny := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		*getTimeSeries(getPromLabels(label11, value11), getSample(floatVal1, msTime1)),
+	}}
+	require.Less(t, tiny.Size(), cfg.CompressionMinBytes)
+	require.NoError(t, prwe.execute(context.Background(), tiny))
+	assert.Empty(t, contentEncoding, "tiny requests should be sent uncompressed")
+	assert.Equal(t, tiny.Timeseries, received.Timeseries)
+
+	large := &prompb.WriteRequest{}
+	for i := 0; i < 100; i++ {
+		large.Timeseries = append(large.Timeseries,
+			*getTimeSeries(getPromLabels(label11, fmt.Sprintf("value-%d", i)), getSample(floatVal1, msTime1)))
+	}
+	require.GreaterOrEqual(t, large.Size(), cfg.CompressionMinBytes)
+	require.NoError(t, prwe.execute(context.Background(), large))
+	assert.Equal(t, "snappy", contentEncoding, "large requests should be compressed")
+	assert.Equal(t, large.Timeseries, received.Timeseries)
+}
+
+func TestSnappyFormat(t *testing.T) {
+	var contentEncoding string
+	received := &prompb.WriteRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentEncoding = r.Header.Get("Content-Encoding")
+		var data []byte
+		var err error
+		switch contentEncoding {
+		case "snappy":
+			var body []byte
+			body, err = io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			data, err = snappy.Decode(nil, body)
+		case "x-snappy-framed":
+			data, err = io.ReadAll(snappy.NewReader(r.Body))
+		default:
+			http.Error(w, "unsupported encoding", http.StatusUnsupportedMediaType)
+			return
+		}
+		assert.NoError(t, err)
+		assert.NoError(t, proto.Unmarshal(data, received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		format                  string
+		expectedContentEncoding string
+	}{
+		{format: "", expectedContentEncoding: "snappy"},
+		{format: snappyFormatBlock, expectedContentEncoding: "snappy"},
+		{format: snappyFormatStream, expectedContentEncoding: "x-snappy-framed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.ClientConfig.Endpoint = server.URL
+			cfg.SnappyFormat = tt.format
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			prwe.client = server.Client()
+
+			req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+				*getTimeSeries(getPromLabels(label11, value11), getSample(floatVal1, msTime1)),
+			}}
+			require.NoError(t, prwe.execute(context.Background(), req))
+			assert.Equal(t, tt.expectedContentEncoding, contentEncoding)
+			assert.Equal(t, req.Timeseries, received.Timeseries)
+		})
+	}
+}
+
 func TestNoMetricsNoError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusAccepted)
@@ -385,6 +484,104 @@ func runExportPipeline(ts *prompb.TimeSeries, endpoint *url.URL) error {
 	return prwe.handleExport(context.Background(), testmap, nil)
 }
 
+// expectedSamplesMetric builds the samples counter the exporter is expected to report for the given per-kind counts.
+func expectedSamplesMetric(samples map[sampleKind]int) metricdata.Metrics {
+	dataPoints := make([]metricdata.DataPoint[int64], 0, len(samples))
+	for kind, n := range samples {
+		dataPoints = append(dataPoints, metricdata.DataPoint[int64]{
+			Value: int64(n),
+			Attributes: attribute.NewSet(
+				attribute.String("exporter", "prometheusremotewrite"),
+				attribute.String("metric_type", kind.metricType),
+				attribute.String("temporality", kind.temporality),
+			),
+		})
+	}
+	return metricdata.Metrics{
+		Name:        "otelcol_exporter_prometheusremotewrite_samples",
+		Description: "Number of Prometheus samples translated from OTel metrics and left to send by the filters, by metric type and temporality",
+		Unit:        "1",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  dataPoints,
+		},
+	}
+}
+
+func expectedLastBatchSeriesMetric(numSeries int) metricdata.Metrics {
+	return metricdata.Metrics{
+		Name:        "otelcol_exporter_prometheusremotewrite_last_batch_series",
+		Description: "Number of time series in the last remote write request assembled from a batch of metrics",
+		Unit:        "1",
+		Data: metricdata.Gauge[int64]{
+			DataPoints: []metricdata.DataPoint[int64]{
+				{Value: int64(numSeries), Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+			},
+		},
+	}
+}
+
+func expectedUnsupportedMetricsMetric(types map[string]int) metricdata.Metrics {
+	dataPoints := make([]metricdata.DataPoint[int64], 0, len(types))
+	for metricType, n := range types {
+		dataPoints = append(dataPoints, metricdata.DataPoint[int64]{
+			Value: int64(n),
+			Attributes: attribute.NewSet(
+				attribute.String("exporter", "prometheusremotewrite"),
+				attribute.String("type", metricType),
+			),
+		})
+	}
+	return metricdata.Metrics{
+		Name:        "otelcol_exporter_prometheusremotewrite_unsupported_metrics",
+		Description: "Number of metrics that weren't translated because their type is unsupported, by type",
+		Unit:        "1",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  dataPoints,
+		},
+	}
+}
+
+func expectedRequestsMetric(endpoint string, outcomes map[string]int) metricdata.Metrics {
+	dataPoints := make([]metricdata.DataPoint[int64], 0, len(outcomes))
+	for outcome, n := range outcomes {
+		dataPoints = append(dataPoints, metricdata.DataPoint[int64]{
+			Value: int64(n),
+			Attributes: attribute.NewSet(
+				attribute.String("endpoint", endpoint),
+				attribute.String("exporter", "prometheusremotewrite"),
+				attribute.String("outcome", outcome),
+			),
+		})
+	}
+	return metricdata.Metrics{
+		Name:        "otelcol_exporter_prometheusremotewrite_requests",
+		Description: "Number of attempts to send a remote write request, by endpoint scheme, host and path, and outcome: success when the endpoint accepted it, unexpected_status when it accepted it with another status than 204 while strict_status_codes is set, failure otherwise",
+		Unit:        "1",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  dataPoints,
+		},
+	}
+}
+
+func expectedQueueDepthMetric(depth int) metricdata.Metrics {
+	return metricdata.Metrics{
+		Name:        "otelcol_exporter_prometheusremotewrite_queue_depth",
+		Description: "Number of remote write requests waiting for a consumer to send them",
+		Unit:        "1",
+		Data: metricdata.Gauge[int64]{
+			DataPoints: []metricdata.DataPoint[int64]{
+				{Value: int64(depth), Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+			},
+		},
+	}
+}
+
 // Test_PushMetrics checks the number of TimeSeries received by server and the number of metrics dropped is the same as
 // expected
 func Test_PushMetrics(t *testing.T) {
@@ -481,6 +678,8 @@ func Test_PushMetrics(t *testing.T) {
 		isStaleMarker              bool
 		skipForWAL                 bool
 		expectedFailedTranslations int
+		expectedSamples            map[sampleKind]int
+		expectedUnsupportedMetrics map[string]int
 	}{
 		{
 			name:                       "invalid_type_case",
@@ -489,6 +688,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:                checkFunc,
 			expectedTimeSeries:         0,
 			expectedFailedTranslations: 1,
+			expectedUnsupportedMetrics: map[string]int{"Empty": 1},
 		},
 		{
 			name:               "intSum_case",
@@ -496,6 +696,8 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 4,
 			httpResponseCode:   http.StatusAccepted,
+			// 20 data points and the 2 samples of the _created series.
+			expectedSamples: map[sampleKind]int{{metricType: "sum", temporality: "cumulative"}: 22},
 		},
 		{
 			name:               "doubleSum_case",
@@ -503,6 +705,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 2,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "sum", temporality: "cumulative"}: 2},
 		},
 		{
 			name:               "doubleGauge_case",
@@ -510,6 +713,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 2,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 2},
 		},
 		{
 			name:               "intGauge_case",
@@ -517,6 +721,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 2,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 2},
 		},
 		{
 			name:               "exponential_histogram_case",
@@ -524,6 +729,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 2,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "exponential_histogram", temporality: "cumulative"}: 2},
 		},
 		{
 			name:               "valid_empty_exponential_histogram_case",
@@ -531,6 +737,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 3,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "exponential_histogram", temporality: "cumulative"}: 4},
 		},
 		{
 			name:               "exponential_histogram_no_sum_case",
@@ -538,6 +745,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 1,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "exponential_histogram", temporality: "cumulative"}: 2},
 		},
 		{
 			name:               "histogram_case",
@@ -545,6 +753,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 12,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "histogram", temporality: "cumulative"}: 12},
 		},
 		{
 			name:               "valid_empty_histogram_case",
@@ -552,6 +761,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 4,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "histogram", temporality: "cumulative"}: 4},
 		},
 		{
 			name:               "histogram_no_sum_case",
@@ -559,6 +769,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 10,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "histogram", temporality: "cumulative"}: 10},
 		},
 		{
 			name:               "summary_case",
@@ -566,6 +777,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 10,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "summary", temporality: "unspecified"}: 10},
 		},
 		{
 			name:               "unmatchedBoundBucketHist_case",
@@ -573,6 +785,7 @@ func Test_PushMetrics(t *testing.T) {
 			reqTestFunc:        checkFunc,
 			expectedTimeSeries: 5,
 			httpResponseCode:   http.StatusAccepted,
+			expectedSamples:    map[sampleKind]int{{metricType: "histogram", temporality: "cumulative"}: 5},
 		},
 		{
 			name:               "5xx_case",
@@ -619,6 +832,10 @@ func Test_PushMetrics(t *testing.T) {
 			httpResponseCode:           http.StatusAccepted,
 			expectedTimeSeries:         4,
 			expectedFailedTranslations: 1,
+			expectedSamples: map[sampleKind]int{
+				{metricType: "sum", temporality: "cumulative"}:    2,
+				{metricType: "gauge", temporality: "unspecified"}: 2,
+			},
 		},
 		{
 			name:               "staleNaNIntGauge_case",
@@ -627,6 +844,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 1,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 1},
 		},
 		{
 			name:               "staleNaNDoubleGauge_case",
@@ -635,6 +853,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 1,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 1},
 		},
 		{
 			name:               "staleNaNIntSum_case",
@@ -643,6 +862,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 1,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "sum", temporality: "cumulative"}: 1},
 		},
 		{
 			name:               "staleNaNSum_case",
@@ -651,6 +871,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 1,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "sum", temporality: "cumulative"}: 1},
 		},
 		{
 			name:               "staleNaNHistogram_case",
@@ -659,6 +880,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 6,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "histogram", temporality: "cumulative"}: 6},
 		},
 		{
 			name:               "staleNaNEmptyHistogram_case",
@@ -667,6 +889,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 3,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "histogram", temporality: "cumulative"}: 3},
 		},
 		{
 			name:               "staleNaNSummary_case",
@@ -675,6 +898,7 @@ func Test_PushMetrics(t *testing.T) {
 			expectedTimeSeries: 5,
 			httpResponseCode:   http.StatusAccepted,
 			isStaleMarker:      true,
+			expectedSamples:    map[sampleKind]int{{metricType: "summary", temporality: "unspecified"}: 5},
 		},
 	}
 
@@ -724,7 +948,8 @@ func Test_PushMetrics(t *testing.T) {
 						CreatedMetric: &CreatedMetric{
 							Enabled: true,
 						},
-						BackOffConfig: retrySettings,
+						NoRecordedValueAsStale: true,
+						BackOffConfig:          retrySettings,
 					}
 
 					if useWAL {
@@ -792,6 +1017,17 @@ func Test_PushMetrics(t *testing.T) {
 							},
 						},
 					})
+					if len(tt.expectedSamples) > 0 {
+						expectedMetrics = append(expectedMetrics, expectedSamplesMetric(tt.expectedSamples))
+					}
+					if len(tt.expectedUnsupportedMetrics) > 0 {
+						expectedMetrics = append(expectedMetrics, expectedUnsupportedMetricsMetric(tt.expectedUnsupportedMetrics))
+					}
+					if tt.expectedTimeSeries > 0 {
+						// All the series fit in a single request, which has been consumed.
+						expectedMetrics = append(expectedMetrics, expectedLastBatchSeriesMetric(tt.expectedTimeSeries), expectedQueueDepthMetric(0),
+							expectedRequestsMetric(server.URL, map[string]int{requestOutcomeSuccess: 1}))
+					}
 					tel.AssertMetrics(t, expectedMetrics, metricdatatest.IgnoreTimestamp())
 					assert.NoError(t, err)
 				})
@@ -800,6 +1036,582 @@ func Test_PushMetrics(t *testing.T) {
 	}
 }
 
+func TestPushMetricsInvalidLabelNamePolicy(t *testing.T) {
+	invalidGauge := getIntGaugeMetric("invalid_label_gauge", getAttributes("my label", value11), intVal1, time1)
+
+	tests := []struct {
+		policy          prometheusremotewrite.InvalidLabelNamePolicy
+		expectedSeries  []string
+		expectedLabel   string
+		expectPermanent bool
+	}{
+		{
+			policy:         prometheusremotewrite.InvalidLabelNamePolicySanitize,
+			expectedSeries: []string{"invalid_label_gauge", validIntGauge},
+			expectedLabel:  "my_label",
+		},
+		{
+			policy:         prometheusremotewrite.InvalidLabelNamePolicyDropSeries,
+			expectedSeries: []string{validIntGauge},
+		},
+		{
+			policy:          prometheusremotewrite.InvalidLabelNamePolicyError,
+			expectPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			var mu sync.Mutex
+			var gotSeries, gotLabels []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				dest, err := snappy.Decode(nil, body)
+				assert.NoError(t, err)
+				wr := &prompb.WriteRequest{}
+				assert.NoError(t, proto.Unmarshal(dest, wr))
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, ts := range wr.Timeseries {
+					for _, l := range ts.Labels {
+						if l.Name == "__name__" {
+							gotSeries = append(gotSeries, l.Value)
+						} else {
+							gotLabels = append(gotLabels, l.Name)
+						}
+					}
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.ClientConfig.Endpoint = server.URL
+			cfg.RemoteWriteQueue.NumConsumers = 1
+			cfg.InvalidLabelNamePolicy = tt.policy
+			require.NoError(t, cfg.Validate())
+
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			require.NoError(t, prwe.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, prwe.Shutdown(context.Background()))
+			}()
+
+			err = prwe.PushMetrics(context.Background(), getMetricsFromMetricList(validMetrics1[validIntGauge], invalidGauge))
+			if tt.expectPermanent {
+				assert.True(t, consumererror.IsPermanent(err), "error should be consumererror.Permanent")
+				var invalidLabelNameErr *prometheusremotewrite.InvalidLabelNameError
+				require.ErrorAs(t, err, &invalidLabelNameErr)
+				assert.Equal(t, "my label", invalidLabelNameErr.Name)
+				assert.Empty(t, gotSeries, "no series should be exported when the batch is rejected")
+				return
+			}
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.ElementsMatch(t, tt.expectedSeries, gotSeries)
+			if tt.expectedLabel != "" {
+				assert.Contains(t, gotLabels, tt.expectedLabel)
+			}
+		})
+	}
+}
+
+func TestPushMetricsPartialTranslationPolicy(t *testing.T) {
+	// A metric without a type can't be translated.
+	untranslatable := pmetric.NewMetric()
+	untranslatable.SetName("untranslatable")
+
+	tests := []struct {
+		policy         string
+		expectedSeries []string
+	}{
+		{policy: "", expectedSeries: []string{validIntGauge}},
+		{policy: partialTranslationPolicyDropResource, expectedSeries: []string{validIntGauge}},
+		{policy: partialTranslationPolicyDropFailed, expectedSeries: []string{validIntGauge}},
+		{policy: partialTranslationPolicyDropBatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var gotSeries []string
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						for _, l := range ts.Labels {
+							if l.Name == "__name__" {
+								gotSeries = append(gotSeries, l.Value)
+							}
+						}
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.PartialTranslationPolicy = tt.policy
+			require.NoError(t, cfg.Validate())
+			tel := metadatatest.SetupTelemetry()
+			prwe, err := newPRWExporter(cfg, tel.NewSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			err = prwe.PushMetrics(context.Background(), getMetricsFromMetricList(validMetrics1[validIntGauge], untranslatable))
+			if tt.policy == partialTranslationPolicyDropBatch {
+				assert.True(t, consumererror.IsPermanent(err), "error should be consumererror.Permanent")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedSeries, gotSeries)
+
+			expectedMetrics := []metricdata.Metrics{
+				{
+					Name:        "otelcol_exporter_prometheusremotewrite_failed_translations",
+					Description: "Number of translation operations that failed to translate metrics from Otel to Prometheus",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.CumulativeTemporality,
+						IsMonotonic: true,
+						DataPoints: []metricdata.DataPoint[int64]{
+							{Value: 1, Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+						},
+					},
+				},
+				expectedUnsupportedMetricsMetric(map[string]int{"Empty": 1}),
+			}
+			if tt.policy != partialTranslationPolicyDropBatch {
+				expectedMetrics = append(expectedMetrics,
+					metricdata.Metrics{
+						Name:        "otelcol_exporter_prometheusremotewrite_translated_time_series",
+						Description: "Number of Prometheus time series that were translated from OTel metrics",
+						Unit:        "1",
+						Data: metricdata.Sum[int64]{
+							Temporality: metricdata.CumulativeTemporality,
+							IsMonotonic: true,
+							DataPoints: []metricdata.DataPoint[int64]{
+								{Value: 1, Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+							},
+						},
+					},
+					expectedSamplesMetric(map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 1}),
+					expectedLastBatchSeriesMetric(1),
+				)
+			}
+			tel.AssertMetrics(t, expectedMetrics, metricdatatest.IgnoreTimestamp())
+		})
+	}
+}
+
+func TestPushMetricsDropZeroValueCounters(t *testing.T) {
+	counter := func(name string, value int64) pmetric.Metric {
+		metric := getIntSumMetric(name, getAttributes(label11, value11), value, time1)
+		metric.Sum().SetIsMonotonic(true)
+		return metric
+	}
+	// The first batch establishes which counters have been nonzero, the second one resets a counter to zero.
+	batches := [][]pmetric.Metric{
+		{counter("always_zero", 0), counter("reset_to_zero", 5), getIntGaugeMetric("zero_gauge", getAttributes(label11, value11), 0, time1)},
+		{counter("always_zero", 0), counter("reset_to_zero", 0), getIntGaugeMetric("zero_gauge", getAttributes(label11, value11), 0, time2)},
+	}
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		expectedSeries [][]string
+	}{
+		{
+			name:    "disabled",
+			enabled: false,
+			expectedSeries: [][]string{
+				{"always_zero", "reset_to_zero", "zero_gauge"},
+				{"always_zero", "reset_to_zero", "zero_gauge"},
+			},
+		},
+		{
+			name:    "enabled",
+			enabled: true,
+			expectedSeries: [][]string{
+				{"reset_to_zero", "zero_gauge"},
+				{"reset_to_zero", "zero_gauge"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSeries []string
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						for _, l := range ts.Labels {
+							if l.Name == "__name__" {
+								gotSeries = append(gotSeries, l.Value)
+							}
+						}
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.AddMetricSuffixes = false
+			cfg.DropZeroValueCounters = tt.enabled
+			require.NoError(t, cfg.Validate())
+
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			for i, batch := range batches {
+				gotSeries = nil
+				require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(batch...)))
+				assert.ElementsMatch(t, tt.expectedSeries[i], gotSeries, "batch %d", i)
+			}
+		})
+	}
+}
+
+func TestPushMetricsNoRecordedValueAsStale(t *testing.T) {
+	gauge := getDoubleGaugeMetric("disappeared_gauge", getAttributes(label11, value11), 5, time1)
+	gauge.Gauge().DataPoints().At(0).SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+
+	for _, asStale := range []bool{true, false} {
+		var got []prompb.Sample
+		sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+			for _, req := range requests {
+				for _, ts := range req.Timeseries {
+					got = append(got, ts.Samples...)
+				}
+			}
+			return nil
+		})
+
+		cfg := createDefaultConfig().(*Config)
+		cfg.TargetInfo.Enabled = false
+		cfg.NoRecordedValueAsStale = asStale
+		require.NoError(t, cfg.Validate())
+		prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+		require.NoError(t, err)
+		require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(gauge)))
+
+		require.Len(t, got, 1)
+		if asStale {
+			assert.True(t, value.IsStaleNaN(got[0].Value), "expected a stale marker, got %v", got[0].Value)
+		} else {
+			assert.Equal(t, 5.0, got[0].Value)
+		}
+	}
+}
+
+func TestPushMetricsSeriesRateLimit(t *testing.T) {
+	// A gauge reporting every 100ms, 10 times the allowed rate of one sample per second.
+	gauge := pmetric.NewMetric()
+	gauge.SetName("high_frequency_gauge")
+	dataPoints := gauge.SetEmptyGauge().DataPoints()
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 20; i++ {
+		dp := dataPoints.AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Duration(i) * 100 * time.Millisecond)))
+		dp.SetDoubleValue(float64(i))
+	}
+
+	var got []prompb.Sample
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				got = append(got, ts.Samples...)
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.MaxSamplesPerSeriesPerInterval = 1
+	cfg.SeriesRateLimitInterval = time.Second
+	require.NoError(t, cfg.Validate())
+
+	tel := metadatatest.SetupTelemetry()
+	prwe, err := newPRWExporter(cfg, tel.NewSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(gauge)))
+
+	// Only the most recent sample of each one second window survives.
+	assert.Equal(t, []prompb.Sample{
+		{Value: 9, Timestamp: start.Add(900 * time.Millisecond).UnixMilli()},
+		{Value: 19, Timestamp: start.Add(1900 * time.Millisecond).UnixMilli()},
+	}, got)
+
+	tel.AssertMetrics(t, []metricdata.Metrics{
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_dropped_samples",
+			Description: "Number of Prometheus samples dropped by the exporter before being sent, by reason",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{
+						Value: 18,
+						Attributes: attribute.NewSet(
+							attribute.String("exporter", "prometheusremotewrite"),
+							attribute.String("reason", "rate_limited"),
+						),
+					},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_translated_time_series",
+			Description: "Number of Prometheus time series that were translated from OTel metrics",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{Value: 1, Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_series_cache_lookups",
+			Description: "Number of lookups of series in the per-series caches of the exporter, by cache and result",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{
+						Value: 1,
+						Attributes: attribute.NewSet(
+							attribute.String("exporter", "prometheusremotewrite"),
+							attribute.String("cache", "series_rate_limit"),
+							attribute.String("result", "miss"),
+						),
+					},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_series_cache_size",
+			Description: "Number of series held by the per-series caches of the exporter, by cache",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{
+						Value: 1,
+						Attributes: attribute.NewSet(
+							attribute.String("exporter", "prometheusremotewrite"),
+							attribute.String("cache", "series_rate_limit"),
+						),
+					},
+				},
+			},
+		},
+		// Only the samples that survive the rate limit are counted.
+		expectedSamplesMetric(map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: 2}),
+		expectedLastBatchSeriesMetric(1),
+	}, metricdatatest.IgnoreTimestamp())
+}
+
+func TestPushMetricsGaugeWithoutCreatedSeries(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	withStart := func(dp pmetric.NumberDataPoint) {
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Minute)))
+		dp.SetDoubleValue(1)
+	}
+	gauge := pmetric.NewMetric()
+	gauge.SetName("gauge")
+	withStart(gauge.SetEmptyGauge().DataPoints().AppendEmpty())
+	upDownCounter := pmetric.NewMetric()
+	upDownCounter.SetName("up_down_counter")
+	upDownCounter.SetEmptySum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	withStart(upDownCounter.Sum().DataPoints().AppendEmpty())
+	counter := pmetric.NewMetric()
+	counter.SetName("counter")
+	counter.SetEmptySum().SetIsMonotonic(true)
+	counter.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	withStart(counter.Sum().DataPoints().AppendEmpty())
+
+	var got []string
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				got = append(got, ts.Labels[0].Value)
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.AddMetricSuffixes = false
+	cfg.CreatedMetric.Enabled = true
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(gauge, upDownCounter, counter)))
+
+	// Only the counter gets a _created series, gauges and non-monotonic sums have no meaningful start time.
+	assert.ElementsMatch(t, []string{"gauge", "up_down_counter", "counter", "counter_created"}, got)
+}
+
+func TestEmitRequestID(t *testing.T) {
+	writeReq := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+	}}}
+	newExporter := func(t *testing.T, emitRequestID bool, handler http.HandlerFunc) *prwExporter {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		cfg := createDefaultConfig().(*Config)
+		cfg.ClientConfig.Endpoint = server.URL
+		cfg.BackOffConfig.InitialInterval = time.Millisecond
+		cfg.BackOffConfig.MaxElapsedTime = time.Second
+		cfg.EmitRequestID = emitRequestID
+		prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+		require.NoError(t, err)
+		prwe.client = server.Client()
+		return prwe
+	}
+
+	t.Run("stable across retries", func(t *testing.T) {
+		var ids []string
+		prwe := newExporter(t, true, func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, r.Header.Get(requestIDHeader))
+			// The first attempt of every request fails and is retried.
+			if len(ids)%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+		require.NoError(t, prwe.execute(context.Background(), writeReq))
+		require.NoError(t, prwe.execute(context.Background(), writeReq))
+
+		require.Len(t, ids, 4)
+		for _, id := range ids {
+			_, err := uuid.Parse(id)
+			assert.NoError(t, err, id)
+		}
+		assert.Equal(t, ids[0], ids[1], "the retry should carry the ID of the request")
+		assert.Equal(t, ids[2], ids[3], "the retry should carry the ID of the request")
+		assert.NotEqual(t, ids[0], ids[2], "every request should get its own ID")
+	})
+
+	t.Run("included in the error", func(t *testing.T) {
+		var id string
+		prwe := newExporter(t, true, func(w http.ResponseWriter, r *http.Request) {
+			id = r.Header.Get(requestIDHeader)
+			w.WriteHeader(http.StatusBadRequest)
+		})
+		err := prwe.execute(context.Background(), writeReq)
+		require.Error(t, err)
+		assert.True(t, consumererror.IsPermanent(err))
+		require.NotEmpty(t, id)
+		assert.Contains(t, err.Error(), id)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var ids []string
+		prwe := newExporter(t, false, func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, r.Header.Values(requestIDHeader)...)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		require.NoError(t, prwe.execute(context.Background(), writeReq))
+		assert.Empty(t, ids)
+	})
+}
+
+func TestExportQueueDepth(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.RemoteWriteQueue.NumConsumers = 1
+	tel := metadatatest.SetupTelemetry()
+	set := tel.NewSettings()
+	// detailed level enables otelhttp client instrumentation which we dont want to test here
+	set.MetricsLevel = configtelemetry.LevelBasic
+	prwe, err := newPRWExporter(cfg, set)
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	requests := []*prompb.WriteRequest{makeReq(0)[0], makeReq(1)[0], makeReq(2)[0]}
+	exported := make(chan error)
+	go func() {
+		exported <- prwe.export(context.Background(), requests)
+	}()
+
+	// The single consumer is sending the first request, the two others are waiting for it.
+	<-received
+	tel.AssertMetrics(t, []metricdata.Metrics{expectedQueueDepthMetric(2)}, metricdatatest.IgnoreTimestamp())
+
+	close(release)
+	<-received
+	<-received
+	require.NoError(t, <-exported)
+	tel.AssertMetrics(t, []metricdata.Metrics{
+		expectedQueueDepthMetric(0),
+		expectedRequestsMetric(server.URL, map[string]int{requestOutcomeSuccess: 3}),
+	}, metricdatatest.IgnoreTimestamp())
+}
+
+func TestPushMetricsHeartbeat(t *testing.T) {
+	gauge := pmetric.NewMetric()
+	gauge.SetName("test_gauge")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+
+	var heartbeats [][]prompb.Label
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				for _, l := range ts.Labels {
+					if l.Name == "__name__" && l.Value == "otelcol_remote_write_up" {
+						require.Len(t, ts.Samples, 1)
+						assert.Equal(t, float64(1), ts.Samples[0].Value)
+						heartbeats = append(heartbeats, ts.Labels)
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.ExternalLabels = map[string]string{"cluster": "test"}
+	cfg.EmitHeartbeat = true
+	cfg.HeartbeatLabels = map[string]string{"collector.name": "gateway"}
+	// The heartbeat is never filtered out, so the rate limit doesn't drop the second one.
+	cfg.MaxSamplesPerSeriesPerInterval = 1
+	cfg.SeriesRateLimitInterval = time.Hour
+	require.NoError(t, cfg.Validate())
+
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(gauge)))
+	require.NoError(t, prwe.PushMetrics(context.Background(), pmetric.NewMetrics()))
+
+	want := []prompb.Label{
+		{Name: "__name__", Value: "otelcol_remote_write_up"},
+		{Name: "cluster", Value: "test"},
+		{Name: "collector_name", Value: "gateway"},
+	}
+	assert.Equal(t, [][]prompb.Label{want, want}, heartbeats, "a heartbeat should be sent on every flush")
+}
+
 func Test_validateAndSanitizeExternalLabels(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -1172,6 +1984,7 @@ func TestRetries(t *testing.T) {
 				retrySettings: configretry.BackOffConfig{
 					Enabled: true,
 				},
+				telemetry: nopTelemetry{},
 			}
 
 			err = exporter.execute(tt.ctx, &prompb.WriteRequest{})
@@ -1182,6 +1995,146 @@ func TestRetries(t *testing.T) {
 	}
 }
 
+// deadlineRecordingTransport records the time left before the deadline of every request, and fails the
+// first failures ones.
+type deadlineRecordingTransport struct {
+	failures  int
+	deadlines []time.Duration
+}
+
+func (d *deadlineRecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return nil, fmt.Errorf("request has no deadline")
+	}
+	d.deadlines = append(d.deadlines, time.Until(deadline))
+	if len(d.deadlines) <= d.failures {
+		return nil, fmt.Errorf("backend unavailable")
+	}
+	return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+}
+
+func TestRetryTimeoutEscalation(t *testing.T) {
+	endpointURL, err := url.Parse("http://localhost:9090/api/v1/write")
+	require.NoError(t, err)
+	transport := &deadlineRecordingTransport{failures: 4}
+	exporter := &prwExporter{
+		endpointURL: endpointURL,
+		client:      &http.Client{Transport: transport},
+		retrySettings: configretry.BackOffConfig{
+			Enabled:         true,
+			InitialInterval: time.Millisecond,
+			MaxInterval:     time.Millisecond,
+		},
+		timeout:                time.Second,
+		retryTimeoutMultiplier: 2,
+		maxRetryTimeout:        5 * time.Second,
+		telemetry:              nopTelemetry{},
+	}
+
+	require.NoError(t, exporter.execute(context.Background(), &prompb.WriteRequest{}))
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	require.Len(t, transport.deadlines, len(want))
+	for i, deadline := range transport.deadlines {
+		assert.InDelta(t, want[i], deadline, float64(100*time.Millisecond), "attempt %d", i)
+	}
+
+	// Without a multiplier, the timeout of the client applies to every attempt.
+	exporter.retryTimeoutMultiplier = 0
+	assert.Equal(t, time.Duration(0), exporter.attemptTimeout(3))
+}
+
+// retryBackoffTelemetry records the values of the retry backoff gauge.
+type retryBackoffTelemetry struct {
+	nopTelemetry
+	intervals []time.Duration
+}
+
+func (r *retryBackoffTelemetry) recordRetryBackoff(_ context.Context, interval time.Duration) {
+	r.intervals = append(r.intervals, interval)
+}
+
+func TestRetryBackoffResetsOnSuccess(t *testing.T) {
+	// The first request fails three times before being sent, the second one fails once.
+	statuses := []int{
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusNoContent,
+		http.StatusInternalServerError, http.StatusNoContent,
+	}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, statuses, "unexpected request")
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.BackOffConfig.InitialInterval = 10 * time.Millisecond
+	cfg.BackOffConfig.RandomizationFactor = 0
+	cfg.BackOffConfig.Multiplier = 4
+	cfg.BackOffConfig.MaxInterval = time.Second
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+	tel := &retryBackoffTelemetry{}
+	prwe.telemetry = tel
+
+	require.NoError(t, prwe.execute(context.Background(), makeReq(0)[0]))
+	require.NoError(t, prwe.execute(context.Background(), makeReq(1)[0]))
+
+	// The retries of the second request start from the initial interval again.
+	assert.Equal(t, []time.Duration{
+		10 * time.Millisecond, 40 * time.Millisecond, 160 * time.Millisecond, 0,
+		10 * time.Millisecond, 0,
+	}, tel.intervals)
+	assert.Empty(t, statuses)
+}
+
+func TestMaxRetries(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		maxRetries     int
+		maxElapsedTime time.Duration
+		wantRequests   int64
+	}{
+		{name: "count reached first", maxRetries: 3, maxElapsedTime: time.Minute, wantRequests: 4},
+		// The retries are 10ms apart, so the time is up long before their count is reached.
+		{name: "time reached first", maxRetries: 1000, maxElapsedTime: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			cfg := createDefaultConfig().(*Config)
+			cfg.ClientConfig.Endpoint = server.URL
+			cfg.BackOffConfig.InitialInterval = 10 * time.Millisecond
+			cfg.BackOffConfig.MaxInterval = 10 * time.Millisecond
+			cfg.BackOffConfig.MaxElapsedTime = tt.maxElapsedTime
+			cfg.MaxRetries = tt.maxRetries
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			prwe.client = server.Client()
+
+			assert.Error(t, prwe.execute(context.Background(), makeReq(0)[0]))
+			if tt.wantRequests > 0 {
+				assert.Equal(t, tt.wantRequests, requests.Load(), "the request should be sent once, then retried max_retries times")
+			} else {
+				assert.Less(t, requests.Load(), int64(tt.maxRetries))
+			}
+		})
+	}
+}
+
 func BenchmarkExecute(b *testing.B) {
 	for _, numSample := range []int{100, 1000, 10000} {
 		b.Run(fmt.Sprintf("numSample=%d", numSample), func(b *testing.B) {
@@ -11,12 +11,14 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.opentelemetry.io/collector/featuregate"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadata"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/resourcetotelemetry"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 )
 
 var retryOn429FeatureGate = featuregate.GlobalRegistry().MustRegister(
@@ -33,16 +35,52 @@ var enableMultipleWorkersFeatureGate = featuregate.GlobalRegistry().MustRegister
 		" spawn multiple workers/goroutines to handle incoming metrics batches concurrently"),
 )
 
+// FactoryOption customizes the exporters created by a factory.
+type FactoryOption func(*factoryOptions)
+
+type factoryOptions struct {
+	exportSink       ExportSink
+	endpointResolver EndpointResolver
+	statusClassifier StatusClassifier
+}
+
+// WithExportSink makes the exporters send their requests to sink rather than to the remote write endpoint.
+// Translation, batching and the WAL are left unchanged.
+func WithExportSink(sink ExportSink) FactoryOption {
+	return func(o *factoryOptions) {
+		o.exportSink = sink
+	}
+}
+
+// WithEndpointResolver makes the exporters ask resolver for the endpoint of every request they send,
+// including retries, instead of using the configured endpoint, to spread the requests over several
+// addresses for example.
+func WithEndpointResolver(resolver EndpointResolver) FactoryOption {
+	return func(o *factoryOptions) {
+		o.endpointResolver = resolver
+	}
+}
+
+// WithStatusClassifier makes the exporters classify the non-2xx status codes returned by the remote write
+// endpoint with classifier, to decide which failed requests are retried, instead of DefaultStatusClassifier.
+func WithStatusClassifier(classifier StatusClassifier) FactoryOption {
+	return func(o *factoryOptions) {
+		o.statusClassifier = classifier
+	}
+}
+
 // NewFactory creates a new Prometheus Remote Write exporter.
-func NewFactory() exporter.Factory {
+func NewFactory(opts ...FactoryOption) exporter.Factory {
 	return exporter.NewFactory(
 		metadata.Type,
 		createDefaultConfig,
-		exporter.WithMetrics(createMetricsExporter, metadata.MetricsStability))
+		exporter.WithMetrics(func(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Metrics, error) {
+			return createMetricsExporter(ctx, set, cfg, opts...)
+		}, metadata.MetricsStability))
 }
 
 func createMetricsExporter(ctx context.Context, set exporter.Settings,
-	cfg component.Config,
+	cfg component.Config, opts ...FactoryOption,
 ) (exporter.Metrics, error) {
 	prwCfg, ok := cfg.(*Config)
 	if !ok {
@@ -53,7 +91,7 @@ func createMetricsExporter(ctx context.Context, set exporter.Settings,
 		set.Logger.Warn("`remote_write_queue.num_consumers` will be used to configure processing parallelism, rather than request parallelism in a future release. This may cause out-of-order issues unless you take action. Please migrate to using `max_batch_request_parallelism` to keep the your existing behavior.")
 	}
 
-	prwe, err := newPRWExporter(prwCfg, set)
+	prwe, err := newPRWExporter(prwCfg, set, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +113,8 @@ func createMetricsExporter(ctx context.Context, set exporter.Settings,
 		}),
 		exporterhelper.WithStart(prwe.Start),
 		exporterhelper.WithShutdown(prwe.Shutdown),
+		// Some options edit the batch in place, dropping always-zero counters for example.
+		exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: prwCfg.mutatesData()}),
 	)
 	if err != nil {
 		return nil, err
@@ -119,5 +159,11 @@ func createDefaultConfig() component.Config {
 		CreatedMetric: &CreatedMetric{
 			Enabled: false,
 		},
+		EnforceSampleOrder:      true,
+		FollowRedirects:         true,
+		InvalidLabelNamePolicy:  prometheusremotewrite.InvalidLabelNamePolicySanitize,
+		NoRecordedValueAsStale:  true,
+		SeriesRateLimitInterval: defaultSeriesRateLimitInterval,
+		TranslationConcurrency:  1,
 	}
 }
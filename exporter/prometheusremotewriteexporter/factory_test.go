@@ -5,15 +5,21 @@ package prometheusremotewriteexporter
 
 import (
 	"context"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configtls"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 )
 
 // Tests whether or not the default Exporter factory can instantiate a properly interfaced Exporter with default conditions
@@ -93,3 +99,125 @@ func Test_createMetricsExporter(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFactoryWithExportSink(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]*prompb.WriteRequest
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, requests)
+		return nil
+	})
+
+	factory := NewFactory(WithExportSink(sink))
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.RemoteWriteQueue.Enabled = false
+	// Small enough for the time series to be split across requests.
+	cfg.MaxBatchSizeBytes = 100
+
+	exp, err := factory.CreateMetrics(context.Background(), exportertest.NewNopSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		assert.NoError(t, exp.Shutdown(context.Background()))
+	}()
+
+	md := getMetricsFromMetricList(validMetrics1[validIntGauge], validMetrics1[validDoubleGauge], validMetrics1[validIntSum])
+	require.NoError(t, exp.ConsumeMetrics(context.Background(), md))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, batches, 1)
+	assert.Greater(t, len(batches[0]), 1, "the batch should have been split into several requests")
+	var names []string
+	for _, req := range batches[0] {
+		for _, ts := range req.Timeseries {
+			for _, l := range ts.Labels {
+				if l.Name == "__name__" {
+					names = append(names, l.Value)
+				}
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"valid_IntGauge", "valid_DoubleGauge", "valid_IntSum"}, names)
+}
+
+// consumeShared consumes md like a pipeline fanning it out to several consumers does: md is read-only, and only
+// copied for the consumers declaring that they mutate it.
+func consumeShared(ctx context.Context, exp exporter.Metrics, md pmetric.Metrics) error {
+	md.MarkReadOnly()
+	if exp.Capabilities().MutatesData {
+		clone := pmetric.NewMetrics()
+		md.CopyTo(clone)
+		md = clone
+	}
+	return exp.ConsumeMetrics(ctx, md)
+}
+
+func TestCreateMetricsExporterSharedData(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	counter := func(value float64, timestamp time.Time) pmetric.Metrics {
+		metric := pmetric.NewMetric()
+		metric.SetName("requests")
+		sum := metric.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		dp := sum.DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		dp.SetDoubleValue(value)
+		return getMetricsFromMetricList(metric)
+	}
+	typeConflict := counter(1, start)
+	gauge := typeConflict.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().AppendEmpty()
+	gauge.SetName("requests")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		// batches are consumed in order, the last ones are edited by the exporter.
+		batches []pmetric.Metrics
+	}{
+		{
+			name:      "emit counter reset samples",
+			configure: func(cfg *Config) { cfg.EmitCounterResetSamples = true },
+			batches:   []pmetric.Metrics{counter(10, start), counter(3, start.Add(time.Minute))},
+		},
+		{
+			name:      "metric type conflict policy",
+			configure: func(cfg *Config) { cfg.MetricTypeConflictPolicy = metricTypeConflictPolicyDropLater },
+			batches:   []pmetric.Metrics{typeConflict},
+		},
+		{
+			name:      "value scaling",
+			configure: func(cfg *Config) { cfg.ValueScaling = []ValueScalingRule{{MetricNamePattern: ".*", Multiplier: 2}} },
+			batches:   []pmetric.Metrics{counter(1, start)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := ExportSinkFunc(func(context.Context, []*prompb.WriteRequest) error { return nil })
+			factory := NewFactory(WithExportSink(sink))
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.RemoteWriteQueue.Enabled = false
+			cfg.TargetInfo.Enabled = false
+			tt.configure(cfg)
+			require.NoError(t, cfg.Validate())
+
+			exp, err := factory.CreateMetrics(context.Background(), exportertest.NewNopSettings(), cfg)
+			require.NoError(t, err)
+			require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				assert.NoError(t, exp.Shutdown(context.Background()))
+			}()
+
+			assert.True(t, exp.Capabilities().MutatesData)
+			for _, md := range tt.batches {
+				assert.NotPanics(t, func() {
+					assert.NoError(t, consumeShared(context.Background(), exp, md))
+				})
+			}
+		})
+	}
+}
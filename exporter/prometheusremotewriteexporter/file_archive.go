@@ -0,0 +1,134 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileArchive configures archiving the remote write requests to local files.
+type FileArchive struct {
+	// Directory is the directory the archive files are written to.
+	Directory string `mapstructure:"directory"`
+
+	// RotationSizeBytes is the size above which a new archive file is started.
+	RotationSizeBytes int64 `mapstructure:"rotation_size_bytes"`
+
+	// ArchiveOnly controls whether the requests are only archived, instead of being archived and sent.
+	ArchiveOnly bool `mapstructure:"archive_only"`
+}
+
+const defaultArchiveRotationSizeBytes = 100 << 20
+
+// fileArchiver appends snappy compressed remote write 1.0 requests to the files of a directory. Every
+// request is preceded by its uvarint encoded size.
+type fileArchiver struct {
+	mu           sync.Mutex // mu protects the fields below.
+	directory    string
+	prefix       string
+	rotationSize int64
+	// maxTotalSize is the size the files of the archiver are kept under, by removing the oldest ones when a
+	// file is started. 0 means no limit.
+	maxTotalSize int64
+	file         *os.File
+	size         int64
+	sequence     int
+}
+
+func newFileArchiver(cfg *FileArchive) *fileArchiver {
+	return &fileArchiver{
+		directory:    cfg.Directory,
+		prefix:       "remote_write",
+		rotationSize: cfg.RotationSizeBytes,
+	}
+}
+
+// write appends the snappy compressed request to the current archive file, starting a new file first
+// if the request would make the current one bigger than the rotation size.
+func (a *fileArchiver) write(compressed []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(compressed)), uint64(len(compressed)))
+	record = append(record, compressed...)
+	if a.file != nil && a.size > 0 && a.size+int64(len(record)) > a.rotationSize {
+		if err := a.closeFile(); err != nil {
+			return err
+		}
+	}
+	if a.file == nil {
+		if err := os.MkdirAll(a.directory, 0o700); err != nil {
+			return err
+		}
+		if a.maxTotalSize > 0 {
+			if err := a.removeOldestFiles(); err != nil {
+				return err
+			}
+		}
+		name := fmt.Sprintf("%s-%d-%06d.archive", a.prefix, time.Now().UnixNano(), a.sequence)
+		file, err := os.OpenFile(filepath.Join(a.directory, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		a.file, a.size = file, 0
+		a.sequence++
+	}
+	n, err := a.file.Write(record)
+	a.size += int64(n)
+	return err
+}
+
+// removeOldestFiles removes the oldest files of the archiver until the remaining ones and a new file of the
+// rotation size fit in the maximum total size. It must be called with a.mu held, before starting a file.
+func (a *fileArchiver) removeOldestFiles() error {
+	entries, err := os.ReadDir(a.directory)
+	if err != nil {
+		return err
+	}
+	// The entries are sorted by name, so from the oldest to the newest file.
+	var names []string
+	var sizes []int64
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), a.prefix+"-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		names, sizes = append(names, entry.Name()), append(sizes, info.Size())
+		total += info.Size()
+	}
+	for i := 0; i < len(names) && total+a.rotationSize > a.maxTotalSize; i++ {
+		if err := os.Remove(filepath.Join(a.directory, names[i])); err != nil {
+			return err
+		}
+		total -= sizes[i]
+	}
+	return nil
+}
+
+func (a *fileArchiver) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.closeFile()
+}
+
+// closeFile syncs and closes the current archive file. It must be called with a.mu held.
+func (a *fileArchiver) closeFile() error {
+	if a.file == nil {
+		return nil
+	}
+	err := errors.Join(a.file.Sync(), a.file.Close())
+	a.file = nil
+	return err
+}
@@ -0,0 +1,113 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+// readArchive returns the requests archived in the files of dir, oldest first.
+func readArchive(t *testing.T, dir string) (files int, reqs []*prompb.WriteRequest) {
+	return readArchiveFiles(t, filepath.Join(dir, "remote_write-*.archive"))
+}
+
+// readArchiveFiles returns the requests held by the archive files matching pattern, oldest first.
+func readArchiveFiles(t *testing.T, pattern string) (files int, reqs []*prompb.WriteRequest) {
+	paths, err := filepath.Glob(pattern)
+	require.NoError(t, err)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		r := bufio.NewReader(f)
+		for {
+			size, err := binary.ReadUvarint(r)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			compressed := make([]byte, size)
+			_, err = io.ReadFull(r, compressed)
+			require.NoError(t, err)
+			data, err := snappy.Decode(nil, compressed)
+			require.NoError(t, err)
+			req := new(prompb.WriteRequest)
+			require.NoError(t, proto.Unmarshal(data, req))
+			reqs = append(reqs, req)
+		}
+		require.NoError(t, f.Close())
+	}
+	return len(paths), reqs
+}
+
+func TestFileArchive(t *testing.T) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reqs := []*prompb.WriteRequest{makeReq(0)[0], makeReq(1)[0], makeReq(2)[0]}
+
+	for _, archiveOnly := range []bool{true, false} {
+		received.Store(0)
+		dir := t.TempDir()
+		cfg := createDefaultConfig().(*Config)
+		cfg.ClientConfig.Endpoint = server.URL
+		// Every request gets a file of its own.
+		cfg.FileArchive = &FileArchive{Directory: dir, RotationSizeBytes: 1, ArchiveOnly: archiveOnly}
+		require.NoError(t, cfg.Validate())
+		prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+		require.NoError(t, err)
+		prwe.client = server.Client()
+
+		for _, req := range reqs {
+			require.NoError(t, prwe.execute(context.Background(), req))
+		}
+		require.NoError(t, prwe.Shutdown(context.Background()))
+
+		files, archived := readArchive(t, dir)
+		assert.Equal(t, len(reqs), files)
+		assert.Equal(t, reqs, archived)
+		if archiveOnly {
+			assert.Zero(t, received.Load(), "archived requests should not be sent")
+		} else {
+			assert.Equal(t, int64(len(reqs)), received.Load(), "archived requests should be sent as well")
+		}
+	}
+}
+
+func TestFileArchiveRotation(t *testing.T) {
+	dir := t.TempDir()
+	archiver := newFileArchiver(&FileArchive{Directory: dir, RotationSizeBytes: 10})
+	// Both records, prefixed by their size, fit in the first file, the third starts a new one.
+	require.NoError(t, archiver.write([]byte("abcd")))
+	require.NoError(t, archiver.write([]byte("efgh")))
+	require.NoError(t, archiver.write([]byte("ijkl")))
+	require.NoError(t, archiver.close())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.archive"))
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	first, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x04abcd\x04efgh"), first)
+}
@@ -0,0 +1,54 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+const (
+	// heartbeatMetricName is the name of the series sent on every flush when the heartbeat is enabled.
+	heartbeatMetricName = "otelcol_remote_write_up"
+	// heartbeatSeriesKey is the key of the heartbeat in the translated series, which are keyed by their index.
+	heartbeatSeriesKey = "heartbeat"
+)
+
+// heartbeatLabels returns the sorted labels of the heartbeat series: the external labels, overridden by
+// the sanitized heartbeat labels.
+func heartbeatLabels(externalLabels, configured map[string]string) ([]prompb.Label, error) {
+	merged := make(map[string]string, len(externalLabels)+len(configured)+1)
+	for name, value := range externalLabels {
+		merged[name] = value
+	}
+	for name, value := range configured {
+		if name == "" || value == "" {
+			return nil, errors.New("prometheus remote write: heartbeat labels configuration contains an empty key or value")
+		}
+		merged[prometheustranslator.NormalizeLabel(name)] = value
+	}
+	merged[labels.MetricName] = heartbeatMetricName
+
+	lbls := make([]prompb.Label, 0, len(merged))
+	for name, value := range merged {
+		lbls = append(lbls, prompb.Label{Name: name, Value: value})
+	}
+	sort.Sort(prometheusremotewrite.ByLabelName(lbls))
+	return lbls, nil
+}
+
+// heartbeatSeries returns the heartbeat series with a sample of value 1 at the current time.
+func (prwe *prwExporter) heartbeatSeries() *prompb.TimeSeries {
+	return &prompb.TimeSeries{
+		Labels:  prwe.heartbeatLabels,
+		Samples: []prompb.Sample{{Value: 1, Timestamp: time.Now().UnixMilli()}},
+	}
+}
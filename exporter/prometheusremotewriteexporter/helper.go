@@ -7,8 +7,16 @@ import (
 	"errors"
 	"math"
 	"sort"
+	"sync"
+	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/prometheus/prometheus/prompb"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 )
 
 type batchTimeSeriesState struct {
@@ -27,8 +35,12 @@ func newBatchTimeServicesState() *batchTimeSeriesState {
 	}
 }
 
-// batchTimeSeries splits series into multiple batch write requests.
-func batchTimeSeries(tsMap map[string]*prompb.TimeSeries, maxBatchByteSize int, m []*prompb.MetricMetadata, state *batchTimeSeriesState) ([]*prompb.WriteRequest, error) {
+// batchTimeSeries splits series into multiple batch write requests. A request is closed as soon as adding the next
+// series would reach maxBatchByteSize, or exceed maxSamplesPerRequest or maxSeriesPerRequest. A zero sample or series
+// limit disables it. Series are never split, so a single series with more samples than the limit is sent on its own.
+func batchTimeSeries(tsMap map[string]*prompb.TimeSeries, maxBatchByteSize, maxSamplesPerRequest, maxSeriesPerRequest int,
+	m []*prompb.MetricMetadata, state *batchTimeSeriesState,
+) ([]*prompb.WriteRequest, error) {
 	if len(tsMap) == 0 {
 		return nil, errors.New("invalid tsMap: cannot be empty map")
 	}
@@ -39,22 +51,28 @@ func batchTimeSeries(tsMap map[string]*prompb.TimeSeries, maxBatchByteSize int,
 	// Allocate a time series buffer 2x the last time series batch size or the length of the input if smaller
 	tsArray := make([]prompb.TimeSeries, 0, min(state.nextTimeSeriesBufferSize, len(tsMap)))
 	sizeOfCurrentBatch := 0
+	samplesInCurrentBatch := 0
 
 	i := 0
 	for _, v := range tsMap {
 		sizeOfSeries := v.Size()
+		samplesInSeries := len(v.Samples) + len(v.Histograms)
 
-		if sizeOfCurrentBatch+sizeOfSeries >= maxBatchByteSize {
+		if sizeOfCurrentBatch+sizeOfSeries >= maxBatchByteSize ||
+			(len(tsArray) > 0 && maxSamplesPerRequest > 0 && samplesInCurrentBatch+samplesInSeries > maxSamplesPerRequest) ||
+			(maxSeriesPerRequest > 0 && len(tsArray) >= maxSeriesPerRequest) {
 			state.nextTimeSeriesBufferSize = max(10, 2*len(tsArray))
 			wrapped := convertTimeseriesToRequest(tsArray)
 			requests = append(requests, wrapped)
 
 			tsArray = make([]prompb.TimeSeries, 0, min(state.nextTimeSeriesBufferSize, len(tsMap)-i))
 			sizeOfCurrentBatch = 0
+			samplesInCurrentBatch = 0
 		}
 
 		tsArray = append(tsArray, *v)
 		sizeOfCurrentBatch += sizeOfSeries
+		samplesInCurrentBatch += samplesInSeries
 		i++
 	}
 
@@ -120,3 +138,326 @@ func orderBySampleTimestamp(tsArray []prompb.TimeSeries) []prompb.TimeSeries {
 	}
 	return tsArray
 }
+
+// sampleKind identifies the OTel metric type and temporality translated samples originate from.
+type sampleKind struct {
+	metricType  string
+	temporality string
+}
+
+// seriesKinds returns the kind of the series of tsMap, by key, from the type and temporality of the metric of md
+// they were translated from, matched by name. The series that weren't translated from a metric of md, like
+// target_info, have none.
+func seriesKinds(md pmetric.Metrics, tsMap map[string]*prompb.TimeSeries, settings prometheusremotewrite.Settings) map[string]sampleKind {
+	byName := make(map[string]sampleKind)
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				name := prometheustranslator.BuildCompliantNameWithUnitSuffixes(metric, settings.Namespace,
+					settings.AddMetricSuffixes, settings.UnitSuffixes)
+				temporality := pmetric.AggregationTemporalityUnspecified
+				//exhaustive:enforce
+				switch metric.Type() {
+				case pmetric.MetricTypeSum:
+					temporality = metric.Sum().AggregationTemporality()
+				case pmetric.MetricTypeHistogram:
+					temporality = metric.Histogram().AggregationTemporality()
+				case pmetric.MetricTypeExponentialHistogram:
+					temporality = metric.ExponentialHistogram().AggregationTemporality()
+				case pmetric.MetricTypeSummary, pmetric.MetricTypeGauge, pmetric.MetricTypeEmpty:
+				}
+				kind := sampleKind{metricType: metricTypeName(metric.Type()), temporality: temporalityName(temporality)}
+				for _, suffix := range seriesNameSuffixes(metric.Type(), true) {
+					if _, found := byName[name+suffix]; !found {
+						byName[name+suffix] = kind
+					}
+				}
+			}
+		}
+	}
+
+	kinds := make(map[string]sampleKind, len(tsMap))
+	for key, ts := range tsMap {
+		if kind, found := byName[seriesMetricName(ts)]; found {
+			kinds[key] = kind
+		}
+	}
+	return kinds
+}
+
+// seriesNameSuffixes returns the suffixes that the translation adds to the name of a metric of the type for its
+// series, the empty one included. The _created one is only returned if created is true.
+func seriesNameSuffixes(metricType pmetric.MetricType, created bool) []string {
+	suffixes := []string{""}
+	//exhaustive:enforce
+	switch metricType {
+	case pmetric.MetricTypeHistogram:
+		suffixes = append(suffixes, "_bucket", "_count", "_sum")
+	case pmetric.MetricTypeSummary:
+		suffixes = append(suffixes, "_count", "_sum")
+	case pmetric.MetricTypeSum:
+	case pmetric.MetricTypeGauge, pmetric.MetricTypeExponentialHistogram, pmetric.MetricTypeEmpty:
+		return suffixes
+	}
+	if created {
+		suffixes = append(suffixes, "_created")
+	}
+	return suffixes
+}
+
+// countSamples returns the number of samples, native histograms included, of the series of tsMap, grouped by
+// their kind. The series without a kind aren't counted.
+func countSamples(tsMap map[string]*prompb.TimeSeries, kinds map[string]sampleKind) map[sampleKind]int {
+	counts := make(map[sampleKind]int)
+	for key, ts := range tsMap {
+		if kind, found := kinds[key]; found {
+			counts[kind] += len(ts.Samples) + len(ts.Histograms)
+		}
+	}
+	return counts
+}
+
+func metricTypeName(metricType pmetric.MetricType) string {
+	//exhaustive:enforce
+	switch metricType {
+	case pmetric.MetricTypeGauge:
+		return "gauge"
+	case pmetric.MetricTypeSum:
+		return "sum"
+	case pmetric.MetricTypeHistogram:
+		return "histogram"
+	case pmetric.MetricTypeExponentialHistogram:
+		return "exponential_histogram"
+	case pmetric.MetricTypeSummary:
+		return "summary"
+	case pmetric.MetricTypeEmpty:
+	}
+	return "empty"
+}
+
+func temporalityName(temporality pmetric.AggregationTemporality) string {
+	switch temporality {
+	case pmetric.AggregationTemporalityCumulative:
+		return "cumulative"
+	case pmetric.AggregationTemporalityDelta:
+		return "delta"
+	}
+	return "unspecified"
+}
+
+// zeroCounterFilter drops the data points of cumulative monotonic sums that have been zero since
+// the exporter started. Once a series reports a nonzero value it is remembered, so a counter that
+// resets to zero afterwards keeps being exported. The least recently seen nonzero series are forgotten
+// once more than maxSeries are remembered.
+type zeroCounterFilter struct {
+	mu      sync.Mutex
+	nonzero *seriesLRU[struct{}]
+}
+
+func newZeroCounterFilter(maxSeries int) *zeroCounterFilter {
+	return &zeroCounterFilter{nonzero: newSeriesLRU[struct{}](maxSeries)}
+}
+
+// filter removes the always-zero counter data points from md in place, along with the metrics
+// left without data points.
+func (f *zeroCounterFilter) filter(md pmetric.Metrics) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		resourceMetrics := resourceMetricsSlice.At(i)
+		scopeMetricsSlice := resourceMetrics.ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			scopeMetrics := scopeMetricsSlice.At(j)
+			scopeMetrics.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				if metric.Type() != pmetric.MetricTypeSum || !metric.Sum().IsMonotonic() ||
+					metric.Sum().AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+					return false
+				}
+				dataPoints := metric.Sum().DataPoints()
+				if dataPoints.Len() == 0 {
+					return false
+				}
+				dataPoints.RemoveIf(func(pt pmetric.NumberDataPoint) bool {
+					if pt.Flags().NoRecordedValue() {
+						return false
+					}
+					key := seriesHash(resourceMetrics.Resource(), scopeMetrics.Scope(), metric.Name(), pt.Attributes())
+					if _, ok := f.nonzero.get(key); ok {
+						return false
+					}
+					if numberDataPointValue(pt) != 0 {
+						f.nonzero.add(key)
+						return false
+					}
+					return true
+				})
+				return dataPoints.Len() == 0
+			})
+		}
+	}
+}
+
+func (f *zeroCounterFilter) takeStats() seriesCacheStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nonzero.takeStats()
+}
+
+func numberDataPointValue(pt pmetric.NumberDataPoint) float64 {
+	if pt.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return float64(pt.IntValue())
+	}
+	return pt.DoubleValue()
+}
+
+// seriesHash identifies a data point series by its metric name, resource, scope and attributes.
+func seriesHash(resource pcommon.Resource, scope pcommon.InstrumentationScope, name string, attributes pcommon.Map) uint64 {
+	h := xxhash.New()
+	writeSeriesHashPart(h, name)
+	writeSeriesHashPart(h, scope.Name())
+	writeSeriesHashPart(h, scope.Version())
+	writeSeriesHashAttributes(h, resource.Attributes())
+	writeSeriesHashAttributes(h, attributes)
+	return h.Sum64()
+}
+
+func writeSeriesHashAttributes(h *xxhash.Digest, attributes pcommon.Map) {
+	keys := make([]string, 0, attributes.Len())
+	attributes.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, _ := attributes.Get(k)
+		writeSeriesHashPart(h, k)
+		writeSeriesHashPart(h, v.AsString())
+	}
+	// Separates the attribute sets so that keys can't shift from one set to the other.
+	_, _ = h.Write([]byte{0xff})
+}
+
+func writeSeriesHashPart(h *xxhash.Digest, s string) {
+	_, _ = h.WriteString(s)
+	_, _ = h.Write([]byte{0})
+}
+
+// seriesRateLimiter drops the samples of a series that exceed a maximum number of samples within any
+// sliding window of a given interval, keeping the most recent ones. Windows are based on the sample
+// timestamps. The accepted timestamps of the least recently seen series are forgotten once the number
+// of tracked series exceeds maxSeries.
+type seriesRateLimiter struct {
+	mu         sync.Mutex
+	maxSamples int
+	interval   int64 // in milliseconds, like sample timestamps.
+	series     *seriesLRU[seriesRateLimitEntry]
+}
+
+type seriesRateLimitEntry struct {
+	// accepted holds the sorted timestamps of the samples accepted within the last interval.
+	accepted []int64
+}
+
+func newSeriesRateLimiter(maxSamples int, interval time.Duration, maxSeries int) *seriesRateLimiter {
+	return &seriesRateLimiter{
+		maxSamples: maxSamples,
+		interval:   interval.Milliseconds(),
+		series:     newSeriesLRU[seriesRateLimitEntry](maxSeries),
+	}
+}
+
+// limit drops the samples exceeding the rate limit from the series of tsMap in place, removing the
+// series left without samples or histograms, and returns the number of dropped samples.
+func (l *seriesRateLimiter) limit(tsMap map[string]*prompb.TimeSeries) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := 0
+	for key, ts := range tsMap {
+		if len(ts.Samples) == 0 {
+			continue
+		}
+		entry, _ := l.series.getOrAdd(labelsHash(ts.Labels))
+
+		sort.Slice(ts.Samples, func(i, j int) bool {
+			return ts.Samples[i].Timestamp < ts.Samples[j].Timestamp
+		})
+		// Go through the samples from the most recent one so that those are the ones kept.
+		kept := make([]bool, len(ts.Samples))
+		numKept := 0
+		for i := len(ts.Samples) - 1; i >= 0; i-- {
+			if t := ts.Samples[i].Timestamp; l.allow(entry.accepted, t) {
+				entry.accepted = insertSorted(entry.accepted, t)
+				kept[i] = true
+				numKept++
+			}
+		}
+		dropped += len(ts.Samples) - numKept
+
+		samples := ts.Samples[:0]
+		for i, sample := range ts.Samples {
+			if kept[i] {
+				samples = append(samples, sample)
+			}
+		}
+		ts.Samples = samples
+		if len(ts.Samples) == 0 && len(ts.Histograms) == 0 {
+			delete(tsMap, key)
+		}
+
+		// Forget the timestamps that can't be part of the same window as future samples anymore.
+		newest := entry.accepted[len(entry.accepted)-1]
+		first := sort.Search(len(entry.accepted), func(i int) bool { return entry.accepted[i] > newest-l.interval })
+		entry.accepted = append(entry.accepted[:0], entry.accepted[first:]...)
+	}
+	return dropped
+}
+
+func (l *seriesRateLimiter) takeStats() seriesCacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.series.takeStats()
+}
+
+// allow reports whether a sample at timestamp t can be accepted without any window holding more than
+// maxSamples of the accepted ones. Only the windows ending at t or at a later accepted sample can hold t.
+func (l *seriesRateLimiter) allow(accepted []int64, t int64) bool {
+	countInWindow := func(end int64) int {
+		from := sort.Search(len(accepted), func(i int) bool { return accepted[i] > end-l.interval })
+		to := sort.Search(len(accepted), func(i int) bool { return accepted[i] > end })
+		return to - from
+	}
+	if countInWindow(t) >= l.maxSamples {
+		return false
+	}
+	for i := sort.Search(len(accepted), func(i int) bool { return accepted[i] > t }); i < len(accepted) && accepted[i] < t+l.interval; i++ {
+		if countInWindow(accepted[i]) >= l.maxSamples {
+			return false
+		}
+	}
+	return true
+}
+
+func insertSorted(timestamps []int64, t int64) []int64 {
+	i := sort.Search(len(timestamps), func(i int) bool { return timestamps[i] >= t })
+	timestamps = append(timestamps, 0)
+	copy(timestamps[i+1:], timestamps[i:])
+	timestamps[i] = t
+	return timestamps
+}
+
+// labelsHash identifies a translated series by its labels, which the translator sorts by name.
+func labelsHash(labels []prompb.Label) uint64 {
+	h := xxhash.New()
+	for _, l := range labels {
+		writeSeriesHashPart(h, l.Name)
+		writeSeriesHashPart(h, l.Value)
+	}
+	return h.Sum64()
+}
@@ -5,10 +5,16 @@ package prometheusremotewriteexporter
 
 import (
 	"math"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 )
 
 // Test_batchTimeSeries checks batchTimeSeries return the correct number of requests
@@ -59,7 +65,7 @@ func Test_batchTimeSeries(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			state := newBatchTimeServicesState()
-			requests, err := batchTimeSeries(tt.tsMap, tt.maxBatchByteSize, nil, state)
+			requests, err := batchTimeSeries(tt.tsMap, tt.maxBatchByteSize, 0, 0, nil, state)
 			if tt.returnErr {
 				assert.Error(t, err)
 				return
@@ -97,7 +103,7 @@ func Test_batchTimeSeriesUpdatesStateForLargeBatches(t *testing.T) {
 	tsMap1 := getTimeseriesMap(tsArray)
 
 	state := newBatchTimeServicesState()
-	requests, err := batchTimeSeries(tsMap1, 1000000, nil, state)
+	requests, err := batchTimeSeries(tsMap1, 1000000, 0, 0, nil, state)
 
 	assert.NoError(t, err)
 	assert.Len(t, requests, 18)
@@ -106,6 +112,202 @@ func Test_batchTimeSeriesUpdatesStateForLargeBatches(t *testing.T) {
 	assert.Equal(t, 36, state.nextRequestBufferSize)
 }
 
+// Test_batchTimeSeriesRequestLimits checks that batches are split on the sample and series limits
+// even when they fit within the byte limit.
+func Test_batchTimeSeriesRequestLimits(t *testing.T) {
+	sample1 := getSample(floatVal1, msTime1)
+	sample2 := getSample(floatVal2, msTime2)
+	sample3 := getSample(floatVal3, msTime3)
+
+	tsArray := make([]*prompb.TimeSeries, 0, 10)
+	for i := 0; i < 10; i++ {
+		tsArray = append(tsArray, getTimeSeries(getPromLabels(label11, strconv.Itoa(i)), sample1, sample2, sample3))
+	}
+	tsMap := getTimeseriesMap(tsArray)
+
+	tests := []struct {
+		name                 string
+		maxSamplesPerRequest int
+		maxSeriesPerRequest  int
+		numExpectedRequests  int
+	}{
+		{
+			name:                "no_limits",
+			numExpectedRequests: 1,
+		},
+		{
+			// 30 samples, at most 2 series of 3 samples fit in 7 samples.
+			name:                 "sample_limit",
+			maxSamplesPerRequest: 7,
+			numExpectedRequests:  5,
+		},
+		{
+			name:                "series_limit",
+			maxSeriesPerRequest: 3,
+			numExpectedRequests: 4,
+		},
+		{
+			name:                 "series_limit_triggers_first",
+			maxSamplesPerRequest: 9,
+			maxSeriesPerRequest:  2,
+			numExpectedRequests:  5,
+		},
+		{
+			name:                 "sample_limit_below_series_size",
+			maxSamplesPerRequest: 2,
+			numExpectedRequests:  10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := batchTimeSeries(tsMap, 3000000, tt.maxSamplesPerRequest, tt.maxSeriesPerRequest, nil, newBatchTimeServicesState())
+			require.NoError(t, err)
+			require.Len(t, requests, tt.numExpectedRequests)
+
+			numSeries := 0
+			for _, req := range requests {
+				samples := 0
+				for _, ts := range req.Timeseries {
+					samples += len(ts.Samples)
+				}
+				if tt.maxSamplesPerRequest > 0 && len(req.Timeseries) > 1 {
+					assert.LessOrEqual(t, samples, tt.maxSamplesPerRequest)
+				}
+				if tt.maxSeriesPerRequest > 0 {
+					assert.LessOrEqual(t, len(req.Timeseries), tt.maxSeriesPerRequest)
+				}
+				numSeries += len(req.Timeseries)
+			}
+			assert.Equal(t, len(tsArray), numSeries)
+		})
+	}
+}
+
+// Test_countSamples checks that countSamples groups the samples of the series translated from a mixed batch by
+// metric type and temporality, and skips the series that were dropped after the translation.
+func Test_countSamples(t *testing.T) {
+	deltaSum := getIntSumMetric("delta_sum", lbs1, intVal1, time1)
+	deltaSum.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+
+	md := getMetricsFromMetricList(
+		validMetrics1[validIntGauge],
+		validMetrics1[validDoubleGauge],
+		validMetrics1[validIntSum],
+		validMetrics2[validSum],
+		validMetrics1[validHistogram],
+		validMetrics2[validHistogramNoSum],
+		validMetrics2[unmatchedBoundBucketHist],
+		validMetrics1[validSummary],
+		getExpHistogramMetric("exponential_hist", lbs1, time1, &floatVal2, uint64(intVal2), 2, []uint64{1, 1}),
+		deltaSum,
+		invalidMetrics[empty],
+		invalidMetrics[emptyGauge],
+	)
+	settings := prometheusremotewrite.Settings{}
+	// The delta sum and the empty metrics aren't translated.
+	tsMap, err := prometheusremotewrite.FromMetrics(md, settings)
+	require.Error(t, err)
+	kinds := seriesKinds(md, tsMap, settings)
+
+	assert.Equal(t, map[sampleKind]int{
+		{metricType: "gauge", temporality: "unspecified"}: 2,
+		{metricType: "sum", temporality: "cumulative"}:    2,
+		// _sum, _count, 3 buckets and +Inf; _count, 3 buckets and +Inf; _sum, _count, 2 buckets and +Inf.
+		{metricType: "histogram", temporality: "cumulative"}:             16,
+		{metricType: "summary", temporality: "unspecified"}:              5,
+		{metricType: "exponential_histogram", temporality: "cumulative"}: 1,
+	}, countSamples(tsMap, kinds))
+
+	for key, ts := range tsMap {
+		if seriesMetricName(ts) == "valid_DoubleGauge" {
+			delete(tsMap, key)
+		}
+	}
+	assert.Equal(t, 1, countSamples(tsMap, kinds)[sampleKind{metricType: "gauge", temporality: "unspecified"}])
+}
+
+func Test_seriesRateLimiter(t *testing.T) {
+	samplesAt := func(timestamps ...int64) []prompb.Sample {
+		samples := make([]prompb.Sample, 0, len(timestamps))
+		for _, ts := range timestamps {
+			samples = append(samples, prompb.Sample{Value: float64(ts), Timestamp: ts})
+		}
+		return samples
+	}
+	newSeries := func(name string, samples []prompb.Sample) *prompb.TimeSeries {
+		return &prompb.TimeSeries{Labels: getPromLabels("__name__", name), Samples: samples}
+	}
+
+	limiter := newSeriesRateLimiter(2, time.Second, 10)
+
+	// The most recent samples of a burst are kept, other series have their own budget.
+	tsMap := map[string]*prompb.TimeSeries{
+		"0": newSeries("burst", samplesAt(400, 100, 300, 200)),
+		"1": newSeries("other", samplesAt(100)),
+	}
+	assert.Equal(t, 2, limiter.limit(tsMap))
+	assert.Equal(t, samplesAt(300, 400), tsMap["0"].Samples)
+	assert.Equal(t, samplesAt(100), tsMap["1"].Samples)
+
+	// The window slides over the samples of previous batches.
+	tsMap = map[string]*prompb.TimeSeries{
+		"0": newSeries("burst", samplesAt(1200, 1350, 1450)),
+	}
+	assert.Equal(t, 1, limiter.limit(tsMap))
+	assert.Equal(t, samplesAt(1350, 1450), tsMap["0"].Samples)
+
+	// Series left without samples are removed.
+	tsMap = map[string]*prompb.TimeSeries{
+		"0": newSeries("burst", samplesAt(1400)),
+	}
+	assert.Equal(t, 1, limiter.limit(tsMap))
+	assert.Empty(t, tsMap)
+}
+
+func Test_seriesRateLimiterForgetsLeastRecentlySeenSeries(t *testing.T) {
+	limiter := newSeriesRateLimiter(1, time.Minute, 2)
+	for _, name := range []string{"a", "b", "a", "c"} {
+		limiter.limit(map[string]*prompb.TimeSeries{
+			"0": {Labels: getPromLabels("__name__", name), Samples: []prompb.Sample{{Timestamp: 1}}},
+		})
+	}
+
+	assert.Equal(t, 2, limiter.series.len())
+	assert.Contains(t, limiter.series.series, labelsHash(getPromLabels("__name__", "a")))
+	assert.Contains(t, limiter.series.series, labelsHash(getPromLabels("__name__", "c")))
+	assert.NotContains(t, limiter.series.series, labelsHash(getPromLabels("__name__", "b")))
+}
+
+func Test_zeroCounterFilterForgetsLeastRecentlySeenSeries(t *testing.T) {
+	filter := newZeroCounterFilter(1)
+	counters := func(values map[string]int64) pmetric.Metrics {
+		var metrics []pmetric.Metric
+		for name, value := range values {
+			metric := getIntSumMetric(name, getAttributes(label11, value11), value, time1)
+			metric.Sum().SetIsMonotonic(true)
+			metrics = append(metrics, metric)
+		}
+		return getMetricsFromMetricList(metrics...)
+	}
+
+	md := counters(map[string]int64{"first": 1})
+	filter.filter(md)
+	assert.Equal(t, 1, md.MetricCount())
+	md = counters(map[string]int64{"second": 1})
+	filter.filter(md)
+	assert.Equal(t, 1, md.MetricCount())
+	assert.Equal(t, seriesCacheStats{misses: 2, evictions: 1, size: 1}, filter.takeStats())
+
+	// The first counter was forgotten, so resetting it to zero drops it again, unlike the second one.
+	md = counters(map[string]int64{"first": 0})
+	filter.filter(md)
+	assert.Zero(t, md.MetricCount())
+	md = counters(map[string]int64{"second": 0})
+	filter.filter(md)
+	assert.Equal(t, 1, md.MetricCount())
+	assert.Equal(t, seriesCacheStats{hits: 1, misses: 1, size: 1}, filter.takeStats())
+}
+
 // Benchmark_batchTimeSeries checks batchTimeSeries
 // To run and gather alloc data:
 // go test -bench ^Benchmark_batchTimeSeries$ -benchmem -benchtime=100x -run=^$ -count=10 -memprofile memprofile.out
@@ -132,7 +334,7 @@ func Benchmark_batchTimeSeries(b *testing.B) {
 	state := newBatchTimeServicesState()
 	// Run batchTimeSeries 100 times with a 1mb max request size
 	for i := 0; i < b.N; i++ {
-		requests, err := batchTimeSeries(tsMap1, 1000000, nil, state)
+		requests, err := batchTimeSeries(tsMap1, 1000000, 0, 0, nil, state)
 		assert.NoError(b, err)
 		assert.Len(b, requests, 18)
 	}
@@ -0,0 +1,32 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const histogramCountSuffix = "_count"
+
+// splitHistogramCounts removes the _count series of the classic histograms from tsMap and returns them. A _count
+// series is told apart from those of the summaries, or of a metric named that way, by the type of its metric in
+// kinds, so that it is found whether the _bucket series are sent or not.
+func splitHistogramCounts(tsMap map[string]*prompb.TimeSeries, kinds map[string]sampleKind) map[string]*prompb.TimeSeries {
+	var counts map[string]*prompb.TimeSeries
+	for key, ts := range tsMap {
+		if kinds[key].metricType != metricTypeName(pmetric.MetricTypeHistogram) ||
+			!strings.HasSuffix(seriesMetricName(ts), histogramCountSuffix) {
+			continue
+		}
+		if counts == nil {
+			counts = make(map[string]*prompb.TimeSeries)
+		}
+		counts[key] = ts
+		delete(tsMap, key)
+	}
+	return counts
+}
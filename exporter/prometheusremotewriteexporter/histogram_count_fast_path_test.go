@@ -0,0 +1,168 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// histogramSends counts the samples and the requests sent by series suffix, _bucket, _count or _sum.
+type histogramSends struct {
+	mu       sync.Mutex
+	samples  map[string]int
+	requests map[string]int
+}
+
+func (h *histogramSends) sink() ExportSinkFunc {
+	return func(_ context.Context, requests []*prompb.WriteRequest) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, req := range requests {
+			suffixes := map[string]struct{}{}
+			for _, ts := range req.Timeseries {
+				name := seriesMetricName(&ts)
+				suffix := name[strings.LastIndex(name, "_"):]
+				h.samples[suffix] += len(ts.Samples)
+				suffixes[suffix] = struct{}{}
+			}
+			for suffix := range suffixes {
+				h.requests[suffix]++
+			}
+		}
+		return nil
+	}
+}
+
+func (h *histogramSends) get() (samples, requests map[string]int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples, requests = map[string]int{}, map[string]int{}
+	for suffix, n := range h.samples {
+		samples[suffix] = n
+	}
+	for suffix, n := range h.requests {
+		requests[suffix] = n
+	}
+	return samples, requests
+}
+
+func newFastPathHistogram(timestamp time.Time, count uint64) pmetric.Metrics {
+	histogram := pmetric.NewMetric()
+	histogram.SetName("latency")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	dp := histogram.Histogram().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	dp.SetCount(count)
+	dp.SetSum(float64(count))
+	dp.ExplicitBounds().FromRaw([]float64{1})
+	dp.BucketCounts().FromRaw([]uint64{count, 0})
+	return getMetricsFromMetricList(histogram)
+}
+
+func newFastPathExporter(t *testing.T, flushInterval, countFlushInterval time.Duration) (*prwExporter, *histogramSends) {
+	sends := &histogramSends{samples: map[string]int{}, requests: map[string]int{}}
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.Coalesce = &Coalesce{FlushInterval: flushInterval}
+	cfg.HistogramCountFastPath = true
+	cfg.HistogramCountFlushInterval = countFlushInterval
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sends.sink()))
+	require.NoError(t, err)
+	return prwe, sends
+}
+
+func TestHistogramCountFastPath(t *testing.T) {
+	prwe, sends := newFastPathExporter(t, time.Hour, time.Minute)
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, prwe.PushMetrics(context.Background(), newFastPathHistogram(start.Add(time.Duration(i)*time.Second), uint64(i+1))))
+	}
+	samples, _ := sends.get()
+	assert.Empty(t, samples, "the series should be held until they are flushed")
+
+	// The _count series are flushed apart from the others.
+	require.NoError(t, prwe.flushHistogramCounts(context.Background()))
+	samples, _ = sends.get()
+	assert.Equal(t, map[string]int{"_count": 3}, samples)
+
+	require.NoError(t, prwe.flushCoalesced(context.Background()))
+	samples, _ = sends.get()
+	// One _bucket series for the bound and one for +Inf.
+	assert.Equal(t, map[string]int{"_count": 3, "_bucket": 6, "_sum": 3}, samples)
+}
+
+func TestHistogramCountFastPathFlushInterval(t *testing.T) {
+	const (
+		flushInterval      = 200 * time.Millisecond
+		countFlushInterval = 20 * time.Millisecond
+	)
+	prwe, sends := newFastPathExporter(t, flushInterval, countFlushInterval)
+	prwe.flushCoalescedPeriodically(flushInterval)
+	prwe.flushHistogramCountsPeriodically(countFlushInterval)
+
+	start := time.Now()
+	for i := 0; time.Since(start) < 3*flushInterval; i++ {
+		require.NoError(t, prwe.PushMetrics(context.Background(), newFastPathHistogram(start.Add(time.Duration(i)*time.Millisecond), uint64(i+1))))
+		time.Sleep(countFlushInterval / 4)
+	}
+	require.NoError(t, prwe.Shutdown(context.Background()))
+
+	samples, requests := sends.get()
+	assert.Equal(t, samples["_count"], samples["_sum"], "every _count sample should be sent once")
+	assert.Positive(t, requests["_bucket"])
+	// The _count series are sent about ten times as often as the _bucket and _sum ones.
+	assert.GreaterOrEqual(t, requests["_count"], 3*requests["_bucket"], "requests: %v", requests)
+	assert.Equal(t, requests["_bucket"], requests["_sum"])
+	for _, suffix := range []string{"_bucket", "_sum"} {
+		assert.LessOrEqual(t, requests[suffix], 4, "the %s series should only be sent every flush interval", suffix)
+	}
+}
+
+func TestHistogramCountFastPathDropHistogramBuckets(t *testing.T) {
+	sends := &histogramSends{samples: map[string]int{}, requests: map[string]int{}}
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.Coalesce = &Coalesce{FlushInterval: time.Hour}
+	cfg.HistogramCountFastPath = true
+	cfg.HistogramCountFlushInterval = time.Minute
+	cfg.DropHistogramBuckets = true
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sends.sink()))
+	require.NoError(t, err)
+
+	// A summary _count series isn't sent on the fast path.
+	summary := pmetric.NewMetric()
+	summary.SetName("duration")
+	dp := summary.SetEmptySummary().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	dp.SetCount(1)
+	require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(summary)))
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, prwe.PushMetrics(context.Background(), newFastPathHistogram(start.Add(time.Duration(i)*time.Second), uint64(i+1))))
+	}
+
+	// The _count series are told apart without their _bucket series.
+	require.NoError(t, prwe.flushHistogramCounts(context.Background()))
+	samples, _ := sends.get()
+	assert.Equal(t, map[string]int{"_count": 3}, samples)
+
+	require.NoError(t, prwe.flushCoalesced(context.Background()))
+	samples, _ = sends.get()
+	assert.Equal(t, map[string]int{"_count": 4, "_sum": 4}, samples)
+}
@@ -24,9 +24,33 @@ func Tracer(settings component.TelemetrySettings) trace.Tracer {
 // TelemetryBuilder provides an interface for components to report telemetry
 // as defined in metadata and user config.
 type TelemetryBuilder struct {
-	meter                                             metric.Meter
-	ExporterPrometheusremotewriteFailedTranslations   metric.Int64Counter
-	ExporterPrometheusremotewriteTranslatedTimeSeries metric.Int64Counter
+	meter                                                  metric.Meter
+	ExporterPrometheusremotewriteDroppedExemplars          metric.Int64Counter
+	ExporterPrometheusremotewriteDroppedSamples            metric.Int64Counter
+	ExporterPrometheusremotewriteEmptyMetrics              metric.Int64Counter
+	ExporterPrometheusremotewriteFailedTranslations        metric.Int64Counter
+	ExporterPrometheusremotewriteLastBatchSeries           metric.Int64Gauge
+	ExporterPrometheusremotewriteLimitViolations           metric.Int64Counter
+	ExporterPrometheusremotewriteLongMetricNames           metric.Int64Counter
+	ExporterPrometheusremotewriteMetricTypeConflicts       metric.Int64Counter
+	ExporterPrometheusremotewriteNegotiatedProtocolVersion metric.Int64Gauge
+	ExporterPrometheusremotewriteNonMonotonicSeries        metric.Int64Counter
+	ExporterPrometheusremotewritePaused                    metric.Int64Gauge
+	ExporterPrometheusremotewriteQueueDepth                metric.Int64Gauge
+	ExporterPrometheusremotewriteRequests                  metric.Int64Counter
+	ExporterPrometheusremotewriteRetryBackoffSeconds       metric.Float64Gauge
+	ExporterPrometheusremotewriteSamples                   metric.Int64Counter
+	ExporterPrometheusremotewriteSeriesCacheEvictions      metric.Int64Counter
+	ExporterPrometheusremotewriteSeriesCacheLookups        metric.Int64Counter
+	ExporterPrometheusremotewriteSeriesCacheSize           metric.Int64Gauge
+	ExporterPrometheusremotewriteSeriesGapsDetected        metric.Int64Counter
+	ExporterPrometheusremotewriteTranslatedTimeSeries      metric.Int64Counter
+	ExporterPrometheusremotewriteUnsupportedMetrics        metric.Int64Counter
+	ExporterPrometheusremotewriteWalAuditFailures          metric.Int64Counter
+	ExporterPrometheusremotewriteWalDiskFullEvents         metric.Int64Counter
+	ExporterPrometheusremotewriteWalOldestEntryAgeSeconds  metric.Float64Gauge
+	ExporterPrometheusremotewriteWalTruncatedIndex         metric.Int64Gauge
+	ExporterPrometheusremotewriteWalTruncations            metric.Int64Counter
 }
 
 // TelemetryBuilderOption applies changes to default builder.
@@ -49,18 +73,162 @@ func NewTelemetryBuilder(settings component.TelemetrySettings, options ...Teleme
 	}
 	builder.meter = Meter(settings)
 	var err, errs error
+	builder.ExporterPrometheusremotewriteDroppedExemplars, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_dropped_exemplars",
+		metric.WithDescription("Number of exemplars dropped because their timestamp is outside the interval of their data point, when exemplar_timestamp_policy is drop"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteDroppedSamples, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_dropped_samples",
+		metric.WithDescription("Number of Prometheus samples dropped by the exporter before being sent, by reason"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteEmptyMetrics, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_empty_metrics",
+		metric.WithDescription("Number of metrics without data points received by the exporter, by metric name, when empty_metrics_policy is count"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ExporterPrometheusremotewriteFailedTranslations, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
 		"otelcol_exporter_prometheusremotewrite_failed_translations",
 		metric.WithDescription("Number of translation operations that failed to translate metrics from Otel to Prometheus"),
 		metric.WithUnit("1"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteLastBatchSeries, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Gauge(
+		"otelcol_exporter_prometheusremotewrite_last_batch_series",
+		metric.WithDescription("Number of time series in the last remote write request assembled from a batch of metrics"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteLimitViolations, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_limit_violations",
+		metric.WithDescription("Number of series dropped, or truncated, for violating a label limit of backend_limits, and of batches split to fit its max_series_per_request, by limit"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteLongMetricNames, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_long_metric_names",
+		metric.WithDescription("Number of metric names longer than max_metric_name_bytes that were truncated or dropped, counted once per batch they are found in"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteMetricTypeConflicts, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_metric_type_conflicts",
+		metric.WithDescription("Number of metrics translated to the same metric name as a metric of another type earlier in their batch, when metric_type_conflict_policy is set"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteNegotiatedProtocolVersion, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Gauge(
+		"otelcol_exporter_prometheusremotewrite_negotiated_protocol_version",
+		metric.WithDescription("Remote write protocol version negotiated with the endpoint when protocol fallback is enabled, 0 while it is being negotiated"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteNonMonotonicSeries, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_non_monotonic_series",
+		metric.WithDescription("Number of series whose samples or histograms aren't in strictly increasing timestamp order in the remote write requests being sent or persisted, when validate_monotonic_timestamps is set"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewritePaused, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Gauge(
+		"otelcol_exporter_prometheusremotewrite_paused",
+		metric.WithDescription("1 while exporting is paused with Pause, 0 otherwise"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteQueueDepth, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Gauge(
+		"otelcol_exporter_prometheusremotewrite_queue_depth",
+		metric.WithDescription("Number of remote write requests waiting for a consumer to send them"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteRequests, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_requests",
+		metric.WithDescription("Number of attempts to send a remote write request, by endpoint scheme, host and path, and outcome: success when the endpoint accepted it, unexpected_status when it accepted it with another status than 204 while strict_status_codes is set, failure otherwise"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteRetryBackoffSeconds, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Float64Gauge(
+		"otelcol_exporter_prometheusremotewrite_retry_backoff_seconds",
+		metric.WithDescription("Delay before the next attempt to send the last remote write request that failed and is retried, reset to 0 once a retried request is sent"),
+		metric.WithUnit("s"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteSamples, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_samples",
+		metric.WithDescription("Number of Prometheus samples translated from OTel metrics and left to send by the filters, by metric type and temporality"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteSeriesCacheEvictions, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_series_cache_evictions",
+		metric.WithDescription("Number of series evicted from the per-series caches of the exporter to stay within their maximum size, by cache"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteSeriesCacheLookups, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_series_cache_lookups",
+		metric.WithDescription("Number of lookups of series in the per-series caches of the exporter, by cache and result"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteSeriesCacheSize, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Gauge(
+		"otelcol_exporter_prometheusremotewrite_series_cache_size",
+		metric.WithDescription("Number of series held by the per-series caches of the exporter, by cache"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteSeriesGapsDetected, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_series_gaps_detected",
+		metric.WithDescription("Number of series that stopped being received for longer than series_gap_threshold times the interval between their samples, when detect_series_gaps is set"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ExporterPrometheusremotewriteTranslatedTimeSeries, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
 		"otelcol_exporter_prometheusremotewrite_translated_time_series",
 		metric.WithDescription("Number of Prometheus time series that were translated from OTel metrics"),
 		metric.WithUnit("1"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteUnsupportedMetrics, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_unsupported_metrics",
+		metric.WithDescription("Number of metrics that weren't translated because their type is unsupported, by type"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteWalAuditFailures, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_wal_audit_failures",
+		metric.WithDescription("Number of WAL entries that failed to be decoded when a sample of the entries was audited, when audit_sample_rate is set"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteWalDiskFullEvents, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_wal_disk_full_events",
+		metric.WithDescription("Number of times the WAL stopped accepting writes because its directory ran out of disk space"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteWalOldestEntryAgeSeconds, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Float64Gauge(
+		"otelcol_exporter_prometheusremotewrite_wal_oldest_entry_age_seconds",
+		metric.WithDescription("Age of the newest sample of the oldest WAL entry that hasn't been exported yet, computed when the WAL is truncated, 0 when there is none"),
+		metric.WithUnit("s"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteWalTruncatedIndex, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Gauge(
+		"otelcol_exporter_prometheusremotewrite_wal_truncated_index",
+		metric.WithDescription("Index up to which the WAL was last truncated"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ExporterPrometheusremotewriteWalTruncations, err = getLeveledMeter(builder.meter, configtelemetry.LevelBasic, settings.MetricsLevel).Int64Counter(
+		"otelcol_exporter_prometheusremotewrite_wal_truncations",
+		metric.WithDescription("Number of times the WAL was truncated from the front after its entries were exported"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
 	return &builder, errs
 }
 
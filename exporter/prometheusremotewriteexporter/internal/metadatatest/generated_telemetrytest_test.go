@@ -20,10 +20,70 @@ func TestSetupTelemetry(t *testing.T) {
 	)
 	require.NoError(t, err)
 	require.NotNil(t, tb)
+	tb.ExporterPrometheusremotewriteDroppedExemplars.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteDroppedSamples.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteEmptyMetrics.Add(context.Background(), 1)
 	tb.ExporterPrometheusremotewriteFailedTranslations.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteLastBatchSeries.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteLimitViolations.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteLongMetricNames.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteMetricTypeConflicts.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteNegotiatedProtocolVersion.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteNonMonotonicSeries.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewritePaused.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteQueueDepth.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteRequests.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteRetryBackoffSeconds.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteSamples.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteSeriesCacheEvictions.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteSeriesCacheLookups.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteSeriesCacheSize.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteSeriesGapsDetected.Add(context.Background(), 1)
 	tb.ExporterPrometheusremotewriteTranslatedTimeSeries.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteUnsupportedMetrics.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteWalAuditFailures.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteWalDiskFullEvents.Add(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteWalOldestEntryAgeSeconds.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteWalTruncatedIndex.Record(context.Background(), 1)
+	tb.ExporterPrometheusremotewriteWalTruncations.Add(context.Background(), 1)
 
 	testTel.AssertMetrics(t, []metricdata.Metrics{
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_dropped_exemplars",
+			Description: "Number of exemplars dropped because their timestamp is outside the interval of their data point, when exemplar_timestamp_policy is drop",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_dropped_samples",
+			Description: "Number of Prometheus samples dropped by the exporter before being sent, by reason",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_empty_metrics",
+			Description: "Number of metrics without data points received by the exporter, by metric name, when empty_metrics_policy is count",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
 		{
 			Name:        "otelcol_exporter_prometheusremotewrite_failed_translations",
 			Description: "Number of translation operations that failed to translate metrics from Otel to Prometheus",
@@ -36,6 +96,174 @@ func TestSetupTelemetry(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_last_batch_series",
+			Description: "Number of time series in the last remote write request assembled from a batch of metrics",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_limit_violations",
+			Description: "Number of series dropped, or truncated, for violating a label limit of backend_limits, and of batches split to fit its max_series_per_request, by limit",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_long_metric_names",
+			Description: "Number of metric names longer than max_metric_name_bytes that were truncated or dropped, counted once per batch they are found in",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_metric_type_conflicts",
+			Description: "Number of metrics translated to the same metric name as a metric of another type earlier in their batch, when metric_type_conflict_policy is set",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_negotiated_protocol_version",
+			Description: "Remote write protocol version negotiated with the endpoint when protocol fallback is enabled, 0 while it is being negotiated",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_non_monotonic_series",
+			Description: "Number of series whose samples or histograms aren't in strictly increasing timestamp order in the remote write requests being sent or persisted, when validate_monotonic_timestamps is set",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_paused",
+			Description: "1 while exporting is paused with Pause, 0 otherwise",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_queue_depth",
+			Description: "Number of remote write requests waiting for a consumer to send them",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_requests",
+			Description: "Number of attempts to send a remote write request, by endpoint scheme, host and path, and outcome: success when the endpoint accepted it, unexpected_status when it accepted it with another status than 204 while strict_status_codes is set, failure otherwise",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_retry_backoff_seconds",
+			Description: "Delay before the next attempt to send the last remote write request that failed and is retried, reset to 0 once a retried request is sent",
+			Unit:        "s",
+			Data: metricdata.Gauge[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_samples",
+			Description: "Number of Prometheus samples translated from OTel metrics and left to send by the filters, by metric type and temporality",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_series_cache_evictions",
+			Description: "Number of series evicted from the per-series caches of the exporter to stay within their maximum size, by cache",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_series_cache_lookups",
+			Description: "Number of lookups of series in the per-series caches of the exporter, by cache and result",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_series_cache_size",
+			Description: "Number of series held by the per-series caches of the exporter, by cache",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_series_gaps_detected",
+			Description: "Number of series that stopped being received for longer than series_gap_threshold times the interval between their samples, when detect_series_gaps is set",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
 		{
 			Name:        "otelcol_exporter_prometheusremotewrite_translated_time_series",
 			Description: "Number of Prometheus time series that were translated from OTel metrics",
@@ -48,6 +276,74 @@ func TestSetupTelemetry(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_unsupported_metrics",
+			Description: "Number of metrics that weren't translated because their type is unsupported, by type",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_wal_audit_failures",
+			Description: "Number of WAL entries that failed to be decoded when a sample of the entries was audited, when audit_sample_rate is set",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_wal_disk_full_events",
+			Description: "Number of times the WAL stopped accepting writes because its directory ran out of disk space",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_wal_oldest_entry_age_seconds",
+			Description: "Age of the newest sample of the oldest WAL entry that hasn't been exported yet, computed when the WAL is truncated, 0 when there is none",
+			Unit:        "s",
+			Data: metricdata.Gauge[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_wal_truncated_index",
+			Description: "Index up to which the WAL was last truncated",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_wal_truncations",
+			Description: "Number of times the WAL was truncated from the front after its entries were exported",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{},
+				},
+			},
+		},
 	}, metricdatatest.IgnoreTimestamp(), metricdatatest.IgnoreValue())
 	require.NoError(t, testTel.Shutdown(context.Background()))
 }
@@ -0,0 +1,13 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+// LastError returns the error of the last remote write request that failed to be sent, after its retries, or nil
+// if the last request was sent or none was sent yet. It lets embedders report the health of the exporter.
+func (prwe *prwExporter) LastError() error {
+	if err := prwe.lastError.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
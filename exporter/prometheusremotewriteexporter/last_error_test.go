@@ -0,0 +1,52 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestLastError(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.RemoteWriteQueue.Enabled = false
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+	assert.NoError(t, prwe.LastError(), "nothing has been sent yet")
+
+	push := func() error {
+		metric := pmetric.NewMetric()
+		metric.SetName("test_metric")
+		metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+		return prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric))
+	}
+
+	failing.Store(true)
+	require.Error(t, push())
+	assert.ErrorContains(t, prwe.LastError(), "400 Bad Request")
+
+	failing.Store(false)
+	require.NoError(t, push())
+	assert.NoError(t, prwe.LastError())
+}
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+// lastSentTracker remembers the timestamp of the most recent sample successfully sent for the recently
+// sent series. The least recently sent series are forgotten once more than maxSeries are tracked.
+type lastSentTracker struct {
+	mu sync.Mutex
+	// series holds the timestamps, in milliseconds like sample timestamps.
+	series *seriesLRU[int64]
+}
+
+func newLastSentTracker(maxSeries int) *lastSentTracker {
+	return &lastSentTracker{series: newSeriesLRU[int64](maxSeries)}
+}
+
+// record tracks the most recent sample, or histogram, of every series of the sent request.
+func (l *lastSentTracker) record(req *prompb.WriteRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, ts := range req.Timeseries {
+		newest, ok := newestTimestamp(ts)
+		if !ok {
+			continue
+		}
+		timestamp, found := l.series.getOrAdd(labelsHash(ts.Labels))
+		if !found || newest > *timestamp {
+			*timestamp = newest
+		}
+	}
+}
+
+func (l *lastSentTracker) takeStats() seriesCacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.series.takeStats()
+}
+
+// lastSent returns the timestamp of the most recent sample sent for the series identified by its sorted labels.
+func (l *lastSentTracker) lastSent(labels []prompb.Label) (int64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	timestamp, ok := l.series.peek(labelsHash(labels))
+	if !ok {
+		return 0, false
+	}
+	return *timestamp, true
+}
+
+func newestTimestamp(ts prompb.TimeSeries) (int64, bool) {
+	var newest int64
+	found := false
+	for _, sample := range ts.Samples {
+		if !found || sample.Timestamp > newest {
+			newest, found = sample.Timestamp, true
+		}
+	}
+	for _, histogram := range ts.Histograms {
+		if !found || histogram.Timestamp > newest {
+			newest, found = histogram.Timestamp, true
+		}
+	}
+	return newest, found
+}
+
+// LastSentTimestamp returns the timestamp of the most recent sample successfully sent for the series with
+// the given labels, including __name__, as they are sent. It is meant for debugging stale series: only the
+// recently sent series are remembered, and nothing is remembered unless track_last_sent is enabled.
+func (prwe *prwExporter) LastSentTimestamp(labels map[string]string) (time.Time, bool) {
+	if prwe.lastSentTracker == nil {
+		return time.Time{}, false
+	}
+	lbls := make([]prompb.Label, 0, len(labels))
+	for name, value := range labels {
+		lbls = append(lbls, prompb.Label{Name: name, Value: value})
+	}
+	sort.Sort(prometheusremotewrite.ByLabelName(lbls))
+	timestamp, ok := prwe.lastSentTracker.lastSent(lbls)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(timestamp), true
+}
@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func TestLastSentTimestamp(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.TrackLastSent = true
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	series := map[string]string{"__name__": "test_metric", "job": "test"}
+	_, ok := prwe.LastSentTimestamp(series)
+	assert.False(t, ok, "nothing has been sent yet")
+
+	req := func(timestamps ...int64) *prompb.WriteRequest {
+		ts := prompb.TimeSeries{Labels: []prompb.Label{{Name: "__name__", Value: "test_metric"}, {Name: "job", Value: "test"}}}
+		for _, timestamp := range timestamps {
+			ts.Samples = append(ts.Samples, prompb.Sample{Value: 1, Timestamp: timestamp})
+		}
+		return &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{ts}}
+	}
+	require.NoError(t, prwe.execute(context.Background(), req(1000, 3000, 2000)))
+	lastSent, ok := prwe.LastSentTimestamp(series)
+	require.True(t, ok)
+	assert.Equal(t, time.UnixMilli(3000), lastSent)
+
+	// Samples that failed to be sent aren't tracked.
+	failing = true
+	require.Error(t, prwe.execute(context.Background(), req(4000)))
+	lastSent, ok = prwe.LastSentTimestamp(series)
+	require.True(t, ok)
+	assert.Equal(t, time.UnixMilli(3000), lastSent)
+
+	_, ok = prwe.LastSentTimestamp(map[string]string{"__name__": "test_metric"})
+	assert.False(t, ok, "the labels should identify the series exactly")
+}
+
+func TestLastSentTrackerForgetsLeastRecentlySentSeries(t *testing.T) {
+	tracker := newLastSentTracker(2)
+	series := func(name string) prompb.TimeSeries {
+		return prompb.TimeSeries{
+			Labels:  []prompb.Label{{Name: "__name__", Value: name}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		}
+	}
+	tracker.record(&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{series("a"), series("b")}})
+	tracker.record(&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{series("a"), series("c")}})
+
+	_, ok := tracker.lastSent(series("a").Labels)
+	assert.True(t, ok)
+	_, ok = tracker.lastSent(series("b").Labels)
+	assert.False(t, ok, "the least recently sent series should be forgotten")
+	_, ok = tracker.lastSent(series("c").Labels)
+	assert.True(t, ok)
+}
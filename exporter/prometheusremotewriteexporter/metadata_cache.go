@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// metadataCache remembers the metadata last sent along with the recently sent remote write 2.0 series, so that
+// their help and unit are only sent again when their metadata changes or resendInterval elapsed. The least
+// recently sent series are forgotten once more than maxSeries are tracked.
+type metadataCache struct {
+	mu             sync.Mutex
+	resendInterval time.Duration
+	series         *seriesLRU[metadataCacheEntry]
+	now            func() time.Time
+}
+
+type metadataCacheEntry struct {
+	// metadata is the hash of the metadata sent in full for the series, at sentAt.
+	metadata uint64
+	sentAt   time.Time
+}
+
+func newMetadataCache(resendInterval time.Duration, maxSeries int) *metadataCache {
+	return &metadataCache{
+		resendInterval: resendInterval,
+		series:         newSeriesLRU[metadataCacheEntry](maxSeries),
+		now:            time.Now,
+	}
+}
+
+// sendFull reports whether the full metadata md has to be sent along with the series identified by key, and
+// remembers it as sent if so. A nil cache always sends the full metadata.
+func (c *metadataCache) sendFull(key uint64, md prompb.MetricMetadata) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	hash := metadataHash(md)
+	entry, found := c.series.getOrAdd(key)
+	if found && entry.metadata == hash && now.Sub(entry.sentAt) < c.resendInterval {
+		return false
+	}
+	entry.metadata, entry.sentAt = hash, now
+	return true
+}
+
+// forget forgets the metadata sent along with the series identified by keys, which failed to be sent, so
+// that it is sent in full again.
+func (c *metadataCache) forget(keys []uint64) {
+	if c == nil || len(keys) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		c.series.remove(key)
+	}
+}
+
+func (c *metadataCache) takeStats() seriesCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.series.takeStats()
+}
+
+func metadataHash(md prompb.MetricMetadata) uint64 {
+	h := xxhash.New()
+	writeSeriesHashPart(h, strconv.Itoa(int(md.Type)))
+	writeSeriesHashPart(h, md.Help)
+	writeSeriesHashPart(h, md.Unit)
+	return h.Sum64()
+}
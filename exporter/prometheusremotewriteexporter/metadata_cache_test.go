@@ -0,0 +1,141 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func newMetadataTestRequest(help string) *prompb.WriteRequest {
+	return &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: "__name__", Value: "test_counter_total"}, {Name: "job", Value: "test"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 10}},
+			},
+		},
+		Metadata: []prompb.MetricMetadata{
+			{MetricFamilyName: "test_counter_total", Type: prompb.MetricMetadata_COUNTER, Help: help, Unit: "seconds"},
+		},
+	}
+}
+
+// v2Metadata returns the type, help and unit sent with the only series of req.
+func v2Metadata(t *testing.T, req *writev2.Request) (writev2.Metadata_MetricType, string, string) {
+	require.Len(t, req.Timeseries, 1)
+	md := req.Timeseries[0].Metadata
+	return md.Type, req.Symbols[md.HelpRef], req.Symbols[md.UnitRef]
+}
+
+func TestToWriteV2RequestMetadataCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := newMetadataCache(time.Minute, 10)
+	cache.now = func() time.Time { return now }
+
+	v2, sent := toWriteV2Request(newMetadataTestRequest("A counter"), cache)
+	mdType, help, unit := v2Metadata(t, v2)
+	assert.Equal(t, writev2.Metadata_METRIC_TYPE_COUNTER, mdType)
+	assert.Equal(t, "A counter", help)
+	assert.Equal(t, "seconds", unit)
+	assert.Len(t, sent, 1)
+
+	// The unchanged metadata is only sent with its type, and its help and unit aren't symbolized.
+	v2, sent = toWriteV2Request(newMetadataTestRequest("A counter"), cache)
+	mdType, help, unit = v2Metadata(t, v2)
+	assert.Equal(t, writev2.Metadata_METRIC_TYPE_COUNTER, mdType)
+	assert.Empty(t, help)
+	assert.Empty(t, unit)
+	assert.NotContains(t, v2.Symbols, "A counter")
+	assert.Empty(t, sent)
+
+	// Changed metadata is sent in full right away.
+	v2, _ = toWriteV2Request(newMetadataTestRequest("A renamed counter"), cache)
+	_, help, _ = v2Metadata(t, v2)
+	assert.Equal(t, "A renamed counter", help)
+
+	// Unchanged metadata is sent in full again once the resend interval elapsed.
+	now = now.Add(30 * time.Second)
+	v2, _ = toWriteV2Request(newMetadataTestRequest("A renamed counter"), cache)
+	_, help, _ = v2Metadata(t, v2)
+	assert.Empty(t, help)
+	now = now.Add(30 * time.Second)
+	v2, sent = toWriteV2Request(newMetadataTestRequest("A renamed counter"), cache)
+	_, help, _ = v2Metadata(t, v2)
+	assert.Equal(t, "A renamed counter", help)
+
+	// Forgotten metadata, which failed to be sent, is sent in full again.
+	cache.forget(sent)
+	v2, _ = toWriteV2Request(newMetadataTestRequest("A renamed counter"), cache)
+	_, help, _ = v2Metadata(t, v2)
+	assert.Equal(t, "A renamed counter", help)
+
+	stats := cache.takeStats()
+	assert.Equal(t, seriesCacheStats{hits: 4, misses: 2, size: 1}, stats)
+}
+
+func TestMetadataCacheEviction(t *testing.T) {
+	cache := newMetadataCache(time.Minute, 1)
+	md := prompb.MetricMetadata{Type: prompb.MetricMetadata_GAUGE, Help: "A gauge"}
+	assert.True(t, cache.sendFull(1, md))
+	assert.True(t, cache.sendFull(2, md))
+	assert.False(t, cache.sendFull(2, md))
+	// The first series was evicted.
+	assert.True(t, cache.sendFull(1, md))
+	assert.Equal(t, seriesCacheStats{hits: 1, misses: 3, evictions: 2, size: 1}, cache.takeStats())
+}
+
+func TestPushMetricsMetadataResendInterval(t *testing.T) {
+	var helps []string
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data, err := snappy.Decode(nil, compressed)
+		assert.NoError(t, err)
+		req := new(writev2.Request)
+		assert.NoError(t, proto.Unmarshal(data, req))
+		_, help, _ := v2Metadata(t, req)
+		helps = append(helps, help)
+		if failing {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.ProtocolFallback = true
+	cfg.MetadataResendInterval = time.Hour
+	cfg.BackOffConfig.Enabled = false
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	require.NoError(t, prwe.execute(context.Background(), newMetadataTestRequest("A counter")))
+	require.NoError(t, prwe.execute(context.Background(), newMetadataTestRequest("A counter")))
+	// The changed metadata fails to be sent, so it is sent in full with the next request.
+	failing = true
+	require.Error(t, prwe.execute(context.Background(), newMetadataTestRequest("A renamed counter")))
+	failing = false
+	require.NoError(t, prwe.execute(context.Background(), newMetadataTestRequest("A renamed counter")))
+	require.NoError(t, prwe.execute(context.Background(), newMetadataTestRequest("A renamed counter")))
+
+	assert.Equal(t, []string{"A counter", "", "A renamed counter", "A renamed counter", ""}, helps)
+}
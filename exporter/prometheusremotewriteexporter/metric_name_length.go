@@ -0,0 +1,154 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+const (
+	// metricNameLengthPolicyTruncate truncates the metric names that are too long, replacing their end with a
+	// hash of the whole name so that distinct names stay distinct. It is the default.
+	metricNameLengthPolicyTruncate = "truncate"
+	// metricNameLengthPolicyDrop drops the series and metadata of the metric names that are too long.
+	metricNameLengthPolicyDrop = "drop"
+)
+
+// metricNameHashBytes is the length of the "_" separated hexadecimal hash ending the truncated metric names.
+const metricNameHashBytes = 1 + 16
+
+// minTruncatedMetricNameBytes is the length that the metric names must be allowed to exceed to be truncated: that
+// of the hash and of the longest suffix of their series, _created.
+const minTruncatedMetricNameBytes = metricNameHashBytes + len("_created")
+
+// metricNameLimiter enforces a maximum length on the metric names, as they are sent, after the namespace and
+// the suffixes were added to them.
+type metricNameLimiter struct {
+	maxBytes int
+	policy   string
+}
+
+// limitedName is the name that the series or metadata of a metric family are sent with, empty when they are
+// dropped.
+type limitedName struct {
+	family string
+	name   string
+}
+
+// limitedNames returns the names of the series and metric families translated from md that are too long, with
+// the name they are sent with instead. The name of a metric family is shortened once, for its longest series
+// name to fit, and its series keep their suffix after it, so that they still match their metadata.
+func (l *metricNameLimiter) limitedNames(md pmetric.Metrics, settings prometheusremotewrite.Settings) map[string]limitedName {
+	names := map[string]limitedName{}
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				family := prometheustranslator.BuildCompliantNameWithUnitSuffixes(metric, settings.Namespace,
+					settings.AddMetricSuffixes, settings.UnitSuffixes)
+				suffixes := seriesNameSuffixes(metric.Type(), settings.ExportCreatedMetric)
+				longest := 0
+				for _, suffix := range suffixes {
+					longest = max(longest, len(suffix))
+				}
+				if len(family)+longest <= l.maxBytes {
+					continue
+				}
+				limited := ""
+				if l.policy != metricNameLengthPolicyDrop {
+					limited = l.truncateTo(family, l.maxBytes-longest)
+				}
+				for _, suffix := range suffixes {
+					if _, found := names[family+suffix]; found {
+						continue
+					}
+					name := limited
+					if name != "" {
+						name += suffix
+					}
+					names[family+suffix] = limitedName{family: family, name: name}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// limitSeries truncates or drops the series whose metric name is too long, and returns the number of distinct
+// metric families that were. The series that weren't translated from a metric, like target_info, are
+// shortened on their own.
+func (l *metricNameLimiter) limitSeries(tsMap map[string]*prompb.TimeSeries, names map[string]limitedName) int {
+	affected := map[string]struct{}{}
+	for key, ts := range tsMap {
+		for i := range ts.Labels {
+			if ts.Labels[i].Name != model.MetricNameLabel {
+				continue
+			}
+			name := ts.Labels[i].Value
+			limited, found := names[name]
+			if !found {
+				if len(name) <= l.maxBytes {
+					break
+				}
+				limited = limitedName{family: name}
+				if l.policy != metricNameLengthPolicyDrop {
+					limited.name = l.truncate(name)
+				}
+			}
+			affected[limited.family] = struct{}{}
+			if limited.name == "" {
+				delete(tsMap, key)
+			} else {
+				ts.Labels[i].Value = limited.name
+			}
+			break
+		}
+	}
+	return len(affected)
+}
+
+// limitMetadata truncates or drops the metadata of the metric families whose name is too long, like their series.
+func (l *metricNameLimiter) limitMetadata(m []*prompb.MetricMetadata, names map[string]limitedName) []*prompb.MetricMetadata {
+	kept := m[:0]
+	for _, entry := range m {
+		limited, found := names[entry.MetricFamilyName]
+		if !found && len(entry.MetricFamilyName) > l.maxBytes {
+			limited, found = limitedName{family: entry.MetricFamilyName}, true
+			if l.policy != metricNameLengthPolicyDrop {
+				limited.name = l.truncate(entry.MetricFamilyName)
+			}
+		}
+		if found {
+			if limited.name == "" {
+				continue
+			}
+			entry.MetricFamilyName = limited.name
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+// truncate shortens name to the maximum length, ending it with a hash of the whole name.
+func (l *metricNameLimiter) truncate(name string) string {
+	return l.truncateTo(name, l.maxBytes)
+}
+
+// truncateTo shortens name to maxBytes, ending it with a hash of the whole name.
+func (l *metricNameLimiter) truncateTo(name string, maxBytes int) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return fmt.Sprintf("%s_%016x", name[:maxBytes-metricNameHashBytes], h.Sum64())
+}
@@ -0,0 +1,175 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
+)
+
+func TestPushMetricsMaxMetricNameBytes(t *testing.T) {
+	const maxBytes = 64
+	longPrefix := strings.Repeat("deeply_nested_", 5)
+	gauge := func(name string) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName(name)
+		metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+		return metric
+	}
+	names := []string{longPrefix + "first", longPrefix + "second", "short"}
+
+	tests := []struct {
+		policy    string
+		wantNames int
+	}{
+		{policy: "", wantNames: 3},
+		{policy: metricNameLengthPolicyTruncate, wantNames: 3},
+		{policy: metricNameLengthPolicyDrop, wantNames: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var got []string
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						got = append(got, ts.Labels[0].Value)
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.MaxMetricNameBytes = maxBytes
+			cfg.MetricNameLengthPolicy = tt.policy
+			require.NoError(t, cfg.Validate())
+			tel := metadatatest.SetupTelemetry()
+			prwe, err := newPRWExporter(cfg, tel.NewSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			md := getMetricsFromMetricList(gauge(names[0]), gauge(names[1]), gauge(names[2]))
+			require.NoError(t, prwe.PushMetrics(context.Background(), md))
+
+			require.Len(t, got, tt.wantNames)
+			assert.Contains(t, got, "short")
+			for _, name := range got {
+				assert.LessOrEqual(t, len(name), maxBytes)
+			}
+			if tt.policy != metricNameLengthPolicyDrop {
+				// The truncated names keep their beginning, and their hashes keep them distinct.
+				truncated := []string{prwe.metricNameLimiter.truncate(names[0]), prwe.metricNameLimiter.truncate(names[1])}
+				assert.ElementsMatch(t, append(truncated, "short"), got)
+				assert.NotEqual(t, truncated[0], truncated[1])
+				for _, name := range truncated {
+					assert.Len(t, name, maxBytes)
+					assert.True(t, strings.HasPrefix(name, longPrefix[:maxBytes-metricNameHashBytes]), name)
+				}
+			}
+
+			tel.AssertMetrics(t, []metricdata.Metrics{
+				{
+					Name:        "otelcol_exporter_prometheusremotewrite_long_metric_names",
+					Description: "Number of metric names longer than max_metric_name_bytes that were truncated or dropped, counted once per batch they are found in",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.CumulativeTemporality,
+						IsMonotonic: true,
+						DataPoints: []metricdata.DataPoint[int64]{
+							{Value: 2, Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+						},
+					},
+				},
+				{
+					Name:        "otelcol_exporter_prometheusremotewrite_translated_time_series",
+					Description: "Number of Prometheus time series that were translated from OTel metrics",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.CumulativeTemporality,
+						IsMonotonic: true,
+						DataPoints: []metricdata.DataPoint[int64]{
+							{Value: 3, Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+						},
+					},
+				},
+				// The names dropped for their length are not counted.
+				expectedSamplesMetric(map[sampleKind]int{{metricType: "gauge", temporality: "unspecified"}: tt.wantNames}),
+				expectedLastBatchSeriesMetric(tt.wantNames),
+			}, metricdatatest.IgnoreTimestamp())
+			require.NoError(t, tel.Shutdown(context.Background()))
+		})
+	}
+}
+
+// TestPushMetricsMaxMetricNameBytesMetadata checks that the series and metadata of a histogram, whose name fits
+// but not the name of its _bucket series, are truncated to the same name or dropped together.
+func TestPushMetricsMaxMetricNameBytesMetadata(t *testing.T) {
+	const maxBytes = 64
+	longName := strings.Repeat("h", 60)
+	histogram := pmetric.NewMetric()
+	histogram.SetName(longName)
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	dp := histogram.Histogram().DataPoints().AppendEmpty()
+	dp.SetCount(1)
+	dp.SetSum(1)
+	dp.ExplicitBounds().FromRaw([]float64{1})
+	dp.BucketCounts().FromRaw([]uint64{1, 0})
+	gauge := pmetric.NewMetric()
+	gauge.SetName("short")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+
+	for _, policy := range []string{metricNameLengthPolicyTruncate, metricNameLengthPolicyDrop} {
+		t.Run(policy, func(t *testing.T) {
+			var seriesNames, families []string
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						seriesNames = append(seriesNames, seriesMetricName(&ts))
+					}
+					for _, m := range req.Metadata {
+						families = append(families, m.MetricFamilyName)
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.SendMetadata = true
+			cfg.MaxMetricNameBytes = maxBytes
+			cfg.MetricNameLengthPolicy = policy
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(histogram, gauge)))
+
+			if policy == metricNameLengthPolicyDrop {
+				assert.Equal(t, []string{"short"}, seriesNames)
+				assert.Equal(t, []string{"short"}, families)
+				return
+			}
+			require.Len(t, families, 2)
+			assert.Contains(t, families, "short")
+			family := families[0]
+			if family == "short" {
+				family = families[1]
+			}
+			assert.NotEqual(t, longName, family)
+			assert.ElementsMatch(t, []string{"short", family + "_bucket", family + "_bucket", family + "_count", family + "_sum"}, seriesNames)
+			for _, name := range seriesNames {
+				assert.LessOrEqual(t, len(name), maxBytes)
+			}
+		})
+	}
+}
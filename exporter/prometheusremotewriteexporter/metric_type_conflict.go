@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+const (
+	// metricTypeConflictPolicyDropLater drops the metrics whose name was already used by a metric of another
+	// type earlier in the batch.
+	metricTypeConflictPolicyDropLater = "drop_later"
+	// metricTypeConflictPolicySuffixType appends their Prometheus type to the names of those metrics.
+	metricTypeConflictPolicySuffixType = "suffix_type"
+	// metricTypeConflictPolicyError rejects the batch.
+	metricTypeConflictPolicyError = "error"
+)
+
+// metricTypeConflictResolver detects the metrics of a batch translated to the same Prometheus metric name as
+// a metric of another type, which Prometheus can't tell apart, and applies policy to them.
+type metricTypeConflictResolver struct {
+	policy   string
+	settings prometheusremotewrite.Settings
+}
+
+// resolve applies the policy to the metrics of md whose name was already used by a metric of another type,
+// the first type seen for a name winning, and returns the number of those metrics. With the "error" policy,
+// md is left unchanged and an error is returned for the first of them.
+func (r *metricTypeConflictResolver) resolve(md pmetric.Metrics) (int, error) {
+	types := map[string]string{}
+	conflicts := 0
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			var err error
+			scopeMetricsSlice.At(j).Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				if err != nil {
+					return false
+				}
+				name := prometheustranslator.BuildCompliantNameWithUnitSuffixes(metric, r.settings.Namespace,
+					r.settings.AddMetricSuffixes, r.settings.UnitSuffixes)
+				metricType := promMetricType(metric)
+				seen, found := types[name]
+				if !found {
+					types[name] = metricType
+					return false
+				}
+				if seen == metricType {
+					return false
+				}
+				conflicts++
+				switch r.policy {
+				case metricTypeConflictPolicyError:
+					err = fmt.Errorf("metric %q of type %s conflicts with a metric of type %s", name, metricType, seen)
+				case metricTypeConflictPolicySuffixType:
+					metric.SetName(metric.Name() + "_" + metricType)
+				default:
+					return true
+				}
+				return false
+			})
+			if err != nil {
+				return conflicts, err
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// promMetricType returns the type of the Prometheus metric family metric is translated to.
+func promMetricType(metric pmetric.Metric) string {
+	//exhaustive:enforce
+	switch metric.Type() {
+	case pmetric.MetricTypeSum:
+		if metric.Sum().IsMonotonic() {
+			return "counter"
+		}
+		return "gauge"
+	case pmetric.MetricTypeHistogram, pmetric.MetricTypeExponentialHistogram:
+		return "histogram"
+	case pmetric.MetricTypeSummary:
+		return "summary"
+	case pmetric.MetricTypeGauge:
+		return "gauge"
+	case pmetric.MetricTypeEmpty:
+	}
+	return "unknown"
+}
@@ -0,0 +1,124 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsMetricTypeConflictPolicy(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	newBatch := func() pmetric.Metrics {
+		gauge := pmetric.NewMetric()
+		gauge.SetName("foo")
+		gaugePt := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+		gaugePt.SetTimestamp(pcommon.NewTimestampFromTime(start))
+		gaugePt.SetDoubleValue(1)
+
+		counter := pmetric.NewMetric()
+		counter.SetName("foo")
+		sum := counter.SetEmptySum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		counterPt := sum.DataPoints().AppendEmpty()
+		counterPt.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Second)))
+		counterPt.SetDoubleValue(2)
+		return getMetricsFromMetricList(gauge, counter)
+	}
+	type sentSample struct {
+		name  string
+		value float64
+	}
+
+	tests := []struct {
+		policy  string
+		want    []sentSample
+		wantErr bool
+	}{
+		{policy: "", want: []sentSample{{"foo", 1}, {"foo", 2}}},
+		{policy: metricTypeConflictPolicyDropLater, want: []sentSample{{"foo", 1}}},
+		{policy: metricTypeConflictPolicySuffixType, want: []sentSample{{"foo", 1}, {"foo_counter", 2}}},
+		{policy: metricTypeConflictPolicyError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var got []sentSample
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						for _, sample := range ts.Samples {
+							got = append(got, sentSample{ts.Labels[0].Value, sample.Value})
+						}
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			// Without the _total suffix, the counter has the same name as the gauge.
+			cfg.AddMetricSuffixes = false
+			cfg.MetricTypeConflictPolicy = tt.policy
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			err = prwe.PushMetrics(context.Background(), newBatch())
+			if tt.wantErr {
+				require.ErrorContains(t, err, `metric "foo" of type counter conflicts with a metric of type gauge`)
+				assert.True(t, consumererror.IsPermanent(err))
+				assert.Empty(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestMetricTypeConflictResolver(t *testing.T) {
+	metric := func(name string, setType func(pmetric.Metric)) pmetric.Metric {
+		m := pmetric.NewMetric()
+		m.SetName(name)
+		setType(m)
+		return m
+	}
+	gauge := func(m pmetric.Metric) { m.SetEmptyGauge() }
+	upDownCounter := func(m pmetric.Metric) { m.SetEmptySum().SetIsMonotonic(false) }
+	histogram := func(m pmetric.Metric) { m.SetEmptyHistogram() }
+	exponentialHistogram := func(m pmetric.Metric) { m.SetEmptyExponentialHistogram() }
+	summary := func(m pmetric.Metric) { m.SetEmptySummary() }
+
+	md := getMetricsFromMetricList(
+		metric("foo", gauge),
+		// Non-monotonic sums are translated to gauges.
+		metric("foo", upDownCounter),
+		metric("foo", histogram),
+		metric("bar", histogram),
+		metric("bar", exponentialHistogram),
+		metric("bar", summary),
+		metric("foo", summary),
+	)
+	r := &metricTypeConflictResolver{policy: metricTypeConflictPolicySuffixType}
+	conflicts, err := r.resolve(md)
+	require.NoError(t, err)
+	assert.Equal(t, 3, conflicts)
+
+	var names []string
+	metrics := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		names = append(names, metrics.At(i).Name())
+	}
+	assert.Equal(t, []string{"foo", "foo", "foo_histogram", "bar", "bar", "bar_summary", "foo_summary"}, names)
+}
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"time"
+)
+
+// flushPacer spaces the requests sent by every consumer by at least an interval, so that a backend charging per
+// request, or rate limiting them, receives fewer and larger requests. The requests waiting for a consumer hold
+// the samples received meanwhile back, which are coalesced into larger requests when coalesce is set or the WAL
+// is enabled.
+type flushPacer struct {
+	interval time.Duration
+	// idle holds, for every idle consumer, the time it last started sending a request, the consumer that has
+	// been idle the longest first.
+	idle chan time.Time
+}
+
+func newFlushPacer(interval time.Duration, consumers int) *flushPacer {
+	idle := make(chan time.Time, max(consumers, 1))
+	for i := 0; i < cap(idle); i++ {
+		idle <- time.Time{}
+	}
+	return &flushPacer{interval: interval, idle: idle}
+}
+
+// acquire waits for a consumer to be idle and for interval to elapse since it last started sending a request.
+// The consumer must be released once the request is sent, unless an error is returned.
+func (p *flushPacer) acquire(ctx context.Context) error {
+	var last time.Time
+	select {
+	case last = <-p.idle:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if wait := time.Until(last.Add(p.interval)); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			p.idle <- last
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// release makes the consumer that started sending a request at started idle again.
+func (p *flushPacer) release(started time.Time) {
+	p.idle <- started
+}
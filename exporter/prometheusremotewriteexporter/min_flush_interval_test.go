@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestMinFlushInterval(t *testing.T) {
+	const minFlushInterval = 100 * time.Millisecond
+	var mu sync.Mutex
+	var posts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		posts = append(posts, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.TargetInfo.Enabled = false
+	cfg.RemoteWriteQueue.Enabled = false
+	cfg.RemoteWriteQueue.NumConsumers = 1
+	cfg.MinFlushInterval = minFlushInterval
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	// A fast stream of metrics, pushed all at once.
+	const numPushes = 5
+	var wg sync.WaitGroup
+	for i := 0; i < numPushes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			metric := pmetric.NewMetric()
+			metric.SetName("metric_" + strconv.Itoa(i))
+			metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+			assert.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric)))
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, posts, numPushes)
+	for i := 1; i < len(posts); i++ {
+		// The requests are spaced when they start being sent, allow for the time they take to be received.
+		assert.GreaterOrEqual(t, posts[i].Sub(posts[i-1]), minFlushInterval-10*time.Millisecond, "request %d", i)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+// droppedReasonOverloadSampled is the reason reported for the samples dropped while the WAL is overloaded.
+const droppedReasonOverloadSampled = "overload_sampled"
+
+// overloadSampler keeps every rate-th sample of every series while the backlog of the WAL is overloaded, from
+// the time it reaches highWaterMark entries until it drains below half of it. The sample counts of the least
+// recently seen series are forgotten once more than maxSeries are tracked.
+type overloadSampler struct {
+	mu            sync.Mutex
+	rate          int
+	highWaterMark uint64
+	sampling      bool
+	series        *seriesLRU[overloadSamplingEntry]
+}
+
+type overloadSamplingEntry struct {
+	// seen is the number of samples of the series seen while sampling.
+	seen uint64
+}
+
+func newOverloadSampler(rate, highWaterMark, maxSeries int) *overloadSampler {
+	return &overloadSampler{
+		rate:          rate,
+		highWaterMark: uint64(highWaterMark),
+		series:        newSeriesLRU[overloadSamplingEntry](maxSeries),
+	}
+}
+
+// update engages or disengages sampling depending on backlog, the number of entries of the WAL that weren't
+// read yet, and returns whether it is sampling and whether that changed.
+func (s *overloadSampler) update(backlog uint64) (sampling, changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case !s.sampling && backlog >= s.highWaterMark:
+		s.sampling = true
+	case s.sampling && backlog < s.highWaterMark/2:
+		s.sampling = false
+		// Sampling starts over with the first sample of every series the next time it engages.
+		s.series.reset()
+	default:
+		return s.sampling, false
+	}
+	return s.sampling, true
+}
+
+// sample drops all but every rate-th sample and histogram of the series of tsMap in place while sampling,
+// removing the series left without any, and returns the number of dropped samples.
+func (s *overloadSampler) sample(tsMap map[string]*prompb.TimeSeries) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.sampling {
+		return 0
+	}
+	dropped := 0
+	for key, ts := range tsMap {
+		entry, _ := s.series.getOrAdd(labelsHash(ts.Labels))
+		samples := ts.Samples[:0]
+		for _, sample := range ts.Samples {
+			if s.keep(entry) {
+				samples = append(samples, sample)
+			}
+		}
+		histograms := ts.Histograms[:0]
+		for _, histogram := range ts.Histograms {
+			if s.keep(entry) {
+				histograms = append(histograms, histogram)
+			}
+		}
+		dropped += len(ts.Samples) - len(samples) + len(ts.Histograms) - len(histograms)
+		ts.Samples, ts.Histograms = samples, histograms
+		if len(ts.Samples) == 0 && len(ts.Histograms) == 0 {
+			delete(tsMap, key)
+		}
+	}
+	return dropped
+}
+
+// keep counts a sample of the series of entry, and reports whether it is kept.
+func (s *overloadSampler) keep(entry *overloadSamplingEntry) bool {
+	kept := entry.seen%uint64(s.rate) == 0
+	entry.seen++
+	return kept
+}
+
+func (s *overloadSampler) takeStats() seriesCacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.series.takeStats()
+}
+
+// sampleIfOverloaded samples the series of tsMap while the backlog of the WAL is overloaded.
+func (prwe *prwExporter) sampleIfOverloaded(ctx context.Context, tsMap map[string]*prompb.TimeSeries) {
+	var backlog uint64
+	for _, wal := range prwe.allWALs() {
+		backlog += wal.backlog()
+	}
+	if sampling, changed := prwe.overloadSampler.update(backlog); changed {
+		if sampling {
+			prwe.settings.Logger.Warn("the WAL is overloaded, keeping only a fraction of the samples of every series",
+				zap.Uint64("backlog", backlog), zap.Int("overload_sampling_rate", prwe.overloadSampler.rate))
+		} else {
+			prwe.settings.Logger.Info("the WAL recovered from overload, keeping all the samples", zap.Uint64("backlog", backlog))
+		}
+	}
+	if dropped := prwe.overloadSampler.sample(tsMap); dropped > 0 {
+		prwe.telemetry.recordDroppedSamples(ctx, droppedReasonOverloadSampled, dropped)
+	}
+	prwe.recordSeriesCacheStats(ctx, seriesCacheOverloadSampling, prwe.overloadSampler)
+}
+
+// backlog returns the number of entries of the WAL that weren't read to be exported yet.
+func (prwe *prweWAL) backlog() uint64 {
+	// Like for readFromWAL, the read index of an empty WAL is 0 but its first entry is 1.
+	next := max(prwe.rWALIndex.Load(), 1)
+	last := prwe.wWALIndex.Load()
+	if last < next {
+		return 0
+	}
+	return last - next + 1
+}
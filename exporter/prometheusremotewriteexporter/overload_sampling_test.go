@@ -0,0 +1,97 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// droppedSamplesTelemetry sums the recorded dropped samples by reason, and discards the rest of the telemetry.
+type droppedSamplesTelemetry struct {
+	nopTelemetry
+	dropped map[string]int
+}
+
+func (t *droppedSamplesTelemetry) recordDroppedSamples(_ context.Context, reason string, numSamples int) {
+	t.dropped[reason] += numSamples
+}
+
+func TestPushMetricsOverloadSampling(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.WAL = &WALConfig{Directory: t.TempDir()}
+	cfg.OverloadSamplingRate = 3
+	cfg.OverloadHighWaterMark = 4
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	telemetry := &droppedSamplesTelemetry{dropped: map[string]int{}}
+	prwe.telemetry = telemetry
+	// The WAL isn't read, so that the pushed samples pile up in it.
+	require.NoError(t, prwe.wal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, prwe.wal.stop())
+	})
+
+	var pushes int
+	// push pushes a sample and returns whether it was written to the WAL.
+	push := func() bool {
+		pushes++
+		metric := pmetric.NewMetric()
+		metric.SetName("test_gauge")
+		dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.Timestamp(int64(pushes) * 1e9))
+		dp.SetDoubleValue(float64(pushes))
+		written := prwe.wal.wWALIndex.Load()
+		require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric)))
+		return prwe.wal.wWALIndex.Load() > written
+	}
+
+	// All the samples are kept until the backlog reaches the high-water mark.
+	for i := 0; i < 4; i++ {
+		assert.True(t, push(), "push %d", pushes)
+	}
+	// Then only every third sample is.
+	assert.True(t, push())
+	assert.False(t, push())
+	assert.False(t, push())
+	assert.True(t, push())
+	assert.Equal(t, uint64(6), prwe.wal.backlog())
+
+	// Sampling keeps going until the backlog drains below half of the high-water mark, as the entries are read
+	// to be exported.
+	prwe.wal.rWALIndex.Store(5)
+	assert.Equal(t, uint64(2), prwe.wal.backlog())
+	assert.False(t, push())
+	prwe.wal.rWALIndex.Store(6)
+	assert.True(t, push())
+	assert.True(t, push())
+
+	assert.Equal(t, map[string]int{droppedReasonOverloadSampled: 3}, telemetry.dropped)
+}
+
+func TestOverloadSamplerResetsOnRecovery(t *testing.T) {
+	s := newOverloadSampler(2, 10, 100)
+	sampling, changed := s.update(10)
+	assert.True(t, sampling)
+	assert.True(t, changed)
+	sampling, changed = s.update(5)
+	assert.True(t, sampling)
+	assert.False(t, changed)
+	_, found := s.series.getOrAdd(1)
+	require.False(t, found)
+	assert.Equal(t, 1, s.series.len())
+
+	sampling, changed = s.update(4)
+	assert.False(t, sampling)
+	assert.True(t, changed)
+	assert.Zero(t, s.series.len(), "the sample counts should be forgotten")
+}
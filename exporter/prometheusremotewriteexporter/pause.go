@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errPaused is returned by PushMetrics while exporting is paused and the WAL isn't enabled. It isn't permanent,
+// so that the sending queue keeps the metrics and retries them.
+var errPaused = errors.New("exporting is paused")
+
+// pauseGate holds back the exports while exporting is paused.
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is closed when exporting is resumed. It is nil while exporting isn't paused.
+	resumed chan struct{}
+}
+
+// pause pauses exporting, and returns false if it was paused already.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		return false
+	}
+	g.resumed = make(chan struct{})
+	return true
+}
+
+// resume resumes exporting, and returns false if it wasn't paused.
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		return false
+	}
+	close(g.resumed)
+	g.resumed = nil
+	return true
+}
+
+// resumedChan returns a channel closed when exporting is resumed, or nil if it isn't paused. A nil gate is
+// never paused.
+func (g *pauseGate) resumedChan() <-chan struct{} {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed
+}
+
+// Pause stops exporting until Resume is called, without shutting the exporter down. With the WAL enabled, the
+// metrics pushed in the meantime are still written to the WAL, which is neither exported nor truncated until
+// exporting resumes. Without the WAL, PushMetrics fails with a retryable error instead.
+func (prwe *prwExporter) Pause() {
+	if prwe.paused.pause() {
+		prwe.settings.Logger.Info("Exporting is paused")
+		prwe.telemetry.recordPaused(context.Background(), true)
+	}
+}
+
+// Resume resumes exporting after Pause. The entries accumulated in the WAL meanwhile are exported right away.
+func (prwe *prwExporter) Resume() {
+	if prwe.paused.resume() {
+		prwe.settings.Logger.Info("Exporting is resumed")
+		prwe.telemetry.recordPaused(context.Background(), false)
+	}
+}
+
+// waitUntilResumed blocks while exporting is paused, and returns false if ctx is done or the WAL is stopped first.
+func (prwe *prweWAL) waitUntilResumed(ctx context.Context) bool {
+	resumed := prwe.paused.resumedChan()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-prwe.stopChan:
+		return false
+	}
+}
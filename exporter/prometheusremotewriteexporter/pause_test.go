@@ -0,0 +1,119 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// pausedTelemetry records the values of the paused gauge.
+type pausedTelemetry struct {
+	nopTelemetry
+	paused []bool
+}
+
+func (p *pausedTelemetry) recordPaused(_ context.Context, paused bool) {
+	p.paused = append(p.paused, paused)
+}
+
+func newPauseTestMetrics() pmetric.Metrics {
+	metric := pmetric.NewMetric()
+	metric.SetName("foo")
+	pt := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+	pt.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	pt.SetDoubleValue(1)
+	return getMetricsFromMetricList(metric)
+}
+
+func TestPauseWithWAL(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        1,
+		TruncateFrequency: 20 * time.Millisecond,
+	}
+	exported := &atomic.Int64{}
+	exportSink := func(_ context.Context, reqL []*prompb.WriteRequest) error {
+		exported.Add(int64(len(reqL)))
+		return nil
+	}
+
+	pwal := newWAL(config, exportSink)
+	pwal.paused = &pauseGate{}
+	truncations := &atomic.Int64{}
+	pwal.openStore = func() (walStore, string, error) {
+		store, walPath, oErr := config.openStore()
+		if oErr != nil {
+			return nil, "", oErr
+		}
+		return &truncationCountingWALStore{walStore: store, truncations: truncations}, walPath, nil
+	}
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	require.True(t, pwal.paused.pause())
+	require.False(t, pwal.paused.pause())
+	// The metrics pushed while paused are still written to the WAL.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(i)))
+	}
+	ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.NewNop()))
+	defer cancel()
+	require.NoError(t, pwal.run(ctx))
+
+	time.Sleep(10 * config.TruncateFrequency)
+	assert.Zero(t, exported.Load(), "nothing should be exported while paused")
+	assert.Zero(t, truncations.Load(), "the WAL should not be truncated while paused")
+
+	require.True(t, pwal.paused.resume())
+	require.False(t, pwal.paused.resume())
+	require.Eventually(t, func() bool {
+		return exported.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond, "the entries written while paused should be exported on resume")
+	require.Eventually(t, func() bool {
+		return truncations.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+}
+
+func TestPauseWithoutWAL(t *testing.T) {
+	exported := &atomic.Int64{}
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		exported.Add(int64(len(requests)))
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	tel := &pausedTelemetry{}
+	prwe.telemetry = tel
+
+	prwe.Pause()
+	prwe.Pause()
+	err = prwe.PushMetrics(context.Background(), newPauseTestMetrics())
+	require.ErrorIs(t, err, errPaused)
+	assert.False(t, consumererror.IsPermanent(err), "the metrics should be retried by the sending queue")
+	assert.Zero(t, exported.Load())
+
+	prwe.Resume()
+	prwe.Resume()
+	require.NoError(t, prwe.PushMetrics(context.Background(), newPauseTestMetrics()))
+	assert.Equal(t, int64(1), exported.Load())
+	assert.Equal(t, []bool{true, false}, tel.paused)
+}
@@ -0,0 +1,193 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// PriorityRule assigns a priority to the series whose metric name matches a pattern.
+type PriorityRule struct {
+	// MetricNamePattern is a regular expression the whole metric name, as it is sent, must match.
+	MetricNamePattern string `mapstructure:"metric_name_pattern"`
+
+	// Priority is the priority of the matching series: "high", "normal" or "low".
+	Priority string `mapstructure:"priority"`
+}
+
+// The priorities of the series, each written to a WAL of its own.
+const (
+	priorityHigh   = "high"
+	priorityNormal = "normal"
+	priorityLow    = "low"
+)
+
+// priorities lists the priorities from the highest one.
+var priorities = []string{priorityHigh, priorityNormal, priorityLow}
+
+// walPriorityPollInterval is how often a WAL waiting for the WALs of higher priority checks whether they
+// were drained.
+const walPriorityPollInterval = 50 * time.Millisecond
+
+// walPriorityMaxWait is how long a WAL waits at most for the WALs of higher priority to be drained, so that a
+// steady flow of series of higher priority doesn't hold back its entries forever.
+const walPriorityMaxWait = 5 * time.Second
+
+// compilePriorityRules compiles the patterns of rules, anchored so that they match whole metric names.
+func compilePriorityRules(rules []PriorityRule) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(rules))
+	for _, rule := range rules {
+		pattern, err := regexp.Compile("^(?:" + rule.MetricNamePattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority_rules metric_name_pattern %q: %w", rule.MetricNamePattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// priorityRouter tells the priority of the series, from the first rule their metric name matches. The series
+// matching no rule have the normal priority.
+type priorityRouter struct {
+	rules    []PriorityRule
+	patterns []*regexp.Regexp
+}
+
+func newPriorityRouter(rules []PriorityRule) (*priorityRouter, error) {
+	patterns, err := compilePriorityRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &priorityRouter{rules: rules, patterns: patterns}, nil
+}
+
+func (r *priorityRouter) priority(labels []prompb.Label) string {
+	for _, label := range labels {
+		if label.Name != model.MetricNameLabel {
+			continue
+		}
+		for i, pattern := range r.patterns {
+			if pattern.MatchString(label.Value) {
+				return r.rules[i].Priority
+			}
+		}
+		break
+	}
+	return priorityNormal
+}
+
+// split splits requests by priority. The metadata is sent along with the series of normal priority.
+func (r *priorityRouter) split(requests []*prompb.WriteRequest) map[string][]*prompb.WriteRequest {
+	split := map[string][]*prompb.WriteRequest{}
+	for _, req := range requests {
+		byPriority := map[string]*prompb.WriteRequest{}
+		for _, ts := range req.Timeseries {
+			priority := r.priority(ts.Labels)
+			if byPriority[priority] == nil {
+				byPriority[priority] = &prompb.WriteRequest{}
+			}
+			byPriority[priority].Timeseries = append(byPriority[priority].Timeseries, ts)
+		}
+		if len(req.Metadata) > 0 {
+			if byPriority[priorityNormal] == nil {
+				byPriority[priorityNormal] = &prompb.WriteRequest{}
+			}
+			byPriority[priorityNormal].Metadata = req.Metadata
+		}
+		for priority, tierReq := range byPriority {
+			split[priority] = append(split[priority], tierReq)
+		}
+	}
+	return split
+}
+
+// newPriorityWALs returns the WALs of the series of high and low priority, next to the WAL of the series of
+// normal priority, and makes each WAL wait for those of higher priority to be drained before exporting, for up
+// to walPriorityMaxWait.
+func newPriorityWALs(normal *prweWAL, exportSink func(context.Context, []*prompb.WriteRequest) error) map[string]*prweWAL {
+	wals := map[string]*prweWAL{priorityNormal: normal}
+	for _, priority := range []string{priorityHigh, priorityLow} {
+		walConfig := *normal.walConfig
+		walConfig.priority = priority
+		wal := newWAL(&walConfig, exportSink)
+		wal.telemetry = normal.telemetry
+		wal.paused = normal.paused
+		wals[priority] = wal
+	}
+	wals[priorityNormal].higherPriority = []*prweWAL{wals[priorityHigh]}
+	wals[priorityLow].higherPriority = []*prweWAL{wals[priorityHigh], wals[priorityNormal]}
+	wals[priorityNormal].priorityMaxWait = walPriorityMaxWait
+	wals[priorityLow].priorityMaxWait = walPriorityMaxWait
+	return wals
+}
+
+// drained reports whether all the entries of the WAL were exported.
+func (prwe *prweWAL) drained() bool {
+	return prwe.backlog() == 0 && prwe.buffered.Load() == 0
+}
+
+// waitForHigherPriorities blocks until the WALs of higher priority are drained or priorityMaxWait elapsed, and
+// returns false if ctx is done or the WAL is stopped first.
+func (prwe *prweWAL) waitForHigherPriorities(ctx context.Context) bool {
+	if len(prwe.higherPriority) == 0 {
+		return true
+	}
+	ticker := time.NewTicker(walPriorityPollInterval)
+	defer ticker.Stop()
+	maxWait := time.NewTimer(prwe.priorityMaxWait)
+	defer maxWait.Stop()
+	for {
+		drained := true
+		for _, higher := range prwe.higherPriority {
+			drained = drained && higher.drained()
+		}
+		if drained {
+			return true
+		}
+		select {
+		case <-ticker.C:
+		case <-maxWait.C:
+			return true
+		case <-ctx.Done():
+			return false
+		case <-prwe.stopChan:
+			return false
+		}
+	}
+}
+
+// persistByPriority writes the series of requests to the WAL of their priority.
+func (prwe *prwExporter) persistByPriority(ctx context.Context, requests []*prompb.WriteRequest) error {
+	if prwe.priorityRouter == nil {
+		return prwe.wal.persistToWAL(ctx, requests)
+	}
+	split := prwe.priorityRouter.split(requests)
+	for _, priority := range priorities {
+		if len(split[priority]) == 0 {
+			continue
+		}
+		if err := prwe.priorityWALs[priority].persistToWAL(ctx, split[priority]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allWALs returns the WALs of the exporter, from the highest priority.
+func (prwe *prwExporter) allWALs() []*prweWAL {
+	if prwe.priorityWALs == nil {
+		return []*prweWAL{prwe.wal}
+	}
+	wals := make([]*prweWAL, 0, len(priorities))
+	for _, priority := range priorities {
+		wals = append(wals, prwe.priorityWALs[priority])
+	}
+	return wals
+}
@@ -0,0 +1,177 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func TestPriorityRouter(t *testing.T) {
+	router, err := newPriorityRouter([]PriorityRule{
+		{MetricNamePattern: "slo_.*", Priority: priorityHigh},
+		{MetricNamePattern: "bulk_.*|debug", Priority: priorityLow},
+		{MetricNamePattern: "slo_debug", Priority: priorityLow},
+	})
+	require.NoError(t, err)
+	series := func(name string) prompb.TimeSeries {
+		return prompb.TimeSeries{Labels: []prompb.Label{{Name: "__name__", Value: name}}}
+	}
+	split := router.split([]*prompb.WriteRequest{{
+		Timeseries: []prompb.TimeSeries{series("slo_latency"), series("slo_debug"), series("bulk_bytes"),
+			series("debug_info"), series("requests")},
+		Metadata: []prompb.MetricMetadata{{MetricFamilyName: "requests"}},
+	}})
+
+	names := map[string][]string{}
+	for priority, reqs := range split {
+		require.Len(t, reqs, 1)
+		for _, ts := range reqs[0].Timeseries {
+			names[priority] = append(names[priority], ts.Labels[0].Value)
+		}
+	}
+	// The first matching rule wins, and the patterns match whole metric names.
+	assert.Equal(t, map[string][]string{
+		priorityHigh:   {"slo_latency", "slo_debug"},
+		priorityNormal: {"debug_info", "requests"},
+		priorityLow:    {"bulk_bytes"},
+	}, names)
+	assert.Len(t, split[priorityNormal][0].Metadata, 1)
+	assert.Empty(t, split[priorityHigh][0].Metadata)
+}
+
+func TestPushMetricsPriorityRules(t *testing.T) {
+	var mu sync.Mutex
+	var exported []string
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				exported = append(exported, ts.Labels[0].Value)
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.WAL = &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        1,
+		TruncateFrequency: 20 * time.Millisecond,
+	}
+	cfg.PriorityRules = []PriorityRule{
+		{MetricNamePattern: "slo_.*", Priority: priorityHigh},
+		{MetricNamePattern: "bulk_.*", Priority: priorityLow},
+	}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+
+	// A backlog of mixed priorities builds up before the WALs are read.
+	gauge := func(name string) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName(name)
+		metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+		return metric
+	}
+	for i := 0; i < 2; i++ {
+		md := getMetricsFromMetricList(gauge("bulk_bytes"), gauge("requests"), gauge("slo_latency"))
+		require.NoError(t, prwe.PushMetrics(context.Background(), md))
+	}
+	require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+	defer func() {
+		assert.NoError(t, prwe.Shutdown(context.Background()))
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(exported) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"slo_latency", "slo_latency", "requests", "requests", "bulk_bytes", "bulk_bytes"}, exported)
+}
+
+func TestPriorityWALsMaxWait(t *testing.T) {
+	var mu sync.Mutex
+	var exported []string
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		// The series of high priority are exported slower than they are written, their WAL is never drained.
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				exported = append(exported, ts.Labels[0].Value)
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.WAL = &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        1,
+		TruncateFrequency: 20 * time.Millisecond,
+	}
+	cfg.PriorityRules = []PriorityRule{
+		{MetricNamePattern: "slo_.*", Priority: priorityHigh},
+		{MetricNamePattern: "bulk_.*", Priority: priorityLow},
+	}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+	prwe.priorityWALs[priorityNormal].priorityMaxWait = 100 * time.Millisecond
+	prwe.priorityWALs[priorityLow].priorityMaxWait = 100 * time.Millisecond
+
+	gauge := func(name string) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName(name)
+		metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+		return metric
+	}
+	md := getMetricsFromMetricList(gauge("bulk_bytes"), gauge("requests"), gauge("slo_latency"))
+	require.NoError(t, prwe.PushMetrics(context.Background(), md))
+	require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				assert.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(gauge("slo_latency"))))
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		wg.Wait()
+		assert.NoError(t, prwe.Shutdown(context.Background()))
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Contains(exported, "requests") && slices.Contains(exported, "bulk_bytes")
+	}, 5*time.Second, 10*time.Millisecond, "the series of lower priorities should be exported despite the steady flow of high priority ones")
+}
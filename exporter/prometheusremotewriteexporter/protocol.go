@@ -0,0 +1,166 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"go.uber.org/zap"
+)
+
+// remoteWriteProtocol is the version of the remote write protocol used to send requests.
+type remoteWriteProtocol int32
+
+const (
+	// protocolUnnegotiated means the version has to be negotiated by probing remote write 2.0.
+	protocolUnnegotiated remoteWriteProtocol = iota
+	protocolV1
+	protocolV2
+)
+
+// contentType returns the Content-Type header of requests sent with the protocol.
+func (p remoteWriteProtocol) contentType() string {
+	if p == protocolV2 {
+		return "application/x-protobuf;proto=io.prometheus.write.v2.Request"
+	}
+	return "application/x-protobuf"
+}
+
+// versionHeader returns the X-Prometheus-Remote-Write-Version header of requests sent with the protocol.
+func (p remoteWriteProtocol) versionHeader() string {
+	if p == protocolV2 {
+		return "2.0.0"
+	}
+	return "0.1.0"
+}
+
+var errUnsupportedProtocol = errors.New("remote write endpoint does not support the protocol version")
+
+// isUnsupportedProtocolStatus reports whether the endpoint rejected a request because of its protocol version.
+func isUnsupportedProtocolStatus(statusCode int) bool {
+	return statusCode == http.StatusUnsupportedMediaType || statusCode == http.StatusNotAcceptable
+}
+
+// protocol returns the protocol version to send the next request with. Without protocol fallback, remote
+// write 1.0 is always used. Otherwise, remote write 2.0 is probed until a version has been negotiated.
+func (prwe *prwExporter) protocol() remoteWriteProtocol {
+	if !prwe.protocolFallback {
+		return protocolV1
+	}
+	if p := remoteWriteProtocol(prwe.negotiatedProtocol.Load()); p != protocolUnnegotiated {
+		return p
+	}
+	return protocolV2
+}
+
+// setNegotiatedProtocol remembers the protocol version negotiated with the endpoint. Setting it to
+// protocolUnnegotiated makes the next request negotiate the version again.
+func (prwe *prwExporter) setNegotiatedProtocol(ctx context.Context, p remoteWriteProtocol) {
+	if remoteWriteProtocol(prwe.negotiatedProtocol.Swap(int32(p))) == p {
+		return
+	}
+	prwe.telemetry.recordNegotiatedProtocol(ctx, int64(p))
+	switch p {
+	case protocolV1:
+		prwe.settings.Logger.Info("remote write endpoint does not support remote write 2.0, falling back to remote write 1.0")
+	case protocolUnnegotiated:
+		prwe.settings.Logger.Debug("remote write request failed, the protocol version will be negotiated again")
+	default:
+		prwe.settings.Logger.Debug("negotiated remote write protocol version", zap.Int32("version", int32(p)))
+	}
+}
+
+// toWriteV2Request converts a remote write 1.0 request to a remote write 2.0 one. Metadata is
+// attached to the series of its metric family. With a metadata cache, the series whose metadata was
+// sent recently only get their type, and the keys of the other series that have metadata are returned,
+// for the cache to forget them if the request fails.
+func toWriteV2Request(req *prompb.WriteRequest, cache *metadataCache) (*writev2.Request, []uint64) {
+	symbols := writev2.NewSymbolTable()
+
+	metadata := make(map[string]prompb.MetricMetadata, len(req.Metadata))
+	for _, md := range req.Metadata {
+		metadata[md.MetricFamilyName] = md
+	}
+
+	var sentMetadata []uint64
+	timeseries := make([]writev2.TimeSeries, 0, len(req.Timeseries))
+	for _, ts := range req.Timeseries {
+		v2 := writev2.TimeSeries{
+			LabelsRefs: symbolizeLabels(&symbols, ts.Labels),
+			Samples:    make([]writev2.Sample, 0, len(ts.Samples)),
+			Exemplars:  make([]writev2.Exemplar, 0, len(ts.Exemplars)),
+			Histograms: make([]writev2.Histogram, 0, len(ts.Histograms)),
+		}
+		if md, ok := metricFamilyMetadata(metadata, ts.Labels); ok {
+			v2.Metadata.Type = writev2.Metadata_MetricType(md.Type)
+			key := labelsHash(ts.Labels)
+			if cache.sendFull(key, md) {
+				v2.Metadata.HelpRef = symbols.Symbolize(md.Help)
+				v2.Metadata.UnitRef = symbols.Symbolize(md.Unit)
+				sentMetadata = append(sentMetadata, key)
+			}
+		}
+		for _, s := range ts.Samples {
+			v2.Samples = append(v2.Samples, writev2.Sample{Value: s.Value, Timestamp: s.Timestamp})
+		}
+		for _, e := range ts.Exemplars {
+			v2.Exemplars = append(v2.Exemplars, writev2.Exemplar{
+				LabelsRefs: symbolizeLabels(&symbols, e.Labels),
+				Value:      e.Value,
+				Timestamp:  e.Timestamp,
+			})
+		}
+		for _, h := range ts.Histograms {
+			if h.IsFloatHistogram() {
+				v2.Histograms = append(v2.Histograms, writev2.FromFloatHistogram(h.Timestamp, h.ToFloatHistogram()))
+			} else {
+				v2.Histograms = append(v2.Histograms, writev2.FromIntHistogram(h.Timestamp, h.ToIntHistogram()))
+			}
+		}
+		timeseries = append(timeseries, v2)
+	}
+
+	return &writev2.Request{
+		Symbols:    symbols.Symbols(),
+		Timeseries: timeseries,
+	}, sentMetadata
+}
+
+func symbolizeLabels(symbols *writev2.SymbolsTable, lbls []prompb.Label) []uint32 {
+	refs := make([]uint32, 0, 2*len(lbls))
+	for _, l := range lbls {
+		refs = append(refs, symbols.Symbolize(l.Name), symbols.Symbolize(l.Value))
+	}
+	return refs
+}
+
+// metricFamilyMetadata returns the metadata of the metric family the series belongs to, if any.
+func metricFamilyMetadata(metadata map[string]prompb.MetricMetadata, lbls []prompb.Label) (prompb.MetricMetadata, bool) {
+	if len(metadata) == 0 {
+		return prompb.MetricMetadata{}, false
+	}
+	var name string
+	for _, l := range lbls {
+		if l.Name == labels.MetricName {
+			name = l.Value
+			break
+		}
+	}
+	if md, ok := metadata[name]; ok {
+		return md, true
+	}
+	// Histograms and summaries are made of several series named after their metric family.
+	for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+		if md, ok := metadata[strings.TrimSuffix(name, suffix)]; ok && strings.HasSuffix(name, suffix) {
+			return md, true
+		}
+	}
+	return prompb.MetricMetadata{}, false
+}
@@ -0,0 +1,190 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
+)
+
+func TestToWriteV2Request(t *testing.T) {
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels:    []prompb.Label{{Name: "__name__", Value: "test_counter_total"}, {Name: "job", Value: "test"}},
+				Samples:   []prompb.Sample{{Value: 1, Timestamp: 10}, {Value: 2, Timestamp: 20}},
+				Exemplars: []prompb.Exemplar{{Labels: []prompb.Label{{Name: "trace_id", Value: "1234"}}, Value: 1, Timestamp: 10}},
+			},
+			{
+				Labels: []prompb.Label{{Name: "__name__", Value: "test_histogram_count"}, {Name: "job", Value: "test"}},
+				Histograms: []prompb.Histogram{
+					prompb.FromIntHistogram(30, &histogram.Histogram{Count: 3, Sum: 6, Schema: 0, PositiveSpans: []histogram.Span{{Offset: 0, Length: 1}}, PositiveBuckets: []int64{3}}),
+				},
+			},
+		},
+		Metadata: []prompb.MetricMetadata{
+			{MetricFamilyName: "test_counter_total", Type: prompb.MetricMetadata_COUNTER, Help: "A counter", Unit: "seconds"},
+			{MetricFamilyName: "test_histogram", Type: prompb.MetricMetadata_HISTOGRAM, Help: "A histogram"},
+		},
+	}
+
+	v2, _ := toWriteV2Request(req, nil)
+	require.Len(t, v2.Timeseries, 2)
+	assert.Equal(t, "", v2.Symbols[0])
+
+	symbol := func(ref uint32) string { return v2.Symbols[ref] }
+	counter := v2.Timeseries[0]
+	assert.Equal(t, []string{"__name__", "test_counter_total", "job", "test"},
+		[]string{symbol(counter.LabelsRefs[0]), symbol(counter.LabelsRefs[1]), symbol(counter.LabelsRefs[2]), symbol(counter.LabelsRefs[3])})
+	assert.Equal(t, []writev2.Sample{{Value: 1, Timestamp: 10}, {Value: 2, Timestamp: 20}}, counter.Samples)
+	require.Len(t, counter.Exemplars, 1)
+	assert.Equal(t, "1234", symbol(counter.Exemplars[0].LabelsRefs[1]))
+	assert.Equal(t, writev2.Metadata_METRIC_TYPE_COUNTER, counter.Metadata.Type)
+	assert.Equal(t, "A counter", symbol(counter.Metadata.HelpRef))
+	assert.Equal(t, "seconds", symbol(counter.Metadata.UnitRef))
+
+	hist := v2.Timeseries[1]
+	require.Len(t, hist.Histograms, 1)
+	assert.Equal(t, int64(30), hist.Histograms[0].Timestamp)
+	assert.Equal(t, uint64(3), hist.Histograms[0].ToIntHistogram().Count)
+	assert.Equal(t, writev2.Metadata_METRIC_TYPE_HISTOGRAM, hist.Metadata.Type, "series should get the metadata of their metric family")
+	assert.Equal(t, "A histogram", symbol(hist.Metadata.HelpRef))
+}
+
+func TestProtocolFallback(t *testing.T) {
+	var v1Requests, v2Probes int
+	var received []*prompb.WriteRequest
+	// The server only supports remote write 1.0.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Content-Type"), "io.prometheus.write.v2.Request") {
+			v2Probes++
+			assert.Equal(t, "2.0.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+			http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+		v1Requests++
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		compressed, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data, err := snappy.Decode(nil, compressed)
+		assert.NoError(t, err)
+		req := new(prompb.WriteRequest)
+		assert.NoError(t, proto.Unmarshal(data, req))
+		received = append(received, req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.ProtocolFallback = true
+	cfg.BackOffConfig.Enabled = false
+	tel := metadatatest.SetupTelemetry()
+	prwe, err := newPRWExporter(cfg, tel.NewSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		{Labels: []prompb.Label{{Name: "__name__", Value: "test_metric"}}, Samples: []prompb.Sample{{Value: 1, Timestamp: 10}}},
+	}}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, prwe.execute(context.Background(), req))
+	}
+
+	assert.Equal(t, 1, v2Probes, "remote write 2.0 should not be probed again once 1.0 has been negotiated")
+	assert.Equal(t, 3, v1Requests)
+	require.Len(t, received, 3)
+	assert.Equal(t, "test_metric", received[0].Timeseries[0].Labels[0].Value)
+	assert.Equal(t, protocolV1, prwe.protocol())
+
+	tel.AssertMetrics(t, []metricdata.Metrics{
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_negotiated_protocol_version",
+			Description: "Remote write protocol version negotiated with the endpoint when protocol fallback is enabled, 0 while it is being negotiated",
+			Unit:        "1",
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{
+					{Value: 1, Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))},
+				},
+			},
+		},
+		// The remote write 2.0 probe failed.
+		expectedRequestsMetric(server.URL, map[string]int{requestOutcomeSuccess: 3, requestOutcomeFailure: 1}),
+	}, metricdatatest.IgnoreTimestamp())
+}
+
+func TestProtocolRenegotiatedAfterFailure(t *testing.T) {
+	var v2Probes int
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Content-Type"), "io.prometheus.write.v2.Request") {
+			v2Probes++
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			return
+		}
+		if failing {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.ProtocolFallback = true
+	cfg.BackOffConfig.Enabled = false
+	tel := metadatatest.SetupTelemetry()
+	prwe, err := newPRWExporter(cfg, tel.NewSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	require.NoError(t, prwe.execute(context.Background(), &prompb.WriteRequest{}))
+	assert.Equal(t, 1, v2Probes)
+
+	failing = true
+	require.Error(t, prwe.execute(context.Background(), &prompb.WriteRequest{}))
+	assert.Equal(t, 1, v2Probes)
+
+	// The failure triggers a new negotiation.
+	failing = false
+	require.NoError(t, prwe.execute(context.Background(), &prompb.WriteRequest{}))
+	assert.Equal(t, 2, v2Probes)
+}
+
+func TestProtocolFallbackDisabled(t *testing.T) {
+	var contentTypes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	tel := metadatatest.SetupTelemetry()
+	prwe, err := newPRWExporter(cfg, tel.NewSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	require.NoError(t, prwe.execute(context.Background(), &prompb.WriteRequest{}))
+	assert.Equal(t, []string{"application/x-protobuf"}, contentTypes)
+}
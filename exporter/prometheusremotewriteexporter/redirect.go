@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/confighttp"
+)
+
+// maxRedirects is the number of redirects followed for a single request, like net/http does by default.
+const maxRedirects = 10
+
+// toClient builds the client sending the requests to the endpoint of clientSettings, with the redirect policy
+// and the dial timeout of the exporter.
+func (prwe *prwExporter) toClient(ctx context.Context, host component.Host, clientSettings *confighttp.ClientConfig) (*http.Client, error) {
+	client, err := clientSettings.ToClient(ctx, host, prwe.settings)
+	if err != nil {
+		return nil, err
+	}
+	client.CheckRedirect = checkRedirect(prwe.followRedirects)
+	if prwe.dialTimeout > 0 {
+		client.Transport = &dialTimeoutRoundTripper{next: client.Transport, timeout: prwe.dialTimeout}
+	}
+	return client, nil
+}
+
+// checkRedirect returns the redirect policy of the client. When redirects are followed, the redirected request
+// gets all the headers of the original one, including those net/http drops when the redirect leads to another
+// host. net/http already sends the body again for 307 and 308 redirects, and the headers and authentication
+// set by the transport are applied to every request. Otherwise the redirect response is handled like any other
+// unsuccessful response.
+func checkRedirect(followRedirects bool) func(*http.Request, []*http.Request) error {
+	if !followRedirects {
+		return func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		for name, values := range via[0].Header {
+			if _, ok := req.Header[name]; !ok {
+				req.Header[name] = slices.Clone(values)
+			}
+		}
+		return nil
+	}
+}
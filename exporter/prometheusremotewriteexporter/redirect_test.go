@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func TestFollowRedirects(t *testing.T) {
+	writeReq := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+	}}}
+
+	for _, followRedirects := range []bool{true, false} {
+		t.Run(map[bool]string{true: "follow", false: "dont_follow"}[followRedirects], func(t *testing.T) {
+			redirected := make(chan *prompb.WriteRequest, 1)
+			var redirectedHeaders http.Header
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				redirectedHeaders = r.Header.Clone()
+				compressed, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				body, err := snappy.Decode(nil, compressed)
+				assert.NoError(t, err)
+				req := new(prompb.WriteRequest)
+				assert.NoError(t, proto.Unmarshal(body, req))
+				redirected <- req
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer target.Close()
+			// Redirect to another host name, for which net/http drops the authorization headers.
+			location := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+			redirects := &atomic.Int64{}
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				redirects.Add(1)
+				http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+			}))
+			defer gateway.Close()
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.ClientConfig.Endpoint = gateway.URL
+			cfg.ClientConfig.Headers = map[string]configopaque.String{"Authorization": "Bearer token"}
+			cfg.BackOffConfig.Enabled = false
+			cfg.FollowRedirects = followRedirects
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			require.NoError(t, prwe.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, prwe.Shutdown(context.Background()))
+			}()
+
+			err = prwe.execute(context.Background(), writeReq)
+			assert.Equal(t, int64(1), redirects.Load())
+			if !followRedirects {
+				assert.Error(t, err)
+				assert.Empty(t, redirected, "the redirect should not be followed")
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, redirected, 1)
+			assert.Equal(t, writeReq, <-redirected)
+			assert.Equal(t, "Bearer token", redirectedHeaders.Get("Authorization"))
+			assert.Equal(t, "snappy", redirectedHeaders.Get("Content-Encoding"))
+			assert.Equal(t, "application/x-protobuf", redirectedHeaders.Get("Content-Type"))
+		})
+	}
+}
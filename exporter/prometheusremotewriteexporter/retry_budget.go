@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// RetryBudget caps the rate of retries across all the consumers of the exporter.
+type RetryBudget struct {
+	// Rate is the number of retries per second allowed across all consumers.
+	Rate float64 `mapstructure:"rate"`
+
+	// Burst is the number of retries that can be made at once when no retry happened for a while.
+	Burst int `mapstructure:"burst"`
+}
+
+const defaultRetryBudgetBurst = 1
+
+var errRetryBudgetExhausted = errors.New("retry budget exhausted, not retrying the request")
+
+// unsentRequestError is the error of a request that wasn't sent as the retry budget was exhausted, which tells
+// the WAL the first of its entries to read again.
+type unsentRequestError struct {
+	request *prompb.WriteRequest
+	err     error
+}
+
+func (e *unsentRequestError) Error() string { return e.err.Error() }
+
+func (e *unsentRequestError) Unwrap() error { return e.err }
+
+// exportError returns the error of request, which couldn't be sent. It is permanent, as the request was retried
+// already, unless the retry budget was exhausted: the request wasn't rejected, so it is worth sending again later.
+func exportError(request *prompb.WriteRequest, err error) error {
+	var unsent *unsentRequestError
+	if errors.As(err, &unsent) {
+		return err
+	}
+	if errors.Is(err, errRetryBudgetExhausted) {
+		return &unsentRequestError{request: request, err: err}
+	}
+	return consumererror.NewPermanent(err)
+}
+
+// retryBudget is a token bucket shared by all the requests of the exporter, every retry takes a token.
+type retryBudget struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRetryBudget(cfg *RetryBudget) *retryBudget {
+	return &retryBudget{
+		rate:   cfg.Rate,
+		burst:  float64(cfg.Burst),
+		tokens: float64(cfg.Burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// allow takes a token from the budget, and reports whether there was one left.
+func (b *retryBudget) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,217 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.uber.org/zap"
+)
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	budget := newRetryBudget(&RetryBudget{Rate: 2, Burst: 3})
+	budget.last = now
+	budget.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, budget.allow(), "the burst should be available right away")
+	}
+	assert.False(t, budget.allow())
+
+	now = now.Add(time.Second)
+	assert.True(t, budget.allow())
+	assert.True(t, budget.allow())
+	assert.False(t, budget.allow(), "the budget should refill at the configured rate")
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, budget.allow())
+	}
+	assert.False(t, budget.allow(), "the budget should not refill past the burst")
+}
+
+func TestRetryBudgetSharedAcrossConsumers(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.MaxInterval = time.Millisecond
+	cfg.BackOffConfig.MaxElapsedTime = 5 * time.Second
+	// The budget barely refills during the test, so that only the burst can be spent.
+	cfg.RetryBudget = &RetryBudget{Rate: 0.001, Burst: 4}
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	const consumers = 5
+	var wg sync.WaitGroup
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := prwe.execute(context.Background(), &prompb.WriteRequest{})
+			assert.ErrorIs(t, err, errRetryBudgetExhausted)
+			assert.False(t, consumererror.IsPermanent(err), "the request should be sent again later")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(consumers+4), requests.Load(), "every consumer should send its request once, and share the retries")
+}
+
+func TestRetryBudgetExhaustedKeepsWALEntries(t *testing.T) {
+	var requests atomic.Int64
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request and its only allowed retry fail, then the retry of the next attempt is deferred.
+		if requests.Add(1) <= 3 {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		assert.NoError(t, err)
+		req := &prompb.WriteRequest{}
+		assert.NoError(t, proto.Unmarshal(data, req))
+		mu.Lock()
+		for _, ts := range req.Timeseries {
+			received = append(received, ts.Labels[0].Value)
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.TargetInfo.Enabled = false
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.MaxInterval = time.Millisecond
+	cfg.BackOffConfig.MaxElapsedTime = 5 * time.Second
+	cfg.RetryBudget = &RetryBudget{Rate: 0.001, Burst: 1}
+	cfg.WAL = &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        1,
+		TruncateFrequency: 10 * time.Millisecond,
+	}
+	require.NoError(t, cfg.Validate())
+
+	seed := newWAL(cfg.WAL, doNothingExportSink)
+	require.NoError(t, seed.retrieveWALIndices())
+	for i := 0; i < 2; i++ {
+		require.NoError(t, seed.persistToWAL(context.Background(), makeReq(i)))
+	}
+	require.NoError(t, seed.stop())
+
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+	require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+	defer func() {
+		assert.NoError(t, prwe.Shutdown(context.Background()))
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, 5*time.Second, 10*time.Millisecond, "the entries should be sent once the budget allows it")
+	mu.Lock()
+	assert.Equal(t, []string{"0", "1"}, received)
+	mu.Unlock()
+}
+
+func TestRetryBudgetExhaustedResendsOnlyUnsentWALEntries(t *testing.T) {
+	var requests atomic.Int64
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first entry is sent, then the second one and its only allowed retry fail, and so does the third one,
+		// which isn't retried.
+		if n := requests.Add(1); n >= 2 && n <= 4 {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		assert.NoError(t, err)
+		req := &prompb.WriteRequest{}
+		assert.NoError(t, proto.Unmarshal(data, req))
+		mu.Lock()
+		for _, ts := range req.Timeseries {
+			received = append(received, ts.Labels[0].Value)
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.TargetInfo.Enabled = false
+	// The requests are sent in order.
+	cfg.RemoteWriteQueue.NumConsumers = 1
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.MaxInterval = time.Millisecond
+	cfg.BackOffConfig.MaxElapsedTime = 5 * time.Second
+	cfg.RetryBudget = &RetryBudget{Rate: 0.001, Burst: 1}
+	cfg.WAL = &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        3,
+		TruncateFrequency: 50 * time.Millisecond,
+	}
+	require.NoError(t, cfg.Validate())
+
+	seed := newWAL(cfg.WAL, doNothingExportSink)
+	require.NoError(t, seed.retrieveWALIndices())
+	// The entries are read 3 at a time, the fourth one lets the unsent ones be read again along with it.
+	for i := 0; i < 4; i++ {
+		require.NoError(t, seed.persistToWAL(context.Background(), makeReq(i)))
+	}
+	require.NoError(t, seed.stop())
+
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+	require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+	defer func() {
+		assert.NoError(t, prwe.Shutdown(context.Background()))
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) >= 4
+	}, 5*time.Second, 10*time.Millisecond, "the entries should be sent once the budget allows it")
+	// Give a resent entry the time to arrive.
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []string{"0", "1", "2", "3"}, received, "the entry that was sent shouldn't be sent again")
+	mu.Unlock()
+	assert.Equal(t, int64(7), requests.Load())
+}
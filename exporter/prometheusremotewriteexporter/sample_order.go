@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+// droppedReasonDuplicateTimestamp is the reason reported for the samples dropped because a later sample of
+// their series has the same timestamp.
+const droppedReasonDuplicateTimestamp = "duplicate_timestamp"
+
+// orderSamples sorts the samples and histograms of every series by timestamp, since Prometheus rejects
+// out of order samples, and keeps only the last of those with the same timestamp. See:
+// * https://github.com/open-telemetry/wg-prometheus/issues/10
+// * https://github.com/open-telemetry/opentelemetry-collector/issues/2315
+func (prwe *prwExporter) orderSamples(ctx context.Context, tsMap map[string]*prompb.TimeSeries) {
+	dropped := 0
+	for _, ts := range tsMap {
+		dropped += orderSeriesByTimestamp(ts)
+	}
+	if dropped > 0 {
+		prwe.telemetry.recordDroppedSamples(ctx, droppedReasonDuplicateTimestamp, dropped)
+	}
+}
+
+// orderSeriesByTimestamp sorts the samples and histograms of ts by timestamp, dropping those with the same
+// timestamp as a later one, and returns the number of samples and histograms dropped.
+func orderSeriesByTimestamp(ts *prompb.TimeSeries) int {
+	var droppedSamples, droppedHistograms int
+	ts.Samples, droppedSamples = orderByTimestamp(ts.Samples, func(s *prompb.Sample) int64 { return s.Timestamp })
+	ts.Histograms, droppedHistograms = orderByTimestamp(ts.Histograms, func(h *prompb.Histogram) int64 { return h.Timestamp })
+	return droppedSamples + droppedHistograms
+}
+
+// orderByTimestamp stably sorts points by timestamp and keeps only the last of the points with the same
+// timestamp, in place. It returns the remaining points and the number of points dropped.
+func orderByTimestamp[T any](points []T, timestamp func(*T) int64) ([]T, int) {
+	if isOrderedByTimestamp(points, timestamp) {
+		return points, 0
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return timestamp(&points[i]) < timestamp(&points[j])
+	})
+	kept := points[:0]
+	for i := range points {
+		if i+1 < len(points) && timestamp(&points[i]) == timestamp(&points[i+1]) {
+			continue
+		}
+		kept = append(kept, points[i])
+	}
+	return kept, len(points) - len(kept)
+}
+
+// isOrderedByTimestamp reports whether the timestamps of points are strictly increasing.
+func isOrderedByTimestamp[T any](points []T, timestamp func(*T) int64) bool {
+	for i := 1; i < len(points); i++ {
+		if timestamp(&points[i-1]) >= timestamp(&points[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSampleOrder counts and logs the series whose samples or histograms aren't in strictly increasing
+// timestamp order, without changing them. It runs before the samples are ordered, so that the violations
+// are observed even when enforce_sample_order fixes them.
+func (prwe *prwExporter) checkSampleOrder(ctx context.Context, tsMap map[string]*prompb.TimeSeries) {
+	violations := 0
+	var example string
+	for _, ts := range tsMap {
+		if isOrderedByTimestamp(ts.Samples, func(s *prompb.Sample) int64 { return s.Timestamp }) &&
+			isOrderedByTimestamp(ts.Histograms, func(h *prompb.Histogram) int64 { return h.Timestamp }) {
+			continue
+		}
+		violations++
+		example = seriesMetricName(ts)
+	}
+	if violations > 0 {
+		prwe.telemetry.recordNonMonotonicSeries(ctx, violations)
+		prwe.settings.Logger.Warn("series with samples out of timestamp order",
+			zap.Int("series", violations), zap.String("example_metric", example))
+	}
+}
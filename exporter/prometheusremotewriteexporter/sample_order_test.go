@@ -0,0 +1,204 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
+)
+
+func TestHandleExportEnforceSampleOrder(t *testing.T) {
+	outOfOrder := func() map[string]*prompb.TimeSeries {
+		return map[string]*prompb.TimeSeries{
+			"0": {
+				Labels: []prompb.Label{{Name: "__name__", Value: "gauge"}},
+				Samples: []prompb.Sample{
+					{Value: 3, Timestamp: 3000},
+					{Value: 1, Timestamp: 1000},
+					{Value: 2, Timestamp: 2000},
+					{Value: 4, Timestamp: 1000},
+					{Value: 5, Timestamp: 3000},
+				},
+			},
+			"1": {
+				Labels: []prompb.Label{{Name: "__name__", Value: "histogram"}},
+				Histograms: []prompb.Histogram{
+					{Sum: 2, Timestamp: 2000},
+					{Sum: 1, Timestamp: 1000},
+					{Sum: 3, Timestamp: 2000},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		enforceSampleOrder bool
+		wantSamples        []prompb.Sample
+		wantHistograms     []prompb.Histogram
+		wantDropped        int64
+	}{
+		{
+			name:               "enabled",
+			enforceSampleOrder: true,
+			wantSamples: []prompb.Sample{
+				{Value: 4, Timestamp: 1000},
+				{Value: 2, Timestamp: 2000},
+				{Value: 5, Timestamp: 3000},
+			},
+			wantHistograms: []prompb.Histogram{
+				{Sum: 1, Timestamp: 1000},
+				{Sum: 3, Timestamp: 2000},
+			},
+			wantDropped: 3,
+		},
+		{
+			name:               "disabled",
+			enforceSampleOrder: false,
+			// The samples are still sorted when they are batched, but those with the same timestamp are kept.
+			wantSamples: []prompb.Sample{
+				{Value: 1, Timestamp: 1000},
+				{Value: 4, Timestamp: 1000},
+				{Value: 2, Timestamp: 2000},
+				{Value: 3, Timestamp: 3000},
+				{Value: 5, Timestamp: 3000},
+			},
+			wantHistograms: outOfOrder()["1"].Histograms,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]prompb.TimeSeries{}
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						got[ts.Labels[0].Value] = ts
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.EnforceSampleOrder = tt.enforceSampleOrder
+			tel := metadatatest.SetupTelemetry()
+			prwe, err := newPRWExporter(cfg, tel.NewSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			require.NoError(t, prwe.handleExport(context.Background(), outOfOrder(), nil))
+
+			require.Len(t, got, 2)
+			assert.Equal(t, tt.wantSamples, got["gauge"].Samples)
+			assert.Equal(t, tt.wantHistograms, got["histogram"].Histograms)
+
+			expected := []metricdata.Metrics{expectedLastBatchSeriesMetric(2)}
+			if tt.wantDropped > 0 {
+				expected = append(expected, metricdata.Metrics{
+					Name:        "otelcol_exporter_prometheusremotewrite_dropped_samples",
+					Description: "Number of Prometheus samples dropped by the exporter before being sent, by reason",
+					Unit:        "1",
+					Data: metricdata.Sum[int64]{
+						Temporality: metricdata.CumulativeTemporality,
+						IsMonotonic: true,
+						DataPoints: []metricdata.DataPoint[int64]{
+							{
+								Value: tt.wantDropped,
+								Attributes: attribute.NewSet(
+									attribute.String("exporter", "prometheusremotewrite"),
+									attribute.String("reason", droppedReasonDuplicateTimestamp),
+								),
+							},
+						},
+					},
+				})
+			}
+			tel.AssertMetrics(t, expected, metricdatatest.IgnoreTimestamp())
+			require.NoError(t, tel.Shutdown(context.Background()))
+		})
+	}
+}
+
+// nonMonotonicTelemetry counts the recorded series out of timestamp order and discards the rest of the telemetry.
+type nonMonotonicTelemetry struct {
+	nopTelemetry
+	series int
+}
+
+func (n *nonMonotonicTelemetry) recordNonMonotonicSeries(_ context.Context, numSeries int) {
+	n.series += numSeries
+}
+
+func TestHandleExportValidateMonotonicTimestamps(t *testing.T) {
+	series := func() map[string]*prompb.TimeSeries {
+		return map[string]*prompb.TimeSeries{
+			"0": {
+				Labels:  []prompb.Label{{Name: "__name__", Value: "ordered"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}, {Value: 2, Timestamp: 2000}},
+			},
+			"1": {
+				Labels:  []prompb.Label{{Name: "__name__", Value: "out_of_order"}},
+				Samples: []prompb.Sample{{Value: 2, Timestamp: 2000}, {Value: 1, Timestamp: 1000}},
+			},
+			"2": {
+				Labels:  []prompb.Label{{Name: "__name__", Value: "duplicate"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}, {Value: 2, Timestamp: 1000}},
+			},
+			"3": {
+				Labels:     []prompb.Label{{Name: "__name__", Value: "histogram"}},
+				Histograms: []prompb.Histogram{{Sum: 2, Timestamp: 2000}, {Sum: 1, Timestamp: 1000}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name                        string
+		validateMonotonicTimestamps bool
+		enforceSampleOrder          bool
+		wantNonMonotonic            int
+	}{
+		{name: "disabled"},
+		{name: "enabled", validateMonotonicTimestamps: true, wantNonMonotonic: 3},
+		{name: "enabled with enforced order", validateMonotonicTimestamps: true, enforceSampleOrder: true, wantNonMonotonic: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]prompb.TimeSeries{}
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						got[ts.Labels[0].Value] = ts
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.EnforceSampleOrder = tt.enforceSampleOrder
+			cfg.ValidateMonotonicTimestamps = tt.validateMonotonicTimestamps
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			tel := &nonMonotonicTelemetry{}
+			prwe.telemetry = tel
+			require.NoError(t, prwe.handleExport(context.Background(), series(), nil))
+
+			assert.Equal(t, tt.wantNonMonotonic, tel.series)
+			if !tt.enforceSampleOrder {
+				// The validation only observes the samples, none of them is dropped.
+				for _, ts := range series() {
+					assert.ElementsMatch(t, ts.Samples, got[ts.Labels[0].Value].Samples)
+					assert.Equal(t, ts.Histograms, got[ts.Labels[0].Value].Histograms)
+				}
+			}
+		})
+	}
+}
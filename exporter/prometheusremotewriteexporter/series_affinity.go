@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/multierr"
+)
+
+// affinityRequests splits the requests into one list per consumer, every series going to the consumer
+// its labels hash to. The order of the requests, and so of the samples of every series, is kept. The
+// metadata of a request is sent by the first consumer.
+func affinityRequests(requests []*prompb.WriteRequest, consumers int) [][]*prompb.WriteRequest {
+	shards := make([][]*prompb.WriteRequest, consumers)
+	for _, request := range requests {
+		split := make([]*prompb.WriteRequest, consumers)
+		if len(request.Metadata) > 0 {
+			split[0] = &prompb.WriteRequest{Metadata: request.Metadata}
+		}
+		for _, ts := range request.Timeseries {
+			consumer := labelsHash(ts.Labels) % uint64(consumers)
+			if split[consumer] == nil {
+				split[consumer] = &prompb.WriteRequest{}
+			}
+			split[consumer].Timeseries = append(split[consumer].Timeseries, ts)
+		}
+		for consumer, req := range split {
+			if req != nil {
+				shards[consumer] = append(shards[consumer], req)
+			}
+		}
+	}
+	return shards
+}
+
+// exportWithAffinity sends the requests using one consumer per shard of series, so that the samples of
+// a series are never sent concurrently. Concurrent exports take turns on every consumer.
+func (prwe *prwExporter) exportWithAffinity(ctx context.Context, requests []*prompb.WriteRequest) error {
+	shards := affinityRequests(requests, len(prwe.affinityMu))
+	var pending int
+	for _, shard := range shards {
+		pending += len(shard)
+	}
+	prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(int64(pending)))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs error
+	for consumer, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prwe.affinityMu[consumer].Lock()
+			defer prwe.affinityMu[consumer].Unlock()
+			for i, request := range shard {
+				if ctx.Err() != nil {
+					// The requests left over when the context is cancelled aren't pending anymore.
+					prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(-int64(len(shard)-i)))
+					return
+				}
+				prwe.telemetry.recordQueueDepth(ctx, prwe.queueDepth.Add(-1))
+				if errExecute := prwe.execute(ctx, request); errExecute != nil {
+					mu.Lock()
+					errs = multierr.Append(errs, exportError(request, errExecute))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,119 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func affinityTestSeries(name string, timestamp int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: name}},
+		Samples: []prompb.Sample{{Value: float64(timestamp), Timestamp: timestamp}},
+	}
+}
+
+func TestAffinityRequests(t *testing.T) {
+	const consumers = 2
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	consumerOf := map[string]int{}
+
+	// The same series are batched differently by every call.
+	for call := 0; call < 3; call++ {
+		var requests []*prompb.WriteRequest
+		for i := 0; i < len(names); i += call + 1 {
+			req := &prompb.WriteRequest{}
+			for _, name := range names[i:min(i+call+1, len(names))] {
+				req.Timeseries = append(req.Timeseries, affinityTestSeries(name, int64(call)))
+			}
+			requests = append(requests, req)
+		}
+		requests[0].Metadata = []prompb.MetricMetadata{{MetricFamilyName: "a"}}
+
+		shards := affinityRequests(requests, consumers)
+		require.Len(t, shards, consumers)
+		assert.Equal(t, requests[0].Metadata, shards[0][0].Metadata, "the metadata should be sent by the first consumer")
+		var sent int
+		for consumer, shard := range shards {
+			for _, req := range shard {
+				for _, ts := range req.Timeseries {
+					name := ts.Labels[0].Value
+					if previous, ok := consumerOf[name]; ok {
+						assert.Equal(t, previous, consumer, "series %q should always be sent by the same consumer", name)
+					}
+					consumerOf[name] = consumer
+					sent++
+				}
+			}
+		}
+		assert.Equal(t, len(names), sent)
+	}
+
+	// Both consumers are used.
+	used := map[int]bool{}
+	for _, consumer := range consumerOf {
+		used[consumer] = true
+	}
+	assert.Len(t, used, consumers)
+}
+
+func TestExportWithAffinityKeepsSeriesOrder(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]int64{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		assert.NoError(t, err)
+		req := &prompb.WriteRequest{}
+		assert.NoError(t, proto.Unmarshal(data, req))
+		mu.Lock()
+		for _, ts := range req.Timeseries {
+			received[ts.Labels[0].Value] = append(received[ts.Labels[0].Value], ts.Samples[0].Timestamp)
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.RemoteWriteQueue.NumConsumers = 2
+	cfg.RemoteWriteQueue.SeriesAffinity = true
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	// Every request holds the next sample of every series.
+	var requests []*prompb.WriteRequest
+	for i := int64(0); i < 20; i++ {
+		req := &prompb.WriteRequest{}
+		for s := 0; s < 4; s++ {
+			req.Timeseries = append(req.Timeseries, affinityTestSeries(fmt.Sprintf("series_%d", s), i))
+		}
+		requests = append(requests, req)
+	}
+	require.NoError(t, prwe.export(context.Background(), requests))
+
+	require.Len(t, received, 4)
+	for name, timestamps := range received {
+		require.Len(t, timestamps, 20, name)
+		for i, timestamp := range timestamps {
+			assert.Equal(t, int64(i), timestamp, "samples of %q should be sent in order", name)
+		}
+	}
+}
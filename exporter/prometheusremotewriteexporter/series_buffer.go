@@ -0,0 +1,79 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	// bufferFullPolicyBlock makes PushMetrics wait for the buffered series to be sent. It is the default.
+	bufferFullPolicyBlock = "block"
+	// bufferFullPolicySpillToWAL writes the coalesced series to the WAL as soon as the buffer is full.
+	bufferFullPolicySpillToWAL = "spill_to_wal"
+)
+
+// seriesBuffer bounds the number of series held in memory, while being coalesced or sent without a WAL.
+type seriesBuffer struct {
+	max  int
+	done <-chan struct{} // done is closed when the exporter shuts down, which stops waiting for room.
+
+	mu       sync.Mutex // mu protects the fields below.
+	buffered int
+	// released is closed, and replaced, whenever buffered series are released.
+	released chan struct{}
+}
+
+func newSeriesBuffer(maxSeries int, done <-chan struct{}) *seriesBuffer {
+	return &seriesBuffer{
+		max:      maxSeries,
+		done:     done,
+		released: make(chan struct{}),
+	}
+}
+
+// acquire waits until n more series fit in the buffer, then buffers them. Series are always accepted by an
+// empty buffer, so that a push bigger than the buffer isn't blocked forever.
+func (b *seriesBuffer) acquire(ctx context.Context, n int) error {
+	for {
+		b.mu.Lock()
+		if b.buffered == 0 || b.buffered+n <= b.max {
+			b.buffered += n
+			b.mu.Unlock()
+			return nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.done:
+			return errors.New("shutdown has been called")
+		}
+	}
+}
+
+// add buffers n more series without waiting, and returns whether they fit in the buffer.
+func (b *seriesBuffer) add(n int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buffered += n
+	return b.buffered <= b.max
+}
+
+// release removes n series from the buffer, waking up the pushes waiting for room.
+func (b *seriesBuffer) release(n int) {
+	if n == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buffered -= n
+	close(b.released)
+	b.released = make(chan struct{})
+}
@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func bufferTestMetrics(names ...string) pmetric.Metrics {
+	metrics := make([]pmetric.Metric, 0, len(names))
+	for _, name := range names {
+		gauge := pmetric.NewMetric()
+		gauge.SetName(name)
+		gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+		metrics = append(metrics, gauge)
+	}
+	return getMetricsFromMetricList(metrics...)
+}
+
+func (b *seriesBuffer) bufferedSeries() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffered
+}
+
+func TestSeriesBufferBlocksWhileSinkStalls(t *testing.T) {
+	exporting := make(chan struct{}, 10)
+	release := make(chan struct{})
+	sink := ExportSinkFunc(func(context.Context, []*prompb.WriteRequest) error {
+		exporting <- struct{}{}
+		<-release
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.MaxBufferedSeries = 2
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+
+	first := make(chan error, 1)
+	go func() {
+		first <- prwe.PushMetrics(context.Background(), bufferTestMetrics("first_a", "first_b"))
+	}()
+	<-exporting
+	assert.Equal(t, 2, prwe.seriesBuffer.bufferedSeries())
+
+	// The buffer is full while the sink stalls, so the next push waits.
+	second := make(chan error, 1)
+	go func() {
+		second <- prwe.PushMetrics(context.Background(), bufferTestMetrics("second"))
+	}()
+	select {
+	case <-exporting:
+		t.Fatal("the second push should wait for room in the buffer")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, 2, prwe.seriesBuffer.bufferedSeries(), "the buffer should stay bounded")
+
+	// A push giving up waiting returns the error of its context.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, prwe.PushMetrics(ctx, bufferTestMetrics("third")), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, <-first)
+	<-exporting
+	require.NoError(t, <-second)
+	assert.Zero(t, prwe.seriesBuffer.bufferedSeries())
+}
+
+func TestSeriesBufferSpillsToWAL(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.WAL = &WALConfig{Directory: t.TempDir()}
+	cfg.Coalesce = &Coalesce{FlushInterval: time.Hour}
+	cfg.MaxBufferedSeries = 2
+	cfg.BufferFullPolicy = bufferFullPolicySpillToWAL
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	// The WAL is opened without exporting its entries, as if the endpoint stalled.
+	require.NoError(t, prwe.wal.retrieveWALIndices())
+	defer func() {
+		require.NoError(t, prwe.Shutdown(context.Background()))
+	}()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, prwe.PushMetrics(context.Background(), bufferTestMetrics(fmt.Sprintf("series_%d", i))))
+		assert.LessOrEqual(t, prwe.seriesBuffer.bufferedSeries(), cfg.MaxBufferedSeries, "the buffer should stay bounded")
+	}
+
+	// The third push overflowed the buffer, spilling the three coalesced series to the WAL.
+	require.Equal(t, uint64(1), prwe.wal.wWALIndex.Load())
+	req, err := prwe.wal.readPrompbFromWAL(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Len(t, req.Timeseries, 3)
+	assert.Equal(t, 2, prwe.seriesBuffer.bufferedSeries())
+}
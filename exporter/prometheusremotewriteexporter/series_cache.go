@@ -0,0 +1,145 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"container/list"
+	"context"
+)
+
+// The names of the per-series caches, as they are reported in the telemetry of the exporter.
+const (
+	seriesCacheCounterReset     = "counter_reset"
+	seriesCacheLastSent         = "last_sent"
+	seriesCacheMetadata         = "metadata"
+	seriesCacheOverloadSampling = "overload_sampling"
+	seriesCacheSeriesGaps       = "series_gaps"
+	seriesCacheSeriesRateLimit  = "series_rate_limit"
+	seriesCacheTargetInfo       = "target_info"
+	seriesCacheZeroCounters     = "zero_counters"
+)
+
+// seriesCacheStats counts the lookups and evictions of a per-series cache since they were last taken.
+type seriesCacheStats struct {
+	hits      int
+	misses    int
+	evictions int
+	// size is the number of series held by the cache when the stats were taken.
+	size int
+}
+
+// take returns the stats along with size, and resets them.
+func (s *seriesCacheStats) take(size int) seriesCacheStats {
+	taken := *s
+	taken.size = size
+	*s = seriesCacheStats{}
+	return taken
+}
+
+// seriesLRU holds a value for each of the maxSeries most recently used series, identified by their hash, and
+// counts its lookups and evictions. It isn't safe for concurrent use, the caches using it hold their own lock.
+type seriesLRU[V any] struct {
+	maxSeries int
+	lru       *list.List // of *seriesLRUEntry[V], the most recently used series first.
+	series    map[uint64]*list.Element
+	stats     seriesCacheStats
+	// onEvict, if set, is called with the value of every series evicted to make room for another one.
+	onEvict func(*V)
+}
+
+type seriesLRUEntry[V any] struct {
+	key   uint64
+	value V
+}
+
+func newSeriesLRU[V any](maxSeries int) *seriesLRU[V] {
+	return &seriesLRU[V]{
+		maxSeries: maxSeries,
+		lru:       list.New(),
+		series:    make(map[uint64]*list.Element),
+	}
+}
+
+// getOrAdd returns the value of the series identified by key, marking it as the most recently used, and
+// whether it was already held. A zero value is added for the series that weren't.
+func (c *seriesLRU[V]) getOrAdd(key uint64) (*V, bool) {
+	if value, found := c.get(key); found {
+		return value, true
+	}
+	return c.add(key), false
+}
+
+// get returns the value of the series identified by key, if it is held, marking it as the most recently used.
+func (c *seriesLRU[V]) get(key uint64) (*V, bool) {
+	elem, found := c.series[key]
+	if !found {
+		c.stats.misses++
+		return nil, false
+	}
+	c.stats.hits++
+	c.lru.MoveToFront(elem)
+	return &elem.Value.(*seriesLRUEntry[V]).value, true
+}
+
+// add adds a zero value for the series identified by key, which mustn't be held, as the most recently used one,
+// evicting the least recently used series once more than maxSeries are held.
+func (c *seriesLRU[V]) add(key uint64) *V {
+	entry := &seriesLRUEntry[V]{key: key}
+	c.series[key] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.maxSeries {
+		c.stats.evictions++
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		evicted := oldest.Value.(*seriesLRUEntry[V])
+		delete(c.series, evicted.key)
+		if c.onEvict != nil {
+			c.onEvict(&evicted.value)
+		}
+	}
+	return &entry.value
+}
+
+// peek returns the value of the series identified by key, if it is held, without marking it as used or
+// counting the lookup.
+func (c *seriesLRU[V]) peek(key uint64) (*V, bool) {
+	elem, found := c.series[key]
+	if !found {
+		return nil, false
+	}
+	return &elem.Value.(*seriesLRUEntry[V]).value, true
+}
+
+// remove forgets the series identified by key.
+func (c *seriesLRU[V]) remove(key uint64) {
+	if elem, found := c.series[key]; found {
+		c.lru.Remove(elem)
+		delete(c.series, key)
+	}
+}
+
+// reset forgets all the series.
+func (c *seriesLRU[V]) reset() {
+	c.lru.Init()
+	clear(c.series)
+}
+
+// len returns the number of series held.
+func (c *seriesLRU[V]) len() int {
+	return c.lru.Len()
+}
+
+// takeStats returns the stats since they were last taken, and resets them.
+func (c *seriesLRU[V]) takeStats() seriesCacheStats {
+	return c.stats.take(c.lru.Len())
+}
+
+// seriesCache is implemented by the per-series caches whose stats are reported.
+type seriesCache interface {
+	takeStats() seriesCacheStats
+}
+
+// recordSeriesCacheStats records the stats of cache, reported under name, since they were last recorded.
+func (prwe *prwExporter) recordSeriesCacheStats(ctx context.Context, name string, cache seriesCache) {
+	prwe.telemetry.recordSeriesCache(ctx, name, cache.takeStats())
+}
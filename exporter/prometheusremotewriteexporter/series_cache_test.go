@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// seriesCacheTelemetry sums the recorded stats of the series caches, keeping their last size, and discards the
+// rest of the telemetry.
+type seriesCacheTelemetry struct {
+	nopTelemetry
+	mu    sync.Mutex
+	stats map[string]seriesCacheStats
+}
+
+func (s *seriesCacheTelemetry) recordSeriesCache(_ context.Context, cache string, stats seriesCacheStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := s.stats[cache]
+	total.hits += stats.hits
+	total.misses += stats.misses
+	total.evictions += stats.evictions
+	total.size = stats.size
+	s.stats[cache] = total
+}
+
+func TestPushMetricsSeriesCacheStats(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	counters := func(timestamp time.Time, names ...string) pmetric.Metrics {
+		var metrics []pmetric.Metric
+		for _, name := range names {
+			metric := pmetric.NewMetric()
+			metric.SetName(name)
+			sum := metric.SetEmptySum()
+			sum.SetIsMonotonic(true)
+			sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			dp := sum.DataPoints().AppendEmpty()
+			dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+			dp.SetDoubleValue(1)
+			metrics = append(metrics, metric)
+		}
+		return getMetricsFromMetricList(metrics...)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.TargetInfo.Enabled = false
+	cfg.EmitCounterResetSamples = true
+	cfg.TrackLastSent = true
+	cfg.MaxSamplesPerSeriesPerInterval = 10
+	cfg.DropZeroValueCounters = true
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+	tel := &seriesCacheTelemetry{stats: map[string]seriesCacheStats{}}
+	prwe.telemetry = tel
+
+	require.NoError(t, prwe.PushMetrics(context.Background(), counters(start, "first", "second")))
+	for _, cache := range []string{seriesCacheCounterReset, seriesCacheLastSent, seriesCacheSeriesRateLimit, seriesCacheZeroCounters} {
+		assert.Equal(t, seriesCacheStats{misses: 2, size: 2}, tel.stats[cache], cache)
+	}
+
+	require.NoError(t, prwe.PushMetrics(context.Background(), counters(start.Add(time.Second), "first", "third")))
+	for _, cache := range []string{seriesCacheCounterReset, seriesCacheLastSent, seriesCacheSeriesRateLimit, seriesCacheZeroCounters} {
+		assert.Equal(t, seriesCacheStats{hits: 1, misses: 3, size: 3}, tel.stats[cache], cache)
+	}
+}
+
+func TestSeriesCacheStatsEvictions(t *testing.T) {
+	cache := newSeriesLRU[int](2)
+	for key := uint64(1); key <= 5; key++ {
+		cache.getOrAdd(key)
+	}
+	cache.getOrAdd(5)
+	assert.Equal(t, seriesCacheStats{hits: 1, misses: 5, evictions: 3, size: 2}, cache.takeStats())
+	// The stats are reset once taken, but not the size.
+	assert.Equal(t, seriesCacheStats{size: 2}, cache.takeStats())
+}
+
+func TestSeriesLRU(t *testing.T) {
+	cache := newSeriesLRU[int](2)
+	var evicted []int
+	cache.onEvict = func(value *int) { evicted = append(evicted, *value) }
+
+	for key := uint64(1); key <= 2; key++ {
+		value, found := cache.getOrAdd(key)
+		require.False(t, found)
+		*value = int(key) * 10
+	}
+	// Using the first series makes the second one the least recently used.
+	value, found := cache.getOrAdd(1)
+	require.True(t, found)
+	assert.Equal(t, 10, *value)
+	_, found = cache.peek(2)
+	require.True(t, found, "peek shouldn't mark the series as used")
+	_, found = cache.getOrAdd(3)
+	require.False(t, found)
+	assert.Equal(t, []int{20}, evicted)
+	_, found = cache.peek(2)
+	assert.False(t, found)
+	assert.Equal(t, seriesCacheStats{hits: 1, misses: 3, evictions: 1, size: 2}, cache.takeStats())
+
+	cache.remove(1)
+	_, found = cache.peek(1)
+	assert.False(t, found)
+	assert.Equal(t, 1, cache.len())
+	cache.reset()
+	assert.Zero(t, cache.len())
+	assert.Equal(t, []int{20}, evicted, "only the series evicted to make room should be passed to onEvict")
+}
@@ -0,0 +1,168 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// defaultSeriesGapThreshold is the number of intervals a series must miss to be reported as having a gap.
+	defaultSeriesGapThreshold = 2
+	// seriesGapCheckInterval is how often the series are checked for gaps, including when nothing is received.
+	seriesGapCheckInterval = time.Second
+)
+
+// seriesGapDetector learns the interval between the samples of the recently received series, and detects the
+// series that stop being received for longer than threshold times their interval. The least recently received
+// series are forgotten once more than maxSeries are tracked.
+type seriesGapDetector struct {
+	mu        sync.Mutex
+	threshold float64
+	now       func() time.Time
+	series    *seriesLRU[seriesGapEntry]
+	// deadlines holds the series whose interval is known and whose gap wasn't detected yet, by their deadline.
+	deadlines seriesGapDeadlines
+}
+
+type seriesGapEntry struct {
+	timestamp int64 // of the newest sample, in milliseconds.
+	// interval is the time between the two newest samples, in milliseconds, 0 until the series was received twice.
+	interval int64
+	// deadline is when the series has a gap if it isn't received again.
+	deadline time.Time
+	// index is the position of the series in the deadlines, -1 while it isn't in them: until its interval is
+	// known, and once its gap is detected until it is received again.
+	index int
+}
+
+func newSeriesGapDetector(threshold float64, maxSeries int) *seriesGapDetector {
+	if threshold <= 0 {
+		threshold = defaultSeriesGapThreshold
+	}
+	d := &seriesGapDetector{
+		threshold: threshold,
+		now:       time.Now,
+		series:    newSeriesLRU[seriesGapEntry](maxSeries),
+	}
+	d.series.onEvict = func(entry *seriesGapEntry) {
+		// A series evicted as it is added was never in the deadlines, whatever its index.
+		if entry.index >= 0 && entry.index < len(d.deadlines) && d.deadlines[entry.index] == entry {
+			heap.Remove(&d.deadlines, entry.index)
+		}
+	}
+	return d
+}
+
+// observe records the newest sample of the series of tsMap, received now.
+func (d *seriesGapDetector) observe(tsMap map[string]*prompb.TimeSeries) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for _, ts := range tsMap {
+		newest, ok := newestTimestamp(*ts)
+		if !ok {
+			continue
+		}
+		entry, found := d.series.getOrAdd(labelsHash(ts.Labels))
+		if !found {
+			*entry = seriesGapEntry{timestamp: newest, index: -1}
+			continue
+		}
+		if newest <= entry.timestamp {
+			// Older or duplicate samples don't tell anything about the interval.
+			continue
+		}
+		if entry.interval == 0 || entry.index >= 0 {
+			// Unless its gap was detected, as the time the series was missing isn't its interval.
+			entry.interval = newest - entry.timestamp
+		}
+		entry.timestamp = newest
+		entry.deadline = now.Add(time.Duration(d.threshold * float64(entry.interval) * float64(time.Millisecond)))
+		if entry.index >= 0 {
+			heap.Fix(&d.deadlines, entry.index)
+		} else {
+			heap.Push(&d.deadlines, entry)
+		}
+	}
+}
+
+// detect returns the number of series that newly stopped being received for longer than threshold times their
+// interval. Only the series past their deadline are visited.
+func (d *seriesGapDetector) detect() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	gaps := 0
+	for len(d.deadlines) > 0 && now.After(d.deadlines[0].deadline) {
+		heap.Pop(&d.deadlines)
+		gaps++
+	}
+	return gaps
+}
+
+func (d *seriesGapDetector) takeStats() seriesCacheStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.series.takeStats()
+}
+
+// seriesGapDeadlines is a min-heap of series by their deadline, which keeps the index of every series up to date.
+type seriesGapDeadlines []*seriesGapEntry
+
+func (h seriesGapDeadlines) Len() int           { return len(h) }
+func (h seriesGapDeadlines) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+
+func (h seriesGapDeadlines) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *seriesGapDeadlines) Push(x any) {
+	entry := x.(*seriesGapEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *seriesGapDeadlines) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// detectSeriesGaps counts the series that newly stopped being received.
+func (prwe *prwExporter) detectSeriesGaps(ctx context.Context) {
+	if numGaps := prwe.seriesGapDetector.detect(); numGaps > 0 {
+		prwe.telemetry.recordSeriesGaps(ctx, numGaps)
+	}
+}
+
+// detectSeriesGapsPeriodically detects the series gaps every check interval until the exporter shuts down, so that
+// they are detected even once nothing is received anymore.
+func (prwe *prwExporter) detectSeriesGapsPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	prwe.wg.Add(1)
+	go func() {
+		defer prwe.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-prwe.closeChan:
+				return
+			case <-ticker.C:
+				prwe.detectSeriesGaps(context.Background())
+			}
+		}
+	}()
+}
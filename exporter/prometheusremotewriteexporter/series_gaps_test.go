@@ -0,0 +1,159 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// seriesGapsTelemetry counts the recorded series gaps and discards the rest of the telemetry.
+type seriesGapsTelemetry struct {
+	nopTelemetry
+	mu   sync.Mutex
+	gaps int
+}
+
+func (s *seriesGapsTelemetry) recordSeriesGaps(_ context.Context, numSeries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gaps += numSeries
+}
+
+func (s *seriesGapsTelemetry) numGaps() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gaps
+}
+
+func newSeriesGapsMetrics(timestamp time.Time, names ...string) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, name := range names {
+		metric := metrics.AppendEmpty()
+		metric.SetName(name)
+		dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetDoubleValue(1)
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	}
+	return md
+}
+
+func newSeriesGapsExporter(t *testing.T, seriesGapThreshold float64) (*prwExporter, *seriesGapsTelemetry) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.DetectSeriesGaps = true
+	cfg.SeriesGapThreshold = seriesGapThreshold
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(ExportSinkFunc(doNothingExportSink)))
+	require.NoError(t, err)
+	tel := &seriesGapsTelemetry{}
+	prwe.telemetry = tel
+	return prwe, tel
+}
+
+func TestDetectSeriesGaps(t *testing.T) {
+	tests := []struct {
+		name               string
+		seriesGapThreshold float64
+		// wantGaps is the number of gaps counted after every push once the "stopping" series stopped.
+		wantGaps []int
+	}{
+		{name: "default threshold", wantGaps: []int{0, 0, 1, 1, 1}},
+		{name: "higher threshold", seriesGapThreshold: 4, wantGaps: []int{0, 0, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prwe, tel := newSeriesGapsExporter(t, tt.seriesGapThreshold)
+
+			// Both series are received every 10s.
+			start := time.Unix(1700000000, 0)
+			now := start
+			prwe.seriesGapDetector.now = func() time.Time { return now }
+			for i := 0; i < 3; i++ {
+				now = start.Add(time.Duration(i) * 10 * time.Second)
+				require.NoError(t, prwe.PushMetrics(context.Background(), newSeriesGapsMetrics(now, "stopping", "regular")))
+				prwe.detectSeriesGaps(context.Background())
+			}
+			require.Zero(t, tel.gaps)
+
+			// Then only the regular one is.
+			lastSeen := now
+			for i, wantGaps := range tt.wantGaps {
+				now = lastSeen.Add(time.Duration(i+1) * 10 * time.Second)
+				require.NoError(t, prwe.PushMetrics(context.Background(), newSeriesGapsMetrics(now, "regular")))
+				prwe.detectSeriesGaps(context.Background())
+				assert.Equal(t, wantGaps, tel.gaps, "after %s", now.Sub(lastSeen))
+			}
+
+			// The series is tracked again once it is received.
+			now = now.Add(10 * time.Second)
+			require.NoError(t, prwe.PushMetrics(context.Background(), newSeriesGapsMetrics(now, "stopping", "regular")))
+			prwe.detectSeriesGaps(context.Background())
+			assert.Equal(t, 1, tel.gaps)
+		})
+	}
+}
+
+func TestDetectSeriesGapsOnceNothingIsReceived(t *testing.T) {
+	prwe, tel := newSeriesGapsExporter(t, 0)
+
+	// The sample timestamps lag behind the time they are received, which doesn't matter.
+	start := time.Unix(1700000000, 0)
+	now := start
+	prwe.seriesGapDetector.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		now = start.Add(time.Duration(i) * 10 * time.Second)
+		timestamp := now.Add(-time.Hour)
+		require.NoError(t, prwe.PushMetrics(context.Background(), newSeriesGapsMetrics(timestamp, "first", "second")))
+	}
+	prwe.detectSeriesGaps(context.Background())
+	require.Zero(t, tel.numGaps())
+
+	// Then nothing is received anymore.
+	now = now.Add(20 * time.Second)
+	prwe.detectSeriesGaps(context.Background())
+	require.Zero(t, tel.numGaps())
+	now = now.Add(time.Millisecond)
+	prwe.detectSeriesGaps(context.Background())
+	assert.Equal(t, 2, tel.numGaps())
+	// The gaps are only counted once.
+	now = now.Add(time.Minute)
+	prwe.detectSeriesGaps(context.Background())
+	assert.Equal(t, 2, tel.numGaps())
+}
+
+func TestDetectSeriesGapsPeriodically(t *testing.T) {
+	prwe, tel := newSeriesGapsExporter(t, 0)
+	start := time.Unix(1700000000, 0)
+	var mu sync.Mutex
+	now := start
+	prwe.seriesGapDetector.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	for i := 0; i < 2; i++ {
+		mu.Lock()
+		now = start.Add(time.Duration(i) * 10 * time.Second)
+		mu.Unlock()
+		require.NoError(t, prwe.PushMetrics(context.Background(), newSeriesGapsMetrics(prwe.seriesGapDetector.now(), "stopping")))
+	}
+
+	prwe.detectSeriesGapsPeriodically(time.Millisecond)
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	assert.Eventually(t, func() bool { return tel.numGaps() == 1 }, 5*time.Second, time.Millisecond)
+	close(prwe.closeChan)
+	prwe.wg.Wait()
+}
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+var errWALDraining = errors.New("attempt to read from WAL while draining")
+
+// stopReading stops reading entries from the WAL, the export in progress, if any, is left to complete.
+func (prwe *prweWAL) stopReading() {
+	prwe.drainOnce.Do(func() { close(prwe.draining) })
+}
+
+// drainWALExports stops reading entries from the WALs and waits, at most shutdown_drain_timeout, for the exports
+// in progress, and those of the entries read but not exported yet, to complete, so that their entries are
+// truncated from the WALs. The exports still in progress are
+// then cancelled, and the idle connections to the endpoint closed.
+func (prwe *prwExporter) drainWALExports(ctx context.Context) {
+	if !prwe.walEnabled() {
+		return
+	}
+	if prwe.shutdownDrainTimeout > 0 {
+		drainCtx, cancel := context.WithTimeout(ctx, prwe.shutdownDrainTimeout)
+		defer cancel()
+		wals := prwe.allWALs()
+		for _, wal := range wals {
+			wal.stopReading()
+		}
+	drain:
+		for _, wal := range wals {
+			if wal.runDone == nil {
+				continue
+			}
+			select {
+			case <-wal.runDone:
+			case <-drainCtx.Done():
+				prwe.settings.Logger.Warn("exports still in progress after shutdown_drain_timeout, cancelling them",
+					zap.Duration("shutdown_drain_timeout", prwe.shutdownDrainTimeout))
+				break drain
+			}
+		}
+	}
+	if prwe.cancelWALRun != nil {
+		prwe.cancelWALRun()
+	}
+	if _, client := prwe.target(); client != nil {
+		client.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.uber.org/zap"
+)
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	tests := []struct {
+		name                 string
+		shutdownDrainTimeout time.Duration
+		wantCompleted        bool
+		wantFirstIndex       uint64
+	}{
+		{name: "drained", shutdownDrainTimeout: 5 * time.Second, wantCompleted: true, wantFirstIndex: 2},
+		{name: "cancelled", wantFirstIndex: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan struct{}, 1)
+			var completed atomic.Bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case received <- struct{}{}:
+				default:
+				}
+				// The request is still in progress when the exporter shuts down.
+				select {
+				case <-time.After(200 * time.Millisecond):
+				case <-r.Context().Done():
+					return
+				}
+				completed.Store(true)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.ClientConfig.Endpoint = server.URL
+			cfg.TargetInfo.Enabled = false
+			cfg.ShutdownDrainTimeout = tt.shutdownDrainTimeout
+			cfg.WAL = &WALConfig{
+				Directory:  t.TempDir(),
+				BufferSize: 1,
+			}
+			require.NoError(t, cfg.Validate())
+
+			// Seed the WAL with two entries, the first one is being exported on shutdown.
+			seed := newWAL(cfg.WAL, doNothingExportSink)
+			require.NoError(t, seed.retrieveWALIndices())
+			for i := 0; i < 2; i++ {
+				require.NoError(t, seed.persistToWAL(context.Background(), makeReq(i)))
+			}
+			require.NoError(t, seed.stop())
+
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			prwe.client = server.Client()
+			require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "the first entry wasn't exported")
+			}
+			require.NoError(t, prwe.Shutdown(context.Background()))
+			assert.Equal(t, tt.wantCompleted, completed.Load())
+
+			// The entry of a request that completed is truncated, the second entry isn't read.
+			reopened := newWAL(cfg.WAL, doNothingExportSink)
+			require.NoError(t, reopened.retrieveWALIndices())
+			defer func() {
+				assert.NoError(t, reopened.stop())
+			}()
+			assert.Equal(t, tt.wantFirstIndex, reopened.rWALIndex.Load())
+			assert.Equal(t, uint64(2), reopened.wWALIndex.Load())
+		})
+	}
+}
+
+func TestShutdownDrainFlushesBufferedEntries(t *testing.T) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.TargetInfo.Enabled = false
+	cfg.ShutdownDrainTimeout = 5 * time.Second
+	// The entries are only buffered, until the exporter shuts down.
+	cfg.WAL = &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        10,
+		TruncateFrequency: time.Hour,
+	}
+	require.NoError(t, cfg.Validate())
+
+	seed := newWAL(cfg.WAL, doNothingExportSink)
+	require.NoError(t, seed.retrieveWALIndices())
+	for i := 0; i < 3; i++ {
+		require.NoError(t, seed.persistToWAL(context.Background(), makeReq(i)))
+	}
+	require.NoError(t, seed.stop())
+
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.client = server.Client()
+	require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+	require.Eventually(t, func() bool {
+		return prwe.wal.buffered.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, prwe.Shutdown(context.Background()))
+	assert.Equal(t, int64(3), received.Load(), "the buffered entries should be exported on shutdown")
+
+	// The exported entries were truncated, but for the last one as the WAL can't be emptied, they aren't sent
+	// again on restart.
+	reopened := newWAL(cfg.WAL, doNothingExportSink)
+	require.NoError(t, reopened.retrieveWALIndices())
+	defer func() {
+		assert.NoError(t, reopened.stop())
+	}()
+	assert.Equal(t, uint64(3), reopened.rWALIndex.Load())
+	assert.Equal(t, uint64(3), reopened.wWALIndex.Load())
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// ErrorClass tells how a request rejected by the remote write endpoint is handled.
+type ErrorClass int
+
+const (
+	// ErrorClassPermanent requests would be rejected again, they aren't retried.
+	ErrorClassPermanent ErrorClass = iota
+	// ErrorClassRetryable requests failed because of a transient error of the endpoint, they are retried.
+	ErrorClassRetryable
+	// ErrorClassThrottled requests were rejected because the endpoint is overloaded, they are retried after
+	// backing off, no sooner than the endpoint asked for in the Retry-After header of its response.
+	ErrorClassThrottled
+)
+
+// StatusClassifier classifies the non-2xx HTTP status codes returned by the remote write endpoint, along with
+// the beginning of the response body, so that the quirks of a backend can be handled. It is called
+// concurrently.
+type StatusClassifier func(statusCode int, body []byte) ErrorClass
+
+// DefaultStatusClassifier classifies the standard status codes: 5xx are retryable, 429 is throttled and the
+// others are permanent. Custom classifiers can fall back to it for the status codes they don't handle.
+func DefaultStatusClassifier(statusCode int, _ []byte) ErrorClass {
+	switch {
+	case statusCode >= 500 && statusCode < 600:
+		return ErrorClassRetryable
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorClassThrottled
+	default:
+		return ErrorClassPermanent
+	}
+}
+
+// classifyStatus classifies a status code returned by the endpoint with the custom classifier, if any.
+// Otherwise, 429 is only retried when the RetryOn429 feature gate is enabled.
+func (prwe *prwExporter) classifyStatus(statusCode int, body []byte) ErrorClass {
+	if prwe.statusClassifier != nil {
+		return prwe.statusClassifier(statusCode, body)
+	}
+	class := DefaultStatusClassifier(statusCode, body)
+	if class == ErrorClassThrottled && !prwe.retryOnHTTP429 {
+		return ErrorClassPermanent
+	}
+	return class
+}
+
+// throttledError is the error of a request throttled by the endpoint, which asked for it to be retried no sooner
+// than retryAfter.
+type throttledError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *throttledError) Error() string { return e.err.Error() }
+
+func (e *throttledError) Unwrap() error { return e.err }
+
+// parseRetryAfter returns the delay of a Retry-After header, given in seconds or as an HTTP date, and 0 if it
+// is missing or invalid.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// retryAfterBackOff backs off at least as long as the endpoint asked for when it throttled the last attempt,
+// up to maxInterval so that a misbehaving endpoint can't hold the request forever.
+type retryAfterBackOff struct {
+	backoff.BackOff
+	maxInterval time.Duration
+	retryAfter  time.Duration
+}
+
+func (b *retryAfterBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	retryAfter := min(b.retryAfter, b.maxInterval)
+	b.retryAfter = 0
+	if next == backoff.Stop {
+		return next
+	}
+	return max(next, retryAfter)
+}
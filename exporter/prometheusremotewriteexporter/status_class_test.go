@@ -0,0 +1,174 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
+)
+
+func TestStatusClassifier(t *testing.T) {
+	// quirkyBackend rejects invalid requests with 422, and too large ones with 413 while it is overloaded.
+	quirkyBackend := func(statusCode int, body []byte) ErrorClass {
+		switch statusCode {
+		case http.StatusUnprocessableEntity:
+			return ErrorClassPermanent
+		case http.StatusRequestEntityTooLarge:
+			return ErrorClassThrottled
+		}
+		return DefaultStatusClassifier(statusCode, body)
+	}
+
+	tests := []struct {
+		name         string
+		classifier   StatusClassifier
+		statusCode   int
+		wantRequests int64
+	}{
+		{name: "default retryable", statusCode: http.StatusServiceUnavailable, wantRequests: 3},
+		{name: "default permanent", statusCode: http.StatusRequestEntityTooLarge, wantRequests: 1},
+		// 429 is only retried by default when the RetryOn429 feature gate is enabled.
+		{name: "default too many requests", statusCode: http.StatusTooManyRequests, wantRequests: 1},
+		{name: "custom permanent", classifier: quirkyBackend, statusCode: http.StatusUnprocessableEntity, wantRequests: 1},
+		{name: "custom throttled", classifier: quirkyBackend, statusCode: http.StatusRequestEntityTooLarge, wantRequests: 3},
+		{name: "custom falling back to default", classifier: quirkyBackend, statusCode: http.StatusTooManyRequests, wantRequests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests.Add(1)
+				http.Error(w, http.StatusText(tt.statusCode), tt.statusCode)
+			}))
+			defer server.Close()
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.ClientConfig.Endpoint = server.URL
+			cfg.BackOffConfig.InitialInterval = 10 * time.Millisecond
+			cfg.BackOffConfig.MaxInterval = 10 * time.Millisecond
+			cfg.MaxRetries = 2
+			var opts []FactoryOption
+			if tt.classifier != nil {
+				opts = append(opts, WithStatusClassifier(tt.classifier))
+			}
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), opts...)
+			require.NoError(t, err)
+			prwe.client = server.Client()
+
+			err = prwe.execute(context.Background(), makeReq(0)[0])
+			require.Error(t, err)
+			assert.True(t, consumererror.IsPermanent(err))
+			assert.Equal(t, tt.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestStrictStatusCodes(t *testing.T) {
+	for _, statusCode := range []int{http.StatusOK, http.StatusAccepted, http.StatusNoContent} {
+		for _, strict := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%d strict=%t", statusCode, strict), func(t *testing.T) {
+				var requests atomic.Int64
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					requests.Add(1)
+					w.WriteHeader(statusCode)
+				}))
+				defer server.Close()
+
+				cfg := createDefaultConfig().(*Config)
+				cfg.ClientConfig.Endpoint = server.URL
+				cfg.BackOffConfig.InitialInterval = 10 * time.Millisecond
+				cfg.StrictStatusCodes = strict
+				tel := metadatatest.SetupTelemetry()
+				settings := tel.NewSettings()
+				core, logs := observer.New(zapcore.WarnLevel)
+				settings.Logger = zap.New(core)
+				prwe, err := newPRWExporter(cfg, settings)
+				require.NoError(t, err)
+				prwe.client = server.Client()
+
+				// Every 2xx status is accepted, the other ones than 204 are only flagged when strict.
+				require.NoError(t, prwe.execute(context.Background(), makeReq(0)[0]))
+				assert.NoError(t, prwe.LastError())
+				// The requests are never retried, the endpoint accepted them.
+				assert.Equal(t, int64(1), requests.Load())
+
+				outcome := requestOutcomeSuccess
+				if strict && statusCode != http.StatusNoContent {
+					outcome = requestOutcomeUnexpectedStatus
+				}
+				assert.Equal(t, outcome == requestOutcomeUnexpectedStatus,
+					logs.FilterMessage("remote write returned another status than 204 No Content").Len() == 1)
+				tel.AssertMetrics(t, []metricdata.Metrics{
+					expectedRequestsMetric(server.URL, map[string]int{outcome: 1}),
+				}, metricdatatest.IgnoreTimestamp())
+			})
+		}
+	}
+}
+
+func TestThrottledRetryAfter(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.MaxInterval = 5 * time.Second
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithStatusClassifier(DefaultStatusClassifier))
+	require.NoError(t, err)
+	prwe.client = server.Client()
+
+	require.NoError(t, prwe.execute(context.Background(), makeReq(0)[0]))
+	require.Len(t, requests, 2)
+	assert.GreaterOrEqual(t, requests[1].Sub(requests[0]), time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 5*time.Second, parseRetryAfter(now.Add(5*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter("", now))
+}
+
+func TestRetryAfterBackOff(t *testing.T) {
+	b := &retryAfterBackOff{BackOff: backoff.NewConstantBackOff(time.Millisecond), maxInterval: time.Minute}
+	b.retryAfter = time.Second
+	assert.Equal(t, time.Second, b.NextBackOff())
+	// The Retry-After only delays the retry that follows the response carrying it.
+	assert.Equal(t, time.Millisecond, b.NextBackOff())
+	b.retryAfter = time.Hour
+	assert.Equal(t, time.Minute, b.NextBackOff())
+
+	b = &retryAfterBackOff{BackOff: &backoff.StopBackOff{}, maxInterval: time.Minute, retryAfter: time.Second}
+	assert.Equal(t, backoff.Stop, b.NextBackOff())
+}
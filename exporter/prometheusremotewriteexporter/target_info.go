@@ -0,0 +1,97 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+)
+
+// targetInfoThrottle remembers when the target_info series of the recently seen resources were last emitted,
+// so that the target_info of a resource is only emitted again once emitInterval elapsed. Resources are
+// identified by the labels of their target_info series. The least recently seen resources are forgotten once
+// more than maxSeries are tracked.
+type targetInfoThrottle struct {
+	mu           sync.Mutex
+	name         string // the name of the target_info series, prefixed with the namespace.
+	emitInterval time.Duration
+	// series holds when the target_info of the resources was last emitted.
+	series *seriesLRU[time.Time]
+	now    func() time.Time
+}
+
+func newTargetInfoThrottle(namespace string, emitInterval time.Duration, maxSeries int) *targetInfoThrottle {
+	return &targetInfoThrottle{
+		name:         targetInfoName(namespace),
+		emitInterval: emitInterval,
+		series:       newSeriesLRU[time.Time](maxSeries),
+		now:          time.Now,
+	}
+}
+
+// filter removes from tsMap the target_info series emitted less than emitInterval ago, and returns the number
+// of removed series.
+func (t *targetInfoThrottle) filter(tsMap map[string]*prompb.TimeSeries) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	removed := 0
+	for key, ts := range tsMap {
+		if !isTargetInfoSeries(ts, t.name) {
+			continue
+		}
+		if !t.emit(labelsHash(ts.Labels), now) {
+			delete(tsMap, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// emit reports whether the target_info series identified by key has to be emitted at now, and remembers it as
+// emitted if so.
+func (t *targetInfoThrottle) emit(key uint64, now time.Time) bool {
+	emittedAt, found := t.series.getOrAdd(key)
+	if found && now.Sub(*emittedAt) < t.emitInterval {
+		return false
+	}
+	*emittedAt = now
+	return true
+}
+
+func (t *targetInfoThrottle) takeStats() seriesCacheStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.series.takeStats()
+}
+
+// targetInfoName returns the name of the target_info series, prefixed with namespace.
+func targetInfoName(namespace string) string {
+	if namespace != "" {
+		return namespace + "_" + prometheustranslator.TargetInfoMetricName
+	}
+	return prometheustranslator.TargetInfoMetricName
+}
+
+// isTargetInfoSeries reports whether ts is a target_info series named name.
+func isTargetInfoSeries(ts *prompb.TimeSeries, name string) bool {
+	return seriesMetricName(ts) == name
+}
+
+// splitTargetInfo moves the target_info series named name out of tsMap, and returns them.
+func splitTargetInfo(tsMap map[string]*prompb.TimeSeries, name string) map[string]*prompb.TimeSeries {
+	targetInfo := make(map[string]*prompb.TimeSeries)
+	for key, ts := range tsMap {
+		if isTargetInfoSeries(ts, name) {
+			targetInfo[key] = ts
+			delete(tsMap, key)
+		}
+	}
+	return targetInfo
+}
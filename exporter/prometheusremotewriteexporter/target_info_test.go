@@ -0,0 +1,131 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func newTargetInfoTestMetrics(hostName string, timestamp time.Time) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	rm.Resource().Attributes().PutStr("service.instance.id", "checkout-1")
+	rm.Resource().Attributes().PutStr("host.name", hostName)
+	metric := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("requests")
+	dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetDoubleValue(1)
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	return md
+}
+
+func TestTargetInfoEmitInterval(t *testing.T) {
+	tests := []struct {
+		name           string
+		namespace      string
+		emitInterval   time.Duration
+		wantTargetInfo []int
+	}{
+		{name: "every flush", wantTargetInfo: []int{1, 1, 1, 1}},
+		{name: "once per interval", emitInterval: 30 * time.Second, wantTargetInfo: []int{1, 0, 1, 1}},
+		{name: "once per interval with namespace", namespace: "shop", emitInterval: 30 * time.Second, wantTargetInfo: []int{1, 0, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var targetInfo, samples int
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						if ts.Labels[0].Value == "target_info" || ts.Labels[0].Value == tt.namespace+"_target_info" {
+							targetInfo++
+						} else {
+							samples += len(ts.Samples)
+						}
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.Namespace = tt.namespace
+			cfg.RemoteWriteQueue.Enabled = false
+			cfg.TargetInfo.EmitInterval = tt.emitInterval
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			start := time.Unix(1700000000, 0)
+			now := start
+			if prwe.targetInfoThrottle != nil {
+				prwe.targetInfoThrottle.now = func() time.Time { return now }
+			}
+
+			flushes := []struct {
+				at       time.Duration
+				hostName string
+			}{
+				{at: 0, hostName: "node-1"},
+				// The unchanged resource was emitted less than the interval ago.
+				{at: 10 * time.Second, hostName: "node-1"},
+				{at: 30 * time.Second, hostName: "node-1"},
+				// A resource whose attributes changed is a new one.
+				{at: 35 * time.Second, hostName: "node-2"},
+			}
+			for i, flush := range flushes {
+				now = start.Add(flush.at)
+				targetInfo, samples = 0, 0
+				require.NoError(t, prwe.PushMetrics(context.Background(), newTargetInfoTestMetrics(flush.hostName, now)))
+				assert.Equal(t, tt.wantTargetInfo[i], targetInfo, "flush %d", i)
+				assert.Equal(t, 1, samples, "the other series are emitted on every flush")
+			}
+		})
+	}
+}
+
+func TestTargetInfoSeparateRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		separateRequest bool
+		wantRequests    [][]string
+	}{
+		{name: "mixed", wantRequests: [][]string{{"requests", "target_info"}}},
+		{name: "separate", separateRequest: true, wantRequests: [][]string{{"target_info"}, {"requests"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					var names []string
+					for _, ts := range req.Timeseries {
+						names = append(names, ts.Labels[0].Value)
+					}
+					sort.Strings(names)
+					got = append(got, names)
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.RemoteWriteQueue.Enabled = false
+			cfg.TargetInfo.SeparateRequest = tt.separateRequest
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+
+			require.NoError(t, prwe.PushMetrics(context.Background(), newTargetInfoTestMetrics("node-1", time.Now())))
+			assert.Equal(t, tt.wantRequests, got)
+		})
+	}
+}
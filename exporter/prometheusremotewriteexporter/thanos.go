@@ -0,0 +1,71 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+// backendThanos is the backend of Thanos Receive endpoints.
+const backendThanos = "thanos"
+
+// defaultThanosTenantHeader is the header Thanos Receive reads the tenant from, unless configured otherwise.
+const defaultThanosTenantHeader = "THANOS-TENANT"
+
+// thanosReplicaHeader is the header Thanos Receive reads the replica number of the replicated requests from.
+const thanosReplicaHeader = "THANOS-REPLICA"
+
+// ThanosConfig configures the requests sent to Thanos Receive.
+type ThanosConfig struct {
+	// Tenant is the tenant the series are written to. Empty means the default tenant of the receivers.
+	Tenant string `mapstructure:"tenant"`
+
+	// TenantHeader is the header the tenant is sent in, it must match the --receive.tenant-header flag of the
+	// receivers.
+	TenantHeader string `mapstructure:"tenant_header"`
+
+	// Replica is the replica number the requests are marked with, for the collectors that replicate the series to
+	// each receiver themselves. The receivers write the marked requests without replicating them. Nil sends no
+	// replica header.
+	Replica *uint64 `mapstructure:"replica"`
+}
+
+// validate checks the Thanos settings against the headers configured for every request, and fills the defaults.
+func (tc *ThanosConfig) validate(headers map[string]configopaque.String) error {
+	if tc.TenantHeader == "" {
+		tc.TenantHeader = defaultThanosTenantHeader
+	}
+	for name := range headers {
+		switch http.CanonicalHeaderKey(name) {
+		case http.CanonicalHeaderKey(tc.TenantHeader):
+			if tc.Tenant != "" {
+				return fmt.Errorf("thanos tenant is also set by the %s header in headers", name)
+			}
+		case http.CanonicalHeaderKey(thanosReplicaHeader):
+			if tc.Replica != nil {
+				return fmt.Errorf("thanos replica is also set by the %s header in headers", name)
+			}
+		}
+	}
+	return nil
+}
+
+// headers returns the headers added to the requests sent to Thanos Receive.
+func (tc *ThanosConfig) headers() map[string]string {
+	headers := map[string]string{}
+	if tc.Tenant != "" {
+		headers[tc.TenantHeader] = tc.Tenant
+	}
+	if tc.Replica != nil {
+		headers[thanosReplicaHeader] = strconv.FormatUint(*tc.Replica, 10)
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
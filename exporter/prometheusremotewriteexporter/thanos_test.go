@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+)
+
+func TestThanosHeaders(t *testing.T) {
+	var replica uint64
+	tests := []struct {
+		name        string
+		backend     string
+		thanos      *ThanosConfig
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "no_backend",
+			wantHeaders: map[string]string{"X-Custom": "value", "THANOS-TENANT": "", "THANOS-REPLICA": ""},
+		},
+		{
+			name:        "default_tenant",
+			backend:     backendThanos,
+			thanos:      &ThanosConfig{},
+			wantHeaders: map[string]string{"X-Custom": "value", "THANOS-TENANT": "", "THANOS-REPLICA": ""},
+		},
+		{
+			name:        "tenant",
+			backend:     backendThanos,
+			thanos:      &ThanosConfig{Tenant: "team-a"},
+			wantHeaders: map[string]string{"X-Custom": "value", "THANOS-TENANT": "team-a"},
+		},
+		{
+			name:        "replica",
+			backend:     backendThanos,
+			thanos:      &ThanosConfig{Tenant: "team-a", Replica: &replica},
+			wantHeaders: map[string]string{"X-Custom": "value", "THANOS-TENANT": "team-a", "THANOS-REPLICA": "0"},
+		},
+		{
+			name:        "custom_tenant_header",
+			backend:     backendThanos,
+			thanos:      &ThanosConfig{Tenant: "team-a", TenantHeader: "X-Tenant"},
+			wantHeaders: map[string]string{"X-Custom": "value", "X-Tenant": "team-a", "THANOS-TENANT": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan http.Header, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				received <- r.Header.Clone()
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.ClientConfig.Endpoint = server.URL
+			cfg.ClientConfig.Headers = map[string]configopaque.String{"X-Custom": "value"}
+			cfg.Backend = tt.backend
+			cfg.Thanos = tt.thanos
+			require.NoError(t, cfg.Validate())
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+			require.NoError(t, err)
+			require.NoError(t, prwe.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, prwe.Shutdown(context.Background()))
+			}()
+
+			require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(validMetrics1[validIntGauge])))
+			headers := <-received
+			for name, want := range tt.wantHeaders {
+				assert.Equal(t, want, headers.Get(name), name)
+			}
+		})
+	}
+}
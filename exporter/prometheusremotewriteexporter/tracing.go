@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"net/url"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const sendSpanName = "prometheusremotewrite/send"
+
+// The attributes of the span of every POST, next to the URL and the HTTP status code.
+const (
+	seriesCountAttribute = "prometheusremotewrite.series_count"
+	compressionAttribute = "prometheusremotewrite.compression"
+	attemptAttribute     = "prometheusremotewrite.attempt"
+)
+
+// startSendSpan starts the span of a single POST of a request, whose series are compressed with contentEncoding,
+// or not compressed when it is empty. attempt counts the attempts to send the request, starting at 1.
+func (prwe *prwExporter) startSendSpan(ctx context.Context, endpointURL *url.URL, numSeries int, contentEncoding string, attempt int) (context.Context, trace.Span) {
+	if prwe.tracer == nil {
+		return ctx, noop.Span{}
+	}
+	compression := contentEncoding
+	if compression == "" {
+		compression = "none"
+	}
+	return prwe.tracer.Start(ctx, sendSpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("url.full", endpointURL.String()),
+			attribute.Int(seriesCountAttribute, numSeries),
+			attribute.String(compressionAttribute, compression),
+			attribute.Int(attemptAttribute, attempt),
+		))
+}
+
+// endSendSpan records the outcome of the POST and ends its span. statusCode is 0 when no response was received.
+func endSendSpan(span trace.Span, statusCode int, err error) {
+	if statusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
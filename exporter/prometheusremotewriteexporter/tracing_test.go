@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSendSpans(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// The first attempt fails, the retry succeeds.
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() {
+		require.NoError(t, tracerProvider.Shutdown(context.Background()))
+	}()
+	set := exportertest.NewNopSettings()
+	set.TracerProvider = tracerProvider
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.ClientConfig.Endpoint = server.URL
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.RandomizationFactor = 0
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, set)
+	require.NoError(t, err)
+	require.NoError(t, prwe.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, prwe.Shutdown(context.Background()))
+	}()
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "PushMetrics")
+	writeReq := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		{Labels: []prompb.Label{{Name: "__name__", Value: "first"}}, Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}}},
+		{Labels: []prompb.Label{{Name: "__name__", Value: "second"}}, Samples: []prompb.Sample{{Value: 2, Timestamp: 1000}}},
+	}}
+	require.NoError(t, prwe.execute(ctx, writeReq))
+	parent.End()
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == sendSpanName {
+			spans = append(spans, span)
+		}
+	}
+	require.Len(t, spans, 2, "a span is expected for every POST")
+	for i, wantStatus := range []int{http.StatusServiceUnavailable, http.StatusNoContent} {
+		span := spans[i]
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		assert.ElementsMatch(t, []attribute.KeyValue{
+			attribute.String("url.full", server.URL),
+			attribute.Int(seriesCountAttribute, 2),
+			attribute.String(compressionAttribute, "snappy"),
+			attribute.Int(attemptAttribute, i+1),
+			attribute.Int("http.response.status_code", wantStatus),
+		}, span.Attributes())
+	}
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
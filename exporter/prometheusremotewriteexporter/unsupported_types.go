@@ -0,0 +1,40 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
+)
+
+const (
+	// unsupportedTypePolicyDrop counts the metrics whose type can't be translated and translates the others, as
+	// partial_translation_policy allows. It is the default.
+	unsupportedTypePolicyDrop = "drop"
+	// unsupportedTypePolicyError rejects the batches holding metrics whose type can't be translated.
+	unsupportedTypePolicyError = "error"
+)
+
+// reportUnsupportedMetricTypes logs and counts the metrics that weren't translated because of their type, out of
+// the translation errors in err. It returns whether the batch must be rejected because of them, otherwise they
+// are handled like the other translation errors.
+func (prwe *prwExporter) reportUnsupportedMetricTypes(ctx context.Context, err error) bool {
+	found := false
+	for _, e := range multierr.Errors(err) {
+		var unsupportedErr *prometheusremotewrite.UnsupportedMetricTypeError
+		if !errors.As(e, &unsupportedErr) {
+			continue
+		}
+		found = true
+		prwe.settings.Logger.Warn("metric has an unsupported type, it isn't translated",
+			zap.String("metric", unsupportedErr.MetricName), zap.String("type", unsupportedErr.Type.String()))
+		prwe.telemetry.recordUnsupportedMetric(ctx, unsupportedErr.Type.String())
+	}
+	return found && prwe.unsupportedTypePolicy == unsupportedTypePolicyError
+}
@@ -0,0 +1,105 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// unsupportedMetricsTelemetry counts the recorded unsupported metrics by type, and the translation failures, and
+// discards the rest of the telemetry.
+type unsupportedMetricsTelemetry struct {
+	nopTelemetry
+	unsupported         map[string]int
+	translationFailures int
+}
+
+func (u *unsupportedMetricsTelemetry) recordUnsupportedMetric(_ context.Context, metricType string) {
+	u.unsupported[metricType]++
+}
+
+func (u *unsupportedMetricsTelemetry) recordTranslationFailure(context.Context) {
+	u.translationFailures++
+}
+
+func TestPushMetricsUnsupportedTypePolicy(t *testing.T) {
+	// A metric whose type was never set, next to one that can be translated.
+	untyped := pmetric.NewMetric()
+	untyped.SetName("untyped")
+	gauge := pmetric.NewMetric()
+	gauge.SetName("gauge")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+
+	tests := []struct {
+		name                     string
+		policy                   string
+		partialTranslationPolicy string
+		wantExported             []string
+		wantTranslationFailures  int
+		wantErr                  bool
+	}{
+		{name: "default", wantExported: []string{"gauge"}, wantTranslationFailures: 1},
+		{name: "drop", policy: unsupportedTypePolicyDrop, wantExported: []string{"gauge"}, wantTranslationFailures: 1},
+		{
+			name:                     "drop with drop_batch",
+			policy:                   unsupportedTypePolicyDrop,
+			partialTranslationPolicy: partialTranslationPolicyDropBatch,
+			wantTranslationFailures:  1,
+			wantErr:                  true,
+		},
+		{name: "error", policy: unsupportedTypePolicyError, wantTranslationFailures: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exported []string
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						exported = append(exported, seriesMetricName(&ts))
+					}
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.UnsupportedTypePolicy = tt.policy
+			cfg.PartialTranslationPolicy = tt.partialTranslationPolicy
+			require.NoError(t, cfg.Validate())
+			set := exportertest.NewNopSettings()
+			core, logs := observer.New(zapcore.WarnLevel)
+			set.Logger = zap.New(core)
+			prwe, err := newPRWExporter(cfg, set, WithExportSink(sink))
+			require.NoError(t, err)
+			tel := &unsupportedMetricsTelemetry{unsupported: map[string]int{}}
+			prwe.telemetry = tel
+
+			err = prwe.PushMetrics(context.Background(), getMetricsFromMetricList(untyped, gauge))
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, consumererror.IsPermanent(err))
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantExported, exported)
+			assert.Equal(t, map[string]int{"Empty": 1}, tel.unsupported)
+			assert.Equal(t, tt.wantTranslationFailures, tel.translationFailures)
+
+			entries := logs.FilterMessage("metric has an unsupported type, it isn't translated").All()
+			require.Len(t, entries, 1)
+			assert.Equal(t, map[string]any{"metric": "untyped", "type": "Empty"}, entries[0].ContextMap())
+		})
+	}
+}
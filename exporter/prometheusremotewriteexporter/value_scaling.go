@@ -0,0 +1,142 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// ValueScalingRule multiplies the values of the metrics whose name matches a pattern, to convert their unit for
+// example.
+type ValueScalingRule struct {
+	// MetricNamePattern is a regular expression the whole OTel metric name, before it is translated, must match.
+	MetricNamePattern string `mapstructure:"metric_name_pattern"`
+
+	// Multiplier is the factor the sample values of the matching series are multiplied by.
+	Multiplier float64 `mapstructure:"multiplier"`
+}
+
+// compileValueScalingRules compiles the patterns of rules, anchored so that they match whole metric names.
+func compileValueScalingRules(rules []ValueScalingRule) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(rules))
+	for _, rule := range rules {
+		pattern, err := regexp.Compile("^(?:" + rule.MetricNamePattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid value_scaling metric_name_pattern %q: %w", rule.MetricNamePattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// valueScaler multiplies the values of the metrics by the multiplier of the first rule their name matches. The
+// metrics matching no rule are left as they are.
+type valueScaler struct {
+	rules    []ValueScalingRule
+	patterns []*regexp.Regexp
+}
+
+func newValueScaler(rules []ValueScalingRule) (*valueScaler, error) {
+	patterns, err := compileValueScalingRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &valueScaler{rules: rules, patterns: patterns}, nil
+}
+
+// scale multiplies the values of the data points of md in place, matching the OTel name of their metric. Only
+// the values in the unit of the metric are scaled: those of gauges and sums, the sums and bounds of histograms,
+// and the sums and quantile values of summaries, never the counts. The values that aren't finite, and those of the
+// data points flagged as having no recorded value, are left as they are, and so are exponential histograms.
+func (s *valueScaler) scale(md pmetric.Metrics) {
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metrics := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				if multiplier := s.multiplier(metric.Name()); multiplier != 1 {
+					scaleMetric(metric, multiplier)
+				}
+			}
+		}
+	}
+}
+
+func scaleMetric(metric pmetric.Metric, multiplier float64) {
+	//exhaustive:enforce
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		scaleNumberDataPoints(metric.Gauge().DataPoints(), multiplier)
+	case pmetric.MetricTypeSum:
+		scaleNumberDataPoints(metric.Sum().DataPoints(), multiplier)
+	case pmetric.MetricTypeHistogram:
+		dataPoints := metric.Histogram().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			dp := dataPoints.At(i)
+			if dp.Flags().NoRecordedValue() {
+				continue
+			}
+			if dp.HasSum() {
+				dp.SetSum(scaleValue(dp.Sum(), multiplier))
+			}
+			bounds := dp.ExplicitBounds()
+			for j := 0; j < bounds.Len(); j++ {
+				bounds.SetAt(j, scaleValue(bounds.At(j), multiplier))
+			}
+		}
+	case pmetric.MetricTypeSummary:
+		dataPoints := metric.Summary().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			dp := dataPoints.At(i)
+			if dp.Flags().NoRecordedValue() {
+				continue
+			}
+			dp.SetSum(scaleValue(dp.Sum(), multiplier))
+			quantiles := dp.QuantileValues()
+			for j := 0; j < quantiles.Len(); j++ {
+				quantiles.At(j).SetValue(scaleValue(quantiles.At(j).Value(), multiplier))
+			}
+		}
+	case pmetric.MetricTypeExponentialHistogram, pmetric.MetricTypeEmpty:
+	}
+}
+
+func scaleNumberDataPoints(dataPoints pmetric.NumberDataPointSlice, multiplier float64) {
+	for i := 0; i < dataPoints.Len(); i++ {
+		dp := dataPoints.At(i)
+		if dp.Flags().NoRecordedValue() {
+			continue
+		}
+		switch dp.ValueType() {
+		case pmetric.NumberDataPointValueTypeInt:
+			dp.SetDoubleValue(float64(dp.IntValue()) * multiplier)
+		case pmetric.NumberDataPointValueTypeDouble:
+			dp.SetDoubleValue(scaleValue(dp.DoubleValue(), multiplier))
+		}
+	}
+}
+
+// scaleValue returns value multiplied by multiplier, or value if it isn't finite.
+func scaleValue(value, multiplier float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+	return value * multiplier
+}
+
+// multiplier returns the multiplier of the first rule name matches, or 1.
+func (s *valueScaler) multiplier(name string) float64 {
+	for i, pattern := range s.patterns {
+		if pattern.MatchString(name) {
+			return s.rules[i].Multiplier
+		}
+	}
+	return 1
+}
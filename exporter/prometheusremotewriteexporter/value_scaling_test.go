@@ -0,0 +1,148 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsValueScaling(t *testing.T) {
+	got := map[string][]float64{}
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				name := seriesMetricName(&ts)
+				for _, sample := range ts.Samples {
+					got[name] = append(got[name], sample.Value)
+				}
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.AddMetricSuffixes = false
+	cfg.ValueScaling = []ValueScalingRule{
+		{MetricNamePattern: "memory_.*", Multiplier: 0.001},
+		// Only the first matching rule applies.
+		{MetricNamePattern: "memory_used", Multiplier: 1000},
+	}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+
+	gauge := func(name string, values ...float64) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName(name)
+		dps := metric.SetEmptyGauge().DataPoints()
+		for i, v := range values {
+			dp := dps.AppendEmpty()
+			dp.SetTimestamp(pcommon.Timestamp(i+1) * 1000000)
+			dp.SetDoubleValue(v)
+		}
+		return metric
+	}
+	md := getMetricsFromMetricList(
+		gauge("memory_used", 2048, math.Inf(1)),
+		gauge("cpu_used", 2048),
+	)
+	require.NoError(t, prwe.PushMetrics(context.Background(), md))
+
+	assert.Equal(t, []float64{2.048, math.Inf(1)}, got["memory_used"], "the values that aren't finite are left as they are")
+	assert.Equal(t, []float64{2048}, got["cpu_used"], "the unmatched metrics are left as they are")
+}
+
+func TestPushMetricsValueScalingHistograms(t *testing.T) {
+	type series struct {
+		name  string
+		le    string
+		value float64
+	}
+	var got []series
+	sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+		for _, req := range requests {
+			for _, ts := range req.Timeseries {
+				var le string
+				for _, label := range ts.Labels {
+					if label.Name == "le" || label.Name == "quantile" {
+						le = label.Value
+					}
+				}
+				for _, sample := range ts.Samples {
+					got = append(got, series{name: seriesMetricName(&ts), le: le, value: sample.Value})
+				}
+			}
+		}
+		return nil
+	})
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.TargetInfo.Enabled = false
+	cfg.AddMetricSuffixes = false
+	cfg.ValueScaling = []ValueScalingRule{{MetricNamePattern: "memory_.*", Multiplier: 0.001}}
+	require.NoError(t, cfg.Validate())
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+	require.NoError(t, err)
+
+	histogram := pmetric.NewMetric()
+	histogram.SetName("memory_allocations")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	hdp := histogram.Histogram().DataPoints().AppendEmpty()
+	hdp.SetTimestamp(pcommon.Timestamp(1000000))
+	hdp.SetCount(3)
+	hdp.SetSum(6000)
+	hdp.ExplicitBounds().FromRaw([]float64{1000})
+	hdp.BucketCounts().FromRaw([]uint64{1, 2})
+
+	summary := pmetric.NewMetric()
+	summary.SetName("memory_requests")
+	sdp := summary.SetEmptySummary().DataPoints().AppendEmpty()
+	sdp.SetTimestamp(pcommon.Timestamp(1000000))
+	sdp.SetCount(3)
+	sdp.SetSum(6000)
+	quantile := sdp.QuantileValues().AppendEmpty()
+	quantile.SetQuantile(0.5)
+	quantile.SetValue(2000)
+
+	require.NoError(t, prwe.PushMetrics(context.Background(), getMetricsFromMetricList(histogram, summary)))
+
+	// The counts are left as they are, the values in the unit of the metric and the bounds are scaled.
+	assert.ElementsMatch(t, []series{
+		{name: "memory_allocations_sum", value: 6},
+		{name: "memory_allocations_count", value: 3},
+		{name: "memory_allocations_bucket", le: "1", value: 1},
+		{name: "memory_allocations_bucket", le: "+Inf", value: 3},
+		{name: "memory_requests_sum", value: 6},
+		{name: "memory_requests_count", value: 3},
+		{name: "memory_requests", le: "0.5", value: 2},
+	}, got)
+}
+
+func TestValueScalerKeepsStaleMarkers(t *testing.T) {
+	scaler, err := newValueScaler([]ValueScalingRule{{MetricNamePattern: ".*", Multiplier: 2}})
+	require.NoError(t, err)
+	metric := pmetric.NewMetric()
+	metric.SetName("gauge")
+	dps := metric.SetEmptyGauge().DataPoints()
+	dps.AppendEmpty().SetIntValue(1)
+	stale := dps.AppendEmpty()
+	stale.SetDoubleValue(1)
+	stale.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+
+	md := getMetricsFromMetricList(metric)
+	scaler.scale(md)
+	dps = md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints()
+	assert.Equal(t, float64(2), dps.At(0).DoubleValue())
+	assert.Equal(t, float64(1), dps.At(1).DoubleValue(), "the data points without a recorded value are left as they are")
+}
@@ -5,11 +5,14 @@ package prometheusremotewriteexporter // import "github.com/open-telemetry/opent
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -20,18 +23,66 @@ import (
 	"go.uber.org/zap"
 )
 
+// walStore is the subset of *wal.Log used by prweWAL. It exists so that
+// tests can inject faults into the underlying storage.
+type walStore interface {
+	FirstIndex() (uint64, error)
+	LastIndex() (uint64, error)
+	Read(index uint64) ([]byte, error)
+	WriteBatch(b *wal.Batch) error
+	Sync() error
+	TruncateFront(index uint64) error
+	TruncateBack(index uint64) error
+	Close() error
+}
+
 type prweWAL struct {
 	mu        sync.Mutex // mu protects the fields below.
-	wal       *wal.Log
+	wal       walStore
 	walConfig *WALConfig
 	walPath   string
+	openStore func() (walStore, string, error)
 
 	exportSink func(ctx context.Context, reqL []*prompb.WriteRequest) error
+	telemetry  prwTelemetry
+	logger     *zap.Logger
 
 	stopOnce  sync.Once
 	stopChan  chan struct{}
 	rWALIndex *atomic.Uint64
 	wWALIndex *atomic.Uint64
+
+	// draining is closed on shutdown to stop reading entries, while the exports in progress complete.
+	draining  chan struct{}
+	drainOnce sync.Once
+	// runDone is closed once the entries are no longer read and exported, nil until the WAL is run.
+	runDone chan struct{}
+
+	// diskFullSince holds the time, in unix nanoseconds, at which a write
+	// last failed because the disk was full. Zero means writes are accepted.
+	diskFullSince atomic.Int64
+	// truncateNotBefore holds the time, in unix nanoseconds, before which
+	// exported entries are kept in the WAL instead of being truncated.
+	truncateNotBefore atomic.Int64
+	// replayUntil holds the index of the last entry found in the WAL on startup.
+	replayUntil atomic.Uint64
+	// replayed is closed once the entries found in the WAL on startup were exported.
+	replayed     chan struct{}
+	replayedOnce sync.Once
+	// ttlIndex holds the timestamp of the newest sample of the entries, maintained when they are written
+	// and rebuilt when the WAL is opened.
+	ttlIndex walTTLIndex
+	// manifestChecked tells whether the manifest of the WAL was checked, which is only done when it is first opened.
+	manifestChecked bool
+	// paused holds back the exports of the entries while exporting is paused, nil if it can't be.
+	paused *pauseGate
+	// higherPriority holds the WALs whose entries are exported before those of this WAL, when the series are
+	// split by priority.
+	higherPriority []*prweWAL
+	// priorityMaxWait bounds how long the entries wait for the WALs of higher priority to be drained.
+	priorityMaxWait time.Duration
+	// buffered is the number of entries read from the WAL that weren't exported yet.
+	buffered atomic.Int64
 }
 
 const (
@@ -43,6 +94,56 @@ type WALConfig struct {
 	Directory         string        `mapstructure:"directory"`
 	BufferSize        int           `mapstructure:"buffer_size"`
 	TruncateFrequency time.Duration `mapstructure:"truncate_frequency"`
+	// ReadChunkSizeBytes bounds the memory used to read WAL entries bigger than it: their time series
+	// are decoded and exported in chunks of at most this many encoded bytes. Zero disables chunking.
+	ReadChunkSizeBytes int `mapstructure:"read_chunk_size_bytes"`
+	// StartupTruncateDelay is how long after the WAL starts that truncation is deferred.
+	// Entries are still exported during the delay, but stay in the WAL until it elapses.
+	StartupTruncateDelay time.Duration `mapstructure:"startup_truncate_delay"`
+	// CorruptionPolicy controls what happens when the WAL is found corrupted on startup: "fail" returns
+	// an error, "quarantine" moves the corrupted WAL aside and starts with an empty one, and "repair"
+	// keeps the entries preceding the corruption. Defaults to "fail".
+	CorruptionPolicy string `mapstructure:"corruption_policy"`
+	// IncompatiblePolicy controls what happens when the manifest of the WAL found on startup describes entries
+	// this exporter can't read, such as ones written with checksums or another compression: "fail" returns an
+	// error, and "quarantine" moves the WAL aside and starts with an empty one. Defaults to "fail".
+	IncompatiblePolicy string `mapstructure:"incompatible_policy"`
+	// ReplayConcurrency bounds how many of the entries found in the WAL on startup are exported at once
+	// while they are replayed, below the number of consumers. Zero means the number of consumers.
+	ReplayConcurrency int `mapstructure:"replay_concurrency"`
+	// MaxEntryAge is the age of the newest sample of an entry above which the entry is dropped instead of
+	// being exported, checked before a batch is read and when the WAL is truncated. Zero means entries never expire.
+	MaxEntryAge time.Duration `mapstructure:"max_entry_age"`
+	// CompactOnShutdown merges the small entries left in the WAL when the exporter shuts down, so that fewer
+	// and larger entries are replayed on the next startup.
+	CompactOnShutdown bool `mapstructure:"compact_on_shutdown"`
+	// AuditSampleRate is the fraction of the entries of the WAL, between 0 and 1, that are decoded every
+	// TruncateFrequency to detect corrupted entries. Zero disables auditing.
+	AuditSampleRate float64 `mapstructure:"audit_sample_rate"`
+	// ShutdownStrategy controls what happens to the entries that weren't exported when the exporter shuts down:
+	// "oldest_first" leaves them to be exported from the oldest one on the next startup, and "newest_first"
+	// exports them from the newest one until the shutdown deadline. Defaults to "oldest_first".
+	ShutdownStrategy string `mapstructure:"shutdown_strategy"`
+	// ReuseReadBuffers decodes the entries read from the WAL into the requests of the previous exports, reusing
+	// the memory of their series, instead of allocating new ones, to reduce the allocations of large replays. The
+	// export sink must then not retain the requests it is handed once it returns.
+	ReuseReadBuffers bool `mapstructure:"reuse_read_buffers"`
+	// ReplayBeforeAccept holds back the metrics pushed while the entries found in the WAL on startup are
+	// exported, so that they are sent after them. Otherwise, they are written to the WAL as they come.
+	ReplayBeforeAccept bool `mapstructure:"replay_before_accept"`
+	// ReplayAcceptTimeout is how long after the WAL starts the metrics are held back at most, when
+	// ReplayBeforeAccept is set. Zero means until the replay completes.
+	ReplayAcceptTimeout time.Duration `mapstructure:"replay_accept_timeout"`
+	// MaxEntryBytes is the encoded size above which the requests aren't written to the WAL as they are, so that
+	// a single entry never needs more memory than that to be replayed. Zero means no limit.
+	MaxEntryBytes int `mapstructure:"max_entry_bytes"`
+	// EntrySizePolicy controls what happens to the requests bigger than MaxEntryBytes: "reject" fails the write
+	// with errEntryTooLarge, and "split" writes their series in several entries. Defaults to "reject".
+	EntrySizePolicy string `mapstructure:"entry_size_policy"`
+
+	// priority is the priority of the series written to the WAL, when they are split by priority. The WAL of
+	// the series of normal priority is the one used when they aren't.
+	priority string
 }
 
 func (wc *WALConfig) bufferSize() int {
@@ -59,6 +160,28 @@ func (wc *WALConfig) truncateFrequency() time.Duration {
 	return defaultWALTruncateFrequency
 }
 
+func (wc *WALConfig) corruptionPolicy() string {
+	if wc.CorruptionPolicy != "" {
+		return wc.CorruptionPolicy
+	}
+	return walCorruptionPolicyFail
+}
+
+func (wc *WALConfig) incompatiblePolicy() string {
+	if wc.IncompatiblePolicy != "" {
+		return wc.IncompatiblePolicy
+	}
+	return walIncompatiblePolicyFail
+}
+
+// path returns the directory holding the segments of the WAL.
+func (wc *WALConfig) path() string {
+	if wc.priority != "" && wc.priority != priorityNormal {
+		return filepath.Join(wc.Directory, "prom_remotewrite_"+wc.priority)
+	}
+	return filepath.Join(wc.Directory, "prom_remotewrite")
+}
+
 func newWAL(walConfig *WALConfig, exportSink func(context.Context, []*prompb.WriteRequest) error) *prweWAL {
 	if walConfig == nil {
 		// There are cases for which the WAL can be disabled.
@@ -69,14 +192,19 @@ func newWAL(walConfig *WALConfig, exportSink func(context.Context, []*prompb.Wri
 	return &prweWAL{
 		exportSink: exportSink,
 		walConfig:  walConfig,
+		openStore:  walConfig.openStore,
+		telemetry:  nopTelemetry{},
+		logger:     zap.NewNop(),
 		stopChan:   make(chan struct{}),
+		draining:   make(chan struct{}),
+		replayed:   make(chan struct{}),
 		rWALIndex:  &atomic.Uint64{},
 		wWALIndex:  &atomic.Uint64{},
 	}
 }
 
 func (wc *WALConfig) createWAL() (*wal.Log, string, error) {
-	walPath := filepath.Join(wc.Directory, "prom_remotewrite")
+	walPath := wc.path()
 	log, err := wal.Open(walPath, &wal.Options{
 		SegmentCacheSize: wc.bufferSize(),
 		NoCopy:           true,
@@ -87,22 +215,59 @@ func (wc *WALConfig) createWAL() (*wal.Log, string, error) {
 	return log, walPath, nil
 }
 
+func (wc *WALConfig) openStore() (walStore, string, error) {
+	log, walPath, err := wc.createWAL()
+	if err != nil {
+		return nil, "", err
+	}
+	return log, walPath, nil
+}
+
 var (
 	errAlreadyClosed = errors.New("already closed")
 	errNilWAL        = errors.New("wal is nil")
+	errDiskFull      = errors.New("wal directory is out of disk space, rejecting writes until space is freed")
+	// errUnsupportedWALCodec is returned when reading an entry compressed with a codec this build can't
+	// decompress, like one written by a newer version of the exporter.
+	errUnsupportedWALCodec = errors.New("unsupported WAL entry codec")
 )
 
-// retrieveWALIndices queries the WriteAheadLog for its current first and last indices.
+// retrieveWALIndices queries the WriteAheadLog for its current first and last indices. The manifest of the
+// WAL is checked the first time, and written if the WAL has none.
 func (prwe *prweWAL) retrieveWALIndices() (err error) {
 	prwe.mu.Lock()
 	defer prwe.mu.Unlock()
 
+	if prwe.manifestChecked {
+		return prwe.reopenWAL()
+	}
+	if err = prwe.loadManifest(); err != nil {
+		return err
+	}
+	if err = prwe.reopenWAL(); err != nil {
+		return err
+	}
+	// The WAL may have been replaced by an empty one, if it was corrupted.
+	if err = prwe.ensureManifest(); err != nil {
+		return err
+	}
+	prwe.manifestChecked = true
+	return nil
+}
+
+// reopenWAL closes and re-opens the underlying store, then reloads the read and
+// write indices from it. It must be called with prwe.mu held.
+func (prwe *prweWAL) reopenWAL() (err error) {
 	err = prwe.closeWAL()
 	if err != nil {
 		return err
 	}
 
-	log, walPath, err := prwe.walConfig.createWAL()
+	log, walPath, err := prwe.openStore()
+	recovered := errors.Is(err, wal.ErrCorrupt)
+	if recovered {
+		log, walPath, err = prwe.recoverCorruptedWAL(err)
+	}
 	if err != nil {
 		return err
 	}
@@ -123,6 +288,10 @@ func (prwe *prweWAL) retrieveWALIndices() (err error) {
 		return fmt.Errorf("prometheusremotewriteexporter: failed to retrieve the last WAL index: %w", err)
 	}
 	prwe.wWALIndex.Store(wIndex)
+	if recovered {
+		prwe.ttlIndex = walTTLIndex{}
+	}
+	prwe.rebuildTTLIndex()
 	return nil
 }
 
@@ -145,18 +314,24 @@ func (prwe *prweWAL) run(ctx context.Context) (err error) {
 	if err != nil {
 		return
 	}
+	prwe.logger = logger
 
 	if err = prwe.retrieveWALIndices(); err != nil {
 		logger.Error("unable to start write-ahead log", zap.Error(err))
 		return
 	}
+	prwe.truncateNotBefore.Store(time.Now().Add(prwe.walConfig.StartupTruncateDelay).UnixNano())
+	prwe.startReplay()
 
 	runCtx, cancel := context.WithCancel(ctx)
+	runDone := make(chan struct{})
+	prwe.runDone = runDone
 
 	// Start the process of exporting but wait until the exporting has started.
 	waitUntilStartedCh := make(chan bool)
 	go func() {
 		signalStart := func() { close(waitUntilStartedCh) }
+		defer close(runDone)
 		defer cancel()
 		for {
 			select {
@@ -164,10 +339,34 @@ func (prwe *prweWAL) run(ctx context.Context) (err error) {
 				return
 			case <-prwe.stopChan:
 				return
+			case <-prwe.draining:
+				return
 			default:
 				err := prwe.continuallyPopWALThenExport(runCtx, signalStart)
 				signalStart = func() {}
+				if errors.Is(err, errRetryBudgetExhausted) {
+					// Wait for the budget to refill before sending the entries again.
+					logger.Warn("retry budget exhausted, exporting the WAL entries again later", zap.Error(err))
+					select {
+					case <-runCtx.Done():
+						return
+					case <-prwe.stopChan:
+						return
+					case <-prwe.draining:
+						return
+					case <-time.After(prwe.walConfig.truncateFrequency()):
+					}
+					continue
+				}
 				if err != nil {
+					select {
+					case <-prwe.stopChan:
+						// The read was interrupted by the WAL being stopped, it isn't re-opened.
+						return
+					case <-prwe.draining:
+						return
+					default:
+					}
 					// log err
 					logger.Error("error processing WAL entries", zap.Error(err))
 					// Restart WAL
@@ -180,6 +379,9 @@ func (prwe *prweWAL) run(ctx context.Context) (err error) {
 		}
 	}()
 	<-waitUntilStartedCh
+	if prwe.walConfig.AuditSampleRate > 0 {
+		go prwe.auditPeriodically(runCtx)
+	}
 	return nil
 }
 
@@ -190,9 +392,32 @@ func (prwe *prweWAL) run(ctx context.Context) (err error) {
 // it last read from.
 func (prwe *prweWAL) continuallyPopWALThenExport(ctx context.Context, signalStart func()) (err error) {
 	var reqL []*prompb.WriteRequest
+	// sourceIDs holds the distinct source IDs of the entries being exported, to tell which ones failed.
+	var sourceIDs []string
+	defer func() {
+		// Deferred first to run last, once the remaining requests were flushed.
+		err = withWALSourceIDs(err, sourceIDs)
+	}()
 	defer func() {
 		// Keeping it within a closure to ensure that the later
 		// updated value of reqL is always flushed to disk.
+		if prwe.paused.resumedChan() != nil {
+			// The requests weren't truncated from the WAL, they are exported once resumed or replayed.
+			return
+		}
+		if errors.Is(err, errRetryBudgetExhausted) {
+			// The read index was moved back, the requests are read from the WAL again.
+			return
+		}
+		select {
+		case <-prwe.draining:
+			// The requests are truncated from the WAL once exported, so that they aren't sent again on restart.
+			if errL := prwe.exportThenFrontTruncateWAL(ctx, reqL); errL != nil {
+				err = multierr.Append(err, errL)
+			}
+			return
+		default:
+		}
 		if errL := prwe.exportSink(ctx, reqL); errL != nil {
 			err = multierr.Append(err, errL)
 		}
@@ -211,6 +436,8 @@ func (prwe *prweWAL) continuallyPopWALThenExport(ctx context.Context, signalStar
 
 	signalStart()
 
+	// No entry was read by this call yet.
+	prwe.buffered.Store(0)
 	maxCountPerUpload := prwe.walConfig.bufferSize()
 	for {
 		select {
@@ -218,15 +445,51 @@ func (prwe *prweWAL) continuallyPopWALThenExport(ctx context.Context, signalStar
 			return ctx.Err()
 		case <-prwe.stopChan:
 			return nil
+		case <-prwe.draining:
+			return nil
 		default:
 		}
 
+		if len(reqL) == 0 {
+			prwe.expireEntriesBeforeRead()
+		}
+		var protoBlob []byte
+		index := prwe.rWALIndex.Load()
+		protoBlob, err = prwe.readFromWAL(ctx, index)
+		if err != nil {
+			return err
+		}
+		var sourceID string
+		if sourceID, protoBlob = splitWALSourceID(protoBlob); sourceID != "" {
+			prwe.logger.Debug("exporting WAL entry", zap.Uint64("index", index), zap.String("source_id", sourceID))
+		}
+		if chunkSize := prwe.walConfig.ReadChunkSizeBytes; chunkSize > 0 && len(protoBlob) > chunkSize {
+			// Export the entries read so far to keep them in order, then stream the large one.
+			if err = prwe.exportThenFrontTruncateWAL(ctx, reqL); err != nil {
+				return err
+			}
+			reqL = reqL[:0]
+			sourceIDs = appendWALSourceID(sourceIDs[:0], sourceID)
+			prwe.buffered.Store(0)
+			if err = prwe.exportEntryInChunks(ctx, protoBlob); err != nil {
+				return err
+			}
+			sourceIDs = sourceIDs[:0]
+			continue
+		}
+		sourceIDs = appendWALSourceID(sourceIDs, sourceID)
+
 		var req *prompb.WriteRequest
-		req, err = prwe.readPrompbFromWAL(ctx, prwe.rWALIndex.Load())
+		if prwe.walConfig.ReuseReadBuffers {
+			req, err = prwe.decodeWALEntryInto(protoBlob, reusableRequest(reqL))
+		} else {
+			req, err = prwe.decodeWALEntry(protoBlob)
+		}
 		if err != nil {
 			return err
 		}
 		reqL = append(reqL, req)
+		prwe.buffered.Store(int64(len(reqL)))
 
 		var shouldExport bool
 		select {
@@ -249,6 +512,8 @@ func (prwe *prweWAL) continuallyPopWALThenExport(ctx context.Context, signalStar
 		}
 		// Reset but reuse the write requests slice.
 		reqL = reqL[:0]
+		sourceIDs = sourceIDs[:0]
+		prwe.buffered.Store(0)
 	}
 }
 
@@ -261,7 +526,7 @@ func (prwe *prweWAL) closeWAL() error {
 	return nil
 }
 
-func (prwe *prweWAL) syncAndTruncateFront() error {
+func (prwe *prweWAL) syncAndTruncateFront(ctx context.Context) error {
 	prwe.mu.Lock()
 	defer prwe.mu.Unlock()
 
@@ -273,14 +538,52 @@ func (prwe *prweWAL) syncAndTruncateFront() error {
 	if err := prwe.wal.Sync(); err != nil {
 		return err
 	}
+	prwe.expireEntries(time.Now())
+	prwe.recordOldestEntryAge(ctx)
+	if time.Now().UnixNano() < prwe.truncateNotBefore.Load() {
+		// Still within the startup grace period, keep the exported entries around.
+		return nil
+	}
 	// Truncate the WAL from the front for the entries that we already
 	// read from the WAL and had already exported.
-	if err := prwe.wal.TruncateFront(prwe.rWALIndex.Load()); err != nil && !errors.Is(err, wal.ErrOutOfRange) {
-		return err
+	index := prwe.rWALIndex.Load()
+	if last, err := prwe.wal.LastIndex(); err == nil && last > 0 && index > last {
+		// The WAL can't be emptied, keep only its last entry.
+		index = last
+	}
+	if err := prwe.wal.TruncateFront(index); err != nil {
+		if !errors.Is(err, wal.ErrOutOfRange) {
+			return err
+		}
+	} else {
+		prwe.ttlIndex.truncateFront(index)
+		prwe.telemetry.recordWALTruncation(ctx, index)
+		prwe.logger.Debug("truncated the front of the WAL", zap.Uint64("index", index))
 	}
+	// Truncating may have freed up space, so let the next write probe the disk.
+	prwe.diskFullSince.Store(0)
 	return nil
 }
 
+// recordOldestEntryAge records the age of the newest sample of the entry at the read index, which is the
+// oldest entry that hasn't been exported yet, or 0 when all the entries were exported. The caller must hold
+// prwe.mu.
+func (prwe *prweWAL) recordOldestEntryAge(ctx context.Context) {
+	var age time.Duration
+	// Like for readFromWAL, the read index of an empty WAL is 0 but its first entry is 1.
+	newest, err := prwe.entryNewestTimestamp(max(prwe.rWALIndex.Load(), 1))
+	switch {
+	case err == nil:
+		if newest != walEntryWithoutSamples {
+			age = max(time.Since(time.UnixMilli(newest)), 0)
+		}
+	case !errors.Is(err, wal.ErrNotFound):
+		prwe.logger.Debug("failed to read the oldest WAL entry", zap.Error(err))
+		return
+	}
+	prwe.telemetry.recordWALOldestEntryAge(ctx, age)
+}
+
 func (prwe *prweWAL) exportThenFrontTruncateWAL(ctx context.Context, reqL []*prompb.WriteRequest) error {
 	if len(reqL) == 0 {
 		return nil
@@ -288,43 +591,397 @@ func (prwe *prweWAL) exportThenFrontTruncateWAL(ctx context.Context, reqL []*pro
 	if cErr := ctx.Err(); cErr != nil {
 		return nil
 	}
+	if !prwe.waitUntilResumed(ctx) || !prwe.waitForHigherPriorities(ctx) {
+		return nil
+	}
 
-	if errL := prwe.exportSink(ctx, reqL); errL != nil {
+	firstIndex := prwe.rWALIndex.Load() - uint64(len(reqL))
+	if errL := prwe.exportBatch(ctx, reqL); errL != nil {
+		if errors.Is(errL, errRetryBudgetExhausted) {
+			// Some requests weren't sent, keep them in the WAL to read them again. The WAL is read in order, so the
+			// requests following the first unsent one are read again too, and those of them that were sent are
+			// sent again: the entries are sent at least once.
+			prwe.rWALIndex.Store(firstIndex + uint64(firstUnsentRequest(errL, reqL)))
+		}
 		return errL
 	}
-	if err := prwe.syncAndTruncateFront(); err != nil {
+	if err := prwe.syncAndTruncateFront(ctx); err != nil {
 		return err
 	}
 	// Reset by retrieving the respective read and write WAL indices.
 	return prwe.retrieveWALIndices()
 }
 
+// firstUnsentRequest returns the position in reqL of the first request that err reports as not sent since the retry
+// budget was exhausted, or 0 when it can't tell them apart, as when the requests were split before being sent.
+func firstUnsentRequest(err error, reqL []*prompb.WriteRequest) int {
+	unsent := make(map[*prompb.WriteRequest]struct{})
+	for _, e := range multierr.Errors(err) {
+		var unsentErr *unsentRequestError
+		if errors.As(e, &unsentErr) {
+			unsent[unsentErr.request] = struct{}{}
+		}
+	}
+	for i, req := range reqL {
+		if _, found := unsent[req]; found {
+			return i
+		}
+	}
+	return 0
+}
+
+// exportBatch exports the requests just read from the WAL. The entries that were in the WAL on startup are
+// handed to the export sink at most walConfig.ReplayConcurrency at a time, so that replaying a large WAL
+// doesn't send as many requests at once as the consumers allow.
+func (prwe *prweWAL) exportBatch(ctx context.Context, reqL []*prompb.WriteRequest) error {
+	limit := prwe.walConfig.ReplayConcurrency
+	replayUntil := prwe.replayUntil.Load()
+	// The read index was moved past the requests already.
+	firstIndex := prwe.rWALIndex.Load() - uint64(len(reqL))
+	if limit <= 0 || replayUntil == 0 || firstIndex > replayUntil {
+		if err := prwe.exportSink(ctx, reqL); err != nil {
+			return err
+		}
+		prwe.markReplayedIfDone()
+		return nil
+	}
+	for len(reqL) > 0 {
+		n := min(limit, len(reqL))
+		if err := prwe.exportSink(ctx, reqL[:n]); err != nil {
+			return err
+		}
+		reqL = reqL[n:]
+	}
+	prwe.markReplayedIfDone()
+	return nil
+}
+
+// exportEntryInChunks exports a single large WAL entry without decoding it all at once, then
+// truncates the WAL past it.
+func (prwe *prweWAL) exportEntryInChunks(ctx context.Context, protoBlob []byte) error {
+	if !prwe.waitUntilResumed(ctx) || !prwe.waitForHigherPriorities(ctx) {
+		return nil
+	}
+	err := decodeWriteRequestInChunks(protoBlob, prwe.walConfig.ReadChunkSizeBytes, func(req *prompb.WriteRequest) error {
+		return prwe.exportSink(ctx, []*prompb.WriteRequest{req})
+	})
+	if err != nil {
+		return err
+	}
+	prwe.rWALIndex.Add(1)
+	prwe.markReplayedIfDone()
+	if err = prwe.syncAndTruncateFront(ctx); err != nil {
+		return err
+	}
+	return prwe.retrieveWALIndices()
+}
+
+// decodeWriteRequestInChunks decodes the proto encoded prompb.WriteRequest in protoBlob into requests
+// holding at most chunkSizeBytes of encoded time series each, a single larger time series aside. Every
+// chunk is handed to fn before the next one is decoded, so that the whole request is never materialized.
+// Metadata is sent along with the chunk that is being filled when it is decoded.
+func decodeWriteRequestInChunks(protoBlob []byte, chunkSizeBytes int, fn func(*prompb.WriteRequest) error) error {
+	chunk, chunkBytes := new(prompb.WriteRequest), 0
+	flush := func() error {
+		if len(chunk.Timeseries) == 0 && len(chunk.Metadata) == 0 {
+			return nil
+		}
+		err := fn(chunk)
+		chunk, chunkBytes = new(prompb.WriteRequest), 0
+		return err
+	}
+
+	for len(protoBlob) > 0 {
+		fieldNum, field, rest, err := nextProtoField(protoBlob)
+		if err != nil {
+			return err
+		}
+		protoBlob = rest
+
+		switch fieldNum {
+		case 1: // repeated TimeSeries timeseries = 1;
+			if chunkBytes > 0 && chunkBytes+len(field) > chunkSizeBytes {
+				if err = flush(); err != nil {
+					return err
+				}
+			}
+			var ts prompb.TimeSeries
+			if err = ts.Unmarshal(field); err != nil {
+				return err
+			}
+			chunk.Timeseries = append(chunk.Timeseries, ts)
+			chunkBytes += len(field)
+		case 3: // repeated MetricMetadata metadata = 3;
+			var md prompb.MetricMetadata
+			if err = md.Unmarshal(field); err != nil {
+				return err
+			}
+			chunk.Metadata = append(chunk.Metadata, md)
+		}
+	}
+	return flush()
+}
+
+var errMalformedWALEntry = errors.New("malformed WAL entry")
+
+// nextProtoField splits the first field off a protobuf message. The returned field only holds the
+// payload of length-delimited fields, it is nil for the other wire types.
+func nextProtoField(b []byte) (fieldNum uint64, field, rest []byte, err error) {
+	key, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, nil, errMalformedWALEntry
+	}
+	b = b[n:]
+
+	switch key & 0x7 {
+	case 0: // varint
+		if _, n = binary.Uvarint(b); n <= 0 {
+			return 0, nil, nil, errMalformedWALEntry
+		}
+		return key >> 3, nil, b[n:], nil
+	case 1: // 64-bit
+		if len(b) < 8 {
+			return 0, nil, nil, errMalformedWALEntry
+		}
+		return key >> 3, nil, b[8:], nil
+	case 2: // length-delimited
+		length, n := binary.Uvarint(b)
+		if n <= 0 || length > uint64(len(b)-n) {
+			return 0, nil, nil, errMalformedWALEntry
+		}
+		end := n + int(length)
+		return key >> 3, b[n:end], b[end:], nil
+	case 5: // 32-bit
+		if len(b) < 4 {
+			return 0, nil, nil, errMalformedWALEntry
+		}
+		return key >> 3, nil, b[4:], nil
+	}
+	return 0, nil, nil, errMalformedWALEntry
+}
+
 // persistToWAL is the routine that'll be hooked into the exporter's receiving side and it'll
 // write them to the Write-Ahead-Log so that shutdowns won't lose data, and that the routine that
-// reads from the WAL can then process the previously serialized requests.
-func (prwe *prweWAL) persistToWAL(requests []*prompb.WriteRequest) error {
+// reads from the WAL can then process the previously serialized requests. The entries are tagged
+// with the source ID of ctx, if any.
+//
+// If the disk fills up, the WAL enters a degraded mode where writes are rejected with errDiskFull,
+// while the entries already in the WAL keep being exported. Writes are accepted again once a
+// truncation frees up space, or the disk is probed again after the truncate frequency elapses.
+func (prwe *prweWAL) persistToWAL(ctx context.Context, requests []*prompb.WriteRequest) error {
 	prwe.mu.Lock()
 	defer prwe.mu.Unlock()
 
+	if since := prwe.diskFullSince.Load(); since != 0 && time.Since(time.Unix(0, since)) < prwe.walConfig.truncateFrequency() {
+		return errDiskFull
+	}
+	if prwe.wal == nil {
+		// A previous failed write could not re-open the WAL, try again.
+		if err := prwe.reopenWAL(); err != nil {
+			return err
+		}
+	}
+
+	entries, err := prwe.walConfig.encodeWALEntries(walSourceIDFromContext(ctx), requests)
+	if err != nil {
+		return err
+	}
+
 	// Write all the requests to the WAL in a batch.
 	batch := new(wal.Batch)
-	for _, req := range requests {
-		protoBlob, err := proto.Marshal(req)
+	for _, entry := range entries {
+		wIndex := prwe.wWALIndex.Add(1)
+		batch.Write(wIndex, entry.blob)
+	}
+
+	err = prwe.wal.WriteBatch(batch)
+	if err == nil {
+		firstIndex := prwe.wWALIndex.Load() - uint64(len(entries)) + 1
+		for i, entry := range entries {
+			prwe.ttlIndex.add(firstIndex+uint64(i), entry.newest)
+		}
+		prwe.diskFullSince.Store(0)
+		return nil
+	}
+
+	// A failed batch can leave both our write index and the store's in-memory
+	// state ahead of what is on disk, so re-open the WAL to resynchronize them.
+	if rErr := prwe.reopenWAL(); rErr != nil {
+		err = errors.Join(err, rErr)
+	}
+	if !errors.Is(err, syscall.ENOSPC) {
+		return err
+	}
+	if prwe.diskFullSince.Swap(time.Now().UnixNano()) == 0 {
+		prwe.telemetry.recordWALDiskFull(context.Background())
+	}
+	return errDiskFull
+}
+
+func (prwe *prweWAL) readPrompbFromWAL(ctx context.Context, index uint64) (*prompb.WriteRequest, error) {
+	req, _, err := prwe.readWALEntry(ctx, index)
+	return req, err
+}
+
+// readWALEntry reads the request stored at index along with the source ID it was tagged with, if any.
+func (prwe *prweWAL) readWALEntry(ctx context.Context, index uint64) (*prompb.WriteRequest, string, error) {
+	protoBlob, err := prwe.readFromWAL(ctx, index)
+	if err != nil {
+		return nil, "", err
+	}
+	sourceID, protoBlob := splitWALSourceID(protoBlob)
+	req, err := prwe.decodeWALEntry(protoBlob)
+	return req, sourceID, err
+}
+
+// walSourceIDField is the protobuf field number the source ID of a WAL entry is stored under. It is
+// written before the fields of the prompb.WriteRequest, and removed when the entry is read.
+const walSourceIDField = 100000
+
+// prependWALSourceID returns protoBlob preceded by the walSourceIDField holding sourceID.
+func prependWALSourceID(sourceID string, protoBlob []byte) []byte {
+	b := make([]byte, 0, 2*binary.MaxVarintLen64+len(sourceID)+len(protoBlob))
+	b = binary.AppendUvarint(b, walSourceIDField<<3|2) // length-delimited
+	b = binary.AppendUvarint(b, uint64(len(sourceID)))
+	b = append(b, sourceID...)
+	return append(b, protoBlob...)
+}
+
+// splitWALSourceID splits the source ID off a WAL entry. Entries that weren't tagged are returned as is.
+func splitWALSourceID(protoBlob []byte) (sourceID string, rest []byte) {
+	fieldNum, field, rest, err := nextProtoField(protoBlob)
+	if err != nil || fieldNum != walSourceIDField {
+		return "", protoBlob
+	}
+	return string(field), rest
+}
+
+// appendWALSourceID appends sourceID to sourceIDs unless it is empty or already in it.
+func appendWALSourceID(sourceIDs []string, sourceID string) []string {
+	if sourceID == "" || slices.Contains(sourceIDs, sourceID) {
+		return sourceIDs
+	}
+	return append(sourceIDs, sourceID)
+}
+
+// withWALSourceIDs returns err mentioning the source IDs of the WAL entries that failed to be exported, so that
+// the failure can be traced back to the pipelines they came from.
+func withWALSourceIDs(err error, sourceIDs []string) error {
+	if err == nil || len(sourceIDs) == 0 {
+		return err
+	}
+	return fmt.Errorf("exporting the WAL entries of the sources %q: %w", sourceIDs, err)
+}
+
+// walCodecField is the protobuf field number the name of the codec a WAL entry is compressed with is stored
+// under. It precedes the rest of the entry, source ID included. Entries without it aren't compressed.
+const walCodecField = 100001
+
+// supportedWALCodecs are the codecs the WAL entries can be read with, by name.
+var supportedWALCodecs = map[string]func([]byte) ([]byte, error){
+	walCompressionNone: func(b []byte) ([]byte, error) { return b, nil },
+}
+
+// splitWALCodec splits the codec header off a WAL entry and decompresses the rest of it, returning an error
+// wrapping errUnsupportedWALCodec if the codec isn't supported.
+func splitWALCodec(protoBlob []byte) ([]byte, error) {
+	fieldNum, field, rest, err := nextProtoField(protoBlob)
+	if err != nil || fieldNum != walCodecField {
+		return protoBlob, nil
+	}
+	decompress, ok := supportedWALCodecs[string(field)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, the entry may have been written by a newer version of the exporter", errUnsupportedWALCodec, field)
+	}
+	return decompress(rest)
+}
+
+// decodeWALEntry unmarshals an entry read from the WAL and moves the read index past it.
+func (prwe *prweWAL) decodeWALEntry(protoBlob []byte) (*prompb.WriteRequest, error) {
+	req := new(prompb.WriteRequest)
+	if err := proto.Unmarshal(protoBlob, req); err != nil {
+		return nil, err
+	}
+
+	// Now increment the WAL's read index.
+	prwe.rWALIndex.Add(1)
+
+	return req, nil
+}
+
+// decodeWALEntryInto is decodeWALEntry, decoding the entry into req with unmarshalWriteRequestInto.
+func (prwe *prweWAL) decodeWALEntryInto(protoBlob []byte, req *prompb.WriteRequest) (*prompb.WriteRequest, error) {
+	if err := unmarshalWriteRequestInto(protoBlob, req); err != nil {
+		return nil, err
+	}
+	prwe.rWALIndex.Add(1)
+	return req, nil
+}
+
+// reusableRequest returns the request following the end of reqL in its backing array, left over from the
+// requests exported before reqL was reset, or a new request if there is none.
+func reusableRequest(reqL []*prompb.WriteRequest) *prompb.WriteRequest {
+	if len(reqL) < cap(reqL) {
+		if req := reqL[:len(reqL)+1][len(reqL)]; req != nil {
+			return req
+		}
+	}
+	return new(prompb.WriteRequest)
+}
+
+// unmarshalWriteRequestInto decodes the proto encoded prompb.WriteRequest in protoBlob into req, overwriting
+// it. The series of req are reused, along with their labels, samples, exemplars and histograms, so that
+// decoding a request of a similar shape doesn't allocate them again.
+func unmarshalWriteRequestInto(protoBlob []byte, req *prompb.WriteRequest) error {
+	req.Timeseries, req.Metadata = req.Timeseries[:0], req.Metadata[:0]
+	for len(protoBlob) > 0 {
+		fieldNum, field, rest, err := nextProtoField(protoBlob)
 		if err != nil {
 			return err
 		}
-		wIndex := prwe.wWALIndex.Add(1)
-		batch.Write(wIndex, protoBlob)
-	}
+		protoBlob = rest
 
-	return prwe.wal.WriteBatch(batch)
+		switch fieldNum {
+		case 1: // repeated TimeSeries timeseries = 1;
+			if field == nil {
+				return errMalformedWALEntry
+			}
+			var ts *prompb.TimeSeries
+			if n := len(req.Timeseries); n < cap(req.Timeseries) {
+				req.Timeseries = req.Timeseries[:n+1]
+				ts = &req.Timeseries[n]
+				*ts = prompb.TimeSeries{
+					Labels:     ts.Labels[:0],
+					Samples:    ts.Samples[:0],
+					Exemplars:  ts.Exemplars[:0],
+					Histograms: ts.Histograms[:0],
+				}
+			} else {
+				req.Timeseries = append(req.Timeseries, prompb.TimeSeries{})
+				ts = &req.Timeseries[n]
+			}
+			if err = ts.Unmarshal(field); err != nil {
+				return err
+			}
+		case 3: // repeated MetricMetadata metadata = 3;
+			if field == nil {
+				return errMalformedWALEntry
+			}
+			req.Metadata = append(req.Metadata, prompb.MetricMetadata{})
+			if err = req.Metadata[len(req.Metadata)-1].Unmarshal(field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (prwe *prweWAL) readPrompbFromWAL(ctx context.Context, index uint64) (wreq *prompb.WriteRequest, err error) {
+// readFromWAL returns the proto encoded prompb.WriteRequest stored at index, waiting for it to be
+// written if necessary. It doesn't move the read index.
+func (prwe *prweWAL) readFromWAL(ctx context.Context, index uint64) (protoBlob []byte, err error) {
 	prwe.mu.Lock()
 	defer prwe.mu.Unlock()
 
-	var protoBlob []byte
 	for i := 0; i < 12; i++ {
 		// Firstly check if we've been terminated, then exit if so.
 		select {
@@ -332,6 +989,8 @@ func (prwe *prweWAL) readPrompbFromWAL(ctx context.Context, index uint64) (wreq
 			return nil, ctx.Err()
 		case <-prwe.stopChan:
 			return nil, fmt.Errorf("attempt to read from WAL after stopped")
+		case <-prwe.draining:
+			return nil, errWALDraining
 		default:
 		}
 
@@ -345,15 +1004,7 @@ func (prwe *prweWAL) readPrompbFromWAL(ctx context.Context, index uint64) (wreq
 
 		protoBlob, err = prwe.wal.Read(index)
 		if err == nil { // The read succeeded.
-			req := new(prompb.WriteRequest)
-			if err = proto.Unmarshal(protoBlob, req); err != nil {
-				return nil, err
-			}
-
-			// Now increment the WAL's read index.
-			prwe.rWALIndex.Add(1)
-
-			return req, nil
+			return splitWALCodec(protoBlob)
 		}
 
 		if !errors.Is(err, wal.ErrNotFound) {
@@ -392,6 +1043,14 @@ func (prwe *prweWAL) readPrompbFromWAL(ctx context.Context, index uint64) (wreq
 				wErr = ctx.Err()
 				return
 
+			case <-prwe.stopChan:
+				wErr = fmt.Errorf("attempt to read from WAL after stopped")
+				return
+
+			case <-prwe.draining:
+				wErr = errWALDraining
+				return
+
 			case event, ok := <-walWatcher.Events:
 				if !ok {
 					return
@@ -414,7 +1073,12 @@ func (prwe *prweWAL) readPrompbFromWAL(ctx context.Context, index uint64) (wreq
 			}
 		}()
 
-		if gerr := <-watchCh; gerr != nil {
+		// The lock is released while waiting, for the write to happen. The watcher was added beforehand, so
+		// that no write is missed.
+		prwe.mu.Unlock()
+		gerr := <-watchCh
+		prwe.mu.Lock()
+		if gerr != nil {
 			return nil, gerr
 		}
 
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/tidwall/wal"
+	"go.uber.org/zap"
+)
+
+// auditPeriodically audits the WAL every truncate_frequency until ctx is done or the WAL is stopped.
+func (prwe *prweWAL) auditPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(prwe.walConfig.truncateFrequency())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prwe.stopChan:
+			return
+		case <-ticker.C:
+			prwe.audit(ctx)
+		}
+	}
+}
+
+// audit decodes a random sample of the entries of the WAL, a fraction audit_sample_rate of them, and returns
+// the number of entries that failed to be read or decoded, which are recorded as audit failures. Entries are
+// read one at a time, so that the WAL keeps accepting writes during the audit.
+func (prwe *prweWAL) audit(ctx context.Context) int {
+	first, last, ok := prwe.auditRange()
+	if !ok {
+		return 0
+	}
+	failures := 0
+	for index := first; index <= last && ctx.Err() == nil; index++ {
+		if rand.Float64() >= prwe.walConfig.AuditSampleRate {
+			continue
+		}
+		err := prwe.auditEntry(index)
+		if err == nil || errors.Is(err, wal.ErrNotFound) {
+			// Entries truncated since the audit started aren't failures.
+			continue
+		}
+		failures++
+		prwe.logger.Warn("WAL audit found an entry that can't be decoded", zap.Uint64("index", index), zap.Error(err))
+	}
+	if failures > 0 {
+		prwe.telemetry.recordWALAuditFailures(ctx, failures)
+	}
+	return failures
+}
+
+// auditRange returns the indices of the first and last entries of the WAL, or false when it is empty or closed.
+func (prwe *prweWAL) auditRange() (first, last uint64, ok bool) {
+	prwe.mu.Lock()
+	defer prwe.mu.Unlock()
+	if prwe.wal == nil {
+		return 0, 0, false
+	}
+	first, err := prwe.wal.FirstIndex()
+	if err != nil {
+		return 0, 0, false
+	}
+	last, err = prwe.wal.LastIndex()
+	if err != nil || last == 0 {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// auditEntry reads and decodes the entry at index, without moving the read index.
+func (prwe *prweWAL) auditEntry(index uint64) error {
+	prwe.mu.Lock()
+	if prwe.wal == nil {
+		prwe.mu.Unlock()
+		return wal.ErrNotFound
+	}
+	protoBlob, err := prwe.wal.Read(index)
+	prwe.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if protoBlob, err = splitWALCodec(protoBlob); err != nil {
+		return err
+	}
+	_, protoBlob = splitWALSourceID(protoBlob)
+	return proto.Unmarshal(protoBlob, new(prompb.WriteRequest))
+}
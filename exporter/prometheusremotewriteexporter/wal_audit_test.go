@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/wal"
+	"go.uber.org/zap"
+)
+
+// auditFailuresTelemetry counts the recorded WAL audit failures and discards the rest of the telemetry.
+type auditFailuresTelemetry struct {
+	nopTelemetry
+	failures atomic.Int64
+}
+
+func (a *auditFailuresTelemetry) recordWALAuditFailures(_ context.Context, numEntries int) {
+	a.failures.Add(int64(numEntries))
+}
+
+func TestWALAudit(t *testing.T) {
+	newAuditedWAL := func(t *testing.T, sampleRate float64) (*prweWAL, *auditFailuresTelemetry) {
+		config := &WALConfig{Directory: t.TempDir(), TruncateFrequency: 10 * time.Millisecond, AuditSampleRate: sampleRate}
+		pwal := newWAL(config, doNothingExportSink)
+		tel := &auditFailuresTelemetry{}
+		pwal.telemetry = tel
+		pwal.logger = zap.NewNop()
+		require.NoError(t, pwal.retrieveWALIndices())
+		t.Cleanup(func() {
+			assert.NoError(t, pwal.stop())
+		})
+
+		req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "audited"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+		}}}
+		for i := 0; i < 10; i++ {
+			require.NoError(t, pwal.persistToWAL(context.Background(), []*prompb.WriteRequest{req}))
+		}
+		// A truncated varint, which can't be decoded.
+		batch := new(wal.Batch)
+		batch.Write(pwal.wWALIndex.Add(1), []byte{0xff, 0xff})
+		pwal.mu.Lock()
+		require.NoError(t, pwal.wal.WriteBatch(batch))
+		pwal.mu.Unlock()
+		return pwal, tel
+	}
+
+	t.Run("every entry", func(t *testing.T) {
+		pwal, tel := newAuditedWAL(t, 1)
+		assert.Equal(t, 1, pwal.audit(context.Background()))
+		assert.Equal(t, int64(1), tel.failures.Load())
+	})
+
+	t.Run("periodically", func(t *testing.T) {
+		pwal, tel := newAuditedWAL(t, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go pwal.auditPeriodically(ctx)
+		assert.Eventually(t, func() bool {
+			return tel.failures.Load() >= 2
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		pwal, tel := newAuditedWAL(t, 1)
+		pwal.mu.Lock()
+		require.NoError(t, pwal.closeWAL())
+		pwal.mu.Unlock()
+		assert.Equal(t, 0, pwal.audit(context.Background()))
+		assert.Zero(t, tel.failures.Load())
+	})
+}
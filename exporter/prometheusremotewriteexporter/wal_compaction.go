@@ -0,0 +1,146 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/tidwall/wal"
+	"go.uber.org/zap"
+)
+
+const (
+	// walCompactedEntryBytes is the size up to which entries are merged when the WAL is compacted. Entries
+	// bigger than it are kept as they are.
+	walCompactedEntryBytes = 1 << 20
+	// walCompactionMinTime is the time left before the shutdown deadline below which the WAL isn't compacted.
+	walCompactionMinTime = time.Second
+)
+
+// compactedEntryBytes returns the size up to which entries are merged, which doesn't exceed the size of the
+// entries read in chunks.
+func (wc *WALConfig) compactedEntryBytes() int {
+	if wc.ReadChunkSizeBytes > 0 {
+		return min(wc.ReadChunkSizeBytes, walCompactedEntryBytes)
+	}
+	return walCompactedEntryBytes
+}
+
+// compact merges the consecutive entries left in the WAL, tagged with the same source ID, so that the next
+// startup replays fewer and larger entries. It must be called once the WAL is stopped. The merged entries are
+// appended to the WAL before the original ones are truncated, so that an interruption leaves every sample in
+// the WAL. Compaction is skipped when the deadline of ctx is too close, and abandoned if it expires.
+func (prwe *prweWAL) compact(ctx context.Context) (err error) {
+	prwe.mu.Lock()
+	defer prwe.mu.Unlock()
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < walCompactionMinTime {
+		prwe.logger.Info("not enough time left to compact the WAL, skipping compaction")
+		return nil
+	}
+
+	store, _, err := prwe.openStore()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, store.Close())
+	}()
+
+	first, err := store.FirstIndex()
+	if err != nil {
+		return err
+	}
+	last, err := store.LastIndex()
+	if err != nil {
+		return err
+	}
+	if last == 0 || last-first < 1 {
+		return nil
+	}
+
+	maxBytes := prwe.walConfig.compactedEntryBytes()
+	next := last + 1
+	var (
+		merged         prompb.WriteRequest
+		mergedSize     int
+		mergedEntries  int
+		mergedSourceID string
+	)
+	flush := func() error {
+		if mergedEntries == 0 {
+			return nil
+		}
+		protoBlob, err := proto.Marshal(&merged)
+		if err != nil {
+			return err
+		}
+		if mergedSourceID != "" {
+			protoBlob = prependWALSourceID(mergedSourceID, protoBlob)
+		}
+		batch := new(wal.Batch)
+		batch.Write(next, protoBlob)
+		if err := store.WriteBatch(batch); err != nil {
+			return err
+		}
+		next++
+		merged, mergedSize, mergedEntries = prompb.WriteRequest{}, 0, 0
+		return nil
+	}
+	// abandon removes the merged entries written so far, leaving the WAL as it was.
+	abandon := func() error {
+		if next == last+1 {
+			return nil
+		}
+		if err := store.TruncateBack(last); err != nil {
+			return fmt.Errorf("failed to remove the compacted WAL entries: %w", err)
+		}
+		return nil
+	}
+
+	for index := first; index <= last; index++ {
+		if ctx.Err() != nil {
+			prwe.logger.Info("shutdown deadline reached, abandoning the compaction of the WAL")
+			return abandon()
+		}
+		protoBlob, err := store.Read(index)
+		if err == nil {
+			protoBlob, err = splitWALCodec(protoBlob)
+		}
+		if err != nil {
+			return errors.Join(err, abandon())
+		}
+		sourceID, protoBlob := splitWALSourceID(protoBlob)
+		if mergedEntries > 0 && (sourceID != mergedSourceID || mergedSize+len(protoBlob) > maxBytes) {
+			if err := flush(); err != nil {
+				return errors.Join(err, abandon())
+			}
+		}
+		req := new(prompb.WriteRequest)
+		if err := proto.Unmarshal(protoBlob, req); err != nil {
+			return errors.Join(err, abandon())
+		}
+		merged.Timeseries = append(merged.Timeseries, req.Timeseries...)
+		merged.Metadata = append(merged.Metadata, req.Metadata...)
+		mergedSize += len(protoBlob)
+		mergedEntries++
+		mergedSourceID = sourceID
+	}
+	if err := flush(); err != nil {
+		return errors.Join(err, abandon())
+	}
+	if err := store.Sync(); err != nil {
+		return errors.Join(err, abandon())
+	}
+	if err := store.TruncateFront(last + 1); err != nil {
+		return err
+	}
+	prwe.logger.Info("compacted the WAL", zap.Uint64("entries", last-first+1), zap.Uint64("compacted_entries", next-last-1))
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAllWALEntries returns the entries of the WAL in config.Directory, along with their source IDs.
+func readAllWALEntries(t *testing.T, config *WALConfig) (reqs []*prompb.WriteRequest, sourceIDs []string) {
+	pwal := newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	defer func() {
+		assert.NoError(t, pwal.stop())
+	}()
+	first, err := pwal.wal.FirstIndex()
+	require.NoError(t, err)
+	last, err := pwal.wal.LastIndex()
+	require.NoError(t, err)
+	for index := first; index <= last; index++ {
+		req, sourceID, err := pwal.readWALEntry(context.Background(), index)
+		require.NoError(t, err)
+		reqs, sourceIDs = append(reqs, req), append(sourceIDs, sourceID)
+	}
+	return reqs, sourceIDs
+}
+
+// cancelingWALStore wraps a walStore and calls cancel once readsLeft entries were read.
+type cancelingWALStore struct {
+	walStore
+	readsLeft int
+	cancel    func()
+}
+
+func (s *cancelingWALStore) Read(index uint64) ([]byte, error) {
+	if s.readsLeft--; s.readsLeft == 0 {
+		s.cancel()
+	}
+	return s.walStore.Read(index)
+}
+
+func TestWALCompactOnShutdown(t *testing.T) {
+	series := func(i int) prompb.TimeSeries {
+		return prompb.TimeSeries{
+			Labels:  []prompb.Label{{Name: "__name__", Value: fmt.Sprintf("metric_%d", i)}},
+			Samples: []prompb.Sample{{Value: float64(i), Timestamp: int64(1000 + i)}},
+		}
+	}
+	persist := func(t *testing.T, config *WALConfig) (*prweWAL, []prompb.TimeSeries) {
+		pwal := newWAL(config, doNothingExportSink)
+		require.NoError(t, pwal.retrieveWALIndices())
+		var all []prompb.TimeSeries
+		// Many small entries, the last ones tagged with a source ID.
+		for i := 0; i < 50; i++ {
+			ctx := context.Background()
+			if i >= 40 {
+				ctx = ContextWithWALSourceID(ctx, "pipeline")
+			}
+			all = append(all, series(i))
+			require.NoError(t, pwal.persistToWAL(ctx, []*prompb.WriteRequest{{Timeseries: []prompb.TimeSeries{series(i)}}}))
+		}
+		return pwal, all
+	}
+
+	t.Run("compacted", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir(), CompactOnShutdown: true}
+		pwal, all := persist(t, config)
+		prwe := &prwExporter{wal: pwal}
+		require.NoError(t, prwe.shutdownWALIfEnabled(context.Background()))
+
+		reqs, sourceIDs := readAllWALEntries(t, config)
+		// The entries are merged, apart from those with another source ID.
+		require.Len(t, reqs, 2)
+		assert.Equal(t, []string{"", "pipeline"}, sourceIDs)
+		var got []prompb.TimeSeries
+		for _, req := range reqs {
+			got = append(got, req.Timeseries...)
+		}
+		assert.Equal(t, all, got)
+	})
+
+	t.Run("merged up to the chunk size", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir(), CompactOnShutdown: true, ReadChunkSizeBytes: 100}
+		pwal, all := persist(t, config)
+		require.NoError(t, pwal.stop())
+		require.NoError(t, pwal.compact(context.Background()))
+
+		reqs, _ := readAllWALEntries(t, config)
+		assert.Greater(t, len(reqs), 2)
+		assert.Less(t, len(reqs), len(all))
+		var got []prompb.TimeSeries
+		for _, req := range reqs {
+			got = append(got, req.Timeseries...)
+		}
+		assert.Equal(t, all, got)
+	})
+
+	t.Run("skipped close to the deadline", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir(), CompactOnShutdown: true}
+		pwal, all := persist(t, config)
+		require.NoError(t, pwal.stop())
+		ctx, cancel := context.WithTimeout(context.Background(), walCompactionMinTime/2)
+		defer cancel()
+		require.NoError(t, pwal.compact(ctx))
+
+		reqs, _ := readAllWALEntries(t, config)
+		assert.Len(t, reqs, len(all))
+	})
+
+	t.Run("abandoned when the deadline expires", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir(), CompactOnShutdown: true, ReadChunkSizeBytes: 100}
+		pwal, all := persist(t, config)
+		require.NoError(t, pwal.stop())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// The context is canceled halfway through, once merged entries were written.
+		pwal.openStore = func() (walStore, string, error) {
+			store, path, err := config.openStore()
+			return &cancelingWALStore{walStore: store, readsLeft: len(all) / 2, cancel: cancel}, path, err
+		}
+		require.NoError(t, pwal.compact(ctx))
+
+		reqs, _ := readAllWALEntries(t, config)
+		assert.Len(t, reqs, len(all))
+	})
+}
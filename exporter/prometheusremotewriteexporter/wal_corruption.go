@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	walCorruptionPolicyFail       = "fail"
+	walCorruptionPolicyQuarantine = "quarantine"
+	walCorruptionPolicyRepair     = "repair"
+)
+
+// recoverCorruptedWAL applies the corruption policy after the WAL failed to open with err, then opens
+// the WAL again. It must be called with prwe.mu held.
+func (prwe *prweWAL) recoverCorruptedWAL(err error) (walStore, string, error) {
+	walPath := prwe.walConfig.path()
+	switch prwe.walConfig.corruptionPolicy() {
+	case walCorruptionPolicyQuarantine:
+		quarantinePath := walPath + ".corrupted-" + time.Now().UTC().Format("20060102T150405Z")
+		if rErr := os.Rename(walPath, quarantinePath); rErr != nil {
+			return nil, "", errors.Join(err, rErr)
+		}
+		prwe.logger.Warn("moved the corrupted WAL aside, starting with an empty WAL",
+			zap.String("quarantine_path", quarantinePath), zap.Error(err))
+	case walCorruptionPolicyRepair:
+		recovered, rErr := repairWAL(walPath)
+		if rErr != nil {
+			return nil, "", errors.Join(err, rErr)
+		}
+		prwe.logger.Warn("repaired the corrupted WAL, the entries following the corruption were dropped",
+			zap.String("path", walPath), zap.Int("recovered_entries", recovered), zap.Error(err))
+	default:
+		return nil, "", err
+	}
+	return prwe.openStore()
+}
+
+// walSegment is a segment file of the WAL, named after the index of its first entry.
+type walSegment struct {
+	index uint64
+	name  string
+}
+
+// repairWAL keeps the entries of the WAL in walPath that precede the first corrupted one, and removes
+// the others. It returns the number of entries that were kept.
+func repairWAL(walPath string) (int, error) {
+	files, err := os.ReadDir(walPath)
+	if err != nil {
+		return 0, err
+	}
+	var segments []walSegment
+	start := 0
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || len(name) < 20 {
+			continue
+		}
+		index, pErr := strconv.ParseUint(name[:20], 10, 64)
+		if pErr != nil || index == 0 {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".END"):
+			// The back of the WAL is never truncated, so this is a leftover of an interrupted write.
+			if rErr := os.Remove(filepath.Join(walPath, name)); rErr != nil {
+				return 0, rErr
+			}
+			continue
+		case strings.HasSuffix(name, ".START"):
+			// The segments preceding an interrupted front truncation are removed when the WAL opens.
+			start = len(segments)
+		case len(name) != 20:
+			continue
+		}
+		segments = append(segments, walSegment{index: index, name: name})
+	}
+
+	recovered := 0
+	for i := start; i < len(segments); i++ {
+		path := filepath.Join(walPath, segments[i].name)
+		data, rErr := os.ReadFile(path)
+		if rErr != nil {
+			return 0, rErr
+		}
+		entries, valid := validWALEntries(data)
+		recovered += entries
+		if valid == len(data) && (i+1 == len(segments) || segments[i].index+uint64(entries) == segments[i+1].index) {
+			continue
+		}
+		// Drop the corrupted entries and everything following them, the indices have to be contiguous.
+		if tErr := os.Truncate(path, int64(valid)); tErr != nil {
+			return 0, tErr
+		}
+		for _, s := range segments[i+1:] {
+			if rErr := os.Remove(filepath.Join(walPath, s.name)); rErr != nil {
+				return 0, rErr
+			}
+		}
+		break
+	}
+	return recovered, nil
+}
+
+// validWALEntries returns the number of well-formed entries at the beginning of the segment data, and
+// the number of bytes they span. Entries are stored as their uvarint encoded size followed by their data.
+func validWALEntries(data []byte) (entries, n int) {
+	for n < len(data) {
+		size, sizeLen := binary.Uvarint(data[n:])
+		if sizeLen <= 0 || uint64(len(data)-n-sizeLen) < size {
+			break
+		}
+		n += sizeLen + int(size)
+		entries++
+	}
+	return entries, n
+}
@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/wal"
+)
+
+// corruptedWAL writes three entries to a WAL in dir, then appends a truncated entry to its last segment.
+func corruptedWAL(t *testing.T, dir string) []*prompb.WriteRequest {
+	var reqs []*prompb.WriteRequest
+	pwal := newWAL(&WALConfig{Directory: dir}, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	for i := 0; i < 3; i++ {
+		req := makeReq(i)
+		reqs = append(reqs, req...)
+		require.NoError(t, pwal.persistToWAL(context.Background(), req))
+	}
+	require.NoError(t, pwal.stop())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "prom_remotewrite", "[0-9]*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, segments)
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	// The entry claims to hold 100 bytes, but only holds 3.
+	_, err = f.Write([]byte{100, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return reqs
+}
+
+func TestWALCorruptionPolicy(t *testing.T) {
+	t.Run("fail", func(t *testing.T) {
+		dir := t.TempDir()
+		corruptedWAL(t, dir)
+
+		pwal := newWAL(&WALConfig{Directory: dir, CorruptionPolicy: walCorruptionPolicyFail}, doNothingExportSink)
+		assert.ErrorIs(t, pwal.retrieveWALIndices(), wal.ErrCorrupt)
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		dir := t.TempDir()
+		corruptedWAL(t, dir)
+
+		pwal := newWAL(&WALConfig{Directory: dir, CorruptionPolicy: walCorruptionPolicyQuarantine}, doNothingExportSink)
+		require.NoError(t, pwal.retrieveWALIndices())
+		t.Cleanup(func() {
+			assert.NoError(t, pwal.stop())
+		})
+		assert.Equal(t, uint64(0), pwal.wWALIndex.Load(), "the WAL should start empty")
+
+		quarantined, err := filepath.Glob(filepath.Join(dir, "prom_remotewrite.corrupted-*"))
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		segments, err := os.ReadDir(quarantined[0])
+		require.NoError(t, err)
+		assert.NotEmpty(t, segments, "the corrupted WAL should be preserved")
+
+		req := makeReq(3)
+		require.NoError(t, pwal.persistToWAL(context.Background(), req))
+		got, err := pwal.readPrompbFromWAL(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, req[0], got)
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		dir := t.TempDir()
+		reqs := corruptedWAL(t, dir)
+
+		pwal := newWAL(&WALConfig{Directory: dir, CorruptionPolicy: walCorruptionPolicyRepair}, doNothingExportSink)
+		require.NoError(t, pwal.retrieveWALIndices())
+		t.Cleanup(func() {
+			assert.NoError(t, pwal.stop())
+		})
+		assert.Equal(t, uint64(3), pwal.wWALIndex.Load(), "the entries preceding the corruption should be kept")
+		for i, want := range reqs {
+			got, err := pwal.readPrompbFromWAL(context.Background(), uint64(i+1))
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+	})
+}
+
+func TestRepairWALDropsSegmentsFollowingCorruption(t *testing.T) {
+	walPath := t.TempDir()
+	// The first segment holds entries 1 and 2, followed by a truncated entry.
+	require.NoError(t, os.WriteFile(filepath.Join(walPath, "00000000000000000001"), []byte{1, 'a', 1, 'b', 5, 'c'}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(walPath, "00000000000000000004"), []byte{1, 'd'}, 0o600))
+
+	recovered, err := repairWAL(walPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recovered)
+
+	log, err := wal.Open(walPath, nil)
+	require.NoError(t, err)
+	defer log.Close()
+	lastIndex, err := log.LastIndex()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), lastIndex)
+	_, err = os.Stat(filepath.Join(walPath, "00000000000000000004"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// walEntrySizePolicyReject rejects the requests bigger than max_entry_bytes, none of the requests written
+	// along with them are written to the WAL. It is the default.
+	walEntrySizePolicyReject = "reject"
+	// walEntrySizePolicySplit splits the requests bigger than max_entry_bytes into several entries, only
+	// rejecting them when a single series or metadata is bigger than it.
+	walEntrySizePolicySplit = "split"
+)
+
+var errEntryTooLarge = errors.New("wal entry is larger than max_entry_bytes")
+
+func (wc *WALConfig) entrySizePolicy() string {
+	if wc.EntrySizePolicy != "" {
+		return wc.EntrySizePolicy
+	}
+	return walEntrySizePolicyReject
+}
+
+// walEntry is a request encoded to be written to the WAL.
+type walEntry struct {
+	blob   []byte
+	newest int64
+}
+
+// encodeWALEntries encodes requests into the entries written to the WAL, tagged with sourceID if it isn't empty.
+// The requests bigger than max_entry_bytes are split or rejected with errEntryTooLarge, according to the entry
+// size policy, so that an entry too big to be replayed never makes it to the WAL.
+func (wc *WALConfig) encodeWALEntries(sourceID string, requests []*prompb.WriteRequest) ([]walEntry, error) {
+	entries := make([]walEntry, 0, len(requests))
+	var encode func(req *prompb.WriteRequest) error
+	encode = func(req *prompb.WriteRequest) error {
+		protoBlob, err := proto.Marshal(req)
+		if err != nil {
+			return err
+		}
+		if sourceID != "" {
+			protoBlob = prependWALSourceID(sourceID, protoBlob)
+		}
+		if wc.MaxEntryBytes > 0 && len(protoBlob) > wc.MaxEntryBytes {
+			if wc.entrySizePolicy() != walEntrySizePolicySplit || len(req.Timeseries)+len(req.Metadata) < 2 {
+				return fmt.Errorf("%w: %d bytes, max_entry_bytes is %d", errEntryTooLarge, len(protoBlob), wc.MaxEntryBytes)
+			}
+			for _, half := range splitWriteRequest(req) {
+				if err := encode(half); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		entries = append(entries, walEntry{blob: protoBlob, newest: walRequestNewestTimestamp(req)})
+		return nil
+	}
+	for _, req := range requests {
+		if err := encode(req); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// splitWriteRequest splits the series and metadata of req, of which there are at least two, in two halves.
+func splitWriteRequest(req *prompb.WriteRequest) [2]*prompb.WriteRequest {
+	if len(req.Timeseries) < 2 {
+		// Keep the series along with the first half of the metadata.
+		half := len(req.Metadata) / 2
+		if len(req.Timeseries) == 0 {
+			half = (len(req.Metadata) + 1) / 2
+		}
+		return [2]*prompb.WriteRequest{
+			{Timeseries: req.Timeseries, Metadata: req.Metadata[:half]},
+			{Metadata: req.Metadata[half:]},
+		}
+	}
+	half := len(req.Timeseries) / 2
+	return [2]*prompb.WriteRequest{
+		{Timeseries: req.Timeseries[:half]},
+		{Timeseries: req.Timeseries[half:], Metadata: req.Metadata},
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// walManifestFile is the name of the manifest in the WAL directory, which the WAL segments can't be
+	// mistaken for since their names are at least 20 digits long.
+	walManifestFile = "MANIFEST.json"
+	// walFormatVersion is the version of the format of the WAL entries, proto encoded remote write 1.0
+	// requests, optionally prefixed with a source ID.
+	walFormatVersion = 1
+	// walCompressionNone is the compression of the WAL entries, which are written as is.
+	walCompressionNone = "none"
+)
+
+const (
+	walIncompatiblePolicyFail       = "fail"
+	walIncompatiblePolicyQuarantine = "quarantine"
+)
+
+var errIncompatibleWAL = errors.New("the WAL was written in a format this exporter can't read")
+
+// walManifest describes how the entries of a WAL are written, so that a WAL written by another version of
+// the exporter isn't misread.
+type walManifest struct {
+	FormatVersion int    `json:"format_version"`
+	Compression   string `json:"compression"`
+	// Checksum tells whether every entry is followed by a checksum of its content.
+	Checksum  bool      `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// currentWALManifest returns the manifest of the WALs written by this exporter.
+func currentWALManifest(createdAt time.Time) walManifest {
+	return walManifest{
+		FormatVersion: walFormatVersion,
+		Compression:   walCompressionNone,
+		Checksum:      false,
+		CreatedAt:     createdAt.UTC(),
+	}
+}
+
+// readWALManifest reads the manifest of the WAL in walPath, and returns false if it has none, either because
+// the WAL doesn't exist yet or because it was written before manifests were.
+func readWALManifest(walPath string) (walManifest, bool, error) {
+	var manifest walManifest
+	data, err := os.ReadFile(filepath.Join(walPath, walManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, false, nil
+	}
+	if err != nil {
+		return manifest, false, fmt.Errorf("prometheusremotewriteexporter: failed to read the WAL manifest: %w", err)
+	}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return manifest, false, fmt.Errorf("prometheusremotewriteexporter: failed to decode the WAL manifest %s: %w",
+			filepath.Join(walPath, walManifestFile), err)
+	}
+	return manifest, true, nil
+}
+
+// writeWALManifest writes manifest to the WAL in walPath, which must exist.
+func writeWALManifest(walPath string, manifest walManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(walPath, walManifestFile), data, 0o600); err != nil {
+		return fmt.Errorf("prometheusremotewriteexporter: failed to write the WAL manifest: %w", err)
+	}
+	return nil
+}
+
+// checkManifest returns an error wrapping errIncompatibleWAL if the WAL described by manifest can't be read
+// by this exporter.
+func checkManifest(manifest walManifest) error {
+	current := currentWALManifest(time.Now())
+	if manifest.FormatVersion != current.FormatVersion {
+		return fmt.Errorf("%w: format version %d, expected %d", errIncompatibleWAL, manifest.FormatVersion, current.FormatVersion)
+	}
+	if manifest.Compression != current.Compression {
+		return fmt.Errorf("%w: entries compressed with %q, expected %q", errIncompatibleWAL, manifest.Compression, current.Compression)
+	}
+	if manifest.Checksum != current.Checksum {
+		return fmt.Errorf("%w: checksum %t, expected %t", errIncompatibleWAL, manifest.Checksum, current.Checksum)
+	}
+	return nil
+}
+
+// loadManifest checks the manifest of the WAL, if it has one, before it is opened, and applies the incompatible
+// policy if the WAL can't be read. It must be called with prwe.mu held.
+func (prwe *prweWAL) loadManifest() error {
+	walPath := prwe.walConfig.path()
+	manifest, found, err := readWALManifest(walPath)
+	if err != nil || !found {
+		return err
+	}
+	if err = checkManifest(manifest); err == nil {
+		return nil
+	}
+	if prwe.walConfig.incompatiblePolicy() != walIncompatiblePolicyQuarantine {
+		return fmt.Errorf("prometheusremotewriteexporter: %w, move %s aside to start with an empty WAL", err, walPath)
+	}
+	quarantinePath := walPath + ".incompatible-" + time.Now().UTC().Format("20060102T150405Z")
+	if rErr := os.Rename(walPath, quarantinePath); rErr != nil {
+		return errors.Join(err, rErr)
+	}
+	prwe.logger.Warn("moved the incompatible WAL aside, starting with an empty WAL",
+		zap.String("quarantine_path", quarantinePath), zap.Error(err))
+	return nil
+}
+
+// ensureManifest writes the manifest of the WAL once it was opened, if it has none. The entries of a WAL
+// written before manifests were are assumed to be in the current format. It must be called with prwe.mu held.
+func (prwe *prweWAL) ensureManifest() error {
+	walPath := prwe.walConfig.path()
+	_, found, err := readWALManifest(walPath)
+	if err != nil || found {
+		return err
+	}
+	if last, err := prwe.wal.LastIndex(); err == nil && last > 0 {
+		prwe.logger.Warn("the WAL has no manifest, assuming its entries are in the current format",
+			zap.String("path", walPath), zap.Int("format_version", walFormatVersion))
+	}
+	return writeWALManifest(walPath, currentWALManifest(time.Now()))
+}
@@ -0,0 +1,160 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALManifestWritten(t *testing.T) {
+	config := &WALConfig{Directory: t.TempDir()}
+	pwal := newWAL(config, doNothingExportSink)
+	before := time.Now()
+	require.NoError(t, pwal.retrieveWALIndices())
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(0)))
+	require.NoError(t, pwal.stop())
+
+	manifest, found, err := readWALManifest(config.path())
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, walFormatVersion, manifest.FormatVersion)
+	assert.Equal(t, walCompressionNone, manifest.Compression)
+	assert.False(t, manifest.Checksum)
+	assert.WithinDuration(t, before, manifest.CreatedAt, time.Minute)
+
+	// The manifest is kept, along with its creation time, when the WAL is opened again.
+	pwal = newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	require.NoError(t, pwal.stop())
+	reopened, found, err := readWALManifest(config.path())
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.True(t, manifest.CreatedAt.Equal(reopened.CreatedAt))
+}
+
+func TestWALManifestMissing(t *testing.T) {
+	config := &WALConfig{Directory: t.TempDir()}
+	pwal := newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(0)))
+	require.NoError(t, pwal.stop())
+
+	// A WAL written before manifests were is assumed to be in the current format.
+	require.NoError(t, os.Remove(filepath.Join(config.path(), walManifestFile)))
+	pwal = newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	assert.Equal(t, uint64(1), pwal.wWALIndex.Load())
+	require.NoError(t, pwal.stop())
+
+	_, found, err := readWALManifest(config.path())
+	require.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestWALManifestMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*walManifest)
+		wantErr string
+	}{
+		{
+			name:    "format version",
+			modify:  func(m *walManifest) { m.FormatVersion = 2 },
+			wantErr: "format version 2, expected 1",
+		},
+		{
+			name:    "compression",
+			modify:  func(m *walManifest) { m.Compression = "zstd" },
+			wantErr: `entries compressed with "zstd", expected "none"`,
+		},
+		{
+			name:    "checksum",
+			modify:  func(m *walManifest) { m.Checksum = true },
+			wantErr: "checksum true, expected false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &WALConfig{Directory: t.TempDir()}
+			manifest := currentWALManifest(time.Now())
+			tt.modify(&manifest)
+			require.NoError(t, os.MkdirAll(config.path(), 0o700))
+			require.NoError(t, writeWALManifest(config.path(), manifest))
+
+			pwal := newWAL(config, doNothingExportSink)
+			err := pwal.retrieveWALIndices()
+			require.ErrorIs(t, err, errIncompatibleWAL)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Nil(t, pwal.wal, "the WAL should not be opened")
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir()}
+		require.NoError(t, os.MkdirAll(config.path(), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(config.path(), walManifestFile), []byte("{"), 0o600))
+
+		pwal := newWAL(config, doNothingExportSink)
+		assert.ErrorContains(t, pwal.retrieveWALIndices(), "failed to decode the WAL manifest")
+	})
+}
+
+func TestWALIncompatiblePolicy(t *testing.T) {
+	// writeWALWithChecksums writes a WAL with an entry, then marks it as written with checksums, as a WAL
+	// written with another configuration would be.
+	writeWALWithChecksums := func(t *testing.T, config *WALConfig) {
+		pwal := newWAL(config, doNothingExportSink)
+		require.NoError(t, pwal.retrieveWALIndices())
+		require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(0)))
+		require.NoError(t, pwal.stop())
+
+		manifest, found, err := readWALManifest(config.path())
+		require.NoError(t, err)
+		require.True(t, found)
+		manifest.Checksum = true
+		require.NoError(t, writeWALManifest(config.path(), manifest))
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir(), IncompatiblePolicy: walIncompatiblePolicyFail}
+		writeWALWithChecksums(t, config)
+
+		pwal := newWAL(config, doNothingExportSink)
+		err := pwal.retrieveWALIndices()
+		require.ErrorIs(t, err, errIncompatibleWAL)
+		assert.ErrorContains(t, err, "checksum true, expected false")
+		assert.Nil(t, pwal.wal, "the WAL should not be opened")
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		config := &WALConfig{Directory: t.TempDir(), IncompatiblePolicy: walIncompatiblePolicyQuarantine}
+		writeWALWithChecksums(t, config)
+
+		pwal := newWAL(config, doNothingExportSink)
+		require.NoError(t, pwal.retrieveWALIndices())
+		assert.Equal(t, uint64(0), pwal.wWALIndex.Load(), "the WAL should start empty")
+		require.NoError(t, pwal.stop())
+
+		manifest, found, err := readWALManifest(config.path())
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.False(t, manifest.Checksum)
+
+		// The incompatible WAL is kept aside, along with its manifest.
+		quarantined, err := filepath.Glob(config.path() + ".incompatible-*")
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		manifest, found, err = readWALManifest(quarantined[0])
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.True(t, manifest.Checksum)
+	})
+}
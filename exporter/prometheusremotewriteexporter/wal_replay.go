@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// startReplay records the entries found in the WAL on startup, which are replayed before the others. If new
+// metrics are held back until then, they are accepted anyway once replay_accept_timeout elapses.
+func (prwe *prweWAL) startReplay() {
+	prwe.replayUntil.Store(prwe.wWALIndex.Load())
+	prwe.markReplayedIfDone()
+	if timeout := prwe.walConfig.ReplayAcceptTimeout; prwe.walConfig.ReplayBeforeAccept && timeout > 0 {
+		logger := prwe.logger
+		time.AfterFunc(timeout, func() {
+			prwe.replayedOnce.Do(func() {
+				logger.Warn("the WAL is still being replayed after replay_accept_timeout, accepting new metrics meanwhile",
+					zap.Duration("replay_accept_timeout", timeout))
+				close(prwe.replayed)
+			})
+		})
+	}
+}
+
+// markReplayedIfDone closes replayed once the entries found in the WAL on startup were all exported. It must be
+// called after the entries preceding the read index were exported.
+func (prwe *prweWAL) markReplayedIfDone() {
+	if replayUntil := prwe.replayUntil.Load(); replayUntil == 0 || prwe.rWALIndex.Load() > replayUntil {
+		prwe.replayedOnce.Do(func() { close(prwe.replayed) })
+	}
+}
+
+// waitForReplay blocks, when replay_before_accept is set, until the entries found in the WALs on startup were
+// exported, so that the metrics pushed meanwhile are sent after them.
+func (prwe *prwExporter) waitForReplay(ctx context.Context) error {
+	if !prwe.walEnabled() || !prwe.wal.walConfig.ReplayBeforeAccept {
+		return nil
+	}
+	for _, wal := range prwe.allWALs() {
+		select {
+		case <-wal.replayed:
+		case <-wal.stopChan:
+			return nil
+		case <-prwe.closeChan:
+			return errors.New("shutdown has been called")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
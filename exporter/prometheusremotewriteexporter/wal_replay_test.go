@@ -0,0 +1,111 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func TestWALReplayBeforeAccept(t *testing.T) {
+	tests := []struct {
+		name                string
+		replayBeforeAccept  bool
+		replayAcceptTimeout time.Duration
+		wantDeferred        bool
+	}{
+		{name: "interleaved"},
+		{name: "replay before accept", replayBeforeAccept: true, wantDeferred: true},
+		{name: "replay accept timeout", replayBeforeAccept: true, replayAcceptTimeout: 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var exported []string
+			// The replay is held until released, the metrics pushed meanwhile aren't.
+			release := make(chan struct{})
+			sink := ExportSinkFunc(func(_ context.Context, requests []*prompb.WriteRequest) error {
+				mu.Lock()
+				for _, req := range requests {
+					for _, ts := range req.Timeseries {
+						exported = append(exported, ts.Labels[0].Name)
+					}
+				}
+				replaying := len(exported) == 1
+				mu.Unlock()
+				if replaying {
+					<-release
+				}
+				return nil
+			})
+
+			cfg := createDefaultConfig().(*Config)
+			cfg.TargetInfo.Enabled = false
+			cfg.WAL = &WALConfig{
+				Directory:           t.TempDir(),
+				BufferSize:          1,
+				TruncateFrequency:   20 * time.Millisecond,
+				ReplayBeforeAccept:  tt.replayBeforeAccept,
+				ReplayAcceptTimeout: tt.replayAcceptTimeout,
+			}
+			require.NoError(t, cfg.Validate())
+
+			// Seed the WAL, then start replaying it.
+			seed := newWAL(cfg.WAL, doNothingExportSink)
+			require.NoError(t, seed.retrieveWALIndices())
+			for i := 0; i < 2; i++ {
+				require.NoError(t, seed.persistToWAL(context.Background(), makeReq(i)))
+			}
+			require.NoError(t, seed.stop())
+
+			prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings(), WithExportSink(sink))
+			require.NoError(t, err)
+			require.NoError(t, prwe.turnOnWALIfEnabled(contextWithLogger(context.Background(), zap.NewNop())))
+			defer func() {
+				assert.NoError(t, prwe.Shutdown(context.Background()))
+			}()
+
+			pushed := make(chan error, 1)
+			go func() {
+				metric := pmetric.NewMetric()
+				metric.SetName("new")
+				metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+				pushed <- prwe.PushMetrics(context.Background(), getMetricsFromMetricList(metric))
+			}()
+			select {
+			case err = <-pushed:
+				assert.False(t, tt.wantDeferred, "the push should wait for the replay")
+				require.NoError(t, err)
+			case <-time.After(200 * time.Millisecond):
+				assert.True(t, tt.wantDeferred, "the push shouldn't wait for the replay")
+				close(release)
+				require.NoError(t, <-pushed)
+				release = nil
+			}
+			if release != nil {
+				close(release)
+			}
+
+			assert.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(exported) == 3
+			}, 5*time.Second, 10*time.Millisecond)
+			if tt.wantDeferred {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.Equal(t, []string{"test_metric_0_0", "test_metric_0_1", "__name__"}, exported)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+const (
+	// walShutdownStrategyOldestFirst leaves the entries that weren't exported in the WAL on shutdown, to be
+	// exported from the oldest one on the next startup. It is the default.
+	walShutdownStrategyOldestFirst = "oldest_first"
+	// walShutdownStrategyNewestFirst exports the entries that weren't exported from the newest one on shutdown,
+	// until the shutdown deadline, leaving the oldest ones in the WAL.
+	walShutdownStrategyNewestFirst = "newest_first"
+)
+
+func (wc *WALConfig) shutdownStrategy() string {
+	if wc.ShutdownStrategy != "" {
+		return wc.ShutdownStrategy
+	}
+	return walShutdownStrategyOldestFirst
+}
+
+// drainNewestFirst exports the entries that weren't read yet from the newest one, removing each of them from
+// the back of the WAL once exported, until they are all exported or ctx expires. It must be called once the WAL
+// is stopped. The oldest entry of the WAL can't be removed from its back, so it is exported again on the next
+// startup if it was drained.
+func (prwe *prweWAL) drainNewestFirst(ctx context.Context) (err error) {
+	prwe.mu.Lock()
+	defer prwe.mu.Unlock()
+
+	store, _, err := prwe.openStore()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, store.Close())
+	}()
+
+	first, err := store.FirstIndex()
+	if err != nil {
+		return err
+	}
+	last, err := store.LastIndex()
+	if err != nil {
+		return err
+	}
+	if last == 0 {
+		return nil
+	}
+	// Like for readFromWAL, the read index of an empty WAL is 0 but its first entry is 1.
+	oldest := max(prwe.rWALIndex.Load(), first, 1)
+
+	drained := 0
+	for index := last; index >= oldest; index-- {
+		if err = prwe.drainEntry(ctx, store, index); err != nil {
+			if ctx.Err() == nil {
+				return err
+			}
+			prwe.logger.Info("shutdown deadline reached, leaving the oldest WAL entries to be exported on the next startup",
+				zap.Int("drained", drained), zap.Uint64("left", index-oldest+1))
+			return nil
+		}
+		drained++
+		if index > first {
+			if err = store.TruncateBack(index - 1); err != nil {
+				return err
+			}
+		}
+	}
+	prwe.logger.Info("exported the WAL entries left on shutdown, from the newest one", zap.Int("drained", drained))
+	return nil
+}
+
+// drainEntry exports the entry of store at index.
+func (prwe *prweWAL) drainEntry(ctx context.Context, store walStore, index uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	protoBlob, err := store.Read(index)
+	if err == nil {
+		protoBlob, err = splitWALCodec(protoBlob)
+	}
+	if err != nil {
+		return err
+	}
+	_, protoBlob = splitWALSourceID(protoBlob)
+	req := new(prompb.WriteRequest)
+	if err = proto.Unmarshal(protoBlob, req); err != nil {
+		return err
+	}
+	return prwe.exportSink(ctx, []*prompb.WriteRequest{req})
+}
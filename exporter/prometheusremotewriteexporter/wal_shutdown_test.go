@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALDrainNewestFirst(t *testing.T) {
+	tests := []struct {
+		name string
+		// exportsBeforeDeadline is the number of entries exported before the shutdown deadline, 0 for none.
+		exportsBeforeDeadline int
+		wantExported          []string
+		wantLeft              []string
+	}{
+		{
+			name:                  "deadline",
+			exportsBeforeDeadline: 3,
+			wantExported:          []string{"4", "3", "2"},
+			wantLeft:              []string{"0", "1"},
+		},
+		{
+			name:         "no deadline",
+			wantExported: []string{"4", "3", "2", "1", "0"},
+			// The oldest entry can't be removed from the back of the WAL.
+			wantLeft: []string{"0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &WALConfig{Directory: t.TempDir(), ShutdownStrategy: walShutdownStrategyNewestFirst}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var exported []string
+			exportSink := func(_ context.Context, reqL []*prompb.WriteRequest) error {
+				for _, req := range reqL {
+					exported = append(exported, req.Timeseries[0].Labels[0].Value)
+				}
+				if len(exported) == tt.exportsBeforeDeadline {
+					cancel()
+				}
+				return nil
+			}
+
+			pwal := newWAL(config, exportSink)
+			require.NoError(t, pwal.retrieveWALIndices())
+			for i := 0; i < 5; i++ {
+				require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(i)))
+			}
+			require.NoError(t, pwal.stop())
+			require.NoError(t, pwal.drainNewestFirst(ctx))
+			assert.Equal(t, tt.wantExported, exported)
+
+			reqs, _ := readAllWALEntries(t, config)
+			var left []string
+			for _, req := range reqs {
+				left = append(left, req.Timeseries[0].Labels[0].Value)
+			}
+			assert.Equal(t, tt.wantLeft, left)
+		})
+	}
+}
@@ -5,16 +5,34 @@ package prometheusremotewriteexporter
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"go.uber.org/zap"
+	"os"
+	"runtime"
 	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/wal"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter/internal/metadatatest"
 )
 
 func doNothingExportSink(_ context.Context, reqL []*prompb.WriteRequest) error {
@@ -130,7 +148,7 @@ func TestWAL_persist(t *testing.T) {
 		assert.NoError(t, pwal.stop())
 	})
 
-	require.NoError(t, pwal.persistToWAL(reqL))
+	require.NoError(t, pwal.persistToWAL(context.Background(), reqL))
 
 	// 2. Read all the entries from the WAL itself, guided by the indices available,
 	// and ensure that they are exactly in order as we'd expect them.
@@ -192,7 +210,7 @@ func TestWALDuplicateDataPrevention(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
 		// Persist duplicate requests to WAL
-		require.NoError(t, pwal.persistToWAL(makeReq(i)))
+		require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(i)))
 
 	}
 
@@ -217,3 +235,802 @@ func makeReq(i int) []*prompb.WriteRequest {
 	}
 	return wr
 }
+
+// faultyWALStore wraps a walStore and fails writes with ENOSPC while full is set.
+type faultyWALStore struct {
+	walStore
+	full   *atomic.Bool
+	writes *atomic.Int64
+}
+
+func (f *faultyWALStore) WriteBatch(b *wal.Batch) error {
+	f.writes.Add(1)
+	if f.full.Load() {
+		return &os.PathError{Op: "write", Path: "wal", Err: syscall.ENOSPC}
+	}
+	return f.walStore.WriteBatch(b)
+}
+
+func TestWALDiskFull(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: 200 * time.Millisecond,
+	}
+	tel := metadatatest.SetupTelemetry()
+	prwTel, err := newPRWTelemetry(tel.NewSettings())
+	require.NoError(t, err)
+
+	pwal := newWAL(config, doNothingExportSink)
+	pwal.telemetry = prwTel
+	full, writes := &atomic.Bool{}, &atomic.Int64{}
+	pwal.openStore = func() (walStore, string, error) {
+		store, walPath, oErr := config.openStore()
+		if oErr != nil {
+			return nil, "", oErr
+		}
+		return &faultyWALStore{walStore: store, full: full, writes: writes}, walPath, nil
+	}
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(0)))
+
+	// The disk fills up, the write fails and the WAL stops accepting writes.
+	full.Store(true)
+	assert.ErrorIs(t, pwal.persistToWAL(context.Background(), makeReq(1)), errDiskFull)
+	assert.ErrorIs(t, pwal.persistToWAL(context.Background(), makeReq(2)), errDiskFull)
+	assert.Equal(t, int64(2), writes.Load(), "writes in degraded mode should not reach the store")
+	assert.Equal(t, uint64(1), pwal.wWALIndex.Load(), "failed writes should not advance the write index")
+
+	// The exporter rejects the batches with a retryable error, so that they are sent again once space is freed.
+	cfg := createDefaultConfig().(*Config)
+	prwe, err := newPRWExporter(cfg, exportertest.NewNopSettings())
+	require.NoError(t, err)
+	prwe.wal = pwal
+	err = prwe.handleExport(context.Background(), map[string]*prompb.TimeSeries{"0": &makeReq(1)[0].Timeseries[0]}, nil)
+	require.ErrorIs(t, err, errDiskFull)
+	assert.False(t, consumererror.IsPermanent(err), "the disk full error should be retryable")
+
+	// Once space is available again, the next probe succeeds and writes resume.
+	full.Store(false)
+	require.Eventually(t, func() bool {
+		return pwal.persistToWAL(context.Background(), makeReq(3)) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(4)))
+
+	req, err := pwal.readPrompbFromWAL(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "test_metric_0_3", req.Timeseries[0].Labels[0].Name)
+
+	tel.AssertMetrics(t, []metricdata.Metrics{
+		{
+			Name:        "otelcol_exporter_prometheusremotewrite_wal_disk_full_events",
+			Description: "Number of times the WAL stopped accepting writes because its directory ran out of disk space",
+			Unit:        "1",
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{
+					{
+						Value:      1,
+						Attributes: attribute.NewSet(attribute.String("exporter", "prometheusremotewrite")),
+					},
+				},
+			},
+		},
+	}, metricdatatest.IgnoreTimestamp())
+}
+
+func TestWALDiskFullRecoversAfterTruncate(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+	}
+	pwal := newWAL(config, doNothingExportSink)
+	full := &atomic.Bool{}
+	pwal.openStore = func() (walStore, string, error) {
+		store, walPath, oErr := config.openStore()
+		if oErr != nil {
+			return nil, "", oErr
+		}
+		return &faultyWALStore{walStore: store, full: full, writes: &atomic.Int64{}}, walPath, nil
+	}
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(0)))
+	full.Store(true)
+	require.ErrorIs(t, pwal.persistToWAL(context.Background(), makeReq(1)), errDiskFull)
+	full.Store(false)
+	require.ErrorIs(t, pwal.persistToWAL(context.Background(), makeReq(1)), errDiskFull)
+
+	// Exporting and truncating the existing entries frees up space.
+	_, err := pwal.readPrompbFromWAL(context.Background(), 1)
+	require.NoError(t, err)
+	require.NoError(t, pwal.exportThenFrontTruncateWAL(context.Background(), []*prompb.WriteRequest{{}}))
+	assert.NoError(t, pwal.persistToWAL(context.Background(), makeReq(1)))
+}
+
+func TestWALSourceID(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+	}
+	pwal := newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	ctx := context.Background()
+	reqA, reqB, untagged := makeReq(0), makeReq(1), makeReq(2)
+	require.NoError(t, pwal.persistToWAL(ContextWithWALSourceID(ctx, "metrics/a"), reqA))
+	require.NoError(t, pwal.persistToWAL(ContextWithWALSourceID(ctx, "metrics/b"), reqB))
+	require.NoError(t, pwal.persistToWAL(ctx, untagged))
+
+	for i, want := range []struct {
+		sourceID string
+		req      *prompb.WriteRequest
+	}{
+		{"metrics/a", reqA[0]},
+		{"metrics/b", reqB[0]},
+		{"", untagged[0]},
+	} {
+		req, sourceID, err := pwal.readWALEntry(ctx, uint64(i+1))
+		require.NoError(t, err)
+		assert.Equal(t, want.sourceID, sourceID)
+		assert.Equal(t, want.req, req)
+	}
+}
+
+// TestWALSourceIDOnExportError checks that the errors exporting WAL entries name the sources of the entries.
+func TestWALSourceIDOnExportError(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        1,
+		TruncateFrequency: time.Hour,
+	}
+	pwal := newWAL(config, func(context.Context, []*prompb.WriteRequest) error {
+		return consumererror.NewPermanent(errors.New("rejected"))
+	})
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+	require.NoError(t, pwal.persistToWAL(ContextWithWALSourceID(context.Background(), "metrics/a"), makeReq(0)))
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.New(core)))
+	defer cancel()
+	require.NoError(t, pwal.run(ctx))
+
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("error processing WAL entries").Len() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	err, ok := logs.FilterMessage("error processing WAL entries").All()[0].ContextMap()["error"].(string)
+	require.True(t, ok)
+	assert.Contains(t, err, `"metrics/a"`)
+	assert.Contains(t, err, "rejected")
+}
+
+// prependWALCodec returns protoBlob preceded by the walCodecField holding codec.
+func prependWALCodec(codec string, protoBlob []byte) []byte {
+	b := binary.AppendUvarint(nil, walCodecField<<3|2) // length-delimited
+	b = binary.AppendUvarint(b, uint64(len(codec)))
+	b = append(b, codec...)
+	return append(b, protoBlob...)
+}
+
+func TestWALEntryCodec(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+	}
+	pwal := newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	req := makeReq(0)[0]
+	protoBlob, err := proto.Marshal(req)
+	require.NoError(t, err)
+	batch := new(wal.Batch)
+	batch.Write(1, prependWALCodec(walCompressionNone, prependWALSourceID("metrics/a", protoBlob)))
+	batch.Write(2, prependWALCodec("unknown", protoBlob))
+	require.NoError(t, pwal.wal.WriteBatch(batch))
+
+	// The entries without compression are read like the others.
+	got, sourceID, err := pwal.readWALEntry(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "metrics/a", sourceID)
+	assert.Equal(t, req, got)
+
+	_, err = pwal.readPrompbFromWAL(context.Background(), 2)
+	require.ErrorIs(t, err, errUnsupportedWALCodec)
+	assert.ErrorContains(t, err, `"unknown"`)
+	assert.ErrorIs(t, pwal.auditEntry(2), errUnsupportedWALCodec)
+}
+
+// truncationCountingWALStore wraps a walStore and counts the calls to TruncateFront.
+type truncationCountingWALStore struct {
+	walStore
+	truncations *atomic.Int64
+}
+
+func (c *truncationCountingWALStore) TruncateFront(index uint64) error {
+	c.truncations.Add(1)
+	return c.walStore.TruncateFront(index)
+}
+
+func TestWALStartupTruncateDelay(t *testing.T) {
+	config := &WALConfig{
+		Directory:            t.TempDir(),
+		BufferSize:           1,
+		TruncateFrequency:    50 * time.Millisecond,
+		StartupTruncateDelay: 500 * time.Millisecond,
+	}
+	exported := &atomic.Int64{}
+	exportSink := func(_ context.Context, reqL []*prompb.WriteRequest) error {
+		exported.Add(int64(len(reqL)))
+		return nil
+	}
+
+	pwal := newWAL(config, exportSink)
+	truncations := &atomic.Int64{}
+	pwal.openStore = func() (walStore, string, error) {
+		store, walPath, oErr := config.openStore()
+		if oErr != nil {
+			return nil, "", oErr
+		}
+		return &truncationCountingWALStore{walStore: store, truncations: truncations}, walPath, nil
+	}
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(i)))
+	}
+
+	ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.NewNop()))
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, pwal.run(ctx))
+
+	// The entries are exported right away, but they must stay in the WAL until the delay elapses.
+	require.Eventually(t, func() bool {
+		return exported.Load() >= 3
+	}, 5*time.Second, 10*time.Millisecond)
+	if time.Since(start) < config.StartupTruncateDelay {
+		assert.Zero(t, truncations.Load(), "the WAL should not be truncated before the startup delay elapses")
+	}
+
+	// Stop the reader, which waits for new entries, and export again once the delay elapsed.
+	cancel()
+	time.Sleep(time.Until(start.Add(config.StartupTruncateDelay)))
+	require.NoError(t, pwal.exportThenFrontTruncateWAL(context.Background(), makeReq(3)))
+	assert.Equal(t, int64(1), truncations.Load())
+	assert.Equal(t, uint64(4), pwal.rWALIndex.Load(), "all exported entries should have been truncated")
+}
+
+func TestWALTruncationTelemetry(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+	}
+	tel := metadatatest.SetupTelemetry()
+	prwTel, err := newPRWTelemetry(tel.NewSettings())
+	require.NoError(t, err)
+
+	pwal := newWAL(config, doNothingExportSink)
+	pwal.telemetry = prwTel
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	ctx := context.Background()
+	expected := func(truncations, index int64) []metricdata.Metrics {
+		attrs := attribute.NewSet(attribute.String("exporter", "prometheusremotewrite"))
+		return []metricdata.Metrics{
+			{
+				// All the entries were exported.
+				Name:        "otelcol_exporter_prometheusremotewrite_wal_oldest_entry_age_seconds",
+				Description: "Age of the newest sample of the oldest WAL entry that hasn't been exported yet, computed when the WAL is truncated, 0 when there is none",
+				Unit:        "s",
+				Data: metricdata.Gauge[float64]{
+					DataPoints: []metricdata.DataPoint[float64]{{Value: 0, Attributes: attrs}},
+				},
+			},
+			{
+				Name:        "otelcol_exporter_prometheusremotewrite_wal_truncated_index",
+				Description: "Index up to which the WAL was last truncated",
+				Unit:        "1",
+				Data: metricdata.Gauge[int64]{
+					DataPoints: []metricdata.DataPoint[int64]{{Value: index, Attributes: attrs}},
+				},
+			},
+			{
+				Name:        "otelcol_exporter_prometheusremotewrite_wal_truncations",
+				Description: "Number of times the WAL was truncated from the front after its entries were exported",
+				Unit:        "1",
+				Data: metricdata.Sum[int64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints:  []metricdata.DataPoint[int64]{{Value: truncations, Attributes: attrs}},
+				},
+			},
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(i)))
+		req, rErr := pwal.readPrompbFromWAL(ctx, pwal.rWALIndex.Load())
+		require.NoError(t, rErr)
+		require.NoError(t, pwal.exportThenFrontTruncateWAL(ctx, []*prompb.WriteRequest{req}))
+	}
+	tel.AssertMetrics(t, expected(2, 2), metricdatatest.IgnoreTimestamp())
+
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(2)))
+	req, err := pwal.readPrompbFromWAL(ctx, pwal.rWALIndex.Load())
+	require.NoError(t, err)
+	require.NoError(t, pwal.exportThenFrontTruncateWAL(ctx, []*prompb.WriteRequest{req}))
+	tel.AssertMetrics(t, expected(3, 3), metricdatatest.IgnoreTimestamp())
+}
+
+func TestWALReplayConcurrency(t *testing.T) {
+	const entries = 30
+	for _, limit := range []int{0, 3} {
+		t.Run(fmt.Sprintf("limit_%d", limit), func(t *testing.T) {
+			config := &WALConfig{
+				Directory:         t.TempDir(),
+				BufferSize:        10,
+				TruncateFrequency: time.Hour,
+				ReplayConcurrency: limit,
+			}
+			// The sink sends the requests of a call concurrently, like the exporter does with enough consumers.
+			var inFlight, maxInFlight, exported atomic.Int64
+			exportSink := func(_ context.Context, reqL []*prompb.WriteRequest) error {
+				var wg sync.WaitGroup
+				for range reqL {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						current := inFlight.Add(1)
+						for previous := maxInFlight.Load(); current > previous; previous = maxInFlight.Load() {
+							if maxInFlight.CompareAndSwap(previous, current) {
+								break
+							}
+						}
+						time.Sleep(5 * time.Millisecond)
+						inFlight.Add(-1)
+						exported.Add(1)
+					}()
+				}
+				wg.Wait()
+				return nil
+			}
+
+			// Seed the WAL, then start replaying it.
+			seed := newWAL(config, doNothingExportSink)
+			require.NoError(t, seed.retrieveWALIndices())
+			for i := 0; i < entries; i++ {
+				require.NoError(t, seed.persistToWAL(context.Background(), makeReq(i)))
+			}
+			require.NoError(t, seed.stop())
+
+			pwal := newWAL(config, exportSink)
+			t.Cleanup(func() {
+				assert.NoError(t, pwal.stop())
+			})
+			ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.NewNop()))
+			defer cancel()
+			require.NoError(t, pwal.run(ctx))
+
+			require.Eventually(t, func() bool {
+				return exported.Load() == entries
+			}, 5*time.Second, 10*time.Millisecond)
+			if limit > 0 {
+				assert.LessOrEqual(t, maxInFlight.Load(), int64(limit), "replayed entries in flight should never exceed the limit")
+			} else {
+				assert.Greater(t, maxInFlight.Load(), int64(3), "a whole batch should be in flight without a limit")
+			}
+		})
+	}
+}
+
+// walAgeTelemetry records the ages of the oldest WAL entry.
+type walAgeTelemetry struct {
+	nopTelemetry
+	ages []time.Duration
+}
+
+func (w *walAgeTelemetry) recordWALOldestEntryAge(_ context.Context, age time.Duration) {
+	w.ages = append(w.ages, age)
+}
+
+func TestWALOldestEntryAge(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+	}
+	tel := &walAgeTelemetry{}
+	pwal := newWAL(config, doNothingExportSink)
+	pwal.telemetry = tel
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+	for _, age := range []time.Duration{2 * time.Hour, time.Hour} {
+		req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+			Labels: []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+			Samples: []prompb.Sample{
+				{Value: 1, Timestamp: now.Add(-age).UnixMilli()},
+				{Value: 2, Timestamp: now.Add(-age - time.Minute).UnixMilli()},
+			},
+		}}}
+		require.NoError(t, pwal.persistToWAL(ctx, []*prompb.WriteRequest{req}))
+	}
+	// Reopen the WAL, like on startup, with the entries seeded but not exported yet.
+	require.NoError(t, pwal.retrieveWALIndices())
+
+	require.NoError(t, pwal.syncAndTruncateFront(ctx))
+	// Export the entries one at a time.
+	for i := 0; i < 2; i++ {
+		req, err := pwal.readPrompbFromWAL(ctx, pwal.rWALIndex.Load())
+		require.NoError(t, err)
+		require.NoError(t, pwal.exportThenFrontTruncateWAL(ctx, []*prompb.WriteRequest{req}))
+	}
+
+	require.Len(t, tel.ages, 3)
+	assert.InDelta(t, (2 * time.Hour).Seconds(), tel.ages[0].Seconds(), time.Minute.Seconds())
+	assert.InDelta(t, time.Hour.Seconds(), tel.ages[1].Seconds(), time.Minute.Seconds())
+	assert.Zero(t, tel.ages[2], "the age should be 0 once all the entries were exported")
+}
+
+// makeLargeWriteRequest returns a request with numSeries time series of roughly 150 encoded bytes each.
+func makeLargeWriteRequest(numSeries int) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{
+		Metadata: []prompb.MetricMetadata{{MetricFamilyName: "test_metric", Type: prompb.MetricMetadata_GAUGE}},
+	}
+	for i := 0; i < numSeries; i++ {
+		req.Timeseries = append(req.Timeseries, prompb.TimeSeries{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "test_metric"},
+				{Name: "series", Value: fmt.Sprintf("%0100d", i)},
+			},
+			Samples: []prompb.Sample{{Value: float64(i), Timestamp: int64(i)}},
+		})
+	}
+	return req
+}
+
+func TestDecodeWriteRequestInChunks(t *testing.T) {
+	const chunkSize = 64 * 1024
+	protoBlob, err := proto.Marshal(makeLargeWriteRequest(20000))
+	require.NoError(t, err)
+	require.Greater(t, len(protoBlob), 2*1024*1024)
+
+	var memStats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&memStats)
+	baseline := memStats.HeapAlloc
+
+	var numChunks, numSeries, numMetadata int
+	var peak uint64
+	err = decodeWriteRequestInChunks(protoBlob, chunkSize, func(req *prompb.WriteRequest) error {
+		encoded := 0
+		for i := range req.Timeseries {
+			assert.Equal(t, fmt.Sprintf("%0100d", numSeries), req.Timeseries[i].Labels[1].Value, "series should be decoded in order")
+			assert.Equal(t, float64(numSeries), req.Timeseries[i].Samples[0].Value)
+			encoded += req.Timeseries[i].Size()
+			numSeries++
+		}
+		assert.LessOrEqual(t, encoded, chunkSize)
+		numChunks++
+		numMetadata += len(req.Metadata)
+
+		runtime.GC()
+		runtime.ReadMemStats(&memStats)
+		if memStats.HeapAlloc > baseline {
+			peak = max(peak, memStats.HeapAlloc-baseline)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 20000, numSeries)
+	assert.Equal(t, 1, numMetadata)
+	assert.GreaterOrEqual(t, numChunks, len(protoBlob)/chunkSize)
+	// Only a chunk is decoded at a time, so the live heap stays a fraction of the size of the entry,
+	// while decoding it whole takes more memory than the entry itself.
+	assert.Less(t, peak, uint64(len(protoBlob)/4), "peak heap growth of %d bytes for an entry of %d bytes", peak, len(protoBlob))
+}
+
+func TestDecodeWriteRequestInChunksMalformed(t *testing.T) {
+	protoBlob, err := proto.Marshal(makeLargeWriteRequest(10))
+	require.NoError(t, err)
+
+	err = decodeWriteRequestInChunks(protoBlob[:len(protoBlob)-3], 1024, func(*prompb.WriteRequest) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestUnmarshalWriteRequestInto(t *testing.T) {
+	req := &prompb.WriteRequest{}
+	// A smaller request decoded into a larger one leaves nothing of it behind.
+	for _, numSeries := range []int{100, 3, 100} {
+		want := makeLargeWriteRequest(numSeries)
+		want.Timeseries[0].Exemplars = []prompb.Exemplar{{Value: 1, Timestamp: 2}}
+		protoBlob, err := proto.Marshal(want)
+		require.NoError(t, err)
+		require.NoError(t, unmarshalWriteRequestInto(protoBlob, req))
+		assert.Equal(t, want, req, "%d series", numSeries)
+	}
+
+	protoBlob, err := proto.Marshal(makeLargeWriteRequest(100))
+	require.NoError(t, err)
+	reused := testing.AllocsPerRun(10, func() {
+		_ = unmarshalWriteRequestInto(protoBlob, req)
+	})
+	allocated := testing.AllocsPerRun(10, func() {
+		_ = proto.Unmarshal(protoBlob, new(prompb.WriteRequest))
+	})
+	assert.Less(t, reused, allocated)
+
+	assert.Error(t, unmarshalWriteRequestInto(protoBlob[:len(protoBlob)-3], req))
+}
+
+func TestWALReuseReadBuffers(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        2,
+		TruncateFrequency: time.Hour,
+		ReuseReadBuffers:  true,
+	}
+	var mu sync.Mutex
+	var exported []string
+	exportSink := func(_ context.Context, reqL []*prompb.WriteRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, req := range reqL {
+			for _, ts := range req.Timeseries {
+				exported = append(exported, ts.Labels[1].Value)
+			}
+		}
+		return nil
+	}
+
+	pwal := newWAL(config, exportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+	var want []string
+	for i := 4; i > 0; i-- {
+		// The requests get smaller, so that the series of the previous ones would show if they were left behind.
+		req := makeLargeWriteRequest(i)
+		for j := range req.Timeseries {
+			req.Timeseries[j].Labels[1].Value = fmt.Sprintf("%d_%d", i, j)
+			want = append(want, req.Timeseries[j].Labels[1].Value)
+		}
+		require.NoError(t, pwal.persistToWAL(context.Background(), []*prompb.WriteRequest{req}))
+	}
+
+	ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.NewNop()))
+	defer cancel()
+	require.NoError(t, pwal.run(ctx))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(exported) == len(want)
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, want, exported)
+}
+
+// BenchmarkWALRead reads and decodes 10k WAL entries like a replay does, in batches of the default buffer size,
+// to compare the allocations with and without reuse_read_buffers using -benchmem.
+func BenchmarkWALRead(b *testing.B) {
+	const entries = 10000
+	config := &WALConfig{Directory: b.TempDir()}
+	pwal := newWAL(config, doNothingExportSink)
+	require.NoError(b, pwal.retrieveWALIndices())
+	b.Cleanup(func() {
+		assert.NoError(b, pwal.stop())
+	})
+	reqs := make([]*prompb.WriteRequest, entries)
+	for i := range reqs {
+		reqs[i] = makeLargeWriteRequest(10)
+	}
+	require.NoError(b, pwal.persistToWAL(context.Background(), reqs))
+
+	for _, reuse := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reuse_read_buffers=%v", reuse), func(b *testing.B) {
+			config.ReuseReadBuffers = reuse
+			ctx := context.Background()
+			var reqL []*prompb.WriteRequest
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				for index := uint64(1); index <= entries; index++ {
+					protoBlob, err := pwal.readFromWAL(ctx, index)
+					if err != nil {
+						b.Fatal(err)
+					}
+					_, protoBlob = splitWALSourceID(protoBlob)
+					var req *prompb.WriteRequest
+					if reuse {
+						req, err = pwal.decodeWALEntryInto(protoBlob, reusableRequest(reqL))
+					} else {
+						req, err = pwal.decodeWALEntry(protoBlob)
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+					if reqL = append(reqL, req); len(reqL) >= config.bufferSize() {
+						reqL = reqL[:0]
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWALChunkedRead(t *testing.T) {
+	config := &WALConfig{
+		Directory:          t.TempDir(),
+		BufferSize:         1,
+		TruncateFrequency:  50 * time.Millisecond,
+		ReadChunkSizeBytes: 64 * 1024,
+	}
+	var mu sync.Mutex
+	var exported []*prompb.WriteRequest
+	exportSink := func(_ context.Context, reqL []*prompb.WriteRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		exported = append(exported, reqL...)
+		return nil
+	}
+
+	pwal := newWAL(config, exportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+	require.NoError(t, pwal.persistToWAL(context.Background(), []*prompb.WriteRequest{makeLargeWriteRequest(20000)}))
+	require.NoError(t, pwal.persistToWAL(context.Background(), makeReq(0)))
+
+	ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.NewNop()))
+	defer cancel()
+	require.NoError(t, pwal.run(ctx))
+
+	numSeries := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, req := range exported {
+			n += len(req.Timeseries)
+		}
+		return n
+	}
+	require.Eventually(t, func() bool {
+		return numSeries() == 20001
+	}, 10*time.Second, 10*time.Millisecond)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Greater(t, len(exported), 2, "the large entry should have been exported in chunks")
+	assert.Equal(t, "test_metric_0_0", exported[len(exported)-1].Timeseries[0].Labels[0].Name, "entries should be exported in order")
+	assert.Equal(t, uint64(3), pwal.rWALIndex.Load())
+}
+
+// walDirSize returns the size of the files in the WAL directory, the manifest included.
+func walDirSize(t *testing.T, dir string) int64 {
+	var size int64
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		require.NoError(t, err)
+		size += info.Size()
+	}
+	return size
+}
+
+func TestWALTruncationKeepsSizeBounded(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+	}
+	pwal := newWAL(config, doNothingExportSink)
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+
+	const entriesPerCycle = 200
+	var maxSize int64
+	for cycle := 0; cycle < 50; cycle++ {
+		reqs := make([]*prompb.WriteRequest, 0, entriesPerCycle)
+		for i := 0; i < entriesPerCycle; i++ {
+			reqs = append(reqs, makeReq(i)...)
+		}
+		require.NoError(t, pwal.persistToWAL(context.Background(), reqs))
+		if cycle == 0 {
+			maxSize = walDirSize(t, config.path())
+		}
+
+		// All the entries but the last one were exported.
+		pwal.rWALIndex.Store(pwal.wWALIndex.Load())
+		require.NoError(t, pwal.syncAndTruncateFront(context.Background()))
+		require.NoError(t, pwal.retrieveWALIndices())
+
+		// The space of the truncated entries is reclaimed, rather than the WAL growing with every cycle.
+		assert.LessOrEqual(t, walDirSize(t, config.path()), maxSize/10, "cycle %d", cycle)
+		pwal.mu.Lock()
+		assert.LessOrEqual(t, len(pwal.ttlIndex.newest), 1, "cycle %d", cycle)
+		pwal.mu.Unlock()
+	}
+}
+
+func TestWALMaxEntryBytes(t *testing.T) {
+	for _, policy := range []string{walEntrySizePolicyReject, walEntrySizePolicySplit} {
+		t.Run(policy, func(t *testing.T) {
+			config := &WALConfig{
+				Directory:         t.TempDir(),
+				TruncateFrequency: time.Hour,
+				MaxEntryBytes:     1024,
+				EntrySizePolicy:   policy,
+			}
+			pwal := newWAL(config, doNothingExportSink)
+			require.NoError(t, pwal.retrieveWALIndices())
+			t.Cleanup(func() {
+				assert.NoError(t, pwal.stop())
+			})
+
+			ctx := context.Background()
+			small, large := makeReq(0)[0], makeLargeWriteRequest(50)
+			err := pwal.persistToWAL(ctx, []*prompb.WriteRequest{small, large})
+			if policy == walEntrySizePolicyReject {
+				require.ErrorIs(t, err, errEntryTooLarge)
+				assert.Zero(t, pwal.wWALIndex.Load(), "no entry should have been written")
+				return
+			}
+			require.NoError(t, err)
+
+			req, err := pwal.readPrompbFromWAL(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, small, req)
+
+			merged := &prompb.WriteRequest{}
+			for index := uint64(2); index <= pwal.wWALIndex.Load(); index++ {
+				protoBlob, err := pwal.readFromWAL(ctx, index)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(protoBlob), config.MaxEntryBytes)
+				req, err := pwal.readPrompbFromWAL(ctx, index)
+				require.NoError(t, err)
+				merged.Timeseries = append(merged.Timeseries, req.Timeseries...)
+				merged.Metadata = append(merged.Metadata, req.Metadata...)
+			}
+			assert.Greater(t, pwal.wWALIndex.Load(), uint64(2), "the large request should have been split")
+			assert.Equal(t, large, merged)
+
+			// A single series bigger than max_entry_bytes can't be split.
+			huge := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+				Labels: []prompb.Label{{Name: "__name__", Value: fmt.Sprintf("%02000d", 0)}},
+			}}}
+			assert.ErrorIs(t, pwal.persistToWAL(ctx, []*prompb.WriteRequest{huge}), errEntryTooLarge)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/prometheusremotewriteexporter"
+
+import (
+	"math"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+// walEntryWithoutSamples is the timestamp indexed for the WAL entries that hold no samples, or can't be decoded.
+const walEntryWithoutSamples = math.MinInt64
+
+// walTTLIndex holds the timestamp, in milliseconds, of the newest sample of a contiguous range of WAL entries,
+// so that the entries that are too old to be exported can be found without reading and decoding them. It is
+// protected by prweWAL.mu.
+type walTTLIndex struct {
+	first  uint64
+	newest []int64
+}
+
+// add indexes the entry at index. An entry that doesn't directly follow the indexed ones resets the index.
+func (ix *walTTLIndex) add(index uint64, newest int64) {
+	if len(ix.newest) == 0 || index != ix.first+uint64(len(ix.newest)) {
+		ix.first, ix.newest = index, ix.newest[:0]
+	}
+	ix.newest = append(ix.newest, newest)
+}
+
+// lookup returns the timestamp of the newest sample of the entry at index, and whether the entry is indexed.
+func (ix *walTTLIndex) lookup(index uint64) (int64, bool) {
+	if index < ix.first || index-ix.first >= uint64(len(ix.newest)) {
+		return 0, false
+	}
+	return ix.newest[index-ix.first], true
+}
+
+// covers returns whether all the entries from first to last are indexed.
+func (ix *walTTLIndex) covers(first, last uint64) bool {
+	return last < first || (first >= ix.first && last-ix.first < uint64(len(ix.newest)))
+}
+
+// truncateFront drops the entries preceding index.
+func (ix *walTTLIndex) truncateFront(index uint64) {
+	if index <= ix.first {
+		return
+	}
+	n := min(index-ix.first, uint64(len(ix.newest)))
+	ix.first += n
+	ix.newest = ix.newest[n:]
+}
+
+// walRequestNewestTimestamp returns the timestamp of the newest sample of req, or walEntryWithoutSamples.
+func walRequestNewestTimestamp(req *prompb.WriteRequest) int64 {
+	newest := int64(walEntryWithoutSamples)
+	for _, ts := range req.Timeseries {
+		if timestamp, ok := newestTimestamp(ts); ok && timestamp > newest {
+			newest = timestamp
+		}
+	}
+	return newest
+}
+
+// decodeWALEntryNewestTimestamp returns the timestamp of the newest sample of the WAL entry in protoBlob, or
+// walEntryWithoutSamples.
+func decodeWALEntryNewestTimestamp(protoBlob []byte) (int64, error) {
+	_, protoBlob = splitWALSourceID(protoBlob)
+	req := new(prompb.WriteRequest)
+	if err := proto.Unmarshal(protoBlob, req); err != nil {
+		return walEntryWithoutSamples, err
+	}
+	return walRequestNewestTimestamp(req), nil
+}
+
+// entryNewestTimestamp returns the timestamp of the newest sample of the entry at index, or
+// walEntryWithoutSamples. Only the entries missing from the TTL index are read and decoded. The caller must
+// hold prwe.mu.
+func (prwe *prweWAL) entryNewestTimestamp(index uint64) (int64, error) {
+	if newest, ok := prwe.ttlIndex.lookup(index); ok {
+		return newest, nil
+	}
+	protoBlob, err := prwe.wal.Read(index)
+	if err != nil {
+		return walEntryWithoutSamples, err
+	}
+	return decodeWALEntryNewestTimestamp(protoBlob)
+}
+
+// rebuildTTLIndex indexes the entries from the read index to the write index, when they aren't already, like
+// on startup. The entries that can't be decoded are indexed as holding no samples, so that they don't expire.
+// The caller must hold prwe.mu.
+func (prwe *prweWAL) rebuildTTLIndex() {
+	first, last := max(prwe.rWALIndex.Load(), 1), prwe.wWALIndex.Load()
+	if prwe.ttlIndex.covers(first, last) {
+		return
+	}
+	prwe.ttlIndex = walTTLIndex{}
+	for index := first; index <= last; index++ {
+		protoBlob, err := prwe.wal.Read(index)
+		if err != nil {
+			prwe.logger.Debug("failed to read a WAL entry to index it", zap.Uint64("index", index), zap.Error(err))
+			return
+		}
+		newest, _ := decodeWALEntryNewestTimestamp(protoBlob)
+		prwe.ttlIndex.add(index, newest)
+	}
+}
+
+// expireEntriesBeforeRead expires the entries at the front of the WAL before a batch is read from it, so that
+// they expire even while exporting them keeps failing, and the WAL isn't truncated.
+func (prwe *prweWAL) expireEntriesBeforeRead() {
+	prwe.mu.Lock()
+	defer prwe.mu.Unlock()
+	if prwe.wal != nil {
+		prwe.expireEntries(time.Now())
+	}
+}
+
+// expireEntries moves the read index past the entries at the front of the WAL whose newest sample is older
+// than walConfig.MaxEntryAge, so that they are truncated without being exported. The entries without samples
+// don't expire. It must be called with prwe.mu held, by the goroutine reading the WAL between two batches.
+func (prwe *prweWAL) expireEntries(now time.Time) {
+	maxAge := prwe.walConfig.MaxEntryAge
+	if maxAge <= 0 {
+		return
+	}
+	cutoff := now.Add(-maxAge).UnixMilli()
+	first := max(prwe.rWALIndex.Load(), 1)
+	index := first
+	for ; index <= prwe.wWALIndex.Load(); index++ {
+		newest, err := prwe.entryNewestTimestamp(index)
+		if err != nil || newest == walEntryWithoutSamples || newest >= cutoff {
+			break
+		}
+	}
+	if index == first {
+		return
+	}
+	prwe.rWALIndex.Store(index)
+	prwe.logger.Warn("dropped the WAL entries older than max_entry_age",
+		zap.Uint64("entries", index-first), zap.Duration("max_entry_age", maxAge))
+}
@@ -0,0 +1,162 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheusremotewriteexporter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// readCountingWALStore wraps a walStore and counts the calls to Read.
+type readCountingWALStore struct {
+	walStore
+	reads *atomic.Int64
+}
+
+func (c *readCountingWALStore) Read(index uint64) ([]byte, error) {
+	c.reads.Add(1)
+	return c.walStore.Read(index)
+}
+
+func TestWALTTLIndexExpiry(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		TruncateFrequency: time.Hour,
+		MaxEntryAge:       time.Hour,
+	}
+	reads := &atomic.Int64{}
+	openWAL := func() *prweWAL {
+		pwal := newWAL(config, doNothingExportSink)
+		pwal.openStore = func() (walStore, string, error) {
+			store, walPath, err := config.openStore()
+			if err != nil {
+				return nil, "", err
+			}
+			return &readCountingWALStore{walStore: store, reads: reads}, walPath, nil
+		}
+		require.NoError(t, pwal.retrieveWALIndices())
+		return pwal
+	}
+	// referenceNewest decodes the entry at index, as if there were no TTL index.
+	referenceNewest := func(pwal *prweWAL, index uint64) int64 {
+		protoBlob, err := pwal.wal.Read(index)
+		require.NoError(t, err)
+		newest, err := decodeWALEntryNewestTimestamp(protoBlob)
+		require.NoError(t, err)
+		return newest
+	}
+	assertIndexMatchesReference := func(pwal *prweWAL) {
+		for index := max(pwal.rWALIndex.Load(), 1); index <= pwal.wWALIndex.Load(); index++ {
+			newest, ok := pwal.ttlIndex.lookup(index)
+			require.True(t, ok, "entry %d should be indexed", index)
+			assert.Equal(t, referenceNewest(pwal, index), newest, "entry %d", index)
+		}
+	}
+
+	now := time.Now()
+	sample := func(age time.Duration) prompb.TimeSeries {
+		return prompb.TimeSeries{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: now.Add(-age).UnixMilli()}},
+		}
+	}
+	histogram := func(age time.Duration) prompb.TimeSeries {
+		return prompb.TimeSeries{
+			Labels:     []prompb.Label{{Name: "__name__", Value: "test_histogram"}},
+			Histograms: []prompb.Histogram{{Sum: 1, Timestamp: now.Add(-age).UnixMilli()}},
+		}
+	}
+	requests := []*prompb.WriteRequest{
+		{Timeseries: []prompb.TimeSeries{sample(3 * time.Hour), sample(5 * time.Hour)}},
+		{Timeseries: []prompb.TimeSeries{histogram(2 * time.Hour)}},
+		{Timeseries: []prompb.TimeSeries{sample(3 * time.Hour), histogram(30 * time.Minute)}},
+		{Timeseries: []prompb.TimeSeries{sample(4 * time.Hour)}},
+		{Metadata: []prompb.MetricMetadata{{MetricFamilyName: "test_metric", Type: prompb.MetricMetadata_GAUGE}}},
+	}
+
+	ctx := context.Background()
+	pwal := openWAL()
+	require.NoError(t, pwal.persistToWAL(ctx, requests[:2]))
+	require.NoError(t, pwal.persistToWAL(ContextWithWALSourceID(ctx, "source"), requests[2:]))
+	assertIndexMatchesReference(pwal)
+
+	// The expiry decisions must match those made by decoding every entry.
+	cutoff := time.Now().Add(-config.MaxEntryAge).UnixMilli()
+	wantReadIndex := uint64(1)
+	for ; wantReadIndex <= pwal.wWALIndex.Load(); wantReadIndex++ {
+		if newest := referenceNewest(pwal, wantReadIndex); newest == walEntryWithoutSamples || newest >= cutoff {
+			break
+		}
+	}
+	require.Equal(t, uint64(3), wantReadIndex)
+
+	reads.Store(0)
+	require.NoError(t, pwal.syncAndTruncateFront(ctx))
+	assert.Equal(t, wantReadIndex, pwal.rWALIndex.Load())
+	assert.Zero(t, reads.Load(), "the truncate path should not read the WAL entries")
+	_, ok := pwal.ttlIndex.lookup(wantReadIndex - 1)
+	assert.False(t, ok, "the truncated entries should be dropped from the index")
+	require.NoError(t, pwal.stop())
+
+	// On startup, the index is rebuilt from the remaining entries.
+	pwal = openWAL()
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+	assert.Equal(t, uint64(3), pwal.rWALIndex.Load())
+	assertIndexMatchesReference(pwal)
+	reads.Store(0)
+	require.NoError(t, pwal.syncAndTruncateFront(ctx))
+	assert.Equal(t, uint64(3), pwal.rWALIndex.Load(), "the entry with a recent sample should not expire")
+	assert.Zero(t, reads.Load(), "the truncate path should not read the WAL entries")
+}
+
+func TestWALEntriesExpireWhileExportFails(t *testing.T) {
+	config := &WALConfig{
+		Directory:         t.TempDir(),
+		BufferSize:        1,
+		TruncateFrequency: 10 * time.Millisecond,
+		MaxEntryAge:       200 * time.Millisecond,
+	}
+	// The endpoint keeps failing, so the WAL is never truncated and the entry is read again and again.
+	exports := &atomic.Int64{}
+	pwal := newWAL(config, func(context.Context, []*prompb.WriteRequest) error {
+		exports.Add(1)
+		return errRetryBudgetExhausted
+	})
+	require.NoError(t, pwal.retrieveWALIndices())
+	t.Cleanup(func() {
+		assert.NoError(t, pwal.stop())
+	})
+	require.NoError(t, pwal.persistToWAL(context.Background(), []*prompb.WriteRequest{{
+		Timeseries: []prompb.TimeSeries{{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "test_metric"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: time.Now().UnixMilli()}},
+		}},
+	}}))
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	ctx, cancel := context.WithCancel(contextWithLogger(context.Background(), zap.New(core)))
+	defer cancel()
+	require.NoError(t, pwal.run(ctx))
+
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("dropped the WAL entries older than max_entry_age").Len() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Positive(t, exports.Load(), "the entry should have been exported before it expired")
+	// The expired entry isn't exported anymore.
+	exported := exports.Load()
+	time.Sleep(10 * config.TruncateFrequency)
+	assert.Equal(t, exported, exports.Load())
+	assert.Equal(t, uint64(2), pwal.rWALIndex.Load())
+}
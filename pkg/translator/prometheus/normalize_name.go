@@ -71,6 +71,26 @@ var normalizeNameGate = featuregate.GlobalRegistry().MustRegister(
 	featuregate.WithRegisterReferenceURL("https://github.com/open-telemetry/opentelemetry-collector-contrib/issues/8950"),
 )
 
+// UnitSuffixMode controls how the unit of a metric is turned into a suffix of its name.
+type UnitSuffixMode string
+
+const (
+	// UnitSuffixModeOTel maps OTel units to Prometheus base unit names, e.g. ms to milliseconds. It is the default.
+	UnitSuffixModeOTel UnitSuffixMode = "otel"
+	// UnitSuffixModeRaw appends the OTel unit as is, only removing the characters that aren't allowed.
+	UnitSuffixModeRaw UnitSuffixMode = "raw"
+	// UnitSuffixModeNone doesn't append units. Type suffixes, like _total, are still appended.
+	UnitSuffixModeNone UnitSuffixMode = "none"
+)
+
+// UnitSuffixes controls how the unit of a metric is appended to its name when metric suffixes are added.
+type UnitSuffixes struct {
+	// Mode is how units are turned into suffixes. UnitSuffixModeOTel is used if it is empty.
+	Mode UnitSuffixMode
+	// Overrides maps OTel units to the suffix used instead of the one Mode would produce, whatever the mode.
+	Overrides map[string]string
+}
+
 // BuildCompliantName builds a Prometheus-compliant metric name for the specified metric
 //
 // Metric name is prefixed with specified namespace and underscore (if any).
@@ -80,11 +100,17 @@ var normalizeNameGate = featuregate.GlobalRegistry().MustRegister(
 // See rules at https://prometheus.io/docs/concepts/data_model/#metric-names-and-labels
 // and https://prometheus.io/docs/practices/naming/#metric-and-label-naming
 func BuildCompliantName(metric pmetric.Metric, namespace string, addMetricSuffixes bool) string {
+	return BuildCompliantNameWithUnitSuffixes(metric, namespace, addMetricSuffixes, UnitSuffixes{})
+}
+
+// BuildCompliantNameWithUnitSuffixes is like BuildCompliantName, turning units into suffixes as
+// configured by unitSuffixes.
+func BuildCompliantNameWithUnitSuffixes(metric pmetric.Metric, namespace string, addMetricSuffixes bool, unitSuffixes UnitSuffixes) string {
 	var metricName string
 
 	// Full normalization following standard Prometheus naming conventions
 	if addMetricSuffixes && normalizeNameGate.IsEnabled() {
-		return normalizeName(metric, namespace)
+		return normalizeName(metric, namespace, unitSuffixes)
 	}
 
 	// Simple case (no full normalization, no units, etc.), we simply trim out forbidden chars
@@ -103,8 +129,27 @@ func BuildCompliantName(metric pmetric.Metric, namespace string, addMetricSuffix
 	return metricName
 }
 
+// unitSuffix returns the suffix of the OTel unit, looking it up with otelSuffix in UnitSuffixModeOTel.
+func (us UnitSuffixes) unitSuffix(unit string, otelSuffix func(string) string) string {
+	if suffix, ok := us.Overrides[unit]; ok {
+		return suffix
+	}
+	switch us.Mode {
+	case UnitSuffixModeRaw:
+		return unit
+	case UnitSuffixModeNone:
+		return ""
+	}
+	return otelSuffix(unit)
+}
+
+// mapsUnits returns whether OTel units are mapped to Prometheus base unit names.
+func (us UnitSuffixes) mapsUnits() bool {
+	return us.Mode == "" || us.Mode == UnitSuffixModeOTel
+}
+
 // Build a normalized name for the specified metric
-func normalizeName(metric pmetric.Metric, namespace string) string {
+func normalizeName(metric pmetric.Metric, namespace string, unitSuffixes UnitSuffixes) string {
 	// Split metric name in "tokens" (remove all non-alphanumeric)
 	nameTokens := strings.FieldsFunc(
 		metric.Name(),
@@ -119,7 +164,7 @@ func normalizeName(metric pmetric.Metric, namespace string) string {
 	if len(unitTokens) > 0 {
 		mainUnitOtel := strings.TrimSpace(unitTokens[0])
 		if mainUnitOtel != "" && !strings.ContainsAny(mainUnitOtel, "{}") {
-			mainUnitProm := CleanUpString(unitMapGetOrDefault(mainUnitOtel))
+			mainUnitProm := CleanUpString(unitSuffixes.unitSuffix(mainUnitOtel, unitMapGetOrDefault))
 			if mainUnitProm != "" && !contains(nameTokens, mainUnitProm) {
 				nameTokens = append(nameTokens, mainUnitProm)
 			}
@@ -130,7 +175,7 @@ func normalizeName(metric pmetric.Metric, namespace string) string {
 		if len(unitTokens) > 1 && unitTokens[1] != "" {
 			perUnitOtel := strings.TrimSpace(unitTokens[1])
 			if perUnitOtel != "" && !strings.ContainsAny(perUnitOtel, "{}") {
-				perUnitProm := CleanUpString(perUnitMapGetOrDefault(perUnitOtel))
+				perUnitProm := CleanUpString(unitSuffixes.unitSuffix(perUnitOtel, perUnitMapGetOrDefault))
 				if perUnitProm != "" && !contains(nameTokens, perUnitProm) {
 					nameTokens = append(append(nameTokens, "per"), perUnitProm)
 				}
@@ -148,7 +193,8 @@ func normalizeName(metric pmetric.Metric, namespace string) string {
 	// See https://github.com/open-telemetry/opentelemetry-collector-contrib/issues?q=is%3Aissue+some+metric+units+don%27t+follow+otel+semantic+conventions
 	// Until these issues have been fixed, we're appending `_ratio` for gauges ONLY
 	// Theoretically, counters could be ratios as well, but it's absurd (for mathematical reasons)
-	if metric.Unit() == "1" && metric.Type() == pmetric.MetricTypeGauge {
+	// The raw and none unit suffix modes don't map units, so they leave the unit "1" alone
+	if metric.Unit() == "1" && metric.Type() == pmetric.MetricTypeGauge && unitSuffixes.mapsUnits() {
 		nameTokens = append(removeItem(nameTokens, "ratio"), "ratio")
 	}
 
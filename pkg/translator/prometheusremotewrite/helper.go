@@ -5,12 +5,12 @@ package prometheusremotewrite // import "github.com/open-telemetry/opentelemetry
 
 import (
 	"encoding/hex"
-	"fmt"
 	"log"
 	"math"
 	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -23,6 +23,7 @@ import (
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	conventions "go.opentelemetry.io/collector/semconv/v1.25.0"
+	"go.uber.org/multierr"
 
 	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
 )
@@ -101,18 +102,110 @@ func timeSeriesSignature(labels []prompb.Label) uint64 {
 
 var seps = []byte{'\xff'}
 
+// labelValueInterner deduplicates the label values assembled during a single translation, so that
+// identical values share backing storage instead of being allocated once per series. It also
+// remembers normalized label names, which are equally repetitive. A nil *labelValueInterner is valid and does not intern anything.
+type labelValueInterner struct {
+	values map[string]string
+	// names caches the normalized form of attribute keys.
+	names map[string]string
+	// buf is scratch space used to format values before looking them up.
+	buf []byte
+}
+
+func newLabelValueInterner() *labelValueInterner {
+	return &labelValueInterner{values: map[string]string{}, names: map[string]string{}}
+}
+
+// normalizeLabel returns prometheustranslator.NormalizeLabel(name), normalizing every distinct name only once.
+func (in *labelValueInterner) normalizeLabel(name string) string {
+	if in == nil {
+		return prometheustranslator.NormalizeLabel(name)
+	}
+	if normalized, ok := in.names[name]; ok {
+		return normalized
+	}
+	normalized := prometheustranslator.NormalizeLabel(name)
+	in.names[name] = normalized
+	return normalized
+}
+
+// intern returns the pooled copy of s, adding s to the pool if it isn't there yet.
+func (in *labelValueInterner) intern(s string) string {
+	if in == nil {
+		return s
+	}
+	if v, ok := in.values[s]; ok {
+		return v
+	}
+	in.values[s] = s
+	return s
+}
+
+// internBytes is like intern, but only allocates a string for b when it isn't pooled yet.
+func (in *labelValueInterner) internBytes(b []byte) string {
+	if v, ok := in.values[string(b)]; ok {
+		return v
+	}
+	s := string(b)
+	in.values[s] = s
+	return s
+}
+
+// internValue returns the pooled string representation of v, as returned by v.AsString().
+func (in *labelValueInterner) internValue(v pcommon.Value) string {
+	if in == nil {
+		return v.AsString()
+	}
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		return in.intern(v.Str())
+	case pcommon.ValueTypeInt:
+		in.buf = strconv.AppendInt(in.buf[:0], v.Int(), 10)
+		return in.internBytes(in.buf)
+	case pcommon.ValueTypeBool:
+		in.buf = strconv.AppendBool(in.buf[:0], v.Bool())
+		return in.internBytes(in.buf)
+	}
+	return in.intern(v.AsString())
+}
+
+// internFloat returns the pooled string representation of f, as used for the le and quantile labels.
+func (in *labelValueInterner) internFloat(f float64) string {
+	if in == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	in.buf = strconv.AppendFloat(in.buf[:0], f, 'f', -1, 64)
+	return in.internBytes(in.buf)
+}
+
+// internConcat returns the pooled concatenation of parts.
+func (in *labelValueInterner) internConcat(parts ...string) string {
+	if in == nil {
+		return strings.Join(parts, "")
+	}
+	in.buf = in.buf[:0]
+	for _, part := range parts {
+		in.buf = append(in.buf, part...)
+	}
+	return in.internBytes(in.buf)
+}
+
 // createAttributes creates a slice of Prometheus Labels with OTLP attributes and pairs of string values.
 // Unpaired string values are ignored. String pairs overwrite OTLP labels if collisions happen and
 // if logOnOverwrite is true, the overwrite is logged. Resulting label names are sanitized.
-func createAttributes(resource pcommon.Resource, attributes pcommon.Map, externalLabels map[string]string,
-	ignoreAttrs []string, logOnOverwrite bool, extras ...string,
-) []prompb.Label {
+// Label values are deduplicated through interner, which may be nil. Unless policy is InvalidLabelNamePolicySanitize
+// or empty, an attribute name that isn't a valid Prometheus label name results in an *InvalidLabelNameError.
+// The __name__ pair is applied according to settings.NameConflictPolicy when the attributes already include it.
+func createAttributes(interner *labelValueInterner, resource pcommon.Resource, attributes pcommon.Map,
+	scopeLabels []prompb.Label, settings Settings, ignoreAttrs []string, logOnOverwrite bool, extras ...string,
+) ([]prompb.Label, error) {
 	resourceAttrs := resource.Attributes()
 	serviceName, haveServiceName := resourceAttrs.Get(conventions.AttributeServiceName)
 	instance, haveInstanceID := resourceAttrs.Get(conventions.AttributeServiceInstanceID)
 
 	// Calculate the maximum possible number of labels we could return so we can preallocate l
-	maxLabelCount := attributes.Len() + len(externalLabels) + len(extras)/2
+	maxLabelCount := attributes.Len() + len(scopeLabels) + len(settings.ExternalLabels) + len(extras)/2
 
 	if haveServiceName {
 		maxLabelCount++
@@ -130,39 +223,78 @@ func createAttributes(resource pcommon.Resource, attributes pcommon.Map, externa
 	labels := make([]prompb.Label, 0, maxLabelCount)
 	// XXX: Should we always drop service namespace/service name/service instance ID from the labels
 	// (as they get mapped to other Prometheus labels)?
+	sanitize := settings.InvalidLabelNamePolicy == "" || settings.InvalidLabelNamePolicy == InvalidLabelNamePolicySanitize
+	var err error
 	attributes.Range(func(key string, value pcommon.Value) bool {
-		if !slices.Contains(ignoreAttrs, key) {
-			labels = append(labels, prompb.Label{Name: key, Value: value.AsString()})
+		if slices.Contains(ignoreAttrs, key) {
+			return true
 		}
+		// Remapped attributes don't need a valid name.
+		_, remapped := settings.LabelNameRemapping[key]
+		if !sanitize && !remapped && !model.LabelName(key).IsValidLegacy() {
+			err = &InvalidLabelNameError{Name: key}
+			return false
+		}
+		labels = append(labels, prompb.Label{Name: key, Value: interner.internValue(value)})
 		return true
 	})
+	if err != nil {
+		return nil, err
+	}
 	sort.Stable(ByLabelName(labels))
 
+	// remappedNames holds the label names set by remapped attributes, when they take precedence.
+	var remappedNames map[string]bool
+	if settings.LabelCollisionPolicy == LabelCollisionPolicyPreferRemapped && len(settings.LabelNameRemapping) > 0 {
+		remappedNames = make(map[string]bool)
+	}
 	for _, label := range labels {
-		finalKey := prometheustranslator.NormalizeLabel(label.Name)
+		finalKey, remapped := settings.LabelNameRemapping[label.Name]
+		if !remapped {
+			finalKey = interner.normalizeLabel(label.Name)
+		}
+		if remappedNames != nil {
+			if remapped && !remappedNames[finalKey] {
+				// Drop the values of the attributes sanitized to the same name.
+				remappedNames[finalKey] = true
+				l[finalKey] = label.Value
+				continue
+			}
+			if !remapped && remappedNames[finalKey] {
+				continue
+			}
+		}
 		if existingValue, alreadyExists := l[finalKey]; alreadyExists {
 			// Only append to existing value if the new value is different
 			if existingValue != label.Value {
-				l[finalKey] = existingValue + ";" + label.Value
+				l[finalKey] = interner.internConcat(existingValue, ";", label.Value)
 			}
 		} else {
 			l[finalKey] = label.Value
 		}
 	}
+	for _, label := range scopeLabels {
+		// Scope labels have already been sanitized
+		if _, alreadyExists := l[label.Name]; alreadyExists {
+			// Skip scope labels if they are overridden by metric attributes
+			continue
+		}
+		l[label.Name] = label.Value
+	}
 
 	// Map service.name + service.namespace to job
 	if haveServiceName {
-		val := serviceName.AsString()
+		val := interner.internValue(serviceName)
 		if serviceNamespace, ok := resourceAttrs.Get(conventions.AttributeServiceNamespace); ok {
-			val = fmt.Sprintf("%s/%s", serviceNamespace.AsString(), val)
+			val = interner.internConcat(serviceNamespace.AsString(), "/", val)
 		}
 		l[model.JobLabel] = val
 	}
 	// Map service.instance.id to instance
 	if haveInstanceID {
-		l[model.InstanceLabel] = instance.AsString()
+		l[model.InstanceLabel] = interner.internValue(instance)
 	}
-	for key, value := range externalLabels {
+	for key, value := range settings.ExternalLabels {
 		// External labels have already been sanitized
 		if _, alreadyExists := l[key]; alreadyExists {
 			// Skip external labels if they are overridden by metric attributes
@@ -175,6 +307,16 @@ func createAttributes(resource pcommon.Resource, attributes pcommon.Map, externa
 		if i+1 >= len(extras) {
 			break
 		}
+		if extras[i] == model.MetricNameLabel {
+			if attribute, ok := l[model.MetricNameLabel]; ok && attribute != extras[i+1] {
+				switch settings.NameConflictPolicy {
+				case NameConflictPolicyPreserve:
+					continue
+				case NameConflictPolicyError:
+					return nil, &NameConflictError{Name: extras[i+1], Attribute: attribute}
+				}
+			}
+		}
 		_, found := l[extras[i]]
 		if found && logOnOverwrite {
 			log.Println("label " + extras[i] + " is overwritten. Check if Prometheus reserved labels are used.")
@@ -182,7 +324,7 @@ func createAttributes(resource pcommon.Resource, attributes pcommon.Map, externa
 		// internal labels should be maintained
 		name := extras[i]
 		if !(len(name) > 4 && name[:2] == "__" && name[len(name)-2:] == "__") {
-			name = prometheustranslator.NormalizeLabel(name)
+			name = interner.normalizeLabel(name)
 		}
 		l[name] = extras[i+1]
 	}
@@ -192,7 +334,32 @@ func createAttributes(resource pcommon.Resource, attributes pcommon.Map, externa
 		labels = append(labels, prompb.Label{Name: k, Value: v})
 	}
 
-	return labels
+	return labels, nil
+}
+
+// aggregationTemporality returns the aggregation temporality of the metric, as a label value, and false if
+// the metric type has none.
+func aggregationTemporality(metric pmetric.Metric) (string, bool) {
+	var temporality pmetric.AggregationTemporality
+	//exhaustive:enforce
+	switch metric.Type() {
+	case pmetric.MetricTypeSum:
+		temporality = metric.Sum().AggregationTemporality()
+	case pmetric.MetricTypeHistogram:
+		temporality = metric.Histogram().AggregationTemporality()
+	case pmetric.MetricTypeExponentialHistogram:
+		temporality = metric.ExponentialHistogram().AggregationTemporality()
+	case pmetric.MetricTypeGauge, pmetric.MetricTypeSummary, pmetric.MetricTypeEmpty:
+		return "", false
+	}
+	switch temporality {
+	case pmetric.AggregationTemporalityCumulative:
+		return "cumulative", true
+	case pmetric.AggregationTemporalityDelta:
+		return "delta", true
+	default:
+		return "", false
+	}
 }
 
 // isValidAggregationTemporality checks whether an OTel metric has a valid
@@ -214,11 +381,20 @@ func isValidAggregationTemporality(metric pmetric.Metric) bool {
 
 func (c *prometheusConverter) addHistogramDataPoints(dataPoints pmetric.HistogramDataPointSlice,
 	resource pcommon.Resource, settings Settings, baseName string,
-) {
+) (errs error) {
 	for x := 0; x < dataPoints.Len(); x++ {
 		pt := dataPoints.At(x)
-		timestamp := convertTimeStamp(pt.Timestamp())
-		baseLabels := createAttributes(resource, pt.Attributes(), settings.ExternalLabels, nil, false)
+		timestamp := convertTimeStamp(pt.Timestamp(), settings.TimestampRounding)
+		baseLabels, err := createAttributes(c.interner, resource, pt.Attributes(), c.scopeLabels, settings, nil, false)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		name, err := applyNameConflictPolicy(baseLabels, baseName, settings)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
 
 		// If the sum is unset, it indicates the _sum metric point should be
 		// omitted
@@ -228,12 +404,23 @@ func (c *prometheusConverter) addHistogramDataPoints(dataPoints pmetric.Histogra
 				Value:     pt.Sum(),
 				Timestamp: timestamp,
 			}
-			if pt.Flags().NoRecordedValue() {
+			keepSum := true
+			switch {
+			case staleMarker(pt.Flags(), settings):
 				sum.Value = math.Float64frombits(value.StaleNaN)
+			case math.IsNaN(sum.Value) && !value.IsStaleNaN(sum.Value):
+				switch settings.HistogramNaNPolicy {
+				case HistogramNaNPolicyDrop:
+					keepSum = false
+				case HistogramNaNPolicyZeroSum:
+					sum.Value = 0
+				}
 			}
 
-			sumlabels := createLabels(baseName+sumStr, baseLabels)
-			c.addSample(sum, sumlabels)
+			if keepSum {
+				sumlabels := createLabels(c.interner.internConcat(name, sumStr), baseLabels)
+				c.addSample(sum, sumlabels)
+			}
 		}
 
 		// treat count as a sample in an individual TimeSeries
@@ -241,56 +428,83 @@ func (c *prometheusConverter) addHistogramDataPoints(dataPoints pmetric.Histogra
 			Value:     float64(pt.Count()),
 			Timestamp: timestamp,
 		}
-		if pt.Flags().NoRecordedValue() {
+		if staleMarker(pt.Flags(), settings) {
 			count.Value = math.Float64frombits(value.StaleNaN)
 		}
 
-		countlabels := createLabels(baseName+countStr, baseLabels)
+		countlabels := createLabels(c.interner.internConcat(name, countStr), baseLabels)
 		c.addSample(count, countlabels)
 
-		// cumulative count for conversion to cumulative histogram
-		var cumulativeCount uint64
-
-		var bucketBounds []bucketBoundsData
-
-		// process each bound, based on histograms proto definition, # of buckets = # of explicit bounds + 1
-		for i := 0; i < pt.ExplicitBounds().Len() && i < pt.BucketCounts().Len(); i++ {
-			bound := pt.ExplicitBounds().At(i)
-			cumulativeCount += pt.BucketCounts().At(i)
-			bucket := &prompb.Sample{
-				Value:     float64(cumulativeCount),
+		// The _bucket series, and the exemplars attached to them, are omitted when only the coarse stats are sent.
+		if !settings.DropHistogramBuckets {
+			// cumulative count for conversion to cumulative histogram
+			var cumulativeCount uint64
+
+			var bucketBounds []bucketBoundsData
+
+			// process each bound, based on histograms proto definition, # of buckets = # of explicit bounds + 1
+			for i := 0; i < pt.ExplicitBounds().Len() && i < pt.BucketCounts().Len(); i++ {
+				bound := pt.ExplicitBounds().At(i)
+				cumulativeCount += pt.BucketCounts().At(i)
+				bucket := &prompb.Sample{
+					Value:     float64(cumulativeCount),
+					Timestamp: timestamp,
+				}
+				if staleMarker(pt.Flags(), settings) {
+					bucket.Value = math.Float64frombits(value.StaleNaN)
+				}
+				boundStr := c.interner.internFloat(bound)
+				labels := createLabels(c.interner.internConcat(name, bucketStr), baseLabels, leStr, boundStr)
+				ts := c.addSample(bucket, labels)
+
+				bucketBounds = append(bucketBounds, bucketBoundsData{ts: ts, bound: bound})
+			}
+			// add le=+Inf bucket
+			infBucket := &prompb.Sample{
 				Timestamp: timestamp,
 			}
-			if pt.Flags().NoRecordedValue() {
-				bucket.Value = math.Float64frombits(value.StaleNaN)
+			if staleMarker(pt.Flags(), settings) {
+				infBucket.Value = math.Float64frombits(value.StaleNaN)
+			} else {
+				infBucket.Value = float64(pt.Count())
 			}
-			boundStr := strconv.FormatFloat(bound, 'f', -1, 64)
-			labels := createLabels(baseName+bucketStr, baseLabels, leStr, boundStr)
-			ts := c.addSample(bucket, labels)
+			infLabels := createLabels(c.interner.internConcat(name, bucketStr), baseLabels, leStr, pInfStr)
+			ts := c.addSample(infBucket, infLabels)
 
-			bucketBounds = append(bucketBounds, bucketBoundsData{ts: ts, bound: bound})
-		}
-		// add le=+Inf bucket
-		infBucket := &prompb.Sample{
-			Timestamp: timestamp,
-		}
-		if pt.Flags().NoRecordedValue() {
-			infBucket.Value = math.Float64frombits(value.StaleNaN)
-		} else {
-			infBucket.Value = float64(pt.Count())
+			bucketBounds = append(bucketBounds, bucketBoundsData{ts: ts, bound: math.Inf(1)})
+			c.addExemplars(pt, bucketBounds, settings)
 		}
-		infLabels := createLabels(baseName+bucketStr, baseLabels, leStr, pInfStr)
-		ts := c.addSample(infBucket, infLabels)
-
-		bucketBounds = append(bucketBounds, bucketBoundsData{ts: ts, bound: math.Inf(1)})
-		c.addExemplars(pt, bucketBounds)
 
 		startTimestamp := pt.StartTimestamp()
 		if settings.ExportCreatedMetric && startTimestamp != 0 && !exportCreatedMetricGate.IsEnabled() {
-			labels := createLabels(baseName+createdSuffix, baseLabels)
-			c.addTimeSeriesIfNeeded(labels, startTimestamp, pt.Timestamp())
+			labels := createLabels(c.interner.internConcat(name, createdSuffix), baseLabels)
+			c.addTimeSeriesIfNeeded(labels, startTimestamp, pt.Timestamp(), settings.TimestampRounding)
+		}
+	}
+	return errs
+}
+
+// promotedScopeLabels returns the labels of the scope attributes listed in settings.PromoteScopeAttributes, with
+// remapped or sanitized names.
+func promotedScopeLabels(interner *labelValueInterner, scope pcommon.InstrumentationScope, settings Settings) []prompb.Label {
+	promoted := settings.PromoteScopeAttributes
+	if len(promoted) == 0 {
+		return nil
+	}
+	var labels []prompb.Label
+	attrs := scope.Attributes()
+	for _, name := range promoted {
+		value, ok := attrs.Get(name)
+		if !ok {
+			continue
 		}
+		labelName, remapped := settings.LabelNameRemapping[name]
+		if !remapped {
+			labelName = interner.normalizeLabel(name)
+		}
+		labels = append(labels, prompb.Label{Name: labelName, Value: interner.internValue(value)})
 	}
+	return labels
 }
 
 type exemplarType interface {
@@ -298,10 +512,16 @@ type exemplarType interface {
 	Exemplars() pmetric.ExemplarSlice
 }
 
-func getPromExemplars[T exemplarType](pt T) []prompb.Exemplar {
+func getPromExemplars[T exemplarType](pt T, settings Settings) []prompb.Exemplar {
+	if settings.skipExemplars {
+		return nil
+	}
 	promExemplars := make([]prompb.Exemplar, 0, pt.Exemplars().Len())
 	for i := 0; i < pt.Exemplars().Len(); i++ {
 		exemplar := pt.Exemplars().At(i)
+		if settings.ExemplarsFromSampledOnly && exemplar.TraceID().IsEmpty() {
+			continue
+		}
 		exemplarRunes := 0
 
 		var promExemplar prompb.Exemplar
@@ -362,6 +582,19 @@ func getPromExemplars[T exemplarType](pt T) []prompb.Exemplar {
 	return promExemplars
 }
 
+// addExemplarsToSeries adds exemplars to ts. If maxExemplars is positive, only the maxExemplars most
+// recent exemplars of the series are kept.
+func addExemplarsToSeries(ts *prompb.TimeSeries, exemplars []prompb.Exemplar, maxExemplars int) {
+	ts.Exemplars = append(ts.Exemplars, exemplars...)
+	if maxExemplars <= 0 || len(ts.Exemplars) <= maxExemplars {
+		return
+	}
+	sort.SliceStable(ts.Exemplars, func(i, j int) bool {
+		return ts.Exemplars[i].Timestamp < ts.Exemplars[j].Timestamp
+	})
+	ts.Exemplars = ts.Exemplars[len(ts.Exemplars)-maxExemplars:]
+}
+
 // mostRecentTimestampInMetric returns the latest timestamp in a batch of metrics
 func mostRecentTimestampInMetric(metric pmetric.Metric) pcommon.Timestamp {
 	var ts pcommon.Timestamp
@@ -399,22 +632,31 @@ func mostRecentTimestampInMetric(metric pmetric.Metric) pcommon.Timestamp {
 
 func (c *prometheusConverter) addSummaryDataPoints(dataPoints pmetric.SummaryDataPointSlice, resource pcommon.Resource,
 	settings Settings, baseName string,
-) {
+) (errs error) {
 	for x := 0; x < dataPoints.Len(); x++ {
 		pt := dataPoints.At(x)
-		timestamp := convertTimeStamp(pt.Timestamp())
-		baseLabels := createAttributes(resource, pt.Attributes(), settings.ExternalLabels, nil, false)
+		timestamp := convertTimeStamp(pt.Timestamp(), settings.TimestampRounding)
+		baseLabels, err := createAttributes(c.interner, resource, pt.Attributes(), c.scopeLabels, settings, nil, false)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		name, err := applyNameConflictPolicy(baseLabels, baseName, settings)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
 
 		// treat sum as a sample in an individual TimeSeries
 		sum := &prompb.Sample{
 			Value:     pt.Sum(),
 			Timestamp: timestamp,
 		}
-		if pt.Flags().NoRecordedValue() {
+		if staleMarker(pt.Flags(), settings) {
 			sum.Value = math.Float64frombits(value.StaleNaN)
 		}
-		// sum and count of the summary should append suffix to baseName
-		sumlabels := createLabels(baseName+sumStr, baseLabels)
+		// sum and count of the summary should append suffix to name
+		sumlabels := createLabels(c.interner.internConcat(name, sumStr), baseLabels)
 		c.addSample(sum, sumlabels)
 
 		// treat count as a sample in an individual TimeSeries
@@ -422,10 +664,10 @@ func (c *prometheusConverter) addSummaryDataPoints(dataPoints pmetric.SummaryDat
 			Value:     float64(pt.Count()),
 			Timestamp: timestamp,
 		}
-		if pt.Flags().NoRecordedValue() {
+		if staleMarker(pt.Flags(), settings) {
 			count.Value = math.Float64frombits(value.StaleNaN)
 		}
-		countlabels := createLabels(baseName+countStr, baseLabels)
+		countlabels := createLabels(c.interner.internConcat(name, countStr), baseLabels)
 		c.addSample(count, countlabels)
 
 		// process each percentile/quantile
@@ -435,29 +677,53 @@ func (c *prometheusConverter) addSummaryDataPoints(dataPoints pmetric.SummaryDat
 				Value:     qt.Value(),
 				Timestamp: timestamp,
 			}
-			if pt.Flags().NoRecordedValue() {
+			if staleMarker(pt.Flags(), settings) {
 				quantile.Value = math.Float64frombits(value.StaleNaN)
 			}
-			percentileStr := strconv.FormatFloat(qt.Quantile(), 'f', -1, 64)
-			qtlabels := createLabels(baseName, baseLabels, quantileStr, percentileStr)
+			percentileStr := c.interner.internFloat(qt.Quantile())
+			qtlabels := createLabels(name, baseLabels, quantileStr, percentileStr)
 			c.addSample(quantile, qtlabels)
 		}
 
 		startTimestamp := pt.StartTimestamp()
 		if settings.ExportCreatedMetric && startTimestamp != 0 && !exportCreatedMetricGate.IsEnabled() {
-			createdLabels := createLabels(baseName+createdSuffix, baseLabels)
-			c.addTimeSeriesIfNeeded(createdLabels, startTimestamp, pt.Timestamp())
+			createdLabels := createLabels(c.interner.internConcat(name, createdSuffix), baseLabels)
+			c.addTimeSeriesIfNeeded(createdLabels, startTimestamp, pt.Timestamp(), settings.TimestampRounding)
+		}
+	}
+	return errs
+}
+
+// applyNameConflictPolicy applies settings.NameConflictPolicy to the __name__ label that baseLabels, created
+// from the attributes of a data point of the metric named baseName, may include. It returns the name the
+// series of the data point are built from by createLabels.
+func applyNameConflictPolicy(baseLabels []prompb.Label, baseName string, settings Settings) (string, error) {
+	for _, label := range baseLabels {
+		if label.Name != model.MetricNameLabel || label.Value == baseName {
+			continue
+		}
+		switch settings.NameConflictPolicy {
+		case NameConflictPolicyPreserve:
+			return label.Value, nil
+		case NameConflictPolicyError:
+			return "", &NameConflictError{Name: baseName, Attribute: label.Value}
 		}
 	}
+	return baseName, nil
 }
 
-// createLabels returns a copy of baseLabels, adding to it the pair model.MetricNameLabel=name.
+// createLabels returns a copy of baseLabels, adding to it the pair model.MetricNameLabel=name in place of
+// the one baseLabels may include.
 // If extras are provided, corresponding label pairs are also added to the returned slice.
 // If extras is uneven length, the last (unpaired) extra will be ignored.
 func createLabels(name string, baseLabels []prompb.Label, extras ...string) []prompb.Label {
 	extraLabelCount := len(extras) / 2
-	labels := make([]prompb.Label, len(baseLabels), len(baseLabels)+extraLabelCount+1) // +1 for name
-	copy(labels, baseLabels)
+	labels := make([]prompb.Label, 0, len(baseLabels)+extraLabelCount+1) // +1 for name
+	for _, label := range baseLabels {
+		if label.Name != model.MetricNameLabel {
+			labels = append(labels, label)
+		}
+	}
 
 	n := len(extras)
 	n -= n % 2
@@ -472,7 +738,11 @@ func createLabels(name string, baseLabels []prompb.Label, extras ...string) []pr
 // getOrCreateTimeSeries returns the time series corresponding to the label set if existent, and false.
 // Otherwise it creates a new one and returns that, and true.
 func (c *prometheusConverter) getOrCreateTimeSeries(lbls []prompb.Label) (*prompb.TimeSeries, bool) {
-	h := timeSeriesSignature(lbls)
+	return c.getOrCreateTimeSeriesWithSignature(timeSeriesSignature(lbls), lbls)
+}
+
+// getOrCreateTimeSeriesWithSignature is like getOrCreateTimeSeries, for labels whose signature is known.
+func (c *prometheusConverter) getOrCreateTimeSeriesWithSignature(h uint64, lbls []prompb.Label) (*prompb.TimeSeries, bool) {
 	ts := c.unique[h]
 	if ts != nil {
 		if isSameMetric(ts, lbls) {
@@ -507,41 +777,55 @@ func (c *prometheusConverter) getOrCreateTimeSeries(lbls []prompb.Label) (*promp
 // addTimeSeriesIfNeeded adds a corresponding time series if it doesn't already exist.
 // If the time series doesn't already exist, it gets added with startTimestamp for its value and timestamp for its timestamp,
 // both converted to milliseconds.
-func (c *prometheusConverter) addTimeSeriesIfNeeded(lbls []prompb.Label, startTimestamp pcommon.Timestamp, timestamp pcommon.Timestamp) {
+func (c *prometheusConverter) addTimeSeriesIfNeeded(lbls []prompb.Label, startTimestamp pcommon.Timestamp, timestamp pcommon.Timestamp,
+	rounding TimestampRounding,
+) {
 	ts, created := c.getOrCreateTimeSeries(lbls)
 	if created {
 		ts.Samples = []prompb.Sample{
 			{
 				// convert ns to ms
-				Value:     float64(convertTimeStamp(startTimestamp)),
-				Timestamp: convertTimeStamp(timestamp),
+				Value:     float64(convertTimeStamp(startTimestamp, rounding)),
+				Timestamp: convertTimeStamp(timestamp, rounding),
 			},
 		}
 	}
 }
 
 // addResourceTargetInfo converts the resource to the target info metric.
-func addResourceTargetInfo(resource pcommon.Resource, settings Settings, timestamp pcommon.Timestamp, converter *prometheusConverter) {
+func addResourceTargetInfo(resource pcommon.Resource, schemaURL string, settings Settings, timestamp pcommon.Timestamp, converter *prometheusConverter) error {
 	if settings.DisableTargetInfo || timestamp == 0 {
-		return
+		return nil
 	}
 
 	attributes := resource.Attributes()
+	if settings.TargetInfoSkipWithoutIdentity {
+		_, haveServiceName := attributes.Get(conventions.AttributeServiceName)
+		_, haveInstanceID := attributes.Get(conventions.AttributeServiceInstanceID)
+		if !haveServiceName && !haveInstanceID {
+			return nil
+		}
+	}
 	identifyingAttrs := []string{
 		conventions.AttributeServiceNamespace,
 		conventions.AttributeServiceName,
 		conventions.AttributeServiceInstanceID,
 	}
-	nonIdentifyingAttrsCount := attributes.Len()
-	for _, a := range identifyingAttrs {
-		_, haveAttr := attributes.Get(a)
-		if haveAttr {
-			nonIdentifyingAttrsCount--
-		}
+	// Excluded attributes are left out of target_info just like the identifying ones.
+	ignoreAttrs := identifyingAttrs
+	if len(settings.TargetInfoExcludeAttributes) > 0 {
+		ignoreAttrs = slices.Concat(identifyingAttrs, settings.TargetInfoExcludeAttributes)
 	}
+	nonIdentifyingAttrsCount := 0
+	attributes.Range(func(key string, _ pcommon.Value) bool {
+		if !slices.Contains(ignoreAttrs, key) {
+			nonIdentifyingAttrsCount++
+		}
+		return true
+	})
 	if nonIdentifyingAttrsCount == 0 {
 		// If we only have job + instance, then target_info isn't useful, so don't add it.
-		return
+		return nil
 	}
 
 	name := prometheustranslator.TargetInfoMetricName
@@ -549,7 +833,14 @@ func addResourceTargetInfo(resource pcommon.Resource, settings Settings, timesta
 		name = settings.Namespace + "_" + name
 	}
 
-	labels := createAttributes(resource, attributes, settings.ExternalLabels, identifyingAttrs, false, model.MetricNameLabel, name)
+	extras := []string{model.MetricNameLabel, name}
+	if settings.TargetInfoIncludeSchemaURL && schemaURL != "" {
+		extras = append(extras, schemaURLLabel, schemaURL)
+	}
+	labels, err := createAttributes(converter.interner, resource, attributes, nil, settings, ignoreAttrs, false, extras...)
+	if err != nil {
+		return err
+	}
 	haveIdentifier := false
 	for _, l := range labels {
 		if l.Name == model.JobLabel || l.Name == model.InstanceLabel {
@@ -560,18 +851,31 @@ func addResourceTargetInfo(resource pcommon.Resource, settings Settings, timesta
 
 	if !haveIdentifier {
 		// We need at least one identifying label to generate target_info.
-		return
+		return nil
 	}
 
 	sample := &prompb.Sample{
 		Value: float64(1),
 		// convert ns to ms
-		Timestamp: convertTimeStamp(timestamp),
+		Timestamp: convertTimeStamp(timestamp, settings.TimestampRounding),
 	}
 	converter.addSample(sample, labels)
+	return nil
 }
 
-// convertTimeStamp converts OTLP timestamp in ns to timestamp in ms
-func convertTimeStamp(timestamp pcommon.Timestamp) int64 {
-	return timestamp.AsTime().UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond))
+// staleMarker reports whether the values of a data point with the given flags are replaced by Prometheus stale
+// markers, which is the case of data points without a recorded value unless settings.KeepNoRecordedValues is set.
+func staleMarker(flags pmetric.DataPointFlags, settings Settings) bool {
+	return flags.NoRecordedValue() && !settings.KeepNoRecordedValues
+}
+
+// convertTimeStamp converts OTLP timestamp in ns to timestamp in ms, rounded as configured. The conversion is done
+// on the unsigned timestamp, so that timestamps beyond the range of time.Time don't overflow.
+func convertTimeStamp(timestamp pcommon.Timestamp, rounding TimestampRounding) int64 {
+	const nanosPerMilli = uint64(time.Millisecond / time.Nanosecond)
+	millis := uint64(timestamp) / nanosPerMilli
+	if rounding == TimestampRoundingNearest && uint64(timestamp)%nanosPerMilli >= nanosPerMilli/2 {
+		millis++
+	}
+	return int64(millis)
 }
@@ -4,14 +4,17 @@
 package prometheusremotewrite
 
 import (
+	"fmt"
 	"math"
 	"sort"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -369,11 +372,99 @@ func Test_createLabelSet(t *testing.T) {
 	// run tests
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.ElementsMatch(t, tt.want, createAttributes(tt.resource, tt.orig, tt.externalLabels, nil, true, tt.extras...))
+			got, err := createAttributes(nil, tt.resource, tt.orig, nil, Settings{ExternalLabels: tt.externalLabels}, nil, true, tt.extras...)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, got)
 		})
 	}
 }
 
+func TestCreateAttributesInvalidLabelNamePolicy(t *testing.T) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("my label", "value")
+
+	for _, policy := range []InvalidLabelNamePolicy{"", InvalidLabelNamePolicySanitize} {
+		labels, err := createAttributes(nil, pcommon.NewResource(), attrs, nil, Settings{InvalidLabelNamePolicy: policy}, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, []prompb.Label{{Name: "my_label", Value: "value"}}, labels)
+	}
+
+	for _, policy := range []InvalidLabelNamePolicy{InvalidLabelNamePolicyDropSeries, InvalidLabelNamePolicyError} {
+		labels, err := createAttributes(nil, pcommon.NewResource(), attrs, nil, Settings{InvalidLabelNamePolicy: policy}, nil, true)
+		var invalidLabelNameErr *InvalidLabelNameError
+		require.ErrorAs(t, err, &invalidLabelNameErr)
+		assert.Equal(t, "my label", invalidLabelNameErr.Name)
+		assert.Nil(t, labels)
+	}
+
+	// Ignored attributes aren't subject to the policy.
+	labels, err := createAttributes(nil, pcommon.NewResource(), attrs, nil, Settings{InvalidLabelNamePolicy: InvalidLabelNamePolicyError},
+		[]string{"my label"}, true)
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+}
+
+func TestCreateAttributesLabelNameRemapping(t *testing.T) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("http.status_code", "200")
+	attrs.PutStr("http.method", "GET")
+	// Sanitized to the name status_code is remapped to.
+	attrs.PutStr("status_code", "ok")
+	// Sorted before the attribute remapped to the same name.
+	attrs.PutStr("region", "eu")
+	attrs.PutStr("zone name", "eu-west-1a")
+	attrs.PutStr("cloud.zone", "eu-west-1b")
+	remapping := map[string]string{
+		"http.status_code": "status_code",
+		"zone name":        "zone",
+		"cloud.zone":       "zone",
+		"cloud.region":     "region",
+	}
+
+	tests := []struct {
+		name   string
+		policy LabelCollisionPolicy
+		want   []prompb.Label
+	}{
+		{
+			name: "concatenate",
+			want: getPromLabels("http_method", "GET", "region", "eu", "status_code", "200;ok", "zone", "eu-west-1b;eu-west-1a"),
+		},
+		{
+			name:   "prefer_remapped",
+			policy: LabelCollisionPolicyPreferRemapped,
+			want:   getPromLabels("http_method", "GET", "region", "eu", "status_code", "200", "zone", "eu-west-1b;eu-west-1a"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := Settings{LabelNameRemapping: remapping, LabelCollisionPolicy: tt.policy}
+			got, err := createAttributes(newLabelValueInterner(), pcommon.NewResource(), attrs, nil, settings, nil, true)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+
+	t.Run("prefer_remapped_over_an_earlier_attribute", func(t *testing.T) {
+		attrs := pcommon.NewMap()
+		attrs.PutStr("region", "eu")
+		attrs.PutStr("cloud.region", "eu-west-1")
+		settings := Settings{LabelNameRemapping: remapping, LabelCollisionPolicy: LabelCollisionPolicyPreferRemapped}
+		got, err := createAttributes(nil, pcommon.NewResource(), attrs, nil, settings, nil, true)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, getPromLabels("region", "eu-west-1"), got)
+	})
+
+	t.Run("remapped_names_are_not_validated", func(t *testing.T) {
+		attrs := pcommon.NewMap()
+		attrs.PutStr("zone name", "eu-west-1a")
+		settings := Settings{InvalidLabelNamePolicy: InvalidLabelNamePolicyError, LabelNameRemapping: remapping}
+		got, err := createAttributes(nil, pcommon.NewResource(), attrs, nil, settings, nil, true)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, getPromLabels("zone", "eu-west-1a"), got)
+	})
+}
+
 func BenchmarkCreateAttributes(b *testing.B) {
 	r := pcommon.NewResource()
 	ext := map[string]string{}
@@ -387,7 +478,71 @@ func BenchmarkCreateAttributes(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		createAttributes(r, m, ext, nil, true)
+		_, _ = createAttributes(nil, r, m, nil, Settings{ExternalLabels: ext}, nil, true)
+	}
+}
+
+func TestLabelValueInterner(t *testing.T) {
+	interner := newLabelValueInterner()
+
+	intValue := pcommon.NewValueInt(200)
+	first := interner.internValue(intValue)
+	second := interner.internValue(intValue)
+	assert.Equal(t, "200", first)
+	assert.Same(t, unsafe.StringData(first), unsafe.StringData(second))
+
+	bound := interner.internFloat(0.5)
+	assert.Equal(t, "0.5", bound)
+	assert.Same(t, unsafe.StringData(bound), unsafe.StringData(interner.internFloat(0.5)))
+
+	job := interner.internConcat("production", "/", "checkout")
+	assert.Equal(t, "production/checkout", job)
+	assert.Same(t, unsafe.StringData(job), unsafe.StringData(interner.internConcat("production", "/", "checkout")))
+	assert.Equal(t, "http_method", interner.normalizeLabel("http.method"))
+
+	// A nil interner formats values without pooling them.
+	var nilInterner *labelValueInterner
+	assert.Equal(t, "true", nilInterner.internValue(pcommon.NewValueBool(true)))
+	assert.Equal(t, "+Inf", nilInterner.internFloat(math.Inf(1)))
+	assert.Equal(t, "a;b", nilInterner.internConcat("a", ";", "b"))
+	assert.Equal(t, "http_method", nilInterner.normalizeLabel("http.method"))
+}
+
+// BenchmarkCreateAttributesSharedLabelValues assembles the labels of many series whose values repeat,
+// with and without interning label values.
+func BenchmarkCreateAttributesSharedLabelValues(b *testing.B) {
+	r := pcommon.NewResource()
+	r.Attributes().PutStr(conventions.AttributeServiceNamespace, "production")
+	r.Attributes().PutStr(conventions.AttributeServiceName, "checkout")
+	ext := map[string]string{}
+
+	attrs := make([]pcommon.Map, 1000)
+	for i := range attrs {
+		attrs[i] = pcommon.NewMap()
+		attrs[i].PutStr("http.method", "GET")
+		attrs[i].PutStr("http.route", fmt.Sprintf("/api/v1/items/%d", i%10))
+		attrs[i].PutInt("http.status_code", 200+int64(i%5))
+		attrs[i].PutInt("net.host.port", 8080)
+		attrs[i].PutBool("error", i%2 == 0)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		newInterner func() *labelValueInterner
+	}{
+		{name: "without_interning", newInterner: func() *labelValueInterner { return nil }},
+		{name: "with_interning", newInterner: newLabelValueInterner},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				interner := tc.newInterner()
+				for _, m := range attrs {
+					_, _ = createAttributes(interner, r, m, nil, Settings{ExternalLabels: ext}, nil, true, model.MetricNameLabel, "http_server_duration")
+				}
+			}
+		})
 	}
 }
 
@@ -451,7 +606,7 @@ func TestPrometheusConverter_addExemplars(t *testing.T) {
 			converter := &prometheusConverter{
 				unique: tt.orig,
 			}
-			converter.addExemplars(tt.dataPoint, tt.bucketBounds)
+			converter.addExemplars(tt.dataPoint, tt.bucketBounds, Settings{})
 			assert.Exactly(t, tt.want, converter.unique)
 		})
 	}
@@ -539,12 +694,44 @@ func Test_getPromExemplars(t *testing.T) {
 	// run tests
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			requests := getPromExemplars(tt.histogram)
+			requests := getPromExemplars(tt.histogram, Settings{})
 			assert.Exactly(t, tt.expected, requests)
 		})
 	}
 }
 
+func Test_getPromExemplarsFromSampledOnly(t *testing.T) {
+	tnow := time.Now()
+	pt := pmetric.NewNumberDataPoint()
+	sampled := pt.Exemplars().AppendEmpty()
+	sampled.SetTimestamp(pcommon.NewTimestampFromTime(tnow))
+	sampled.SetDoubleValue(floatVal1)
+	sampled.SetTraceID([16]byte{1})
+	unsampled := pt.Exemplars().AppendEmpty()
+	unsampled.SetTimestamp(pcommon.NewTimestampFromTime(tnow))
+	unsampled.SetDoubleValue(floatVal2)
+
+	assert.Len(t, getPromExemplars(pt, Settings{}), 2)
+
+	exemplars := getPromExemplars(pt, Settings{ExemplarsFromSampledOnly: true})
+	require.Len(t, exemplars, 1)
+	assert.Equal(t, floatVal1, exemplars[0].Value)
+	assert.Equal(t, prometheustranslator.ExemplarTraceIDKey, exemplars[0].Labels[0].Name)
+}
+
+func Test_addExemplarsToSeries(t *testing.T) {
+	exemplars := []prompb.Exemplar{{Value: 1, Timestamp: 30}, {Value: 2, Timestamp: 10}, {Value: 3, Timestamp: 20}}
+
+	ts := &prompb.TimeSeries{}
+	addExemplarsToSeries(ts, exemplars, 0)
+	assert.Len(t, ts.Exemplars, 3, "0 should not limit the number of exemplars")
+
+	ts = &prompb.TimeSeries{Exemplars: []prompb.Exemplar{{Value: 4, Timestamp: 40}}}
+	addExemplarsToSeries(ts, exemplars, 2)
+	assert.Equal(t, []prompb.Exemplar{{Value: 1, Timestamp: 30}, {Value: 4, Timestamp: 40}}, ts.Exemplars,
+		"the most recent exemplars should be kept")
+}
+
 func TestAddResourceTargetInfo(t *testing.T) {
 	resourceAttrMap := map[string]any{
 		conventions.AttributeServiceName:       "service-name",
@@ -564,6 +751,16 @@ func TestAddResourceTargetInfo(t *testing.T) {
 	resourceWithOnlyServiceID := pcommon.NewResource()
 	resourceWithOnlyServiceID.Attributes().PutStr(conventions.AttributeServiceInstanceID, "service-instance-id")
 	resourceWithOnlyServiceID.Attributes().PutStr("resource_attr", "resource-attr-val-1")
+	resourceWithNoisyAttrs := pcommon.NewResource()
+	require.NoError(t, resourceWithNoisyAttrs.Attributes().FromRaw(resourceAttrMap))
+	resourceWithNoisyAttrs.Attributes().PutStr("resource_attr", "resource-attr-val-1")
+	resourceWithNoisyAttrs.Attributes().PutStr(conventions.AttributeProcessCommandLine, "/usr/bin/app --flag")
+	resourceWithNoisyAttrs.Attributes().PutInt(conventions.AttributeProcessPID, 1234)
+	resourceWithOnlyNoisyAttrs := pcommon.NewResource()
+	require.NoError(t, resourceWithOnlyNoisyAttrs.Attributes().FromRaw(resourceAttrMap))
+	resourceWithOnlyNoisyAttrs.Attributes().PutStr(conventions.AttributeProcessCommandLine, "/usr/bin/app --flag")
+	resourceWithoutIdentity := pcommon.NewResource()
+	resourceWithoutIdentity.Attributes().PutStr("resource_attr", "resource-attr-val-1")
 	for _, tc := range []struct {
 		desc       string
 		resource   pcommon.Resource
@@ -632,6 +829,59 @@ func TestAddResourceTargetInfo(t *testing.T) {
 			resource:  resourceWithOnlyServiceAttrs,
 			timestamp: testdata.TestMetricStartTimestamp,
 		},
+		{
+			desc:      "with resource, with excluded attributes",
+			resource:  resourceWithNoisyAttrs,
+			timestamp: testdata.TestMetricStartTimestamp,
+			settings: Settings{
+				TargetInfoExcludeAttributes: []string{conventions.AttributeProcessCommandLine, conventions.AttributeProcessPID},
+			},
+			wantLabels: []prompb.Label{
+				{Name: model.MetricNameLabel, Value: "target_info"},
+				{Name: model.InstanceLabel, Value: "service-instance-id"},
+				{Name: model.JobLabel, Value: "service-namespace/service-name"},
+				{Name: "resource_attr", Value: "resource-attr-val-1"},
+			},
+		},
+		{
+			desc:      "with resource, with only service and excluded attributes",
+			resource:  resourceWithOnlyNoisyAttrs,
+			timestamp: testdata.TestMetricStartTimestamp,
+			settings: Settings{
+				TargetInfoExcludeAttributes: []string{conventions.AttributeProcessCommandLine},
+			},
+		},
+		{
+			desc:      "with resource missing identity, with instance external label",
+			resource:  resourceWithoutIdentity,
+			timestamp: testdata.TestMetricStartTimestamp,
+			settings:  Settings{ExternalLabels: map[string]string{model.InstanceLabel: "collector"}},
+			wantLabels: []prompb.Label{
+				{Name: model.MetricNameLabel, Value: "target_info"},
+				{Name: model.InstanceLabel, Value: "collector"},
+				{Name: "resource_attr", Value: "resource-attr-val-1"},
+			},
+		},
+		{
+			desc:      "with resource missing identity, with instance external label, skipped without identity",
+			resource:  resourceWithoutIdentity,
+			timestamp: testdata.TestMetricStartTimestamp,
+			settings: Settings{
+				ExternalLabels:                map[string]string{model.InstanceLabel: "collector"},
+				TargetInfoSkipWithoutIdentity: true,
+			},
+		},
+		{
+			desc:      "with resource including service.name, skipped without identity",
+			resource:  resourceWithOnlyServiceName,
+			timestamp: testdata.TestMetricStartTimestamp,
+			settings:  Settings{TargetInfoSkipWithoutIdentity: true},
+			wantLabels: []prompb.Label{
+				{Name: model.MetricNameLabel, Value: "target_info"},
+				{Name: model.JobLabel, Value: "service-name"},
+				{Name: "resource_attr", Value: "resource-attr-val-1"},
+			},
+		},
 		{
 			// If there's no timestamp, target_info shouldn't be generated, since we don't know when the write is from.
 			desc:      "with resource, with service attributes, without timestamp",
@@ -642,7 +892,7 @@ func TestAddResourceTargetInfo(t *testing.T) {
 		t.Run(tc.desc, func(t *testing.T) {
 			converter := newPrometheusConverter()
 
-			addResourceTargetInfo(tc.resource, tc.settings, tc.timestamp, converter)
+			require.NoError(t, addResourceTargetInfo(tc.resource, "", tc.settings, tc.timestamp, converter))
 
 			if len(tc.wantLabels) == 0 || tc.settings.DisableTargetInfo {
 				assert.Empty(t, converter.timeSeries())
@@ -694,6 +944,29 @@ func TestMostRecentTimestampInMetric(t *testing.T) {
 	}
 }
 
+func TestConvertTimeStamp(t *testing.T) {
+	for _, tc := range []struct {
+		desc         string
+		timestamp    pcommon.Timestamp
+		wantTruncate int64
+		wantNearest  int64
+	}{
+		{desc: "zero", timestamp: 0, wantTruncate: 0, wantNearest: 0},
+		{desc: "whole millisecond", timestamp: 1_500_000_000, wantTruncate: 1500, wantNearest: 1500},
+		{desc: "below half a millisecond", timestamp: 1_500_499_999, wantTruncate: 1500, wantNearest: 1500},
+		{desc: "half a millisecond", timestamp: 1_500_500_000, wantTruncate: 1500, wantNearest: 1501},
+		{desc: "above half a millisecond", timestamp: 1_500_999_999, wantTruncate: 1500, wantNearest: 1501},
+		{desc: "beyond int64 nanoseconds", timestamp: 1<<63 + 1, wantTruncate: 9223372036854, wantNearest: 9223372036855},
+		{desc: "max", timestamp: math.MaxUint64, wantTruncate: 18446744073709, wantNearest: 18446744073710},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.wantTruncate, convertTimeStamp(tc.timestamp, TimestampRoundingTruncate))
+			assert.Equal(t, tc.wantTruncate, convertTimeStamp(tc.timestamp, ""), "truncating should be the default")
+			assert.Equal(t, tc.wantNearest, convertTimeStamp(tc.timestamp, TimestampRoundingNearest))
+		})
+	}
+}
+
 func TestPrometheusConverter_AddSummaryDataPoints(t *testing.T) {
 	ts := pcommon.Timestamp(time.Now().UnixNano())
 	tests := []struct {
@@ -730,19 +1003,19 @@ func TestPrometheusConverter_AddSummaryDataPoints(t *testing.T) {
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(sumLabels): {
 						Labels: sumLabels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(createdLabels): {
 						Labels: createdLabels,
 						Samples: []prompb.Sample{
-							{Value: float64(convertTimeStamp(ts)), Timestamp: convertTimeStamp(ts)},
+							{Value: float64(convertTimeStamp(ts, TimestampRoundingTruncate)), Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -772,13 +1045,13 @@ func TestPrometheusConverter_AddSummaryDataPoints(t *testing.T) {
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(sumLabels): {
 						Labels: sumLabels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -809,13 +1082,13 @@ func TestPrometheusConverter_AddSummaryDataPoints(t *testing.T) {
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(sumLabels): {
 						Labels: sumLabels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -831,14 +1104,14 @@ func TestPrometheusConverter_AddSummaryDataPoints(t *testing.T) {
 			metric := tt.metric()
 			converter := newPrometheusConverter()
 
-			converter.addSummaryDataPoints(
+			require.NoError(t, converter.addSummaryDataPoints(
 				metric.Summary().DataPoints(),
 				pcommon.NewResource(),
 				Settings{
 					ExportCreatedMetric: true,
 				},
 				metric.Name(),
-			)
+			))
 
 			assert.Equal(t, tt.want(), converter.unique)
 			assert.Empty(t, converter.conflicts)
@@ -883,19 +1156,19 @@ func TestPrometheusConverter_AddHistogramDataPoints(t *testing.T) {
 					timeSeriesSignature(infLabels): {
 						Labels: infLabels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(createdLabels): {
 						Labels: createdLabels,
 						Samples: []prompb.Sample{
-							{Value: float64(convertTimeStamp(ts)), Timestamp: convertTimeStamp(ts)},
+							{Value: float64(convertTimeStamp(ts, TimestampRoundingTruncate)), Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -926,13 +1199,13 @@ func TestPrometheusConverter_AddHistogramDataPoints(t *testing.T) {
 					timeSeriesSignature(infLabels): {
 						Labels: infLabels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -964,13 +1237,13 @@ func TestPrometheusConverter_AddHistogramDataPoints(t *testing.T) {
 					timeSeriesSignature(infLabels): {
 						Labels: infLabels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -986,14 +1259,14 @@ func TestPrometheusConverter_AddHistogramDataPoints(t *testing.T) {
 			metric := tt.metric()
 			converter := newPrometheusConverter()
 
-			converter.addHistogramDataPoints(
+			require.NoError(t, converter.addHistogramDataPoints(
 				metric.Histogram().DataPoints(),
 				pcommon.NewResource(),
 				Settings{
 					ExportCreatedMetric: true,
 				},
 				metric.Name(),
-			)
+			))
 
 			assert.Equal(t, tt.want(), converter.unique)
 			assert.Empty(t, converter.conflicts)
@@ -1001,6 +1274,128 @@ func TestPrometheusConverter_AddHistogramDataPoints(t *testing.T) {
 	}
 }
 
+func TestPrometheusConverter_AddHistogramDataPointsNaNSum(t *testing.T) {
+	ts := pcommon.Timestamp(time.Now().UnixNano())
+	tests := []struct {
+		name    string
+		policy  HistogramNaNPolicy
+		sum     float64
+		wantSum func(t *testing.T, got []prompb.Sample)
+	}{
+		{
+			name:   "default",
+			policy: "",
+			sum:    math.NaN(),
+			wantSum: func(t *testing.T, got []prompb.Sample) {
+				require.Len(t, got, 1)
+				assert.True(t, math.IsNaN(got[0].Value))
+				assert.False(t, value.IsStaleNaN(got[0].Value))
+			},
+		},
+		{
+			name:   "pass",
+			policy: HistogramNaNPolicyPass,
+			sum:    math.NaN(),
+			wantSum: func(t *testing.T, got []prompb.Sample) {
+				require.Len(t, got, 1)
+				assert.True(t, math.IsNaN(got[0].Value))
+			},
+		},
+		{
+			name:   "drop",
+			policy: HistogramNaNPolicyDrop,
+			sum:    math.NaN(),
+			wantSum: func(t *testing.T, got []prompb.Sample) {
+				assert.Nil(t, got)
+			},
+		},
+		{
+			name:   "zero_sum",
+			policy: HistogramNaNPolicyZeroSum,
+			sum:    math.NaN(),
+			wantSum: func(t *testing.T, got []prompb.Sample) {
+				require.Len(t, got, 1)
+				assert.Equal(t, 0.0, got[0].Value)
+			},
+		},
+		{
+			name:   "drop keeps stale marker",
+			policy: HistogramNaNPolicyDrop,
+			sum:    math.Float64frombits(value.StaleNaN),
+			wantSum: func(t *testing.T, got []prompb.Sample) {
+				require.Len(t, got, 1)
+				assert.True(t, value.IsStaleNaN(got[0].Value))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric := pmetric.NewMetric()
+			metric.SetName("test_hist")
+			metric.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			pt := metric.Histogram().DataPoints().AppendEmpty()
+			pt.SetTimestamp(ts)
+			pt.SetCount(1)
+			pt.SetSum(tt.sum)
+
+			converter := newPrometheusConverter()
+			require.NoError(t, converter.addHistogramDataPoints(
+				metric.Histogram().DataPoints(),
+				pcommon.NewResource(),
+				Settings{HistogramNaNPolicy: tt.policy},
+				metric.Name(),
+			))
+
+			seriesOf := func(name string, extra ...prompb.Label) []prompb.Sample {
+				labels := append([]prompb.Label{{Name: model.MetricNameLabel, Value: name}}, extra...)
+				if series, ok := converter.unique[timeSeriesSignature(labels)]; ok {
+					return series.Samples
+				}
+				return nil
+			}
+			tt.wantSum(t, seriesOf("test_hist"+sumStr))
+			assert.Equal(t, []prompb.Sample{{Value: 1, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)}}, seriesOf("test_hist"+countStr))
+			assert.Len(t, seriesOf("test_hist_bucket", prompb.Label{Name: model.BucketLabel, Value: "+Inf"}), 1)
+		})
+	}
+}
+
+func TestPrometheusConverter_AddHistogramDataPointsDropBuckets(t *testing.T) {
+	for _, dropBuckets := range []bool{false, true} {
+		t.Run(fmt.Sprintf("drop buckets %t", dropBuckets), func(t *testing.T) {
+			metric := pmetric.NewMetric()
+			metric.SetName("test_hist")
+			metric.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			pt := metric.Histogram().DataPoints().AppendEmpty()
+			pt.SetTimestamp(pcommon.Timestamp(time.Now().UnixNano()))
+			pt.SetCount(3)
+			pt.SetSum(12)
+			pt.ExplicitBounds().FromRaw([]float64{1, 5})
+			pt.BucketCounts().FromRaw([]uint64{1, 1, 1})
+			pt.Exemplars().AppendEmpty().SetDoubleValue(4)
+
+			converter := newPrometheusConverter()
+			require.NoError(t, converter.addHistogramDataPoints(
+				metric.Histogram().DataPoints(),
+				pcommon.NewResource(),
+				Settings{DropHistogramBuckets: dropBuckets},
+				metric.Name(),
+			))
+
+			var names []string
+			for _, series := range converter.unique {
+				names = append(names, series.Labels[0].Value)
+			}
+			if dropBuckets {
+				assert.ElementsMatch(t, []string{"test_hist_sum", "test_hist_count"}, names)
+				return
+			}
+			assert.ElementsMatch(t, []string{"test_hist_sum", "test_hist_count", "test_hist_bucket", "test_hist_bucket",
+				"test_hist_bucket"}, names)
+		})
+	}
+}
+
 func TestPrometheusConverter_getOrCreateTimeSeries(t *testing.T) {
 	converter := newPrometheusConverter()
 	lbls := []prompb.Label{
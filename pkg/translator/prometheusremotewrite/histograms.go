@@ -12,42 +12,50 @@ import (
 	"github.com/prometheus/prometheus/prompb"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/multierr"
 )
 
 const defaultZeroThreshold = 1e-128
 
 func (c *prometheusConverter) addExponentialHistogramDataPoints(dataPoints pmetric.ExponentialHistogramDataPointSlice,
 	resource pcommon.Resource, settings Settings, baseName string,
-) error {
+) (errs error) {
 	for x := 0; x < dataPoints.Len(); x++ {
 		pt := dataPoints.At(x)
-		lbls := createAttributes(
+		lbls, err := createAttributes(
+			c.interner,
 			resource,
 			pt.Attributes(),
-			settings.ExternalLabels,
+			c.scopeLabels,
+			settings,
 			nil,
 			true,
 			model.MetricNameLabel,
 			baseName,
 		)
-		ts, _ := c.getOrCreateTimeSeries(lbls)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
 
-		histogram, err := exponentialToNativeHistogram(pt)
+		histogram, err := exponentialToNativeHistogram(pt, settings)
 		if err != nil {
-			return err
+			errs = multierr.Append(errs, err)
+			continue
 		}
+		ts, _ := c.getOrCreateTimeSeries(lbls)
 		ts.Histograms = append(ts.Histograms, histogram)
 
-		exemplars := getPromExemplars[pmetric.ExponentialHistogramDataPoint](pt)
-		ts.Exemplars = append(ts.Exemplars, exemplars...)
+		exemplars := getPromExemplars[pmetric.ExponentialHistogramDataPoint](pt, settings)
+		addExemplarsToSeries(ts, exemplars, settings.MaxExemplarsPerSeries)
 	}
 
-	return nil
+	return errs
 }
 
 // exponentialToNativeHistogram  translates OTel Exponential Histogram data point
 // to Prometheus Native Histogram.
-func exponentialToNativeHistogram(p pmetric.ExponentialHistogramDataPoint) (prompb.Histogram, error) {
+func exponentialToNativeHistogram(p pmetric.ExponentialHistogramDataPoint, settings Settings) (prompb.Histogram, error) {
 	scale := p.Scale()
 	if scale < -4 {
 		return prompb.Histogram{},
@@ -87,10 +95,10 @@ func exponentialToNativeHistogram(p pmetric.ExponentialHistogramDataPoint) (prom
 		NegativeSpans:  nSpans,
 		NegativeDeltas: nDeltas,
 
-		Timestamp: convertTimeStamp(p.Timestamp()),
+		Timestamp: convertTimeStamp(p.Timestamp(), settings.TimestampRounding),
 	}
 
-	if p.Flags().NoRecordedValue() {
+	if staleMarker(p.Flags(), settings) {
 		h.Sum = math.Float64frombits(value.StaleNaN)
 		h.Count = &prompb.Histogram_CountInt{CountInt: value.StaleNaN}
 	} else {
@@ -553,7 +553,7 @@ func TestExponentialToNativeHistogram(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			validateExponentialHistogramCount(t, tt.exponentialHist()) // Sanity check.
-			got, err := exponentialToNativeHistogram(tt.exponentialHist())
+			got, err := exponentialToNativeHistogram(tt.exponentialHist(), Settings{})
 			if tt.wantErrMessage != "" {
 				assert.ErrorContains(t, err, tt.wantErrMessage)
 				return
@@ -751,3 +751,51 @@ func TestPrometheusConverter_addExponentialHistogramDataPoints(t *testing.T) {
 		})
 	}
 }
+
+// TestPrometheusConverter_addExponentialHistogramDataPointsErrors checks that the data points that can't be
+// translated don't keep the ones after them from being translated.
+func TestPrometheusConverter_addExponentialHistogramDataPointsErrors(t *testing.T) {
+	metric := pmetric.NewMetric()
+	metric.SetName("test_hist")
+	metric.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+	pt := metric.ExponentialHistogram().DataPoints().AppendEmpty()
+	pt.SetScale(-10)
+	pt.Attributes().PutStr("attr", "invalid_scale")
+
+	pt = metric.ExponentialHistogram().DataPoints().AppendEmpty()
+	pt.SetCount(4)
+	pt.SetScale(1)
+	pt.Positive().SetOffset(-1)
+	pt.Positive().BucketCounts().FromRaw([]uint64{4})
+	pt.Attributes().PutStr("attr", "test_attr")
+
+	converter := newPrometheusConverter()
+	err := converter.addExponentialHistogramDataPoints(
+		metric.ExponentialHistogram().DataPoints(),
+		pcommon.NewResource(),
+		Settings{},
+		prometheustranslator.BuildCompliantName(metric, "", true),
+	)
+	assert.ErrorContains(t, err, "Scale must be >= -4, was -10")
+
+	labels := []prompb.Label{
+		{Name: model.MetricNameLabel, Value: "test_hist"},
+		{Name: "attr", Value: "test_attr"},
+	}
+	assert.Equal(t, map[uint64]*prompb.TimeSeries{
+		timeSeriesSignature(labels): {
+			Labels: labels,
+			Histograms: []prompb.Histogram{
+				{
+					Count:          &prompb.Histogram_CountInt{CountInt: 4},
+					Schema:         1,
+					ZeroThreshold:  defaultZeroThreshold,
+					ZeroCount:      &prompb.Histogram_ZeroCountInt{ZeroCountInt: 0},
+					PositiveSpans:  []prompb.BucketSpan{{Offset: 0, Length: 1}},
+					PositiveDeltas: []int64{4},
+				},
+			},
+		},
+	}, converter.unique)
+}
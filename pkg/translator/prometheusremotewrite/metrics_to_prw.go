@@ -4,10 +4,11 @@
 package prometheusremotewrite // import "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 
 import (
-	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
+	"sync"
 
 	"github.com/prometheus/prometheus/prompb"
 	"go.opentelemetry.io/collector/pdata/pcommon"
@@ -24,12 +25,174 @@ type Settings struct {
 	ExportCreatedMetric bool
 	AddMetricSuffixes   bool
 	SendMetadata        bool
+	// InvalidLabelNamePolicy controls how attributes that aren't valid Prometheus label names are translated.
+	InvalidLabelNamePolicy InvalidLabelNamePolicy
+	// LabelNameRemapping maps attribute keys to the label names they are translated to, instead of sanitizing
+	// them. The label names are expected to be valid.
+	LabelNameRemapping map[string]string
+	// LabelCollisionPolicy controls how the values of attributes translated to the same label name are merged.
+	LabelCollisionPolicy LabelCollisionPolicy
+	// NameConflictPolicy controls the metric name of the series whose attributes include a __name__ that differs
+	// from the translated metric name.
+	NameConflictPolicy NameConflictPolicy
+	// HistogramNaNPolicy controls the _sum series of the histogram data points whose sum is NaN.
+	HistogramNaNPolicy HistogramNaNPolicy
+	// DropHistogramBuckets omits the _bucket series of the classic histograms, only sending their _count and _sum.
+	DropHistogramBuckets bool
+	// TargetInfoExcludeAttributes lists the resource attributes that are not added to target_info.
+	TargetInfoExcludeAttributes []string
+	// TargetInfoSkipWithoutIdentity skips target_info for the resources that have neither service.name nor
+	// service.instance.id, even when external labels or attributes provide the job or instance labels.
+	TargetInfoSkipWithoutIdentity bool
+	// TargetInfoIncludeSchemaURL adds the schema URL of the resource, when it has one, to target_info as the
+	// schema_url label.
+	TargetInfoIncludeSchemaURL bool
+	// ExemplarsFromSampledOnly drops the exemplars that aren't linked to a sampled trace. OTLP exemplars
+	// don't carry trace flags, so exemplars are considered sampled when they have a trace ID.
+	ExemplarsFromSampledOnly bool
+	// MaxExemplarsPerSeries caps the number of exemplars of each series, keeping the most recent ones.
+	// 0 means no limit.
+	MaxExemplarsPerSeries int
+	// ExemplarMetricFilter only keeps the exemplars of the metrics whose translated name it matches, without the
+	// suffixes of the series of the histograms and summaries. The exemplars of all the metrics are kept if it is nil.
+	ExemplarMetricFilter *regexp.Regexp
+	// PromoteScopeAttributes lists the instrumentation scope attributes that are added as labels to the
+	// series of the scope. Attributes of the data points take precedence over them.
+	PromoteScopeAttributes []string
+	// EmitDroppedAttributesLabel adds the otel_dropped_attributes_count label, holding the number of attributes
+	// the resource and the instrumentation scope of a series dropped, to the series for which it isn't zero.
+	// OTLP data points don't carry a dropped attributes count of their own.
+	EmitDroppedAttributesLabel bool
+	// TemporalityLabel is the name of a label added to the series of the sums and histograms, holding the
+	// aggregation temporality of their metric: "cumulative" or "delta". No label is added when it is empty.
+	TemporalityLabel string
+	// KeepNoRecordedValues sends the values of the data points flagged with NoRecordedValue as they are, instead
+	// of replacing them with Prometheus stale markers.
+	KeepNoRecordedValues bool
+	// TimestampRounding controls how the nanosecond timestamps of the data points are rounded to the milliseconds
+	// of Prometheus samples. Defaults to TimestampRoundingTruncate.
+	TimestampRounding TimestampRounding
+	// UnitSuffixes controls how the units of the metrics are appended to their names when AddMetricSuffixes
+	// is set.
+	UnitSuffixes prometheustranslator.UnitSuffixes
+	// TranslationConcurrency is the number of goroutines FromMetrics translates the resources of the metrics
+	// with. 0 and 1 translate them sequentially.
+	TranslationConcurrency int
+
+	// skipExemplars drops the exemplars of the metric being translated, when it doesn't match ExemplarMetricFilter.
+	skipExemplars bool
+}
+
+// InvalidLabelNamePolicy controls how attributes whose names aren't valid Prometheus label names are translated.
+type InvalidLabelNamePolicy string
+
+const (
+	// InvalidLabelNamePolicySanitize replaces the invalid characters of the name with underscores. It is the default.
+	InvalidLabelNamePolicySanitize InvalidLabelNamePolicy = "sanitize"
+	// InvalidLabelNamePolicyDropSeries drops the series and reports an *InvalidLabelNameError for it.
+	InvalidLabelNamePolicyDropSeries InvalidLabelNamePolicy = "drop_series"
+	// InvalidLabelNamePolicyError drops the series and reports an *InvalidLabelNameError for it, like
+	// InvalidLabelNamePolicyDropSeries, but callers are expected to reject the whole batch.
+	InvalidLabelNamePolicyError InvalidLabelNamePolicy = "error"
+)
+
+// InvalidLabelNameError reports an attribute name that isn't a valid Prometheus label name.
+type InvalidLabelNameError struct {
+	Name string
+}
+
+func (e *InvalidLabelNameError) Error() string {
+	return fmt.Sprintf("attribute %q is not a valid Prometheus label name", e.Name)
+}
+
+// UnsupportedMetricTypeError reports a metric that isn't translated because its type, such as an unset one, has
+// no Prometheus equivalent.
+type UnsupportedMetricTypeError struct {
+	MetricName string
+	Type       pmetric.MetricType
+}
+
+func (e *UnsupportedMetricTypeError) Error() string {
+	return fmt.Sprintf("metric %q has the unsupported type %q", e.MetricName, e.Type)
+}
+
+// TimestampRounding controls how the nanosecond timestamps of OTLP data points are rounded to milliseconds.
+type TimestampRounding string
+
+const (
+	// TimestampRoundingTruncate drops the sub-millisecond part of the timestamps. It is the default.
+	TimestampRoundingTruncate TimestampRounding = "truncate"
+	// TimestampRoundingNearest rounds the timestamps to the nearest millisecond, half a millisecond being
+	// rounded up.
+	TimestampRoundingNearest TimestampRounding = "nearest"
+)
+
+// droppedAttributesCountLabel is the label added when Settings.EmitDroppedAttributesLabel is set.
+const droppedAttributesCountLabel = "otel_dropped_attributes_count"
+
+// schemaURLLabel is the label of target_info added when Settings.TargetInfoIncludeSchemaURL is set.
+const schemaURLLabel = "schema_url"
+
+// LabelCollisionPolicy controls how the values of attributes translated to the same label name are merged.
+type LabelCollisionPolicy string
+
+const (
+	// LabelCollisionPolicyConcatenate joins the distinct values with ";", in the order of the attribute keys.
+	// It is the default.
+	LabelCollisionPolicyConcatenate LabelCollisionPolicy = "concatenate"
+	// LabelCollisionPolicyPreferRemapped keeps the value of the attribute remapped by LabelNameRemapping,
+	// dropping the values of the attributes sanitized to the same name. The values of attributes remapped to
+	// the same name are concatenated.
+	LabelCollisionPolicyPreferRemapped LabelCollisionPolicy = "prefer_remapped"
+)
+
+// NameConflictPolicy controls the metric name of the series whose attributes include a __name__ label that
+// differs from the translated metric name.
+type NameConflictPolicy string
+
+const (
+	// NameConflictPolicyOverride names the series after the translated metric name. It is the default.
+	NameConflictPolicyOverride NameConflictPolicy = "override"
+	// NameConflictPolicyPreserve keeps the __name__ of the attributes. The series of histograms and summaries
+	// are named after it, followed by their usual suffixes.
+	NameConflictPolicyPreserve NameConflictPolicy = "preserve"
+	// NameConflictPolicyError drops the series and reports a *NameConflictError for it.
+	NameConflictPolicyError NameConflictPolicy = "error"
+)
+
+// NameConflictError reports a __name__ attribute that differs from the translated metric name.
+type NameConflictError struct {
+	Name      string
+	Attribute string
+}
+
+func (e *NameConflictError) Error() string {
+	return fmt.Sprintf("__name__ attribute %q conflicts with the metric name %q", e.Attribute, e.Name)
 }
 
+// HistogramNaNPolicy controls the _sum series of the histogram data points whose sum is NaN, which some SDKs
+// report after negative observations. Their _count and _bucket series are sent whatever the policy, and the sums
+// that are stale markers are kept.
+type HistogramNaNPolicy string
+
+const (
+	// HistogramNaNPolicyPass sends the NaN sum as is. It is the default.
+	HistogramNaNPolicyPass HistogramNaNPolicy = "pass"
+	// HistogramNaNPolicyDrop omits the _sum sample.
+	HistogramNaNPolicyDrop HistogramNaNPolicy = "drop"
+	// HistogramNaNPolicyZeroSum sends 0 instead of the NaN sum.
+	HistogramNaNPolicyZeroSum HistogramNaNPolicy = "zero_sum"
+)
+
 // FromMetrics converts pmetric.Metrics to Prometheus remote write format.
 func FromMetrics(md pmetric.Metrics, settings Settings) (map[string]*prompb.TimeSeries, error) {
 	c := newPrometheusConverter()
-	errs := c.fromMetrics(md, settings)
+	var errs error
+	if settings.TranslationConcurrency > 1 && md.ResourceMetrics().Len() > 1 {
+		errs = c.fromMetricsConcurrently(md, settings)
+	} else {
+		errs = c.fromMetrics(md, settings)
+	}
 	tss := c.timeSeries()
 	out := make(map[string]*prompb.TimeSeries, len(tss))
 	for i := range tss {
@@ -43,12 +206,17 @@ func FromMetrics(md pmetric.Metrics, settings Settings) (map[string]*prompb.Time
 type prometheusConverter struct {
 	unique    map[uint64]*prompb.TimeSeries
 	conflicts map[uint64][]*prompb.TimeSeries
+	interner  *labelValueInterner
+	// scopeLabels holds the promoted attributes of the scope whose metrics are being converted, and its
+	// dropped attributes count label.
+	scopeLabels []prompb.Label
 }
 
 func newPrometheusConverter() *prometheusConverter {
 	return &prometheusConverter{
 		unique:    map[uint64]*prompb.TimeSeries{},
 		conflicts: map[uint64][]*prompb.TimeSeries{},
+		interner:  newLabelValueInterner(),
 	}
 }
 
@@ -56,81 +224,166 @@ func newPrometheusConverter() *prometheusConverter {
 func (c *prometheusConverter) fromMetrics(md pmetric.Metrics, settings Settings) (errs error) {
 	resourceMetricsSlice := md.ResourceMetrics()
 	for i := 0; i < resourceMetricsSlice.Len(); i++ {
-		resourceMetrics := resourceMetricsSlice.At(i)
-		resource := resourceMetrics.Resource()
-		scopeMetricsSlice := resourceMetrics.ScopeMetrics()
-		// keep track of the most recent timestamp in the ResourceMetrics for
-		// use with the "target" info metric
-		var mostRecentTimestamp pcommon.Timestamp
-		for j := 0; j < scopeMetricsSlice.Len(); j++ {
-			metricSlice := scopeMetricsSlice.At(j).Metrics()
-
-			// TODO: decide if instrumentation library information should be exported as labels
-			for k := 0; k < metricSlice.Len(); k++ {
-				metric := metricSlice.At(k)
-				mostRecentTimestamp = max(mostRecentTimestamp, mostRecentTimestampInMetric(metric))
-
-				if !isValidAggregationTemporality(metric) {
-					errs = multierr.Append(errs, fmt.Errorf("invalid temporality and type combination for metric %q", metric.Name()))
-					continue
-				}
+		errs = multierr.Append(errs, c.fromResourceMetrics(resourceMetricsSlice.At(i), settings))
+	}
+	return
+}
+
+// fromResourceMetrics converts the metrics of a single resource to Prometheus remote write format.
+func (c *prometheusConverter) fromResourceMetrics(resourceMetrics pmetric.ResourceMetrics, settings Settings) (errs error) {
+	resource := resourceMetrics.Resource()
+	scopeMetricsSlice := resourceMetrics.ScopeMetrics()
+	// keep track of the most recent timestamp in the ResourceMetrics for
+	// use with the "target" info metric
+	var mostRecentTimestamp pcommon.Timestamp
+	for j := 0; j < scopeMetricsSlice.Len(); j++ {
+		scopeMetrics := scopeMetricsSlice.At(j)
+		c.scopeLabels = promotedScopeLabels(c.interner, scopeMetrics.Scope(), settings)
+		if settings.EmitDroppedAttributesLabel {
+			if dropped := resource.DroppedAttributesCount() + scopeMetrics.Scope().DroppedAttributesCount(); dropped > 0 {
+				c.scopeLabels = append(c.scopeLabels, prompb.Label{
+					Name:  droppedAttributesCountLabel,
+					Value: c.interner.intern(strconv.FormatUint(uint64(dropped), 10)),
+				})
+			}
+		}
+		scopeLabels := c.scopeLabels
+		metricSlice := scopeMetrics.Metrics()
+
+		// TODO: decide if instrumentation library information should be exported as labels
+		for k := 0; k < metricSlice.Len(); k++ {
+			metric := metricSlice.At(k)
+			mostRecentTimestamp = max(mostRecentTimestamp, mostRecentTimestampInMetric(metric))
+
+			if metric.Type() == pmetric.MetricTypeEmpty {
+				errs = multierr.Append(errs, &UnsupportedMetricTypeError{MetricName: metric.Name(), Type: metric.Type()})
+				continue
+			}
+			if !isValidAggregationTemporality(metric) {
+				errs = multierr.Append(errs, fmt.Errorf("invalid temporality and type combination for metric %q", metric.Name()))
+				continue
+			}
+
+			promName := prometheustranslator.BuildCompliantNameWithUnitSuffixes(metric, settings.Namespace, settings.AddMetricSuffixes, settings.UnitSuffixes)
+			c.scopeLabels = scopeLabels
+			if temporality, ok := aggregationTemporality(metric); ok && settings.TemporalityLabel != "" {
+				// The labels of the scope are copied, so that those of the next metrics don't get the label.
+				c.scopeLabels = append(scopeLabels[:len(scopeLabels):len(scopeLabels)], prompb.Label{
+					Name:  settings.TemporalityLabel,
+					Value: temporality,
+				})
+			}
 
-				promName := prometheustranslator.BuildCompliantName(metric, settings.Namespace, settings.AddMetricSuffixes)
-
-				// handle individual metrics based on type
-				//exhaustive:enforce
-				switch metric.Type() {
-				case pmetric.MetricTypeGauge:
-					dataPoints := metric.Gauge().DataPoints()
-					if dataPoints.Len() == 0 {
-						errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
-						break
-					}
-					c.addGaugeNumberDataPoints(dataPoints, resource, settings, promName)
-				case pmetric.MetricTypeSum:
-					dataPoints := metric.Sum().DataPoints()
-					if dataPoints.Len() == 0 {
-						errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
-						break
-					}
-					c.addSumNumberDataPoints(dataPoints, resource, metric, settings, promName)
-				case pmetric.MetricTypeHistogram:
-					dataPoints := metric.Histogram().DataPoints()
-					if dataPoints.Len() == 0 {
-						errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
-						break
-					}
-					c.addHistogramDataPoints(dataPoints, resource, settings, promName)
-				case pmetric.MetricTypeExponentialHistogram:
-					dataPoints := metric.ExponentialHistogram().DataPoints()
-					if dataPoints.Len() == 0 {
-						errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
-						break
-					}
-					errs = multierr.Append(errs, c.addExponentialHistogramDataPoints(
-						dataPoints,
-						resource,
-						settings,
-						promName,
-					))
-				case pmetric.MetricTypeSummary:
-					dataPoints := metric.Summary().DataPoints()
-					if dataPoints.Len() == 0 {
-						errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
-						break
-					}
-					c.addSummaryDataPoints(dataPoints, resource, settings, promName)
-				default:
-					errs = multierr.Append(errs, errors.New("unsupported metric type"))
+			metricSettings := settings
+			metricSettings.skipExemplars = settings.ExemplarMetricFilter != nil && !settings.ExemplarMetricFilter.MatchString(promName)
+
+			// handle individual metrics based on type
+			//exhaustive:enforce
+			switch metric.Type() {
+			case pmetric.MetricTypeGauge:
+				dataPoints := metric.Gauge().DataPoints()
+				if dataPoints.Len() == 0 {
+					errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
+					break
+				}
+				errs = multierr.Append(errs, c.addGaugeNumberDataPoints(dataPoints, resource, metricSettings, promName))
+			case pmetric.MetricTypeSum:
+				dataPoints := metric.Sum().DataPoints()
+				if dataPoints.Len() == 0 {
+					errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
+					break
+				}
+				errs = multierr.Append(errs, c.addSumNumberDataPoints(dataPoints, resource, metric, metricSettings, promName))
+			case pmetric.MetricTypeHistogram:
+				dataPoints := metric.Histogram().DataPoints()
+				if dataPoints.Len() == 0 {
+					errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
+					break
+				}
+				errs = multierr.Append(errs, c.addHistogramDataPoints(dataPoints, resource, metricSettings, promName))
+			case pmetric.MetricTypeExponentialHistogram:
+				dataPoints := metric.ExponentialHistogram().DataPoints()
+				if dataPoints.Len() == 0 {
+					errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
+					break
 				}
+				errs = multierr.Append(errs, c.addExponentialHistogramDataPoints(
+					dataPoints,
+					resource,
+					metricSettings,
+					promName,
+				))
+			case pmetric.MetricTypeSummary:
+				dataPoints := metric.Summary().DataPoints()
+				if dataPoints.Len() == 0 {
+					errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
+					break
+				}
+				errs = multierr.Append(errs, c.addSummaryDataPoints(dataPoints, resource, metricSettings, promName))
+			default:
+				errs = multierr.Append(errs, &UnsupportedMetricTypeError{MetricName: metric.Name(), Type: metric.Type()})
 			}
 		}
-		addResourceTargetInfo(resource, settings, mostRecentTimestamp, c)
 	}
-
+	errs = multierr.Append(errs, addResourceTargetInfo(resource, resourceMetrics.SchemaUrl(), settings, mostRecentTimestamp, c))
 	return
 }
 
+// fromMetricsConcurrently converts pmetric.Metrics like fromMetrics, splitting the resources in contiguous
+// ranges that are translated concurrently by converters of their own, as converters aren't safe for
+// concurrent use. The results are merged in the order of the resources, so that they match those of
+// fromMetrics.
+func (c *prometheusConverter) fromMetricsConcurrently(md pmetric.Metrics, settings Settings) error {
+	resourceMetricsSlice := md.ResourceMetrics()
+	workers := min(settings.TranslationConcurrency, resourceMetricsSlice.Len())
+	converters := make([]*prometheusConverter, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		converters[w] = newPrometheusConverter()
+		first, last := w*resourceMetricsSlice.Len()/workers, (w+1)*resourceMetricsSlice.Len()/workers
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := first; i < last; i++ {
+				errs[w] = multierr.Append(errs[w], converters[w].fromResourceMetrics(resourceMetricsSlice.At(i), settings))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// c takes over the series of the first converter, those of the others are merged into them.
+	c.unique, c.conflicts = converters[0].unique, converters[0].conflicts
+	merged := errs[0]
+	for w := 1; w < workers; w++ {
+		c.merge(converters[w], settings)
+		merged = multierr.Append(merged, errs[w])
+	}
+	return merged
+}
+
+// merge adds the series of other, which converted the metrics following those c converted, to c.
+func (c *prometheusConverter) merge(other *prometheusConverter, settings Settings) {
+	mergeSeries := func(h uint64, ts *prompb.TimeSeries) {
+		merged, created := c.getOrCreateTimeSeriesWithSignature(h, ts.Labels)
+		if created {
+			*merged = *ts
+			return
+		}
+		merged.Samples = append(merged.Samples, ts.Samples...)
+		merged.Histograms = append(merged.Histograms, ts.Histograms...)
+		addExemplarsToSeries(merged, ts.Exemplars, settings.MaxExemplarsPerSeries)
+	}
+	for h, ts := range other.unique {
+		mergeSeries(h, ts)
+	}
+	for h, conflicts := range other.conflicts {
+		for _, ts := range conflicts {
+			mergeSeries(h, ts)
+		}
+	}
+}
+
 // timeSeries returns a slice of the prompb.TimeSeries that were converted from OTel format.
 func (c *prometheusConverter) timeSeries() []prompb.TimeSeries {
 	conflicts := 0
@@ -164,12 +417,12 @@ func isSameMetric(ts *prompb.TimeSeries, lbls []prompb.Label) bool {
 
 // addExemplars adds exemplars for the dataPoint. For each exemplar, if it can find a bucket bound corresponding to its value,
 // the exemplar is added to the bucket bound's time series, provided that the time series' has samples.
-func (c *prometheusConverter) addExemplars(dataPoint pmetric.HistogramDataPoint, bucketBounds []bucketBoundsData) {
+func (c *prometheusConverter) addExemplars(dataPoint pmetric.HistogramDataPoint, bucketBounds []bucketBoundsData, settings Settings) {
 	if len(bucketBounds) == 0 {
 		return
 	}
 
-	exemplars := getPromExemplars(dataPoint)
+	exemplars := getPromExemplars(dataPoint, settings)
 	if len(exemplars) == 0 {
 		return
 	}
@@ -178,7 +431,7 @@ func (c *prometheusConverter) addExemplars(dataPoint pmetric.HistogramDataPoint,
 	for _, exemplar := range exemplars {
 		for _, bound := range bucketBounds {
 			if len(bound.ts.Samples) > 0 && exemplar.Value <= bound.bound {
-				bound.ts.Exemplars = append(bound.ts.Exemplars, exemplar)
+				addExemplarsToSeries(bound.ts, []prompb.Exemplar{exemplar}, settings.MaxExemplarsPerSeries)
 				break
 			}
 		}
@@ -5,9 +5,12 @@ package prometheusremotewrite
 
 import (
 	"fmt"
+	"math"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -90,6 +93,35 @@ func BenchmarkPrometheusConverter_FromMetrics(b *testing.B) {
 	}
 }
 
+func BenchmarkFromMetricsTranslationConcurrency(b *testing.B) {
+	md := createMultiResourceMetrics(64, pcommon.Timestamp(uint64(time.Now().UnixNano())))
+	for _, concurrency := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("concurrency: %v", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tsMap, err := FromMetrics(md, Settings{TranslationConcurrency: concurrency})
+				require.NoError(b, err)
+				require.NotEmpty(b, tsMap)
+			}
+		})
+	}
+}
+
+// createMultiResourceMetrics returns metrics of resourceCount resources, whose series are distinct except
+// those of every third resource, which has no attributes and shares its series with the others of its kind.
+func createMultiResourceMetrics(resourceCount int, timestamp pcommon.Timestamp) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	for i := 0; i < resourceCount; i++ {
+		request := createExportRequest(0, 20, 50, 5, 2, timestamp+pcommon.Timestamp(i)*pcommon.Timestamp(time.Millisecond))
+		if i%3 != 0 {
+			attrs := request.Metrics().ResourceMetrics().At(0).Resource().Attributes()
+			attrs.PutStr("service.name", "service")
+			attrs.PutStr("service.instance.id", fmt.Sprintf("instance-%v", i))
+		}
+		request.Metrics().ResourceMetrics().MoveAndAppendTo(md.ResourceMetrics())
+	}
+	return md
+}
+
 func createExportRequest(resourceAttributeCount int, histogramCount int, nonHistogramCount int, labelsPerMetric int, exemplarsPerSeries int, timestamp pcommon.Timestamp) pmetricotlp.ExportRequest {
 	request := pmetricotlp.NewExportRequest()
 
@@ -200,3 +232,448 @@ func TestIsSameMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestFromMetricsPromoteScopeAttributes(t *testing.T) {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	for _, team := range []string{"a", "b"} {
+		sm := rm.ScopeMetrics().AppendEmpty()
+		sm.Scope().SetName("test-scope")
+		sm.Scope().Attributes().PutStr("routing.team", team)
+		sm.Scope().Attributes().PutStr("not_promoted", "value")
+		m := sm.Metrics().AppendEmpty()
+		m.SetName("test_gauge")
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		dp.SetDoubleValue(1)
+	}
+	// Attributes of the data points take precedence over the scope ones.
+	overridden := rm.ScopeMetrics().At(1).Metrics().AppendEmpty()
+	overridden.SetName("test_overridden_gauge")
+	dp := overridden.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	dp.Attributes().PutStr("routing_team", "c")
+
+	tsMap, err := FromMetrics(md, Settings{DisableTargetInfo: true, PromoteScopeAttributes: []string{"routing.team"}})
+	require.NoError(t, err)
+
+	var teams []string
+	for _, ts := range tsMap {
+		var name, team string
+		for _, l := range ts.Labels {
+			switch l.Name {
+			case "__name__":
+				name = l.Value
+			case "routing_team":
+				team = l.Value
+			case "not_promoted":
+				assert.Fail(t, "scope attributes that aren't listed should not be added as labels")
+			}
+		}
+		teams = append(teams, name+"/"+team)
+	}
+	assert.ElementsMatch(t, []string{"test_gauge/a", "test_gauge/b", "test_overridden_gauge/c"}, teams)
+
+	// Promoted scope attributes are remapped like the data point ones.
+	tsMap, err = FromMetrics(md, Settings{
+		DisableTargetInfo:      true,
+		PromoteScopeAttributes: []string{"routing.team"},
+		LabelNameRemapping:     map[string]string{"routing.team": "team"},
+	})
+	require.NoError(t, err)
+	teams = nil
+	for _, ts := range tsMap {
+		for _, l := range ts.Labels {
+			if l.Name == "team" {
+				teams = append(teams, l.Value)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "b"}, teams)
+
+	// The remote write 2.0 translation promotes them too.
+	tsMapV2, symbolsTable, err := FromMetricsV2(md, Settings{DisableTargetInfo: true, PromoteScopeAttributes: []string{"routing.team"}})
+	require.NoError(t, err)
+	symbols := symbolsTable.Symbols()
+	teams = nil
+	for _, ts := range tsMapV2 {
+		var name, team string
+		for i := 0; i+1 < len(ts.LabelsRefs); i += 2 {
+			switch symbols[ts.LabelsRefs[i]] {
+			case "__name__":
+				name = symbols[ts.LabelsRefs[i+1]]
+			case "routing_team":
+				team = symbols[ts.LabelsRefs[i+1]]
+			}
+		}
+		teams = append(teams, name+"/"+team)
+	}
+	assert.ElementsMatch(t, []string{"test_gauge/a", "test_gauge/b", "test_overridden_gauge/c"}, teams)
+}
+
+func TestFromMetricsDroppedAttributesLabel(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for i, dropped := range []uint32{2, 0} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", fmt.Sprintf("service_%d", i))
+		rm.Resource().SetDroppedAttributesCount(dropped)
+		sm := rm.ScopeMetrics().AppendEmpty()
+		sm.Scope().SetDroppedAttributesCount(dropped / 2)
+		m := sm.Metrics().AppendEmpty()
+		m.SetName("test_gauge")
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		dp.SetDoubleValue(1)
+	}
+
+	droppedByJob := func(settings Settings) map[string]string {
+		tsMap, err := FromMetrics(md, settings)
+		require.NoError(t, err)
+		dropped := map[string]string{}
+		for _, ts := range tsMap {
+			var job string
+			for _, l := range ts.Labels {
+				switch l.Name {
+				case "job":
+					job = l.Value
+				case droppedAttributesCountLabel:
+					dropped[job] = l.Value
+				}
+			}
+		}
+		return dropped
+	}
+
+	// The label isn't added to the series whose resource and scope didn't drop attributes.
+	assert.Equal(t, map[string]string{"service_0": "3"}, droppedByJob(Settings{DisableTargetInfo: true, EmitDroppedAttributesLabel: true}))
+	assert.Empty(t, droppedByJob(Settings{DisableTargetInfo: true}))
+
+	// The remote write 2.0 translation adds it too.
+	tsMapV2, symbolsTable, err := FromMetricsV2(md, Settings{DisableTargetInfo: true, EmitDroppedAttributesLabel: true})
+	require.NoError(t, err)
+	symbols := symbolsTable.Symbols()
+	dropped := map[string]string{}
+	for _, ts := range tsMapV2 {
+		var job, count string
+		for i := 0; i+1 < len(ts.LabelsRefs); i += 2 {
+			switch symbols[ts.LabelsRefs[i]] {
+			case "job":
+				job = symbols[ts.LabelsRefs[i+1]]
+			case droppedAttributesCountLabel:
+				count = symbols[ts.LabelsRefs[i+1]]
+			}
+		}
+		if count != "" {
+			dropped[job] = count
+		}
+	}
+	assert.Equal(t, map[string]string{"service_0": "3"}, dropped)
+}
+
+func TestFromMetricsTemporalityLabel(t *testing.T) {
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	ts := pcommon.NewTimestampFromTime(time.Now())
+	sum := sm.Metrics().AppendEmpty()
+	sum.SetName("test_sum")
+	sum.SetEmptySum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	sum.Sum().DataPoints().AppendEmpty().SetTimestamp(ts)
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("test_histogram")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	histogramDP := histogram.Histogram().DataPoints().AppendEmpty()
+	histogramDP.SetTimestamp(ts)
+	histogramDP.SetSum(1)
+	// The label isn't added to the gauges, even after a sum of the same scope.
+	gauge := sm.Metrics().AppendEmpty()
+	gauge.SetName("test_gauge")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetTimestamp(ts)
+
+	temporalityByName := func(settings Settings) map[string]string {
+		tsMap, err := FromMetrics(md, settings)
+		require.NoError(t, err)
+		temporalities := map[string]string{}
+		for _, ts := range tsMap {
+			var name, temporality string
+			for _, l := range ts.Labels {
+				switch l.Name {
+				case "__name__":
+					name = l.Value
+				case "__temporality__":
+					temporality = l.Value
+				}
+			}
+			temporalities[name] = temporality
+		}
+		return temporalities
+	}
+
+	assert.Equal(t, map[string]string{
+		"test_sum":              "cumulative",
+		"test_histogram_bucket": "cumulative",
+		"test_histogram_count":  "cumulative",
+		"test_histogram_sum":    "cumulative",
+		"test_gauge":            "",
+	}, temporalityByName(Settings{DisableTargetInfo: true, TemporalityLabel: "__temporality__"}))
+	for name, temporality := range temporalityByName(Settings{DisableTargetInfo: true}) {
+		assert.Empty(t, temporality, name)
+	}
+}
+
+func TestFromMetricsExemplarMetricFilter(t *testing.T) {
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	ts := pcommon.NewTimestampFromTime(time.Now())
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("http_request_duration_seconds")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	histogramDP := histogram.Histogram().DataPoints().AppendEmpty()
+	histogramDP.SetTimestamp(ts)
+	histogramDP.ExplicitBounds().FromRaw([]float64{1})
+	histogramDP.BucketCounts().FromRaw([]uint64{1, 0})
+	histogramDP.SetCount(1)
+	histogramDP.Exemplars().AppendEmpty().SetDoubleValue(0.5)
+	counter := sm.Metrics().AppendEmpty()
+	counter.SetName("http_requests")
+	counter.SetEmptySum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	counter.Sum().SetIsMonotonic(true)
+	counterDP := counter.Sum().DataPoints().AppendEmpty()
+	counterDP.SetTimestamp(ts)
+	counterDP.SetDoubleValue(1)
+	counterDP.Exemplars().AppendEmpty().SetDoubleValue(1)
+
+	exemplarsByName := func(settings Settings) map[string]int {
+		tsMap, err := FromMetrics(md, settings)
+		require.NoError(t, err)
+		exemplars := map[string]int{}
+		for _, ts := range tsMap {
+			for _, l := range ts.Labels {
+				if l.Name == "__name__" {
+					exemplars[l.Value] += len(ts.Exemplars)
+				}
+			}
+		}
+		return exemplars
+	}
+
+	all := exemplarsByName(Settings{DisableTargetInfo: true})
+	assert.Equal(t, 1, all["http_request_duration_seconds_bucket"])
+	assert.Equal(t, 1, all["http_requests"])
+
+	// The filter matches the name of the histogram, without the suffixes of its series.
+	filtered := exemplarsByName(Settings{DisableTargetInfo: true, ExemplarMetricFilter: regexp.MustCompile("^.*_seconds$")})
+	assert.Equal(t, 1, filtered["http_request_duration_seconds_bucket"])
+	assert.Equal(t, 0, filtered["http_requests"])
+}
+
+func TestFromMetricsTargetInfoSchemaURL(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for i, schemaURL := range []string{"https://opentelemetry.io/schemas/1.26.0", ""} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.SetSchemaUrl(schemaURL)
+		rm.Resource().Attributes().PutStr("service.name", fmt.Sprintf("service_%d", i))
+		rm.Resource().Attributes().PutStr("host.name", "host")
+		m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("test_gauge")
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		dp.SetDoubleValue(1)
+	}
+
+	schemaURLByJob := func(settings Settings) map[string]string {
+		tsMap, err := FromMetrics(md, settings)
+		require.NoError(t, err)
+		schemaURLs := map[string]string{}
+		for _, ts := range tsMap {
+			var name, job, schemaURL string
+			for _, l := range ts.Labels {
+				switch l.Name {
+				case "__name__":
+					name = l.Value
+				case "job":
+					job = l.Value
+				case schemaURLLabel:
+					schemaURL = l.Value
+				}
+			}
+			if name == "target_info" {
+				schemaURLs[job] = schemaURL
+			} else {
+				assert.Empty(t, schemaURL, "only target_info should get the schema URL")
+			}
+		}
+		return schemaURLs
+	}
+
+	// The label is skipped for the resources without a schema URL.
+	assert.Equal(t, map[string]string{"service_0": "https://opentelemetry.io/schemas/1.26.0", "service_1": ""},
+		schemaURLByJob(Settings{TargetInfoIncludeSchemaURL: true}))
+	assert.Equal(t, map[string]string{"service_0": "", "service_1": ""}, schemaURLByJob(Settings{}))
+}
+
+func TestFromMetricsTimestampRounding(t *testing.T) {
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("test_gauge")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.Timestamp(1_700_000_000_123_600_000))
+	dp.SetDoubleValue(1)
+
+	for rounding, want := range map[TimestampRounding]int64{
+		"":                        1_700_000_000_123,
+		TimestampRoundingTruncate: 1_700_000_000_123,
+		TimestampRoundingNearest:  1_700_000_000_124,
+	} {
+		tsMap, err := FromMetrics(md, Settings{DisableTargetInfo: true, TimestampRounding: rounding})
+		require.NoError(t, err)
+		require.Len(t, tsMap, 1)
+		for _, ts := range tsMap {
+			assert.Equal(t, []prompb.Sample{{Value: 1, Timestamp: want}}, ts.Samples, rounding)
+		}
+	}
+}
+
+func TestFromMetricsUnsupportedMetricType(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	metrics.AppendEmpty().SetName("untyped")
+	m := metrics.AppendEmpty()
+	m.SetName("test_gauge")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.Timestamp(1_700_000_000_000_000_000))
+	dp.SetDoubleValue(1)
+
+	tsMap, err := FromMetrics(md, Settings{DisableTargetInfo: true})
+	var unsupportedErr *UnsupportedMetricTypeError
+	require.ErrorAs(t, err, &unsupportedErr)
+	assert.Equal(t, &UnsupportedMetricTypeError{MetricName: "untyped", Type: pmetric.MetricTypeEmpty}, unsupportedErr)
+	// The other metrics are still translated.
+	assert.Len(t, tsMap, 1)
+}
+
+func TestFromMetricsTranslationConcurrency(t *testing.T) {
+	md := createMultiResourceMetrics(10, pcommon.Timestamp(1_700_000_000_000_000_000))
+	// Series are compared by their labels, as the keys of the translated series are arbitrary.
+	bySignature := func(tsMap map[string]*prompb.TimeSeries) map[uint64]*prompb.TimeSeries {
+		out := make(map[uint64]*prompb.TimeSeries, len(tsMap))
+		for _, ts := range tsMap {
+			out[timeSeriesSignature(ts.Labels)] = ts
+		}
+		return out
+	}
+
+	want, err := FromMetrics(md, Settings{})
+	require.NoError(t, err)
+	for _, concurrency := range []int{2, 4, 16} {
+		got, err := FromMetrics(md, Settings{TranslationConcurrency: concurrency})
+		require.NoError(t, err)
+		require.Len(t, got, len(want))
+		// The samples of the series shared by several resources are in the order of the resources.
+		assert.Equal(t, bySignature(want), bySignature(got), "concurrency %d", concurrency)
+	}
+}
+
+func TestFromMetricsKeepNoRecordedValues(t *testing.T) {
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("test_gauge")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.Timestamp(1_700_000_000_000_000_000))
+	dp.SetDoubleValue(5)
+	dp.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+
+	for keep, want := range map[bool]uint64{
+		false: value.StaleNaN,
+		true:  math.Float64bits(5),
+	} {
+		tsMap, err := FromMetrics(md, Settings{DisableTargetInfo: true, KeepNoRecordedValues: keep})
+		require.NoError(t, err)
+		require.Len(t, tsMap, 1)
+		for _, ts := range tsMap {
+			require.Len(t, ts.Samples, 1)
+			assert.Equal(t, want, math.Float64bits(ts.Samples[0].Value), "keep no recorded values: %v", keep)
+		}
+	}
+}
+
+func TestFromMetricsNameConflictPolicy(t *testing.T) {
+	newMetrics := func(metricType pmetric.MetricType) pmetric.Metrics {
+		md := pmetric.NewMetrics()
+		m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("translated")
+		var attrs pcommon.Map
+		//exhaustive:enforce
+		switch metricType {
+		case pmetric.MetricTypeGauge:
+			dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+			dp.SetTimestamp(pcommon.Timestamp(1_700_000_000_000_000_000))
+			dp.SetDoubleValue(1)
+			attrs = dp.Attributes()
+		case pmetric.MetricTypeHistogram:
+			m.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			dp := m.Histogram().DataPoints().AppendEmpty()
+			dp.SetTimestamp(pcommon.Timestamp(1_700_000_000_000_000_000))
+			dp.SetCount(1)
+			dp.SetSum(1)
+			dp.ExplicitBounds().FromRaw([]float64{1})
+			dp.BucketCounts().FromRaw([]uint64{1, 0})
+			attrs = dp.Attributes()
+		case pmetric.MetricTypeSummary:
+			dp := m.SetEmptySummary().DataPoints().AppendEmpty()
+			dp.SetTimestamp(pcommon.Timestamp(1_700_000_000_000_000_000))
+			dp.SetCount(1)
+			dp.SetSum(1)
+			quantile := dp.QuantileValues().AppendEmpty()
+			quantile.SetQuantile(0.5)
+			quantile.SetValue(1)
+			attrs = dp.Attributes()
+		case pmetric.MetricTypeEmpty, pmetric.MetricTypeSum, pmetric.MetricTypeExponentialHistogram:
+			t.Fatalf("unexpected metric type %v", metricType)
+		}
+		attrs.PutStr("__name__", "incoming")
+		return md
+	}
+
+	tests := []struct {
+		policy   NameConflictPolicy
+		wantName string
+	}{
+		{policy: "", wantName: "translated"},
+		{policy: NameConflictPolicyOverride, wantName: "translated"},
+		{policy: NameConflictPolicyPreserve, wantName: "incoming"},
+		{policy: NameConflictPolicyError},
+	}
+	metricTypes := map[pmetric.MetricType][]string{
+		pmetric.MetricTypeGauge:     {""},
+		pmetric.MetricTypeHistogram: {"_sum", "_count", "_bucket", "_bucket"},
+		pmetric.MetricTypeSummary:   {"_sum", "_count", ""},
+	}
+	for metricType, suffixes := range metricTypes {
+		for _, tt := range tests {
+			t.Run(metricType.String()+"/"+string(tt.policy), func(t *testing.T) {
+				tsMap, err := FromMetrics(newMetrics(metricType), Settings{DisableTargetInfo: true, NameConflictPolicy: tt.policy})
+				if tt.policy == NameConflictPolicyError {
+					var conflictErr *NameConflictError
+					require.ErrorAs(t, err, &conflictErr)
+					assert.Equal(t, &NameConflictError{Name: "translated", Attribute: "incoming"}, conflictErr)
+					assert.Empty(t, tsMap)
+					return
+				}
+				require.NoError(t, err)
+				var wantNames, gotNames []string
+				for _, suffix := range suffixes {
+					wantNames = append(wantNames, tt.wantName+suffix)
+				}
+				for _, ts := range tsMap {
+					for _, label := range ts.Labels {
+						if label.Name == "__name__" {
+							gotNames = append(gotNames, label.Value)
+						}
+					}
+				}
+				// Every series has a single __name__.
+				assert.ElementsMatch(t, wantNames, gotNames)
+			})
+		}
+	}
+}
@@ -35,12 +35,17 @@ type prometheusConverterV2 struct {
 	// TODO handle conflicts
 	unique      map[uint64]*writev2.TimeSeries
 	symbolTable writev2.SymbolsTable
+	interner    *labelValueInterner
+	// scopeLabels holds the promoted attributes of the scope whose metrics are being converted, and its
+	// dropped attributes count label.
+	scopeLabels []prompb.Label
 }
 
 func newPrometheusConverterV2() *prometheusConverterV2 {
 	return &prometheusConverterV2{
 		unique:      map[uint64]*writev2.TimeSeries{},
 		symbolTable: writev2.NewSymbolTable(),
+		interner:    newLabelValueInterner(),
 	}
 }
 
@@ -55,7 +60,17 @@ func (c *prometheusConverterV2) fromMetrics(md pmetric.Metrics, settings Setting
 		// use with the "target" info metric
 		var mostRecentTimestamp pcommon.Timestamp
 		for j := 0; j < scopeMetricsSlice.Len(); j++ {
-			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			scopeMetrics := scopeMetricsSlice.At(j)
+			c.scopeLabels = promotedScopeLabels(c.interner, scopeMetrics.Scope(), settings)
+			if settings.EmitDroppedAttributesLabel {
+				if dropped := resource.DroppedAttributesCount() + scopeMetrics.Scope().DroppedAttributesCount(); dropped > 0 {
+					c.scopeLabels = append(c.scopeLabels, prompb.Label{
+						Name:  droppedAttributesCountLabel,
+						Value: c.interner.intern(strconv.FormatUint(uint64(dropped), 10)),
+					})
+				}
+			}
+			metricSlice := scopeMetrics.Metrics()
 
 			// TODO: decide if instrumentation library information should be exported as labels
 			for k := 0; k < metricSlice.Len(); k++ {
@@ -67,7 +82,7 @@ func (c *prometheusConverterV2) fromMetrics(md pmetric.Metrics, settings Setting
 					continue
 				}
 
-				promName := prometheustranslator.BuildCompliantName(metric, settings.Namespace, settings.AddMetricSuffixes)
+				promName := prometheustranslator.BuildCompliantNameWithUnitSuffixes(metric, settings.Namespace, settings.AddMetricSuffixes, settings.UnitSuffixes)
 
 				// handle individual metrics based on type
 				//exhaustive:enforce
@@ -78,7 +93,7 @@ func (c *prometheusConverterV2) fromMetrics(md pmetric.Metrics, settings Setting
 						errs = multierr.Append(errs, fmt.Errorf("empty data points. %s is dropped", metric.Name()))
 						break
 					}
-					c.addGaugeNumberDataPoints(dataPoints, resource, settings, promName)
+					errs = multierr.Append(errs, c.addGaugeNumberDataPoints(dataPoints, resource, settings, promName))
 				case pmetric.MetricTypeSum:
 					// TODO implement
 				case pmetric.MetricTypeHistogram:
@@ -29,7 +29,7 @@ func TestFromMetricsV2(t *testing.T) {
 			"0": {
 				LabelsRefs: []uint32{1, 2, 3, 4, 5, 6, 7, 8},
 				Samples: []writev2.Sample{
-					{Timestamp: convertTimeStamp(pcommon.Timestamp(ts)), Value: 1.23},
+					{Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate), Value: 1.23},
 				},
 			},
 		}
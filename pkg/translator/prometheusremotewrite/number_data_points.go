@@ -11,25 +11,34 @@ import (
 	"github.com/prometheus/prometheus/prompb"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/multierr"
 )
 
+// addGaugeNumberDataPoints adds a sample per data point of a gauge. The start time of gauges isn't meaningful, so
+// no _created series is added for them, whatever settings.ExportCreatedMetric.
 func (c *prometheusConverter) addGaugeNumberDataPoints(dataPoints pmetric.NumberDataPointSlice,
 	resource pcommon.Resource, settings Settings, name string,
-) {
+) (errs error) {
 	for x := 0; x < dataPoints.Len(); x++ {
 		pt := dataPoints.At(x)
-		labels := createAttributes(
+		labels, err := createAttributes(
+			c.interner,
 			resource,
 			pt.Attributes(),
-			settings.ExternalLabels,
+			c.scopeLabels,
+			settings,
 			nil,
 			true,
 			model.MetricNameLabel,
 			name,
 		)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
 		sample := &prompb.Sample{
 			// convert ns to ms
-			Timestamp: convertTimeStamp(pt.Timestamp()),
+			Timestamp: convertTimeStamp(pt.Timestamp(), settings.TimestampRounding),
 		}
 		switch pt.ValueType() {
 		case pmetric.NumberDataPointValueTypeInt:
@@ -37,30 +46,37 @@ func (c *prometheusConverter) addGaugeNumberDataPoints(dataPoints pmetric.Number
 		case pmetric.NumberDataPointValueTypeDouble:
 			sample.Value = pt.DoubleValue()
 		}
-		if pt.Flags().NoRecordedValue() {
+		if staleMarker(pt.Flags(), settings) {
 			sample.Value = math.Float64frombits(value.StaleNaN)
 		}
 		c.addSample(sample, labels)
 	}
+	return errs
 }
 
 func (c *prometheusConverter) addSumNumberDataPoints(dataPoints pmetric.NumberDataPointSlice,
 	resource pcommon.Resource, metric pmetric.Metric, settings Settings, name string,
-) {
+) (errs error) {
 	for x := 0; x < dataPoints.Len(); x++ {
 		pt := dataPoints.At(x)
-		lbls := createAttributes(
+		lbls, err := createAttributes(
+			c.interner,
 			resource,
 			pt.Attributes(),
-			settings.ExternalLabels,
+			c.scopeLabels,
+			settings,
 			nil,
 			true,
 			model.MetricNameLabel,
 			name,
 		)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
 		sample := &prompb.Sample{
 			// convert ns to ms
-			Timestamp: convertTimeStamp(pt.Timestamp()),
+			Timestamp: convertTimeStamp(pt.Timestamp(), settings.TimestampRounding),
 		}
 		switch pt.ValueType() {
 		case pmetric.NumberDataPointValueTypeInt:
@@ -68,20 +84,20 @@ func (c *prometheusConverter) addSumNumberDataPoints(dataPoints pmetric.NumberDa
 		case pmetric.NumberDataPointValueTypeDouble:
 			sample.Value = pt.DoubleValue()
 		}
-		if pt.Flags().NoRecordedValue() {
+		if staleMarker(pt.Flags(), settings) {
 			sample.Value = math.Float64frombits(value.StaleNaN)
 		}
 		ts := c.addSample(sample, lbls)
 		if ts != nil {
-			exemplars := getPromExemplars[pmetric.NumberDataPoint](pt)
-			ts.Exemplars = append(ts.Exemplars, exemplars...)
+			exemplars := getPromExemplars[pmetric.NumberDataPoint](pt, settings)
+			addExemplarsToSeries(ts, exemplars, settings.MaxExemplarsPerSeries)
 		}
 
 		// add created time series if needed
 		if settings.ExportCreatedMetric && metric.Sum().IsMonotonic() {
 			startTimestamp := pt.StartTimestamp()
 			if startTimestamp == 0 {
-				return
+				return errs
 			}
 
 			createdLabels := make([]prompb.Label, len(lbls))
@@ -92,7 +108,8 @@ func (c *prometheusConverter) addSumNumberDataPoints(dataPoints pmetric.NumberDa
 					break
 				}
 			}
-			c.addTimeSeriesIfNeeded(createdLabels, startTimestamp, pt.Timestamp())
+			c.addTimeSeriesIfNeeded(createdLabels, startTimestamp, pt.Timestamp(), settings.TimestampRounding)
 		}
 	}
+	return errs
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 )
@@ -17,9 +18,10 @@ import (
 func TestPrometheusConverter_addGaugeNumberDataPoints(t *testing.T) {
 	ts := uint64(time.Now().UnixNano())
 	tests := []struct {
-		name   string
-		metric func() pmetric.Metric
-		want   func() map[uint64]*prompb.TimeSeries
+		name     string
+		metric   func() pmetric.Metric
+		settings Settings
+		want     func() map[uint64]*prompb.TimeSeries
 	}{
 		{
 			name: "gauge",
@@ -40,7 +42,37 @@ func TestPrometheusConverter_addGaugeNumberDataPoints(t *testing.T) {
 						Samples: []prompb.Sample{
 							{
 								Value:     1,
-								Timestamp: convertTimeStamp(pcommon.Timestamp(ts)),
+								Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate),
+							},
+						},
+					},
+				}
+			},
+		},
+		{
+			name: "gauge with start timestamp and created metric enabled",
+			metric: func() pmetric.Metric {
+				metric := getIntGaugeMetric(
+					"test",
+					pcommon.NewMap(),
+					1, ts,
+				)
+				metric.Gauge().DataPoints().At(0).SetStartTimestamp(pcommon.Timestamp(ts - uint64(time.Minute)))
+				return metric
+			},
+			settings: Settings{ExportCreatedMetric: true},
+			want: func() map[uint64]*prompb.TimeSeries {
+				// Gauges have no start time, no _created series is added for them.
+				labels := []prompb.Label{
+					{Name: model.MetricNameLabel, Value: "test"},
+				}
+				return map[uint64]*prompb.TimeSeries{
+					timeSeriesSignature(labels): {
+						Labels: labels,
+						Samples: []prompb.Sample{
+							{
+								Value:     1,
+								Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate),
 							},
 						},
 					},
@@ -53,12 +85,12 @@ func TestPrometheusConverter_addGaugeNumberDataPoints(t *testing.T) {
 			metric := tt.metric()
 			converter := newPrometheusConverter()
 
-			converter.addGaugeNumberDataPoints(
+			require.NoError(t, converter.addGaugeNumberDataPoints(
 				metric.Gauge().DataPoints(),
 				pcommon.NewResource(),
-				Settings{},
+				tt.settings,
 				metric.Name(),
-			)
+			))
 
 			assert.Equal(t, tt.want(), converter.unique)
 			assert.Empty(t, converter.conflicts)
@@ -93,7 +125,7 @@ func TestPrometheusConverter_addSumNumberDataPoints(t *testing.T) {
 						Samples: []prompb.Sample{
 							{
 								Value:     1,
-								Timestamp: convertTimeStamp(ts),
+								Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate),
 							},
 						},
 					},
@@ -121,7 +153,7 @@ func TestPrometheusConverter_addSumNumberDataPoints(t *testing.T) {
 						Labels: labels,
 						Samples: []prompb.Sample{{
 							Value:     1,
-							Timestamp: convertTimeStamp(ts),
+							Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate),
 						}},
 						Exemplars: []prompb.Exemplar{
 							{Value: 2},
@@ -156,13 +188,13 @@ func TestPrometheusConverter_addSumNumberDataPoints(t *testing.T) {
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 1, Timestamp: convertTimeStamp(ts)},
+							{Value: 1, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 					timeSeriesSignature(createdLabels): {
 						Labels: createdLabels,
 						Samples: []prompb.Sample{
-							{Value: float64(convertTimeStamp(ts)), Timestamp: convertTimeStamp(ts)},
+							{Value: float64(convertTimeStamp(ts, TimestampRoundingTruncate)), Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -189,7 +221,7 @@ func TestPrometheusConverter_addSumNumberDataPoints(t *testing.T) {
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -216,7 +248,7 @@ func TestPrometheusConverter_addSumNumberDataPoints(t *testing.T) {
 					timeSeriesSignature(labels): {
 						Labels: labels,
 						Samples: []prompb.Sample{
-							{Value: 0, Timestamp: convertTimeStamp(ts)},
+							{Value: 0, Timestamp: convertTimeStamp(ts, TimestampRoundingTruncate)},
 						},
 					},
 				}
@@ -228,13 +260,13 @@ func TestPrometheusConverter_addSumNumberDataPoints(t *testing.T) {
 			metric := tt.metric()
 			converter := newPrometheusConverter()
 
-			converter.addSumNumberDataPoints(
+			require.NoError(t, converter.addSumNumberDataPoints(
 				metric.Sum().DataPoints(),
 				pcommon.NewResource(),
 				metric,
 				Settings{ExportCreatedMetric: true},
 				metric.Name(),
-			)
+			))
 
 			assert.Equal(t, tt.want(), converter.unique)
 			assert.Empty(t, converter.conflicts)
@@ -11,27 +11,34 @@ import (
 	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/multierr"
 )
 
 func (c *prometheusConverterV2) addGaugeNumberDataPoints(dataPoints pmetric.NumberDataPointSlice,
 	resource pcommon.Resource, settings Settings, name string,
-) {
+) (errs error) {
 	for x := 0; x < dataPoints.Len(); x++ {
 		pt := dataPoints.At(x)
 
-		labels := createAttributes(
+		labels, err := createAttributes(
+			c.interner,
 			resource,
 			pt.Attributes(),
-			settings.ExternalLabels,
+			c.scopeLabels,
+			settings,
 			nil,
 			true,
 			model.MetricNameLabel,
 			name,
 		)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
 
 		sample := &writev2.Sample{
 			// convert ns to ms
-			Timestamp: convertTimeStamp(pt.Timestamp()),
+			Timestamp: convertTimeStamp(pt.Timestamp(), settings.TimestampRounding),
 		}
 		switch pt.ValueType() {
 		case pmetric.NumberDataPointValueTypeInt:
@@ -39,9 +46,10 @@ func (c *prometheusConverterV2) addGaugeNumberDataPoints(dataPoints pmetric.Numb
 		case pmetric.NumberDataPointValueTypeDouble:
 			sample.Value = pt.DoubleValue()
 		}
-		if pt.Flags().NoRecordedValue() {
+		if staleMarker(pt.Flags(), settings) {
 			sample.Value = math.Float64frombits(value.StaleNaN)
 		}
 		c.addSample(sample, labels)
 	}
+	return errs
 }
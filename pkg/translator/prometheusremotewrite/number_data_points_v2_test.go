@@ -14,6 +14,7 @@ import (
 	"github.com/prometheus/prometheus/model/value"
 	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 )
@@ -45,7 +46,7 @@ func TestPrometheusConverterV2_addGaugeNumberDataPoints(t *testing.T) {
 					labels.Hash(): {
 						LabelsRefs: []uint32{1, 2},
 						Samples: []writev2.Sample{
-							{Timestamp: convertTimeStamp(pcommon.Timestamp(ts)), Value: 1},
+							{Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate), Value: 1},
 						},
 					},
 				}
@@ -71,7 +72,7 @@ func TestPrometheusConverterV2_addGaugeNumberDataPoints(t *testing.T) {
 					labels.Hash(): {
 						LabelsRefs: []uint32{1, 2},
 						Samples: []writev2.Sample{
-							{Timestamp: convertTimeStamp(pcommon.Timestamp(ts)), Value: 1.5},
+							{Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate), Value: 1.5},
 						},
 					},
 				}
@@ -97,7 +98,7 @@ func TestPrometheusConverterV2_addGaugeNumberDataPoints(t *testing.T) {
 					labels.Hash(): {
 						LabelsRefs: []uint32{1, 2},
 						Samples: []writev2.Sample{
-							{Timestamp: convertTimeStamp(pcommon.Timestamp(ts)), Value: math.Float64frombits(value.StaleNaN)},
+							{Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate), Value: math.Float64frombits(value.StaleNaN)},
 						},
 					},
 				}
@@ -117,7 +118,7 @@ func TestPrometheusConverterV2_addGaugeNumberDataPoints(t *testing.T) {
 				SendMetadata:        false,
 			}
 			converter := newPrometheusConverterV2()
-			converter.addGaugeNumberDataPoints(metric.Gauge().DataPoints(), pcommon.NewResource(), settings, metric.Name())
+			require.NoError(t, converter.addGaugeNumberDataPoints(metric.Gauge().DataPoints(), pcommon.NewResource(), settings, metric.Name()))
 			w := tt.want()
 
 			diff := cmp.Diff(w, converter.unique, cmpopts.EquateNaNs())
@@ -151,7 +152,7 @@ func TestPrometheusConverterV2_addGaugeNumberDataPointsDuplicate(t *testing.T) {
 			labels.Hash(): {
 				LabelsRefs: []uint32{1, 2},
 				Samples: []writev2.Sample{
-					{Timestamp: convertTimeStamp(pcommon.Timestamp(ts)), Value: 2},
+					{Timestamp: convertTimeStamp(pcommon.Timestamp(ts), TimestampRoundingTruncate), Value: 2},
 				},
 			},
 		}
@@ -167,8 +168,8 @@ func TestPrometheusConverterV2_addGaugeNumberDataPointsDuplicate(t *testing.T) {
 	}
 
 	converter := newPrometheusConverterV2()
-	converter.addGaugeNumberDataPoints(metric1.Gauge().DataPoints(), pcommon.NewResource(), settings, metric1.Name())
-	converter.addGaugeNumberDataPoints(metric2.Gauge().DataPoints(), pcommon.NewResource(), settings, metric2.Name())
+	require.NoError(t, converter.addGaugeNumberDataPoints(metric1.Gauge().DataPoints(), pcommon.NewResource(), settings, metric1.Name()))
+	require.NoError(t, converter.addGaugeNumberDataPoints(metric2.Gauge().DataPoints(), pcommon.NewResource(), settings, metric2.Name()))
 
 	assert.Equal(t, want(), converter.unique)
 }
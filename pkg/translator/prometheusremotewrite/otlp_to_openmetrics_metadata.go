@@ -43,6 +43,12 @@ func otelMetricTypeToPromMetricType(otelMetric pmetric.Metric) prompb.MetricMeta
 }
 
 func OtelMetricsToMetadata(md pmetric.Metrics, addMetricSuffixes bool) []*prompb.MetricMetadata {
+	return OtelMetricsToMetadataWithUnitSuffixes(md, addMetricSuffixes, prometheustranslator.UnitSuffixes{})
+}
+
+// OtelMetricsToMetadataWithUnitSuffixes is like OtelMetricsToMetadata, naming the metric families the
+// way FromMetrics does with the same unit suffixes settings.
+func OtelMetricsToMetadataWithUnitSuffixes(md pmetric.Metrics, addMetricSuffixes bool, unitSuffixes prometheustranslator.UnitSuffixes) []*prompb.MetricMetadata {
 	resourceMetricsSlice := md.ResourceMetrics()
 
 	metadataLength := 0
@@ -64,7 +70,7 @@ func OtelMetricsToMetadata(md pmetric.Metrics, addMetricSuffixes bool) []*prompb
 				metric := scopeMetrics.Metrics().At(k)
 				entry := prompb.MetricMetadata{
 					Type:             otelMetricTypeToPromMetricType(metric),
-					MetricFamilyName: prometheustranslator.BuildCompliantName(metric, "", addMetricSuffixes),
+					MetricFamilyName: prometheustranslator.BuildCompliantNameWithUnitSuffixes(metric, "", addMetricSuffixes, unitSuffixes),
 					Help:             metric.Description(),
 				}
 				metadata = append(metadata, &entry)